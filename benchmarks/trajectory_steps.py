@@ -0,0 +1,59 @@
+"""Times integration steps of the trajectory loop
+
+Compares the Vector update of one step done with operators that allocate new Vectors
+(the integration loop before Vector.add_in_place and Vector.mul_add_in_place)
+to the in-place update the loop uses now, and times a whole 1000 yd solve per step.
+
+Usage, from the root of the repository: python -m benchmarks.trajectory_steps [repeat]
+"""
+import sys
+import timeit
+
+from py_ballisticcalc import Ammo, Calculator, DragModel, Distance, Shot, TableG7, TrajectoryHooks, Velocity, Weapon
+from py_ballisticcalc.trajectory_calc import Vector
+
+STEPS = 10000  # Vector updates per timing of step_allocating() and step_in_place()
+
+
+def step_allocating(velocity_vector: Vector, range_vector: Vector, velocity_adjusted: Vector,
+                    gravity_vector: Vector, drag: float, delta_time: float, calc_step: float) -> None:
+    for _ in range(STEPS):
+        velocity_vector -= (velocity_adjusted * drag - gravity_vector) * delta_time
+        range_vector += Vector(calc_step, velocity_vector.y * delta_time, velocity_vector.z * delta_time)
+
+
+def step_in_place(velocity_vector: Vector, range_vector: Vector, velocity_adjusted: Vector,
+                  gravity_vector: Vector, drag: float, delta_time: float, calc_step: float) -> None:
+    for _ in range(STEPS):
+        velocity_vector.mul_add_in_place(velocity_adjusted, -drag * delta_time)
+        velocity_vector.mul_add_in_place(gravity_vector, delta_time)
+        range_vector.add_in_place(Vector(calc_step, velocity_vector.y * delta_time, velocity_vector.z * delta_time))
+
+
+def time_vector_updates(repeat: int) -> tuple[float, float]:
+    """:return: best seconds per step of step_allocating() and of step_in_place()"""
+    def args():
+        return Vector(2750, 0, 0), Vector(0, 0, 0), Vector(2750, 0, 0), Vector(0, -32.17405, 0), 1e-5, 1e-4, 0.25
+    allocating = min(timeit.repeat(lambda: step_allocating(*args()), number=1, repeat=repeat)) / STEPS
+    in_place = min(timeit.repeat(lambda: step_in_place(*args()), number=1, repeat=repeat)) / STEPS
+    return allocating, in_place
+
+
+def time_solve(repeat: int) -> tuple[float, int]:
+    """:return: best seconds of a 1000 yd solve and its number of integration steps"""
+    shot = Shot(weapon=Weapon(2), ammo=Ammo(DragModel(0.223, TableG7, 168, 0.308, 1.282), Velocity.FPS(2750)))
+    steps = []
+    Calculator(TrajectoryHooks(on_step=steps.append)).fire(shot, Distance.Yard(1000), Distance.Yard(100))
+    calc = Calculator()
+    seconds = min(timeit.repeat(lambda: calc.fire(shot, Distance.Yard(1000), Distance.Yard(100)),
+                                number=1, repeat=repeat))
+    return seconds, len(steps)
+
+
+if __name__ == '__main__':
+    repeat = int(sys.argv[1]) if len(sys.argv) > 1 else 20
+    allocating, in_place = time_vector_updates(repeat)
+    print(f"Vector update per step: allocating {allocating * 1e6:.2f} us, in place {in_place * 1e6:.2f} us "
+          f"({(1 - in_place / allocating) * 100:.0f}% less)")
+    seconds, steps = time_solve(repeat)
+    print(f"1000 yd solve: {seconds:.3f} s for {steps} steps, {seconds / steps * 1e6:.2f} us per step")
@@ -11,9 +11,35 @@ __credits__ = ["o-murphy", "dbookstaber"]
 import os
 
 from .backend import *
+from .convergence import *
+from .config import *
 from .drag_tables import *
 from .drag_model import *
+from .hooks import *
 from .interface import *
+from .pejsa import *
+from .mpm import *
+from .siacci import *
+from .analytic import *
+from .reference import *
+from .incline import *
+from .localization import *
+from .export import *
+from .dope import *
+from .chart import *
+from .engagement import *
+from .stability import *
+from .comparison import *
+from .chronograph import *
+from .envelope import *
+from .leads import *
+from .mcdrag import *
+from .acoustics import *
+from .sight_in import *
+from .truing import *
+from .solution import *
+from .dispersion import *
+from .reticle import *
 from .logger import logger
 from .trajectory_data import *
 from .conditions import *
@@ -81,6 +107,14 @@ def _load_config(filepath=None):
 
                     if use_powder_sensitivity := calculator.get('use_powder_sensitivity'):
                         set_global_use_powder_sensitivity(use_powder_sensitivity)
+
+                    if (gravity := calculator.get('gravity')) is not None:
+                        try:
+                            _val = gravity.get("value")
+                            _units = Unit[gravity.get("units")]
+                            set_global_gravity(_units(_val))
+                        except (KeyError, TypeError, ValueError):
+                            logger.warning("Wrong gravity units or value")
                 else:
                     logger.warning("Config has not `pybc.calculator` section")
             else:
@@ -92,20 +126,23 @@ def _load_config(filepath=None):
 def _basic_config(filename=None,
                   max_calc_step_size: [float, Distance] = None,
                   use_powder_sensitivity: bool = False,
-                  preferred_units: dict[str, Unit] = None):
+                  preferred_units: dict[str, Unit] = None,
+                  gravity: [float, Acceleration] = None):
 
     """
     Method to load preferred units from file or Mapping
     """
-    if filename and (preferred_units or max_calc_step_size or use_powder_sensitivity):
+    if filename and (preferred_units or max_calc_step_size or use_powder_sensitivity or gravity is not None):
         raise ValueError("Can't use preferred_units and config file at same time")
-    if not filename and (preferred_units or max_calc_step_size or use_powder_sensitivity):
+    if not filename and (preferred_units or max_calc_step_size or use_powder_sensitivity or gravity is not None):
         if preferred_units:
             PreferredUnits.set(**preferred_units)
         if max_calc_step_size:
             set_global_max_calc_step_size(max_calc_step_size)
         if use_powder_sensitivity:
             set_global_use_powder_sensitivity(use_powder_sensitivity)
+        if gravity is not None:
+            set_global_gravity(gravity)
     else:
         # trying to load definitions from pybc.toml
         _load_config(filename)
@@ -117,26 +154,137 @@ basicConfig()
 
 __all__ = [
     'Calculator',
+    'CalculatorConfig',
+    'AdjustmentReference',
+    'SpinDriftModel',
+    'TransonicDegradation',
+    'relative_angle_sweep',
+    'muzzle_velocity_sweep',
+    'shot_string_sweep',
+    'ShotString',
+    'ShotScenario',
     'basicConfig',
     'logger',
     'TrajectoryCalc',
+    'PejsaCalc',
+    'ModifiedPointMassCalc',
+    'SiacciCalc',
+    'VacuumCalc',
+    'ConstantDragCalc',
+    'ReferenceRow',
+    'ReferenceTrajectory',
+    'Residual',
+    'ResidualStats',
+    'ReferenceComparison',
+    'InclineHolds',
+    'incline_holds',
+    'Localization',
+    'Locales',
+    'to_record_batch',
+    'to_arrow_table',
+    'write_parquet',
+    'write_csv',
+    'DopeBook',
+    'DopeScenario',
+    'DopeSolution',
+    'DopeImpact',
+    'DopeEntry',
+    'render_svg',
+    'render_png',
+    'save_chart',
+    'CHART_QUANTITIES',
+    'EngagementPoint',
+    'EngagementLimit',
+    'max_engagement_distance',
+    'miller_stability',
+    'TwistRequirement',
+    'TwistRecommendation',
+    'recommend_twist',
+    'AmmoComparisonEntry',
+    'AmmoComparison',
+    'compare_ammo',
+    'LoadDelta',
+    'LoadComparisonEntry',
+    'LoadComparison',
+    'compare_loads',
+    'BCMeasurement',
+    'measure_bc',
+    'ChronographString',
+    'read_labradar',
+    'read_magnetospeed',
+    'read_garmin_xero',
+    'read_chronograph',
+    'ElevationSweep',
+    'ReachEnvelope',
+    'elevation_envelope',
+    'TARGET_SPEEDS',
+    'LeadRow',
+    'lead_holds',
+    'lead_card',
+    'BoundaryLayer',
+    'ProjectileGeometry',
+    'DragBreakdown',
+    'drag_breakdown',
+    'estimate_drag_table',
+    'estimate_drag_model',
+    'SoundArrival',
+    'sound_arrivals',
+    'range_from_crack_thump',
+    'SightInCorrection',
+    'sight_in_correction',
+    'rezero_correction',
+    'TRUING_PARAMETERS',
+    'DropObservation',
+    'TruingResult',
+    'true_ammo',
+    'FiringSolution',
+    'firing_solution',
+    'ShotUncertainty',
+    'SampledImpact',
+    'ImpactDistribution',
+    'sample_impacts',
+    'Reticle',
+    'HoldoverMark',
+    'reticle_holdovers',
+    'holdover_card',
     'get_global_max_calc_step_size',
     'get_global_use_powder_sensitivity',
     'set_global_max_calc_step_size',
     'set_global_use_powder_sensitivity',
+    'get_global_gravity',
+    'set_global_gravity',
     'reset_globals',
     'DragModel',
     'DragDataPoint',
     'BCPoint',
+    'BCReference',
+    'sectional_density',
+    'form_factor',
+    'convert_bc',
     'DragModelMultiBC',
+    'DragModelCD',
     'TrajectoryData',
+    'TrajectoryColumns',
     'HitResult',
+    'RangeError',
     'TrajFlag',
+    'StepState',
+    'StepConvergence',
+    'step_size_convergence',
+    'TrajectoryHooks',
+    'CancelContext',
+    'TrajectoryCancelledError',
+    'AtmosphereModel',
     'Atmo',
     'Wind',
+    'WindLayer',
     'Shot',
+    'Gravity',
     'Weapon',
     'Ammo',
+    'ProjectilePhase',
+    'PowderTemperaturePoint',
+    'AeroCoefficients',
     'Sight',
     'Unit',
     'UnitType',
@@ -144,6 +292,10 @@ __all__ = [
     'UnitAliasError',
     'UnitTypeError',
     'UnitConversionError',
+    'UnitJSONEncoder',
+    'unit_json_hook',
+    'IMPERIAL_UNITS',
+    'METRIC_UNITS',
     'AbstractUnit',
     'AbstractUnitType',
     'UnitProps',
@@ -154,10 +306,16 @@ __all__ = [
     'Temperature',
     'Pressure',
     'Energy',
+    'Time',
+    'Density',
+    'Acceleration',
     'Weight',
     'Dimension',
     'PreferredUnits',
-    'get_drag_tables_names'
+    'get_drag_tables_names',
+    'register_drag_table',
+    'get_drag_table',
+    'registered_drag_tables',
 ]
 
 __all__ += ["TableG%s" % n for n in (1, 7, 2, 5, 6, 8, 'I', 'S')]
@@ -0,0 +1,98 @@
+"""Sound arrival times along a trajectory: the supersonic crack of the bullet and the muzzle report ("thump")"""
+import math
+from typing import Iterable, NamedTuple, Optional
+
+from .conditions import Shot
+from .interface import Calculator
+from .unit import Angular, Distance, Velocity, PreferredUnits
+
+__all__ = ('SoundArrival', 'sound_arrivals', 'range_from_crack_thump')
+
+cCrackSubdivisions = 500  # Trajectory rows over the farthest range, searched for the origin of the crack
+
+
+class SoundArrival(NamedTuple):
+    """
+    Sounds heard by a listener beside the trajectory at one range
+
+    Attributes:
+        distance (Distance): downrange distance of the listener
+        bullet_time (float): seconds from the shot until the bullet passes the listener
+        report_time (float): seconds from the shot until the muzzle report reaches the listener
+        crack_time (float): seconds from the shot until the bullet's shock wave reaches the listener;
+            None if the bullet never went supersonic before this range
+        crack_thump (float): seconds between crack and report, as timed by the listener; None without a crack
+        mach (float): bullet velocity in Mach at this range
+        mach_angle (Angular): half-angle of the bullet's shock cone at this range; None if subsonic
+        crack_origin (Distance): downrange distance from which the crack heard by the listener was emitted
+    """
+    distance: Distance
+    bullet_time: float
+    report_time: float
+    crack_time: Optional[float]
+    crack_thump: Optional[float]
+    mach: float
+    mach_angle: Optional[Angular]
+    crack_origin: Optional[Distance]
+
+
+def _position(row) -> tuple[float, float, float]:
+    return row.distance >> Distance.Foot, row.height >> Distance.Foot, row.windage >> Distance.Foot
+
+
+def sound_arrivals(calc: Calculator, shot: Shot, ranges: Iterable[[float, Distance]],
+                   offset: [float, Distance] = 0) -> list[SoundArrival]:
+    """Sound propagates straight at the speed of sound of shot.atmo (at the shooter), in still air.
+        The crack heard is the first shock wave to arrive: from the point of the supersonic trajectory
+        whose emission reaches the listener earliest, which lies on the bullet's Mach cone.
+    :param calc: Calculator to solve the trajectory
+    :param shot: zeroed shot
+    :param ranges: downrange distances of the listeners, as for Calculator.fire_at_ranges()
+    :param offset: distance of each listener to the side of the bullet path, default 0 (at the bullet's path)
+    """
+    ranges = [PreferredUnits.distance(r) for r in ranges]
+    if not ranges:
+        raise ValueError("At least one range is required")
+    offset = PreferredUnits.distance(offset) >> Distance.Foot
+    speed_of_sound = shot.atmo.mach >> Velocity.FPS
+    result = calc.fire_at_ranges(shot, ranges)
+    rows = result.trajectory
+    if len(rows) < len(ranges):
+        raise result.range_error(ranges[len(rows)])
+    farthest = max(ranges, key=lambda r: r.raw_value)
+    path = calc.fire(shot, farthest, farthest.unit_value / cCrackSubdivisions).trajectory
+    muzzle = _position(path[0])
+
+    arrivals = []
+    for row in rows:
+        x, y, z = _position(row)
+        listener = (x, y, z + offset)
+        report_time = math.dist(muzzle, listener) / speed_of_sound
+        crack_time, crack_origin = None, None
+        for emitter in [p for p in path if p.mach >= 1 and p.distance.raw_value < row.distance.raw_value] + [row]:
+            if emitter.mach < 1:
+                continue
+            arrival = emitter.time + math.dist(_position(emitter), listener) / speed_of_sound
+            if crack_time is None or arrival < crack_time:
+                crack_time, crack_origin = arrival, emitter.distance
+        mach_angle = Angular.Radian(math.asin(1 / row.mach)) << PreferredUnits.angular if row.mach > 1 else None
+        arrivals.append(SoundArrival(
+            row.distance << PreferredUnits.distance, row.time, report_time, crack_time,
+            None if crack_time is None else report_time - crack_time, row.mach, mach_angle,
+            None if crack_origin is None else crack_origin << PreferredUnits.distance))
+    return arrivals
+
+
+def range_from_crack_thump(arrivals: list[SoundArrival], crack_thump: float) -> Optional[Distance]:
+    """Acoustic ranging: the crack-thump interval grows with the distance to the shooter
+    :param arrivals: sound_arrivals() in ascending order of distance
+    :param crack_thump: seconds measured between crack and report
+    :return: distance interpolated between arrivals; None if crack_thump is outside them
+    """
+    timed = [a for a in arrivals if a.crack_thump is not None]
+    for a, b in zip(timed, timed[1:]):
+        if min(a.crack_thump, b.crack_thump) <= crack_thump <= max(a.crack_thump, b.crack_thump):
+            xa, xb = a.distance >> Distance.Foot, b.distance >> Distance.Foot
+            f = (crack_thump - a.crack_thump) / (b.crack_thump - a.crack_thump) if b.crack_thump != a.crack_thump else 0
+            return Distance.Foot(xa + f * (xb - xa)) << PreferredUnits.distance
+    return None
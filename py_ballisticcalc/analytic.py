@@ -0,0 +1,93 @@
+"""Closed-form vacuum and constant-drag trajectories for validating TrajectoryCalc"""
+import math
+
+from .conditions import Shot
+from .pejsa import PejsaCalc
+from .unit import Angular, Distance
+
+__all__ = ('VacuumCalc', 'ConstantDragCalc')
+
+
+class VacuumCalc(PejsaCalc):
+    """
+    Exact trajectory without air: the projectile keeps its horizontal velocity and falls at constant gravity.
+        Wind, drag and spin drift have no effect.
+
+    Use as Calculator(engine=VacuumCalc).  TrajectoryCalc with a drag table of zero drag coefficients
+        has to reproduce these results to within its integration error.
+    """
+
+    def _retardation_coefficient(self, velocity: float) -> float:
+        return math.inf
+
+    def drag_by_mach(self, mach: float) -> float:
+        return 0
+
+    def spin_drift(self, time) -> float:
+        return 0
+
+    def _fit(self, maximum_range: float):
+        """Horizontal velocity is constant, so no fit is needed"""
+        self.horizontal_velocity = self.muzzle_velocity * math.cos(self.barrel_elevation)
+
+    def _velocity(self, x: float) -> float:
+        slope = math.tan(self.barrel_elevation) - self.gravity * self._slope_integral(x)
+        return self.horizontal_velocity * math.sqrt(1 + slope * slope)
+
+    def _time(self, x: float) -> float:
+        return x / self.horizontal_velocity
+
+    def _slope_integral(self, x: float) -> float:
+        return x / (self.horizontal_velocity * self.horizontal_velocity)
+
+    def _drop_integral(self, x: float) -> float:
+        return x * x / (2 * self.horizontal_velocity * self.horizontal_velocity)
+
+    def _wind_drift(self, shot_info: Shot, x: float) -> float:
+        return 0
+
+    def zero_angle(self, shot_info: Shot, distance: Distance, initial_elevation: Angular = None) -> Angular:
+        """Barrel elevation needed for a particular zero: the lower root of
+            x tan(phi) - g x^2 (1 + tan(phi)^2) / (2 V0^2) = height
+        :param shot_info: Shot parameters
+        :param distance: Zero distance
+        :param initial_elevation: Unused; accepted for compatibility with TrajectoryCalc
+        :return: Barrel elevation to hit height zero at zero distance
+        """
+        self._init_trajectory(shot_info)
+        x = math.cos(self.look_angle) * (distance >> Distance.Foot)
+        height = math.sin(self.look_angle) * (distance >> Distance.Foot) + self.cant_cosine * self.sight_height
+        if self.gravity == 0:
+            return Angular.Radian(math.atan(height / x))
+        a = self.gravity * x * x / (2 * self.muzzle_velocity * self.muzzle_velocity)
+        discriminant = x * x - 4 * a * (height + a)
+        if discriminant < 0:
+            raise Exception(f'Zero distance {distance} is out of range.')
+        return Angular.Radian(math.atan((x - math.sqrt(discriminant)) / (2 * a)))
+
+
+class ConstantDragCalc(PejsaCalc):
+    """
+    Trajectory with the drag coefficient fixed at its muzzle value and air density fixed at the shooter's altitude,
+        so velocity decays exponentially with distance: V = V0 exp(-x / F0), where F0 = -V / (dV/dx).
+        Drop uses the flat-fire approximation, and wind drift uses the lag rule.
+
+    Use as Calculator(engine=ConstantDragCalc).  For a drag table of constant drag coefficient,
+        level fire with CalculatorConfig(gravity=0) is exact, and TrajectoryCalc has to reproduce it
+        to within its integration error.
+    """
+
+    def _fit(self, maximum_range: float):
+        """Drag coefficient is constant, so no fit is needed"""
+
+    def _velocity(self, x: float) -> float:
+        return self.muzzle_velocity * math.exp(-x / self.f0)
+
+    def _time(self, x: float) -> float:
+        return self.f0 * math.expm1(x / self.f0) / self.muzzle_velocity
+
+    def _slope_integral(self, x: float) -> float:
+        return self.f0 * math.expm1(2 * x / self.f0) / (2 * self.muzzle_velocity ** 2)
+
+    def _drop_integral(self, x: float) -> float:
+        return self.f0 * (self.f0 * math.expm1(2 * x / self.f0) / 2 - x) / (2 * self.muzzle_velocity ** 2)
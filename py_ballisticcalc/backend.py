@@ -10,6 +10,8 @@ try:
                                        get_global_use_powder_sensitivity,
                                        set_global_max_calc_step_size,
                                        set_global_use_powder_sensitivity,
+                                       get_global_gravity,
+                                       set_global_gravity,
                                        reset_globals)
 
     logger.debug("Binary modules found, running in binary mode")
@@ -19,6 +21,8 @@ except ImportError as error:
                                   get_global_use_powder_sensitivity,
                                   set_global_max_calc_step_size,
                                   set_global_use_powder_sensitivity,
+                                  get_global_gravity,
+                                  set_global_gravity,
                                   reset_globals)
 
     logger.info("Library running in pure python mode. "
@@ -30,5 +34,7 @@ __all__ = (
     'get_global_use_powder_sensitivity',
     'set_global_max_calc_step_size',
     'set_global_use_powder_sensitivity',
+    'get_global_gravity',
+    'set_global_gravity',
     'reset_globals',
 )
@@ -0,0 +1,297 @@
+"""Dependency-free SVG and PNG charts of drop, windage and velocity versus range"""
+import math
+import struct
+import zlib
+from typing import NamedTuple, Sequence
+from xml.sax.saxutils import escape
+
+from .trajectory_data import HitResult
+from .unit import PreferredUnits
+
+__all__ = ('render_svg', 'render_png', 'save_chart', 'CHART_QUANTITIES')
+
+# Quantity => (title, TrajectoryData attribute, PreferredUnits attribute)
+CHART_QUANTITIES = {
+    'drop': ('Drop', 'target_drop', 'drop'),
+    'height': ('Height', 'height', 'drop'),
+    'windage': ('Windage', 'windage', 'drop'),
+    'velocity': ('Velocity', 'velocity', 'velocity'),
+}
+
+MARGIN_LEFT = 80
+MARGIN_RIGHT = 30
+MARGIN_TOP = 30
+MARGIN_BOTTOM = 50
+PANEL_GAP = 40
+TICKS = 5
+
+DATA_COLOR = (31, 119, 180)
+ZERO_COLOR = (120, 120, 120)
+MACH_COLOR = (214, 39, 40)
+GRID_COLOR = (221, 221, 221)
+TEXT_COLOR = (0, 0, 0)
+BACKGROUND = (255, 255, 255)
+
+# 5x7 bitmap font for PNG text; lowercase letters are drawn as uppercase
+FONT = {
+    ' ': '00000 00000 00000 00000 00000 00000 00000',
+    '0': '01110 10001 10011 10101 11001 10001 01110',
+    '1': '00100 01100 00100 00100 00100 00100 01110',
+    '2': '01110 10001 00001 00010 00100 01000 11111',
+    '3': '11111 00010 00100 00010 00001 10001 01110',
+    '4': '00010 00110 01010 10010 11111 00010 00010',
+    '5': '11111 10000 11110 00001 00001 10001 01110',
+    '6': '00110 01000 10000 11110 10001 10001 01110',
+    '7': '11111 00001 00010 00100 01000 01000 01000',
+    '8': '01110 10001 10001 01110 10001 10001 01110',
+    '9': '01110 10001 10001 01111 00001 00010 01100',
+    'A': '01110 10001 10001 11111 10001 10001 10001',
+    'B': '11110 10001 10001 11110 10001 10001 11110',
+    'C': '01110 10001 10000 10000 10000 10001 01110',
+    'D': '11100 10010 10001 10001 10001 10010 11100',
+    'E': '11111 10000 10000 11110 10000 10000 11111',
+    'F': '11111 10000 10000 11110 10000 10000 10000',
+    'G': '01110 10001 10000 10111 10001 10001 01111',
+    'H': '10001 10001 10001 11111 10001 10001 10001',
+    'I': '01110 00100 00100 00100 00100 00100 01110',
+    'J': '00111 00010 00010 00010 00010 10010 01100',
+    'K': '10001 10010 10100 11000 10100 10010 10001',
+    'L': '10000 10000 10000 10000 10000 10000 11111',
+    'M': '10001 11011 10101 10101 10001 10001 10001',
+    'N': '10001 10001 11001 10101 10011 10001 10001',
+    'O': '01110 10001 10001 10001 10001 10001 01110',
+    'P': '11110 10001 10001 11110 10000 10000 10000',
+    'Q': '01110 10001 10001 10001 10101 10010 01101',
+    'R': '11110 10001 10001 11110 10100 10010 10001',
+    'S': '01111 10000 10000 01110 00001 00001 11110',
+    'T': '11111 00100 00100 00100 00100 00100 00100',
+    'U': '10001 10001 10001 10001 10001 10001 01110',
+    'V': '10001 10001 10001 10001 10001 01010 00100',
+    'W': '10001 10001 10001 10101 10101 10101 01010',
+    'X': '10001 10001 01010 00100 01010 10001 10001',
+    'Y': '10001 10001 10001 01010 00100 00100 00100',
+    'Z': '11111 00001 00010 00100 01000 10000 11111',
+    '.': '00000 00000 00000 00000 00000 01100 01100',
+    ',': '00000 00000 00000 00000 01100 00100 01000',
+    '-': '00000 00000 00000 11111 00000 00000 00000',
+    '/': '00000 00001 00010 00100 01000 10000 00000',
+    '(': '00010 00100 01000 01000 01000 00100 00010',
+    ')': '01000 00100 00010 00010 00010 00100 01000',
+    ':': '00000 01100 01100 00000 01100 01100 00000',
+    '°': '01100 10010 10010 01100 00000 00000 00000',
+    '·': '00000 00000 00000 01100 01100 00000 00000',
+}
+FONT_SCALE = 2
+GLYPH_WIDTH = 6 * FONT_SCALE
+GLYPH_HEIGHT = 7 * FONT_SCALE
+SVG_FONT_SIZE = 13
+
+
+class _Line(NamedTuple):
+    x1: float
+    y1: float
+    x2: float
+    y2: float
+    color: tuple
+    dashed: bool = False
+
+
+class _Polyline(NamedTuple):
+    points: list
+    color: tuple
+
+
+class _Text(NamedTuple):
+    x: float
+    y: float
+    text: str
+    anchor: str  # 'start', 'middle' or 'end'; y is the text baseline
+
+
+def _nice_ticks(low: float, high: float, count: int = TICKS) -> list[float]:
+    """:return: round tick values covering low..high"""
+    if high <= low:
+        high = low + 1
+    raw = (high - low) / count
+    magnitude = math.pow(10, math.floor(math.log10(raw)))
+    step = next(m * magnitude for m in (1, 2, 2.5, 5, 10) if m * magnitude >= raw)
+    first = math.floor(low / step) * step
+    return [first + i * step for i in range(int(math.ceil((high - first) / step - 1e-9)) + 1)]
+
+
+def _label(value: float, step: float) -> str:
+    digits = max(0, -int(math.floor(math.log10(step)))) if step < 1 else 0
+    return f"{value:.{digits}f}"
+
+
+def _scene(hit: HitResult, quantities: Sequence[str], width: int, height: int) -> list:
+    """:return: chart primitives in pixel coordinates"""
+    if len(hit.trajectory) < 2:
+        raise ValueError("At least two trajectory rows are required for a chart")
+    for quantity in quantities:
+        if quantity not in CHART_QUANTITIES:
+            raise KeyError(f"Unknown chart quantity {quantity!r}, use one of {tuple(CHART_QUANTITIES)}")
+    distance_units = PreferredUnits.distance
+    xs = [row.distance >> distance_units for row in hit.trajectory]
+    x_ticks = _nice_ticks(min(xs), max(xs))
+    x_low, x_high = x_ticks[0], x_ticks[-1]
+    mach_distance = hit.subsonic_distance()
+    if mach_distance is not None:
+        mach_distance = mach_distance >> distance_units
+
+    scene = []
+    panel_height = (height - MARGIN_TOP - MARGIN_BOTTOM - PANEL_GAP * (len(quantities) - 1)) / len(quantities)
+    left, right = MARGIN_LEFT, width - MARGIN_RIGHT
+
+    def px(x):
+        return left + (x - x_low) / (x_high - x_low) * (right - left)
+
+    for index, quantity in enumerate(quantities):
+        title, attribute, preferred = CHART_QUANTITIES[quantity]
+        units = getattr(PreferredUnits, preferred)
+        ys = [getattr(row, attribute) >> units for row in hit.trajectory]
+        y_ticks = _nice_ticks(min(ys), max(ys))
+        y_low, y_high = y_ticks[0], y_ticks[-1]
+        top = MARGIN_TOP + index * (panel_height + PANEL_GAP)
+        bottom = top + panel_height
+
+        def py(y, top=top, bottom=bottom, y_low=y_low, y_high=y_high):
+            return bottom - (y - y_low) / (y_high - y_low) * (bottom - top)
+
+        for y in y_ticks:
+            scene.append(_Line(left, py(y), right, py(y), GRID_COLOR))
+            scene.append(_Text(left - 6, py(y) + GLYPH_HEIGHT / 2, _label(y, y_ticks[1] - y_ticks[0]), 'end'))
+        for x in x_ticks:
+            scene.append(_Line(px(x), top, px(x), bottom, GRID_COLOR))
+        if y_low < 0 < y_high and quantity != 'velocity':
+            scene.append(_Line(left, py(0), right, py(0), ZERO_COLOR))
+        if mach_distance is not None:
+            scene.append(_Line(px(mach_distance), top, px(mach_distance), bottom, MACH_COLOR, dashed=True))
+            scene.append(_Text(px(mach_distance) + 4, top + GLYPH_HEIGHT + 2, 'Mach 1', 'start'))
+        scene.append(_Polyline([(px(x), py(y)) for x, y in zip(xs, ys)], DATA_COLOR))
+        for x1, y1, x2, y2 in ((left, top, right, top), (right, top, right, bottom),
+                               (right, bottom, left, bottom), (left, bottom, left, top)):
+            scene.append(_Line(x1, y1, x2, y2, TEXT_COLOR))
+        scene.append(_Text(left, top - 8, f"{title}, {units.symbol}", 'start'))
+
+    bottom = height - MARGIN_BOTTOM
+    for x in x_ticks:
+        scene.append(_Text(px(x), bottom + GLYPH_HEIGHT + 6, _label(x, x_ticks[1] - x_ticks[0]), 'middle'))
+    scene.append(_Text((left + right) / 2, height - 4, f"Range, {distance_units.symbol}", 'middle'))
+    return scene
+
+
+def _hex(color: tuple) -> str:
+    return '#%02x%02x%02x' % color
+
+
+def render_svg(hit: HitResult, quantities: Sequence[str] = ('drop', 'windage', 'velocity'),
+               width: int = 800, height: int = 600) -> str:
+    """Draws panels of quantities versus range, with the zero line and a Mach 1 marker
+    :param hit: trajectory to draw
+    :param quantities: keys of CHART_QUANTITIES, one panel each from top to bottom
+    :return: SVG document
+    """
+    parts = [f'<svg xmlns="http://www.w3.org/2000/svg" width="{width}" height="{height}" '
+             f'viewBox="0 0 {width} {height}" font-family="sans-serif" font-size="{SVG_FONT_SIZE}">',
+             f'<rect width="{width}" height="{height}" fill="{_hex(BACKGROUND)}"/>']
+    for item in _scene(hit, quantities, width, height):
+        if isinstance(item, _Line):
+            dash = ' stroke-dasharray="6,4"' if item.dashed else ''
+            parts.append(f'<line x1="{item.x1:.1f}" y1="{item.y1:.1f}" x2="{item.x2:.1f}" y2="{item.y2:.1f}" '
+                         f'stroke="{_hex(item.color)}"{dash}/>')
+        elif isinstance(item, _Polyline):
+            points = ' '.join(f'{x:.1f},{y:.1f}' for x, y in item.points)
+            parts.append(f'<polyline points="{points}" fill="none" stroke="{_hex(item.color)}" stroke-width="2"/>')
+        else:
+            parts.append(f'<text x="{item.x:.1f}" y="{item.y:.1f}" text-anchor="{item.anchor}" '
+                         f'fill="{_hex(TEXT_COLOR)}">{escape(item.text)}</text>')
+    parts.append('</svg>')
+    return '\n'.join(parts)
+
+
+class _Canvas:
+    """RGB raster with the few drawing operations the charts need"""
+
+    def __init__(self, width: int, height: int):
+        self.width = width
+        self.height = height
+        self.pixels = bytearray(bytes(BACKGROUND) * (width * height))
+
+    def point(self, x: int, y: int, color: tuple):
+        if 0 <= x < self.width and 0 <= y < self.height:
+            i = 3 * (y * self.width + x)
+            self.pixels[i:i + 3] = bytes(color)
+
+    def line(self, x1: float, y1: float, x2: float, y2: float, color: tuple,
+             thickness: int = 1, dashed: bool = False):
+        steps = max(int(max(abs(x2 - x1), abs(y2 - y1))), 1)
+        for i in range(steps + 1):
+            if dashed and (i // 6) % 2:
+                continue
+            x = round(x1 + (x2 - x1) * i / steps)
+            y = round(y1 + (y2 - y1) * i / steps)
+            for d in range(thickness):
+                if abs(x2 - x1) > abs(y2 - y1):
+                    self.point(x, y + d, color)
+                else:
+                    self.point(x + d, y, color)
+
+    def text(self, x: float, y: float, text: str, anchor: str, color: tuple):
+        text = text.upper()
+        if anchor == 'end':
+            x -= len(text) * GLYPH_WIDTH
+        elif anchor == 'middle':
+            x -= len(text) * GLYPH_WIDTH / 2
+        x, top = round(x), round(y - GLYPH_HEIGHT)
+        for c in text:
+            rows = FONT.get(c, FONT[' ']).split()
+            for row, bits in enumerate(rows):
+                for column, bit in enumerate(bits):
+                    if bit == '1':
+                        for dx in range(FONT_SCALE):
+                            for dy in range(FONT_SCALE):
+                                self.point(x + column * FONT_SCALE + dx, top + row * FONT_SCALE + dy, color)
+            x += GLYPH_WIDTH
+
+    def png(self) -> bytes:
+        def chunk(kind: bytes, data: bytes) -> bytes:
+            return struct.pack('>I', len(data)) + kind + data + struct.pack('>I', zlib.crc32(kind + data))
+
+        stride = 3 * self.width
+        raw = b''.join(b'\x00' + bytes(self.pixels[y * stride:(y + 1) * stride]) for y in range(self.height))
+        return (b'\x89PNG\r\n\x1a\n'
+                + chunk(b'IHDR', struct.pack('>IIBBBBB', self.width, self.height, 8, 2, 0, 0, 0))
+                + chunk(b'IDAT', zlib.compress(raw, 9))
+                + chunk(b'IEND', b''))
+
+
+def render_png(hit: HitResult, quantities: Sequence[str] = ('drop', 'windage', 'velocity'),
+               width: int = 800, height: int = 600) -> bytes:
+    """Draws the chart of render_svg() as a PNG image, using a built-in bitmap font
+    :return: PNG file contents
+    """
+    canvas = _Canvas(width, height)
+    for item in _scene(hit, quantities, width, height):
+        if isinstance(item, _Line):
+            canvas.line(item.x1, item.y1, item.x2, item.y2, item.color, dashed=item.dashed)
+        elif isinstance(item, _Polyline):
+            for (x1, y1), (x2, y2) in zip(item.points, item.points[1:]):
+                canvas.line(x1, y1, x2, y2, item.color, thickness=2)
+        else:
+            canvas.text(item.x, item.y, item.text, item.anchor, TEXT_COLOR)
+    return canvas.png()
+
+
+def save_chart(hit: HitResult, path: str, quantities: Sequence[str] = ('drop', 'windage', 'velocity'),
+               width: int = 800, height: int = 600) -> None:
+    """Writes the chart as SVG or PNG, according to the extension of path"""
+    lower = path.lower()
+    if lower.endswith('.svg'):
+        with open(path, 'w', encoding='utf-8') as fp:
+            fp.write(render_svg(hit, quantities, width, height))
+    elif lower.endswith('.png'):
+        with open(path, 'wb') as fp:
+            fp.write(render_png(hit, quantities, width, height))
+    else:
+        raise ValueError(f"Chart file has to be .svg or .png: {path}")
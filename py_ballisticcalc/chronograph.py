@@ -0,0 +1,287 @@
+"""Chronograph data: shot strings imported from chronograph exports,
+    and ballistic coefficient measured with two chronographs a known distance apart"""
+import csv
+import math
+import re
+from typing import NamedTuple, Optional
+
+from .conditions import Atmo, Shot
+from .drag_model import DragModel, DragTableDataType
+from .interface import Calculator
+from .munition import Ammo, Weapon
+from .unit import Distance, Temperature, Velocity, Weight, Unit, PreferredUnits
+
+__all__ = ('BCMeasurement', 'measure_bc', 'ChronographString', 'read_labradar', 'read_magnetospeed',
+           'read_garmin_xero', 'read_chronograph')
+
+cBCVelocityTolerance = 1e-3  # fps, difference from the far velocity at which the BC is solved
+cBCMaxIterations = 30
+cBCSensitivityStep = 1.0  # fps, velocity change for the numerical derivatives of BC
+
+
+class BCMeasurement(NamedTuple):
+    """
+    Attributes:
+        bc (float): ballistic coefficient for drag_table, corrected to standard atmosphere
+        sd (float): standard deviation of bc propagated from the velocity standard deviations
+        drag_table (list): drag table the BC refers to
+        near_velocity (Velocity): velocity at the near chronograph
+        far_velocity (Velocity): velocity at the far chronograph
+        separation (Distance): distance between the chronographs
+    """
+    bc: float
+    sd: float
+    drag_table: DragTableDataType
+    near_velocity: Velocity
+    far_velocity: Velocity
+    separation: Distance
+
+    def drag_model(self, weight: [float, Weight] = 0, diameter: [float, Distance] = 0,
+                   length: [float, Distance] = 0) -> DragModel:
+        """:return: DragModel with the measured BC"""
+        return DragModel(self.bc, self.drag_table, weight, diameter, length)
+
+
+def _solve_bc(calc: Calculator, near: float, far: float, separation: Distance,
+              drag_table: DragTableDataType, atmo: Atmo) -> float:
+    """:return: BC with which velocity near (fps) slows to far (fps) over separation"""
+    bc = 0.25
+    for _ in range(cBCMaxIterations):
+        shot = Shot(weapon=Weapon(), ammo=Ammo(DragModel(bc, drag_table), Velocity.FPS(near)), atmo=atmo)
+        rows = calc.fire_at_ranges(shot, [separation]).trajectory
+        if not rows:
+            raise ArithmeticError(f"Calculated trajectory doesn't reach {separation} with BC {bc}")
+        velocity = rows[0].velocity >> Velocity.FPS
+        if math.fabs(velocity - far) < cBCVelocityTolerance:
+            return bc
+        # Velocity loss over a short separation is nearly inversely proportional to BC
+        bc *= (near - velocity) / (near - far)
+    raise ArithmeticError(f"BC did not converge in {cBCMaxIterations} iterations")
+
+
+def measure_bc(near_velocity: [float, Velocity], far_velocity: [float, Velocity],
+               separation: [float, Distance], drag_table: DragTableDataType, atmo: Atmo = None,
+               near_sd: [float, Velocity] = 0, far_sd: [float, Velocity] = 0, shots: int = 1,
+               calc: Calculator = None) -> BCMeasurement:
+    """Finds the BC with which the calculator reproduces the velocity drop between two chronographs
+    :param near_velocity: average velocity at the near chronograph
+    :param far_velocity: average velocity at the far chronograph
+    :param separation: distance between the chronographs
+    :param drag_table: standard drag table of the BC, e.g. TableG7
+    :param atmo: atmosphere during the measurement, default standard ICAO at sea level
+    :param near_sd: standard deviation of a near chronograph reading
+    :param far_sd: standard deviation of a far chronograph reading
+    :param shots: number of shots averaged in near_velocity and far_velocity
+    :param calc: Calculator to solve the trajectories
+    """
+    near_velocity = PreferredUnits.velocity(near_velocity)
+    far_velocity = PreferredUnits.velocity(far_velocity)
+    separation = PreferredUnits.distance(separation)
+    near = near_velocity >> Velocity.FPS
+    far = far_velocity >> Velocity.FPS
+    if not near > far > 0:
+        raise ValueError(f"Far velocity {far_velocity} has to be positive and below near velocity {near_velocity}")
+    if separation.raw_value <= 0:
+        raise ValueError(f"Chronograph separation has to be positive, got {separation}")
+    if shots < 1:
+        raise ValueError(f"At least one shot is required, got {shots}")
+    calc = calc or Calculator()
+    atmo = atmo or Atmo.icao()
+
+    bc = _solve_bc(calc, near, far, separation, drag_table, atmo)
+    near_sd = (PreferredUnits.velocity(near_sd) >> Velocity.FPS) / math.sqrt(shots)
+    far_sd = (PreferredUnits.velocity(far_sd) >> Velocity.FPS) / math.sqrt(shots)
+    sd = 0.0
+    if near_sd or far_sd:
+        h = min(cBCSensitivityStep, (near - far) / 4)
+        d_near = (_solve_bc(calc, near + h, far, separation, drag_table, atmo) - bc) / h
+        d_far = (_solve_bc(calc, near, far + h, separation, drag_table, atmo) - bc) / h
+        sd = math.hypot(d_near * near_sd, d_far * far_sd)
+    return BCMeasurement(bc, sd, drag_table, near_velocity, far_velocity, separation)
+
+
+# Velocity unit labels used by chronograph exports
+CHRONOGRAPH_UNITS = {'fps': Unit.FPS, 'ft/s': Unit.FPS, 'mps': Unit.MPS, 'm/s': Unit.MPS, 'km/h': Unit.KMH}
+COLD_BORE_MARKS = ('yes', 'y', 'true', '1', 'x')
+_UNITS_IN_TEXT = re.compile(r'\b(fps|ft/s|mps|m/s|km/h)(?![a-z])', re.IGNORECASE)
+
+
+class ChronographString(NamedTuple):
+    """
+    Velocities of a shot string read from a chronograph export
+
+    Attributes:
+        velocities (list[Velocity]): muzzle velocity of each shot, in firing order
+        cold_bore (list[bool]): True for shots marked as fired from a cold barrel
+        source (str): file the string was read from
+    """
+    velocities: list[Velocity]
+    cold_bore: list[bool]
+    source: str = None
+
+    @property
+    def count(self) -> int:
+        """:return: number of shots"""
+        return len(self.velocities)
+
+    @property
+    def mean(self) -> Velocity:
+        """:return: average velocity"""
+        return Velocity.FPS(sum(self._fps()) / self.count) << PreferredUnits.velocity
+
+    @property
+    def sd(self) -> Velocity:
+        """:return: sample standard deviation of velocity, 0 for a single shot"""
+        values = self._fps()
+        if len(values) < 2:
+            return Velocity.FPS(0) << PreferredUnits.velocity
+        mean = sum(values) / len(values)
+        return Velocity.FPS(math.sqrt(sum((v - mean) ** 2 for v in values) / (len(values) - 1))
+                            ) << PreferredUnits.velocity
+
+    @property
+    def es(self) -> Velocity:
+        """:return: extreme spread, highest minus lowest velocity"""
+        values = self._fps()
+        return Velocity.FPS(max(values) - min(values)) << PreferredUnits.velocity
+
+    def _fps(self) -> list[float]:
+        if not self.velocities:
+            raise ValueError(f"No shots in chronograph string {self.source}")
+        return [v >> Velocity.FPS for v in self.velocities]
+
+    def ammo(self, dm: DragModel, powder_temp: [float, Temperature] = None, fit_drift: bool = False) -> Ammo:
+        """Ammo with the muzzle velocity of this string.
+            Shots marked cold-bore set Ammo.cold_bore_offset relative to the average of the others.
+        :param dm: DragModel of the projectile
+        :param powder_temp: temperature at which the string was fired
+        :param fit_drift: True => set Ammo.shot_mv_drift and mv to the least-squares line through
+            the warm shots (use with strings fired without pauses)
+        """
+        values = self._fps()
+        cold = [v for v, is_cold in zip(values, self.cold_bore) if is_cold]
+        warm = [v for v, is_cold in zip(values, self.cold_bore) if not is_cold] or values
+        mv = sum(warm) / len(warm)
+        drift = 0
+        if fit_drift and len(warm) > 1:
+            n = len(warm)
+            mean_index = (n - 1) / 2
+            drift = (sum((i - mean_index) * (v - mv) for i, v in enumerate(warm))
+                     / sum((i - mean_index) ** 2 for i in range(n)))
+            # Ammo.mv_for_shot() counts warm shots from 2 after a cold-bore shot, from 1 otherwise
+            mv -= drift * (mean_index + (1 if cold else 0))
+        cold_bore_offset = sum(cold) / len(cold) - mv if cold and len(warm) < len(values) else 0
+        return Ammo(dm, Velocity.FPS(mv) << PreferredUnits.velocity, powder_temp,
+                    cold_bore_offset=Velocity.FPS(cold_bore_offset) << PreferredUnits.velocity,
+                    shot_mv_drift=Velocity.FPS(drift) << PreferredUnits.velocity)
+
+
+def _read_rows(path: str) -> list[list[str]]:
+    """:return: cells of a CSV file, with the delimiter given by a 'sep=' line or guessed from the first line"""
+    with open(path, 'r', encoding='utf-8-sig', newline='') as fp:
+        lines = fp.read().splitlines()
+    delimiter = ','
+    if lines and lines[0].lower().startswith('sep='):
+        delimiter = lines.pop(0)[4:5] or ','
+    elif lines and lines[0].count(';') > lines[0].count(','):
+        delimiter = ';'
+    return [[cell.strip() for cell in row] for row in csv.reader(lines, delimiter=delimiter)]
+
+
+def _number(cell: str) -> Optional[float]:
+    try:
+        return float(cell.replace(',', '.'))
+    except ValueError:
+        return None
+
+
+def _units(text: str, default: Unit = None) -> Unit:
+    """:return: velocity Unit named in text"""
+    if (match := _UNITS_IN_TEXT.search(text)) is not None:
+        return CHRONOGRAPH_UNITS[match.group(1).lower()]
+    if default is None:
+        raise ValueError(f"Can't find velocity units in {text!r}")
+    return default
+
+
+def _find_header(rows: list[list[str]], source: str, *names: str) -> tuple[int, list[int]]:
+    """:return: index of the first row having cells that start with each of names, and their column indexes"""
+    for index, row in enumerate(rows):
+        labels = [cell.lower() for cell in row]
+        columns = [next((i for i, label in enumerate(labels) if label.startswith(name)), None) for name in names]
+        if None not in columns:
+            return index, columns
+    raise ValueError(f"{source} has no header row with columns {names}")
+
+
+def _read_shots(rows: list[list[str]], start: int, speed: int, units: Unit, cold: int = None) -> tuple[list, list]:
+    """:return: velocities and cold-bore marks of the rows after start that begin with a shot number"""
+    velocities, cold_bore = [], []
+    for row in rows[start + 1:]:
+        if not row or _number(row[0]) is None:
+            if velocities:
+                break
+            continue
+        if len(row) <= speed or (value := _number(row[speed])) is None:
+            continue
+        velocities.append(units(value))
+        cold_bore.append(cold is not None and len(row) > cold and row[cold].lower() in COLD_BORE_MARKS)
+    return velocities, cold_bore
+
+
+def read_labradar(path: str) -> ChronographString:
+    """Reads a LabRadar series report ('SR0001 Report.csv'): muzzle velocities are the V0 column,
+        in the units of its 'Units velocity' line
+    """
+    rows = _read_rows(path)
+    units_row = next((row for row in rows if row and row[0].lower() == 'units velocity'), None)
+    units = _units(units_row[1] if units_row and len(units_row) > 1 else '', Unit.FPS)
+    header, (_, speed) = _find_header(rows, path, 'shot id', 'v0')
+    velocities, cold_bore = _read_shots(rows, header, speed, units)
+    return ChronographString(velocities, cold_bore, path)
+
+
+def read_magnetospeed(path: str, series: int = None) -> ChronographString:
+    """Reads a MagnetoSpeed export with 'Series', 'Shot' and 'Speed' columns
+    :param series: series number to read; None for the first series in the file
+    """
+    rows = _read_rows(path)
+    header, (series_column, _, speed) = _find_header(rows, path, 'series', 'shot', 'speed')
+    # Units of the Speed column, or else the first ones mentioned in the file
+    units = _units(' '.join([rows[header][speed]] + [' '.join(row) for row in rows]), Unit.FPS)
+    velocities, cold_bore = [], []
+    for row in rows[header + 1:]:
+        if len(row) <= speed or (number := _number(row[series_column])) is None \
+                or (value := _number(row[speed])) is None:
+            continue
+        if series is None:
+            series = int(number)
+        if int(number) == series:
+            velocities.append(units(value))
+            cold_bore.append(False)
+    return ChronographString(velocities, cold_bore, path)
+
+
+def read_garmin_xero(path: str) -> ChronographString:
+    """Reads a Garmin Xero C1 session export: '#' and 'SPEED (FPS)' or 'SPEED (M/S)' columns,
+        and the 'COLD BORE' column if present
+    """
+    rows = _read_rows(path)
+    header, (_, speed) = _find_header(rows, path, '#', 'speed')
+    labels = [cell.lower() for cell in rows[header]]
+    cold = labels.index('cold bore') if 'cold bore' in labels else None
+    velocities, cold_bore = _read_shots(rows, header, speed, _units(rows[header][speed], Unit.FPS), cold)
+    return ChronographString(velocities, cold_bore, path)
+
+
+def read_chronograph(path: str) -> ChronographString:
+    """Reads a LabRadar, MagnetoSpeed or Garmin Xero export, recognized by its columns"""
+    rows = _read_rows(path)
+    for reader, names in ((read_labradar, ('shot id', 'v0')), (read_garmin_xero, ('#', 'speed')),
+                          (read_magnetospeed, ('series', 'shot', 'speed'))):
+        try:
+            _find_header(rows, path, *names)
+        except ValueError:
+            continue
+        return reader(path)
+    raise ValueError(f"{path} is not a recognized chronograph export")
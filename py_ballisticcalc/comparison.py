@@ -0,0 +1,216 @@
+"""Side-by-side comparison of ammunition and weapon combinations fired in one environment"""
+from dataclasses import replace
+from typing import Iterable, Mapping, NamedTuple, Optional, Union
+
+from .conditions import Atmo, Shot, Wind
+from .interface import Calculator
+from .munition import Ammo, Weapon
+from .trajectory_data import TrajectoryData
+from .unit import AbstractUnit, Angular, Distance, Velocity, PreferredUnits
+
+try:
+    import pandas as pd
+except ImportError:
+    pd = None
+
+__all__ = ('AmmoComparisonEntry', 'AmmoComparison', 'compare_ammo',
+           'LoadDelta', 'LoadComparisonEntry', 'LoadComparison', 'compare_loads')
+
+# TrajectoryData fields shown by AmmoComparison.dataframe()
+COMPARISON_FIELDS = ('drop_adj', 'target_drop', 'windage_adj', 'windage', 'velocity', 'energy', 'time')
+
+
+class AmmoComparisonEntry(NamedTuple):
+    """
+    Attributes:
+        name (str): candidate name
+        shot (Shot): candidate zeroed on its own copy of the weapon, with the reference wind
+        supersonic_range (Distance): distance where the candidate goes subsonic; None if it stays
+            supersonic within max_range
+        rows (list[TrajectoryData]): trajectory at each of AmmoComparison.distances
+    """
+    name: str
+    shot: Shot
+    supersonic_range: Optional[Distance]
+    rows: list[TrajectoryData]
+
+
+class AmmoComparison(NamedTuple):
+    """Results of compare_ammo(): one entry per candidate, one row per distance"""
+    distances: list[Distance]
+    entries: list[AmmoComparisonEntry]
+
+    def matrix(self, quantity: str) -> list[list[Union[AbstractUnit, float]]]:
+        """
+        :param quantity: field of TrajectoryData, e.g. 'drop_adj', 'windage' or 'energy'
+        :return: values of quantity with a row for each candidate and a column for each distance
+        """
+        if quantity not in TrajectoryData._fields:
+            raise KeyError(f"Unknown quantity {quantity!r}, use a field of TrajectoryData")
+        return [[getattr(row, quantity) for row in entry.rows] for entry in self.entries]
+
+    def dataframe(self) -> 'DataFrame':
+        """:return: table indexed by candidate name and distance (in PreferredUnits)"""
+        if pd is None:
+            raise ImportError("Install pandas to get the comparison as dataframe")
+        records = []
+        for entry in self.entries:
+            supersonic = None if entry.supersonic_range is None \
+                else entry.supersonic_range >> PreferredUnits.distance
+            for distance, row in zip(self.distances, entry.rows):
+                values = dict(zip(TrajectoryData._fields, row.in_def_units()))
+                record = {'name': entry.name, 'distance': distance >> PreferredUnits.distance}
+                record.update({name: values[name] for name in COMPARISON_FIELDS})
+                record['supersonic_range'] = supersonic
+                records.append(record)
+        return pd.DataFrame(records).set_index(['name', 'distance'])
+
+
+def compare_ammo(calc: Calculator, weapon: Weapon, candidates: Union[Mapping[str, Ammo], Iterable[Ammo]],
+                 distances: Iterable[[float, Distance]], atmo: Atmo = None,
+                 zero_distance: [float, Distance] = Distance.Yard(100),
+                 reference_wind: [float, Velocity] = Velocity.MPH(10),
+                 max_range: [float, Distance] = Distance.Yard(3000)) -> AmmoComparison:
+    """Zeroes each candidate on a copy of weapon and solves it at distances in the same conditions
+    :param calc: Calculator to solve the trajectories
+    :param weapon: weapon shared by the candidates; its zero_elevation is not changed
+    :param candidates: Ammo by name, or a sequence of Ammo named '1', '2', ...
+    :param distances: distances at which to compare
+    :param atmo: environment, default standard ICAO at sea level
+    :param zero_distance: distance at which every candidate is zeroed
+    :param reference_wind: full-value crosswind from the right (3 o'clock) for windage comparison
+    :param max_range: distance up to which supersonic_range is searched
+    """
+    if not isinstance(candidates, Mapping):
+        candidates = {str(i + 1): ammo for i, ammo in enumerate(candidates)}
+    distances = [PreferredUnits.distance(d) for d in distances]
+    wind = Wind(PreferredUnits.velocity(reference_wind), Angular.OClock(3))
+    entries = []
+    for name, ammo in candidates.items():
+        shot = Shot(weapon=replace(weapon), ammo=ammo, atmo=atmo)
+        calc.set_weapon_zero(shot, zero_distance)
+        shot = replace(shot, winds=[wind])
+        result = calc.fire_at_ranges(shot, distances)
+        rows = result.trajectory
+        if len(rows) < len(distances):
+            raise result.range_error(f"distance {distances[len(rows)]} with {name}")
+        supersonic = calc.fire(shot, max_range).subsonic_distance()
+        entries.append(AmmoComparisonEntry(name, shot, supersonic, rows))
+    return AmmoComparison(distances, entries)
+
+
+class LoadDelta(NamedTuple):
+    """
+    Difference of a load from the baseline load at one distance
+
+    Attributes:
+        distance (Distance): downrange distance
+        drop (Distance): difference of target_drop, positive => load hits higher
+        drift (Distance): difference of windage, positive => load hits further right
+        time (float): difference of time of flight in seconds
+        drop_adj (Angular): difference of drop_adj
+        windage_adj (Angular): difference of windage_adj
+    """
+    distance: Distance
+    drop: Distance
+    drift: Distance
+    time: float
+    drop_adj: Angular
+    windage_adj: Angular
+
+
+class LoadComparisonEntry(NamedTuple):
+    """
+    Attributes:
+        name (str): load name
+        shot (Shot): shot of the load, with its own copy of the weapon
+        rows (list[TrajectoryData]): trajectory at each of LoadComparison.distances
+        deltas (list[LoadDelta]): difference from the baseline at each of LoadComparison.distances
+    """
+    name: str
+    shot: Shot
+    rows: list[TrajectoryData]
+    deltas: list[LoadDelta]
+
+
+class LoadComparison(NamedTuple):
+    """Results of compare_loads(): one entry per load, aligned on distances"""
+    distances: list[Distance]
+    baseline: str
+    entries: list[LoadComparisonEntry]
+
+    def entry(self, name: str) -> LoadComparisonEntry:
+        """:return: entry of the load named name"""
+        for entry in self.entries:
+            if entry.name == name:
+                return entry
+        raise KeyError(f"Unknown load {name!r}")
+
+    def delta_matrix(self, quantity: str) -> list[list[Union[AbstractUnit, float]]]:
+        """
+        :param quantity: field of LoadDelta, e.g. 'drop', 'drift' or 'time'
+        :return: differences with a row for each load and a column for each distance
+        """
+        if quantity not in LoadDelta._fields or quantity == 'distance':
+            raise KeyError(f"Unknown quantity {quantity!r}, use a field of LoadDelta")
+        return [[getattr(delta, quantity) for delta in entry.deltas] for entry in self.entries]
+
+
+def _load_delta(distance: Distance, row: TrajectoryData, base: TrajectoryData) -> LoadDelta:
+    return LoadDelta(distance,
+                     Distance.Foot((row.target_drop >> Distance.Foot) - (base.target_drop >> Distance.Foot))
+                     << PreferredUnits.drop,
+                     Distance.Foot((row.windage >> Distance.Foot) - (base.windage >> Distance.Foot))
+                     << PreferredUnits.drop,
+                     row.time - base.time,
+                     Angular.Radian((row.drop_adj >> Angular.Radian) - (base.drop_adj >> Angular.Radian))
+                     << PreferredUnits.adjustment,
+                     Angular.Radian((row.windage_adj >> Angular.Radian) - (base.windage_adj >> Angular.Radian))
+                     << PreferredUnits.adjustment)
+
+
+def compare_loads(calc: Calculator, shot: Shot,
+                  loads: Union[Mapping[str, Union[Ammo, Weapon, tuple[Weapon, Ammo]]],
+                               Iterable[Union[Ammo, Weapon, tuple[Weapon, Ammo]]]],
+                  distances: Iterable[[float, Distance]], zero_distance: [float, Distance] = None,
+                  baseline: str = None) -> LoadComparison:
+    """Fires each load with the conditions of shot and aligns the results on distances
+    :param calc: Calculator to solve the trajectories
+    :param shot: shot conditions (atmosphere, winds, angles) shared by the loads
+    :param loads: by name, or a sequence named '1', '2', ... of Ammo (fired from shot.weapon),
+        Weapon (firing shot.ammo), or (Weapon, Ammo)
+    :param distances: distances at which to compare
+    :param zero_distance: distance at which every load is zeroed on its own copy of the weapon;
+        default keeps the zero_elevation of each weapon
+    :param baseline: name of the load the deltas are relative to, default the first
+    """
+    if not isinstance(loads, Mapping):
+        loads = {str(i + 1): load for i, load in enumerate(loads)}
+    if not loads:
+        raise ValueError("No loads to compare")
+    baseline = next(iter(loads)) if baseline is None else baseline
+    if baseline not in loads:
+        raise KeyError(f"Unknown baseline load {baseline!r}")
+    distances = [PreferredUnits.distance(d) for d in distances]
+    results = {}
+    for name, load in loads.items():
+        if isinstance(load, Ammo):
+            weapon, ammo = shot.weapon, load
+        elif isinstance(load, Weapon):
+            weapon, ammo = load, shot.ammo
+        else:
+            weapon, ammo = load
+        load_shot = replace(shot, weapon=replace(weapon), ammo=ammo)
+        if zero_distance is not None:
+            # Zeroed level in calm air, then fired in the conditions of shot
+            calc.set_weapon_zero(Shot(weapon=load_shot.weapon, ammo=ammo, atmo=shot.atmo), zero_distance)
+        result = calc.fire_at_ranges(load_shot, distances)
+        rows = result.trajectory
+        if len(rows) < len(distances):
+            raise result.range_error(f"distance {distances[len(rows)]} with {name}")
+        results[name] = (load_shot, rows)
+    base_rows = results[baseline][1]
+    entries = [LoadComparisonEntry(name, load_shot, rows,
+                                   [_load_delta(d, row, base) for d, row, base in zip(distances, rows, base_rows)])
+               for name, (load_shot, rows) in results.items()]
+    return LoadComparison(distances, baseline, entries)
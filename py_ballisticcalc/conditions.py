@@ -1,22 +1,18 @@
 """Classes to define zeroing or current environment conditions"""
 
 import math
-from dataclasses import dataclass, field
+from abc import ABC, abstractmethod
+from dataclasses import dataclass, field, replace
 
 from .munition import Weapon, Ammo
 # from .settings import Settings as Set
-from .unit import Distance, Velocity, Temperature, Pressure, Angular, Dimension, PreferredUnits
+from .unit import (Distance, Velocity, Temperature, Pressure, Angular, Density, Acceleration, Dimension,
+                   PreferredUnits)
 
-__all__ = ('Atmo', 'Wind', 'Shot')
+__all__ = ('AtmosphereModel', 'Atmo', 'Wind', 'WindLayer', 'Shot', 'Gravity')
 
 cStandardHumidity: float = 0.0  # Relative Humidity
 cPressureExponent: float = 5.255876  # =g*M/R*L
-cA0: float = 1.24871
-cA1: float = 0.0988438
-cA2: float = 0.00152907
-cA3: float = -3.07031e-06
-cA4: float = 4.21329e-07
-cA5: float = 3.342e-04
 # ISA, metric prefer_units: (https://www.engineeringtoolbox.com/international-standard-atmosphere-d_985.html)
 cDegreesCtoK: float = 273.15  # °K = °C + 273.15
 cStandardTemperatureC: float = 15.0  # °C
@@ -33,9 +29,43 @@ cStandardPressure: float = 29.92  # InHg
 cSpeedOfSoundImperial: float = 49.0223  # Mach1 in fps = cSpeedOfSound * sqrt(°R)
 cStandardDensity: float = 0.076474  # lb/ft^3
 
+# CIPM-2007 equation for the density of moist air (Picard et al., Metrologia 45 (2008) 149-155)
+cGasConstant: float = 8.314472  # J/(mol·K)
+cMolarMassDryAir: float = 28.96546e-3  # kg/mol, with 400 ppm of CO2
+cMolarMassWater: float = 18.01528e-3  # kg/mol
+cSaturationVapor: tuple = (1.2378847e-5, -1.9121316e-2, 33.93711047, -6.3431645e3)  # A, B, C, D
+cEnhancement: tuple = (1.00062, 3.14e-8, 5.6e-7)  # alpha, beta, gamma
+cCompressibility: tuple = (1.58123e-6, -2.9331e-8, 1.1043e-10,  # a0, a1, a2
+                           5.707e-6, -2.051e-8,  # b0, b1
+                           1.9898e-4, -2.376e-6,  # c0, c1
+                           1.83e-11, -0.765e-8)  # d, e
+cHeatCapacityRatioDryAir: float = 1.4
+cHeatCapacityRatioWater: float = 1.33
+
+
+class Gravity:  # pylint: disable=too-few-public-methods
+    """Surface gravity presets for set_global_gravity() and CalculatorConfig.gravity (acceleration magnitude)"""
+    Earth = Acceleration.FootPerSecondSquared(32.17405)  # Standard gravity, 9.80665 m/s^2
+    Moon = Acceleration.MeterPerSecondSquared(1.625)
+    Mars = Acceleration.MeterPerSecondSquared(3.72076)
+
+
+class AtmosphereModel(ABC):  # pylint: disable=too-few-public-methods
+    """Air density and speed of sound along the trajectory, as consumed by the Calculator.
+        Implement it for custom lapse rates, measured soundings or other environments, and set it
+        to Shot.atmo_model; Shot.atmo still sets the altitude and temperature at the muzzle.
+    """
+
+    @abstractmethod
+    def get_density_factor_and_mach_for_altitude(self, altitude: float) -> (float, float):
+        """
+        :param altitude: ASL in units of feet
+        :return: density ratio (density / cStandardDensity) and Mach 1 (fps) for the specified altitude
+        """
+
 
 @dataclass
-class Atmo(PreferredUnits.Mixin):  # pylint: disable=too-many-instance-attributes
+class Atmo(PreferredUnits.Mixin, AtmosphereModel):  # pylint: disable=too-many-instance-attributes
     """Atmospheric conditions and density calculations"""
 
     altitude: [float, Pressure] = Dimension(prefer_units="distance")
@@ -68,7 +98,9 @@ class Atmo(PreferredUnits.Mixin):  # pylint: disable=too-many-instance-attribute
         self._a0 = self.altitude >> Distance.Foot
         self._ta = self._a0 * cLapseRateImperial + cStandardTemperatureF
         self.density_ratio = self.calculate_density(self._t0, self._p0) / cStandardDensity
-        self._mach1 = Atmo.machF(self._t0)
+        self._mach1 = Atmo.machF(self._t0) * Atmo.moist_air_mach_factor(self.temperature >> Temperature.Celsius,
+                                                                         self.pressure >> Pressure.Pascal,
+                                                                         self.humidity)
         self.mach = Velocity.FPS(self._mach1)
 
     @staticmethod
@@ -123,20 +155,68 @@ class Atmo(PreferredUnits.Mixin):  # pylint: disable=too-many-instance-attribute
         """:return: Mach 1 in m/s for Celsius temperature"""
         return math.sqrt(1 + celsius / cDegreesCtoK) * cSpeedOfSoundMetric
 
+    @staticmethod
+    def vapor_mole_fraction(celsius: float, pascals: float, humidity: float) -> float:
+        """CIPM-2007 mole fraction of water vapor
+        :param celsius: temperature in °C
+        :param pascals: pressure in Pa
+        :param humidity: relative humidity [0 to 1]
+        """
+        if humidity <= 0:
+            return 0.0
+        kelvin = celsius + cDegreesCtoK
+        a, b, c, d = cSaturationVapor
+        saturation = math.exp(a * kelvin ** 2 + b * kelvin + c + d / kelvin)
+        alpha, beta, gamma = cEnhancement
+        enhancement = alpha + beta * pascals + gamma * celsius ** 2
+        return min(humidity * enhancement * saturation / pascals, 1.0)
+
+    @staticmethod
+    def moist_air_density(celsius: float, pascals: float, humidity: float) -> float:
+        """CIPM-2007 density of moist air
+        :param celsius: temperature in °C
+        :param pascals: pressure in Pa
+        :param humidity: relative humidity [0 to 1]
+        :return: density in kg/m^3
+        """
+        kelvin = celsius + cDegreesCtoK
+        xv = Atmo.vapor_mole_fraction(celsius, pascals, humidity)
+        a0, a1, a2, b0, b1, c0, c1, d, e = cCompressibility
+        z = (1 - pascals / kelvin * (a0 + a1 * celsius + a2 * celsius ** 2
+                                     + (b0 + b1 * celsius) * xv + (c0 + c1 * celsius) * xv ** 2)
+             + (pascals / kelvin) ** 2 * (d + e * xv ** 2))
+        return (pascals * cMolarMassDryAir / (z * cGasConstant * kelvin)
+                * (1 - xv * (1 - cMolarMassWater / cMolarMassDryAir)))
+
+    @staticmethod
+    def moist_air_mach_factor(celsius: float, pascals: float, humidity: float) -> float:
+        """Speed of sound in moist air relative to dry air of the same temperature: the ideal gas mixture
+            of dry air and water vapor has lower molar mass and lower ratio of heat capacities
+        :param celsius: temperature in °C
+        :param pascals: pressure in Pa
+        :param humidity: relative humidity [0 to 1]
+        """
+        xv = Atmo.vapor_mole_fraction(celsius, pascals, humidity)
+        if not xv:
+            return 1.0
+        # Molar heat capacity at constant volume, in units of the gas constant
+        cv = (1 - xv) / (cHeatCapacityRatioDryAir - 1) + xv / (cHeatCapacityRatioWater - 1)
+        gamma = 1 + 1 / cv
+        molar_mass = (1 - xv) * cMolarMassDryAir + xv * cMolarMassWater
+        return math.sqrt(gamma / cHeatCapacityRatioDryAir * cMolarMassDryAir / molar_mass)
+
     @staticmethod
     def air_density(t: Temperature, p: Pressure, humidity: float) -> float:
-        """Source: https://en.wikipedia.org/wiki/Density_of_air#Humid_air
+        """CIPM-2007 density of moist air
         :return: Density in Imperial units (lb/ft^3)
         """
-        tC = t >> Temperature.Celsius
-        pM = (p >> Pressure.hPa) * 100  # Pressure in Pascals
-        # Tetens approximation to saturation vapor pressure:
-        psat = 6.1078 * math.pow(10, 17.27 * tC / (tC + 237.3))
-        pv = humidity * psat  # Pressure of water vapor in Pascals
-        pd = pM - pv  # Partial pressure of dry air in Pascals
-        # Density in metric units kg/m^3
-        density = (pd * 0.0289652 + pv * 0.018016) / (8.31446 * (tC + cDegreesCtoK))
-        return density / cDensityImperialToMetric
+        return Atmo.moist_air_density(t >> Temperature.Celsius, p >> Pressure.Pascal,
+                                      humidity) / cDensityImperialToMetric
+
+    @property
+    def density(self) -> Density:
+        """:return: air density at the altitude of the atmosphere"""
+        return Density.KgPerCubicMeter(self.density_metric) << PreferredUnits.density
 
     @property
     def density_metric(self) -> float:
@@ -155,18 +235,27 @@ class Atmo(PreferredUnits.Mixin):  # pylint: disable=too-many-instance-attribute
         """
         return (altitude - self._a0) * cLapseRateImperial + self._t0
 
+    def pressure_at_altitude(self, altitude: float) -> float:
+        """ Pressure at altitude, in the ratio of ICAO standard pressures to the pressure at reference altitude
+        :param altitude: ASL in ft
+        :return: pressure in inHg
+        """
+        return self._p0 * math.pow((3.73145 - 2.56555e-05 * altitude) / (3.73145 - 2.56555e-05 * self._a0),
+                                   cPressureExponent)
+
     def calculate_density(self, t: float, p: float) -> float:
         """
         :param t: temperature in °F
         :param p: pressure in inHg
         :return: density with specified atmosphere
         """
-        if t > 0:
-            et0 = cA0 + t * (cA1 + t * (cA2 + t * (cA3 + t * cA4)))
-            et = cA5 * self.humidity * et0
-            hc = (p - 0.3783 * et) / cStandardPressure
-        else:
-            hc = 1.0
+        hc = p / cStandardPressure
+        if self.humidity > 0:
+            # Water vapor displaces the heavier dry air: CIPM-2007 ratio of moist to dry air density
+            celsius = Temperature.Fahrenheit(t) >> Temperature.Celsius
+            pascals = Pressure.InHg(p) >> Pressure.Pascal
+            hc *= (Atmo.moist_air_density(celsius, pascals, self.humidity)
+                   / Atmo.moist_air_density(celsius, pascals, 0))
 
         density = cStandardDensity * (
                 (cStandardTemperatureF + cDegreesFtoR) / (t + cDegreesFtoR)
@@ -187,6 +276,11 @@ class Atmo(PreferredUnits.Mixin):  # pylint: disable=too-many-instance-attribute
             density_ratio = math.exp(-altitude / 34112.0)
             t = self.temperature_at_altitude(altitude)
             mach = Atmo.machF(t)
+            if self.humidity > 0:
+                # Same relative humidity, at the temperature and pressure of the altitude
+                pascals = Pressure.InHg(self.pressure_at_altitude(altitude)) >> Pressure.Pascal
+                mach *= Atmo.moist_air_mach_factor(Temperature.Fahrenheit(t) >> Temperature.Celsius, pascals,
+                                                   self.humidity)
         return density_ratio, mach
 
 
@@ -196,6 +290,8 @@ class Wind(PreferredUnits.Mixin):
     Wind direction and velocity by down-range distance.
     direction_from = 0 is blowing from behind shooter. 
     direction_from = 90 degrees is blowing from shooter's left towards right.
+    until_distance is horizontal down-range distance; for boundaries measured along the sight line
+        of an inclined shot use Shot.set_winds_along_sight_line().
     """
 
     velocity: [float, Velocity] = Dimension(prefer_units='velocity')
@@ -211,6 +307,24 @@ class Wind(PreferredUnits.Mixin):
             self.velocity = 0
 
 
+@dataclass
+class WindLayer(Wind):
+    """
+    Wind aloft: wind blowing at and above from_height, up to until_distance down-range.
+    from_height is measured like TrajectoryData.height, from the horizontal through the sight;
+        for a height above level ground subtract the height of the sight above the ground.
+    Where the projectile is at or above the from_height of any WindLayer covering its distance,
+        the layer with the greatest from_height replaces the Wind of Shot.winds.
+    """
+
+    from_height: [float, Distance] = Dimension(prefer_units='target_height')
+
+    def __post_init__(self) -> None:
+        super().__post_init__()
+        if not self.from_height:
+            self.from_height = 0
+
+
 @dataclass
 class Shot(PreferredUnits.Mixin):
     """
@@ -224,6 +338,8 @@ class Shot(PreferredUnits.Mixin):
     :param relative_angle: Elevation adjustment added to weapon.zero_elevation for a particular shot.
     :param cant_angle: Tilt of gun from vertical, which shifts any barrel elevation
         from the vertical plane into the horizontal plane by sine(cant_angle)
+    :param wind_layers: Winds aloft, which replace winds where the projectile is high enough (see WindLayer)
+    :param atmo_model: Air density and speed of sound along the trajectory, default atmo
     """
 
     look_angle: [float, Angular] = Dimension(prefer_units='angular')
@@ -234,6 +350,8 @@ class Shot(PreferredUnits.Mixin):
     ammo: Ammo = field(default=None)
     atmo: Atmo = field(default=None)
     winds: list[Wind] = field(default=None)
+    wind_layers: list[WindLayer] = field(default=None)
+    atmo_model: AtmosphereModel = field(default=None)
 
     # NOTE: Calculator assumes that winds are sorted by Wind.until_distance (ascending)
 
@@ -263,3 +381,36 @@ class Shot(PreferredUnits.Mixin):
             self.atmo = Atmo.icao()
         if not self.winds:
             self.winds = [Wind()]
+        if not self.wind_layers:
+            self.wind_layers = []
+
+    def set_winds_along_sight_line(self, winds: list[Wind]) -> list[Wind]:
+        """Sets winds whose until_distance is measured along the sight line (e.g. ranged wind flags
+            on a slope), converting boundaries to the horizontal down-range distances used by the Calculator.
+            Uses the current look_angle, so call again after changing it.
+        :param winds: Winds with sight-line until_distance; they are not modified
+        :return: Converted winds, sorted by until_distance, as set to self.winds
+        """
+        cosine = math.cos(self.look_angle >> Angular.Radian)
+        self.winds = sorted((
+            replace(wind, until_distance=wind.until_distance
+                    if (wind.until_distance >> Distance.Foot) >= Wind.MAX_DISTANCE_FEET
+                    else Distance.Foot((wind.until_distance >> Distance.Foot) * cosine)
+                    << wind.until_distance.units)
+            for wind in winds
+        ), key=lambda wind: wind.until_distance.raw_value) or [Wind()]
+        return self.winds
+
+    def set_target_offset(self, horizontal_distance: [float, Distance],
+                          vertical_offset: [float, Distance]) -> Distance:
+        """Sets look_angle to a target at a known height offset instead of a known angle
+        :param horizontal_distance: Horizontal (ground) distance to the target
+        :param vertical_offset: Height of the target relative to the sight (negative when below)
+        :return: Sight-line distance to the target, to pass to Calculator.barrel_elevation_for_target()
+        """
+        x = PreferredUnits.distance(horizontal_distance) >> Distance.Foot
+        y = PreferredUnits.distance(vertical_offset) >> Distance.Foot
+        if x <= 0:
+            raise ValueError("Horizontal distance to target have to be > 0")
+        self.look_angle = Angular.Radian(math.atan2(y, x)) << PreferredUnits.angular
+        return Distance.Foot(math.hypot(x, y)) << PreferredUnits.distance
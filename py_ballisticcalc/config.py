@@ -0,0 +1,194 @@
+"""Calculator configuration: integration step, stop conditions and enabled effects"""
+import json
+from dataclasses import dataclass, field, fields
+from enum import Enum
+
+try:
+    import tomllib
+except ImportError:
+    import tomli as tomllib
+
+from .conditions import Gravity
+from .unit import (Distance, Velocity, Energy, Angular, Acceleration, Time, Unit, Dimension, AbstractUnit,
+                   UnitTypeError)
+
+__all__ = ('CalculatorConfig', 'AdjustmentReference', 'SpinDriftModel', 'TransonicDegradation')
+
+
+def _convert_to_preferred_units(instance) -> None:
+    """Converts numbers in Dimension fields of a frozen dataclass to their prefer_units,
+        as PreferredUnits.Mixin does for mutable dataclasses"""
+    for _field in fields(instance):
+        value = getattr(instance, _field.name)
+        if (units := _field.metadata.get('prefer_units')) and value is not None and not isinstance(value, AbstractUnit):
+            object.__setattr__(instance, _field.name, _preferred_units(units)(value))
+
+
+def _preferred_units(prefer_units: [str, Unit]) -> Unit:
+    """:return: Unit of Dimension(prefer_units=...), given as a Unit or as a name of PreferredUnits"""
+    return prefer_units if isinstance(prefer_units, Unit) else Unit.parse_unit(prefer_units)
+
+
+class AdjustmentReference(str, Enum):
+    """Line from which TrajectoryData.drop_adj and .windage_adj are measured.
+    All references give zero adjustment at zero distance.
+
+    SIGHT: sight line through the sight at look_angle (adjustment to dial on a scope)
+    BORE: bore line through the muzzle at the barrel elevation and azimuth
+    HORIZONTAL: horizontal line through the sight
+    """
+    SIGHT = 'sight'
+    BORE = 'bore'
+    HORIZONTAL = 'horizontal'
+
+
+class SpinDriftModel(str, Enum):
+    """Model of the drift of a spin-stabilized projectile to the side of its twist.
+
+    LITZ: 1.25 * (Sg + 1.2) * t^1.83 inches, with gyroscopic stability Sg at the muzzle (Litz's approximation)
+    YAW_OF_REPOSE: lift of the yaw of repose integrated along the trajectory (after McCoy), following
+        spin decay, velocity and trajectory angle, so it also holds for lobbed and subsonic trajectories
+    NONE: no spin drift
+    """
+    LITZ = 'litz'
+    YAW_OF_REPOSE = 'yaw_of_repose'
+    NONE = 'none'
+
+
+@dataclass(frozen=True)
+class TransonicDegradation:
+    """
+    Model of limit-cycle yaw of a marginally stable projectile going transonic.
+    Once the projectile is below mach_threshold with gyroscopic stability below stability_threshold,
+        drag is increased by drag_increase and dispersion grows by the dispersion angle
+        for the rest of the flight.
+    Frozen, as part of CalculatorConfig: use dataclasses.replace() for a changed copy.
+
+    :param stability_threshold: Gyroscopic stability below which the projectile starts to yaw
+    :param mach_threshold: Mach number below which the projectile is considered transonic
+    :param drag_increase: Fractional increase of drag while yawing (0.1 = 10% more drag)
+    :param dispersion: Additional dispersion angle (diameter) from the onset point
+    """
+    stability_threshold: float = field(default=1.4)
+    mach_threshold: float = field(default=1.2)
+    drag_increase: float = field(default=0.1)
+    dispersion: [float, Angular] = Dimension(prefer_units='adjustment')
+
+    def __post_init__(self):
+        _convert_to_preferred_units(self)
+        if self.dispersion is None:
+            object.__setattr__(self, 'dispersion', Angular.MOA(1))
+        if self.drag_increase < 0:
+            raise ValueError("drag_increase have to be >= 0")
+
+
+@dataclass(frozen=True)
+class CalculatorConfig:  # pylint: disable=too-many-instance-attributes
+    """
+    Settings for TrajectoryCalc.  Pass to Calculator(config=...) to use instead of the global settings.
+    Frozen, so that calculators sharing it can't be changed while they run:
+        use dataclasses.replace() for a changed copy.
+
+    :param max_calc_step_size: Maximum distance between integration steps
+    :param step_tolerance: Target error of positions over the calculated range (None = fixed step).
+        Steps then shrink where the trajectory curves or drag changes fast (transonic drag rise)
+        and grow elsewhere, between 1/16 and 16 times the fixed step.
+    :param minimum_velocity: Stop calculation when velocity falls below this
+    :param minimum_energy: Stop calculation when kinetic energy falls below this (None = no limit).
+        Requires DragModel.weight.
+    :param maximum_drop: Stop calculation when height relative to the muzzle falls below this (negative)
+    :param minimum_altitude: Stop calculation when altitude above sea level falls below this (None = no limit)
+    :param maximum_time: Stop calculation when time of flight (float in seconds) exceeds this (None = no limit)
+    :param zero_finding_accuracy: Vertical tolerance of zero_angle() solution
+    :param max_iterations: Maximum number of zero_angle() iterations
+    :param gravity: Gravitational acceleration (float in PreferredUnits.acceleration), e.g. Gravity.Moon
+    :param use_powder_sensitivity: Correct muzzle velocity for powder temperature (Ammo.temp_modifier)
+    :param use_spin_drift: Include spin drift in windage
+    :param spin_drift_model: SpinDriftModel used if use_spin_drift, default LITZ
+    :param adjustment_reference: Line from which drop and windage adjustments are measured
+    :param transonic_degradation: Optional TransonicDegradation model (None = disabled)
+    :param extreme_range: Integrate over time along the path instead of in steps down range, with gravity
+        toward the center of the Earth and air density by height above its curved surface.
+        For shots past 2000 m and steep angles; slower, and max_calc_step_size is then the length of path.
+    """
+    max_calc_step_size: [float, Distance] = Dimension(prefer_units='distance')
+    step_tolerance: [float, Distance] = Dimension(prefer_units='drop')
+    minimum_velocity: [float, Velocity] = Dimension(prefer_units='velocity')
+    minimum_energy: [float, Energy] = Dimension(prefer_units='energy')
+    maximum_drop: [float, Distance] = Dimension(prefer_units='distance')
+    minimum_altitude: [float, Distance] = Dimension(prefer_units='distance')
+    maximum_time: [float, Time] = Dimension(prefer_units=Unit.Second)
+    zero_finding_accuracy: [float, Distance] = Dimension(prefer_units='distance')
+    max_iterations: int = field(default=20)
+    gravity: [float, Acceleration] = Dimension(prefer_units='acceleration')
+    use_powder_sensitivity: bool = field(default=False)
+    use_spin_drift: bool = field(default=True)
+    spin_drift_model: SpinDriftModel = field(default=SpinDriftModel.LITZ)
+    adjustment_reference: AdjustmentReference = field(default=AdjustmentReference.SIGHT)
+    transonic_degradation: TransonicDegradation = field(default=None)
+    extreme_range: bool = field(default=False)
+
+    def __post_init__(self):
+        _convert_to_preferred_units(self)
+        _set = object.__setattr__
+        if self.max_calc_step_size is None:
+            _set(self, 'max_calc_step_size', Distance.Foot(0.5))
+        if self.minimum_velocity is None:
+            _set(self, 'minimum_velocity', Velocity.FPS(50))
+        if self.maximum_drop is None:
+            _set(self, 'maximum_drop', Distance.Foot(-15000))
+        if self.zero_finding_accuracy is None:
+            _set(self, 'zero_finding_accuracy', Distance.Foot(0.000005))
+        if self.gravity is None:
+            _set(self, 'gravity', Gravity.Earth)
+        _set(self, 'adjustment_reference', AdjustmentReference(self.adjustment_reference))
+        _set(self, 'spin_drift_model', SpinDriftModel(self.spin_drift_model))
+        if isinstance(self.transonic_degradation, dict):
+            _set(self, 'transonic_degradation', TransonicDegradation(**self.transonic_degradation))
+        if self.max_calc_step_size.raw_value <= 0:
+            raise ValueError("max_calc_step_size have to be > 0")
+        if self.step_tolerance is not None and self.step_tolerance.raw_value <= 0:
+            raise ValueError("step_tolerance have to be > 0")
+        if not isinstance(self.gravity, Acceleration):
+            raise UnitTypeError(f"gravity has to be an Acceleration, not {type(self.gravity).__name__}")
+        if self.gravity.raw_value < 0:
+            raise ValueError("gravity have to be >= 0")
+        if self.maximum_time is not None and self.maximum_time.raw_value <= 0:
+            raise ValueError("maximum_time have to be > 0")
+        if self.max_iterations < 1:
+            raise ValueError("max_iterations have to be >= 1")
+
+    @classmethod
+    def from_dict(cls, data: dict) -> 'CalculatorConfig':
+        """Creates config from a mapping of field names to values.
+        Dimensions may be given as {value=..., units=...} (see AbstractUnit.from_dict()), as a string like "0.5ft",
+            as a unit instance, or as a number in PreferredUnits.
+        """
+        kwargs = {}
+        known = {f.name: f for f in fields(cls)}
+        for key, value in data.items():
+            if (_field := known.get(key)) is None:
+                raise KeyError(f"Unknown calculator config property {key!r}")
+            if (preferred := _field.metadata.get('prefer_units')) and not isinstance(value, AbstractUnit):
+                if isinstance(value, dict):
+                    # Class of the preferred units, so that units of another dimension are rejected
+                    value = type(_preferred_units(preferred)(0)).from_dict(value)
+                elif isinstance(value, str):
+                    value = Unit.parse_value(value, preferred)
+            kwargs[key] = value
+        return cls(**kwargs)
+
+    @classmethod
+    def from_toml(cls, path: str) -> 'CalculatorConfig':
+        """Loads config from the [pybc.calculator] table of a TOML file (or from its root table without [pybc])"""
+        with open(path, 'rb') as fp:
+            data = tomllib.load(fp)
+        if 'pybc' in data:
+            data = data['pybc'].get('calculator', {})
+        return cls.from_dict(data)
+
+    @classmethod
+    def from_json(cls, path: str) -> 'CalculatorConfig':
+        """Loads config from a JSON object, in the same format as from_dict()"""
+        with open(path, 'r', encoding='utf-8') as fp:
+            return cls.from_dict(json.load(fp))
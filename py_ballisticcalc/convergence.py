@@ -0,0 +1,87 @@
+"""Step-size convergence report for choosing max_calc_step_size"""
+from dataclasses import replace
+from typing import NamedTuple
+
+# pylint: disable=import-error,no-name-in-module,wildcard-import,unused-wildcard-import
+from .backend import *
+from .conditions import Shot
+from .config import CalculatorConfig
+from .trajectory_data import RangeError
+from .unit import Distance, PreferredUnits
+
+__all__ = ('StepConvergence', 'step_size_convergence')
+
+
+class StepConvergence(NamedTuple):
+    """
+    Trajectory values at one range computed with one integration step size,
+    and their differences from the values computed with the finest step size.
+
+    Attributes:
+        step_size (Distance): max_calc_step_size used for the calculation
+        distance (Distance): downrange distance of the sample
+        height (Distance): height of the trajectory at distance
+        windage (Distance): windage at distance
+        time (float): time of flight to distance
+        height_error (Distance): height - height at finest step size
+        windage_error (Distance): windage - windage at finest step size
+        time_error (float): time - time at finest step size
+    """
+    step_size: Distance
+    distance: Distance
+    height: Distance
+    windage: Distance
+    time: float
+    height_error: Distance
+    windage_error: Distance
+    time_error: float
+
+
+def step_size_convergence(shot: Shot, ranges: list[[float, Distance]],
+                          step_sizes: list[[float, Distance]] = None,
+                          config: CalculatorConfig = None) -> list[StepConvergence]:
+    """Runs the same shot with several integration step sizes to show how results converge.
+    Errors are reported relative to the finest step size, so users can pick the largest
+        max_calc_step_size with an acceptable error bound.
+    :param shot: Shot to calculate
+    :param ranges: Downrange distances at which to compare height, windage and time of flight
+    :param step_sizes: Values of max_calc_step_size to compare.
+        Default is 4, 2, 1, 1/2 and 1/4 times the max_calc_step_size of config.
+    :param config: CalculatorConfig whose other settings are used for every step size;
+        None for the global settings.  Global settings are not changed.
+    :return: StepConvergence for each step size (coarsest first) and each range (nearest first)
+    """
+    distances = sorted(PreferredUnits.distance(r) for r in ranges)
+    if not distances:
+        raise ValueError("At least one range is required")
+    if config is None:
+        config = CalculatorConfig(max_calc_step_size=get_global_max_calc_step_size(), gravity=get_global_gravity(),
+                                  use_powder_sensitivity=get_global_use_powder_sensitivity())
+    if step_sizes is None:
+        step_sizes = [Distance.Foot((config.max_calc_step_size >> Distance.Foot) * k) for k in (4, 2, 1, 0.5, 0.25)]
+    step_sizes = sorted((PreferredUnits.distance(s) for s in step_sizes), key=lambda s: s.raw_value, reverse=True)
+
+    results = []  # (height, windage) in feet and time of flight at each distance, for each step size
+    for step_size in step_sizes:
+        calc = TrajectoryCalc(shot.ammo, config=replace(config, max_calc_step_size=step_size))
+        rows = calc.trajectory_at_ranges(shot, distances)
+        if len(rows) < len(distances):
+            raise RangeError(calc.termination_reason, f"distance {distances[-1]}", rows[-1] if rows else None)
+        results.append([(row.height >> Distance.Foot, row.windage >> Distance.Foot, row.time) for row in rows])
+
+    reference = results[-1]
+    report = []
+    for step_size, samples in zip(step_sizes, results):
+        for distance, (height, windage, time), (ref_height, ref_windage, ref_time) in zip(distances, samples,
+                                                                                           reference):
+            report.append(StepConvergence(
+                step_size=step_size,
+                distance=distance,
+                height=Distance.Foot(height) << PreferredUnits.drop,
+                windage=Distance.Foot(windage) << PreferredUnits.drop,
+                time=time,
+                height_error=Distance.Foot(height - ref_height) << PreferredUnits.drop,
+                windage_error=Distance.Foot(windage - ref_windage) << PreferredUnits.drop,
+                time_error=time - ref_time
+            ))
+    return report
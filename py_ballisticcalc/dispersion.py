@@ -0,0 +1,168 @@
+"""Monte Carlo dispersion: impacts sampled from errors of muzzle velocity, BC, wind and ranging, and hit probability"""
+import math
+import random
+from dataclasses import dataclass, field, replace
+from typing import NamedTuple
+
+from .conditions import Shot, Wind
+from .engagement import GROUP_SIGMAS
+from .interface import Calculator, ShotScenario
+from .unit import Angular, Distance, Velocity, PreferredUnits, Dimension
+
+__all__ = ('ShotUncertainty', 'SampledImpact', 'ImpactDistribution', 'sample_impacts')
+
+
+@dataclass
+class ShotUncertainty(PreferredUnits.Mixin):
+    """
+    Random errors of a shot, each sampled from a normal distribution
+
+    :param mv_sd: Standard deviation of muzzle velocity (shot to shot)
+    :param bc_sd: Standard deviation of the BC, as a fraction of the BC (e.g. 0.02 for 2%)
+    :param wind_sd: Standard deviation of the crosswind estimate
+    :param range_sd: Standard deviation of the ranging error
+    :param precision: Angular diameter holding 95% of shots of the rifle and shooter (group size)
+    """
+    mv_sd: [float, Velocity] = Dimension(prefer_units='velocity')
+    bc_sd: float = field(default=0)
+    wind_sd: [float, Velocity] = Dimension(prefer_units='velocity')
+    range_sd: [float, Distance] = Dimension(prefer_units='distance')
+    precision: [float, Angular] = Dimension(prefer_units='adjustment')
+
+    def __post_init__(self):
+        if not self.mv_sd:
+            self.mv_sd = 0
+        if not self.wind_sd:
+            self.wind_sd = 0
+        if not self.range_sd:
+            self.range_sd = 0
+        if not self.precision:
+            self.precision = 0
+        if self.bc_sd < 0 or min(self.mv_sd.raw_value, self.wind_sd.raw_value, self.range_sd.raw_value,
+                                 self.precision.raw_value) < 0:
+            raise ValueError("Uncertainties can't be negative")
+
+
+class SampledImpact(NamedTuple):
+    """
+    Attributes:
+        vertical (Distance): impact relative to the target center, positive => high
+        horizontal (Distance): impact relative to the target center, positive => right
+    """
+    vertical: Distance
+    horizontal: Distance
+
+
+class ImpactDistribution(NamedTuple):
+    """
+    Attributes:
+        distance (Distance): downrange distance of the target
+        impacts (list[SampledImpact]): sampled impacts
+    """
+    distance: Distance
+    impacts: list[SampledImpact]
+
+    def _offsets(self, axis: int) -> list[float]:
+        return [impact[axis] >> Distance.Inch for impact in self.impacts]
+
+    @property
+    def mean_point(self) -> SampledImpact:
+        """:return: mean point of impact"""
+        return SampledImpact(*(Distance.Inch(sum(offsets) / len(offsets)) << PreferredUnits.drop
+                               for offsets in (self._offsets(0), self._offsets(1))))
+
+    @property
+    def standard_deviation(self) -> SampledImpact:
+        """:return: sample standard deviation of impacts on each axis"""
+        result = []
+        for offsets in (self._offsets(0), self._offsets(1)):
+            mean = sum(offsets) / len(offsets)
+            variance = sum((o - mean) ** 2 for o in offsets) / max(len(offsets) - 1, 1)
+            result.append(Distance.Inch(math.sqrt(variance)) << PreferredUnits.drop)
+        return SampledImpact(*result)
+
+    def hit_probability(self, width: [float, Distance], height: [float, Distance] = None) -> float:
+        """:return: fraction of impacts on a rectangular target centered on the point of aim
+        :param width: target width
+        :param height: target height, default the width
+        """
+        half_width = (PreferredUnits.target_height(width) >> Distance.Inch) / 2
+        half_height = half_width if height is None else (PreferredUnits.target_height(height) >> Distance.Inch) / 2
+        hits = sum(1 for v, h in zip(self._offsets(0), self._offsets(1))
+                   if abs(v) <= half_height and abs(h) <= half_width)
+        return hits / len(self.impacts)
+
+
+def _with_crosswind(winds: list[Wind], error: float) -> list[Wind]:
+    """:return: winds with error (fps) added to the crosswind of each, or calm air with the error"""
+    if not winds and error:
+        winds = [Wind()]
+    result = []
+    for wind in winds:
+        speed = wind.velocity >> Velocity.FPS
+        direction = wind.direction_from >> Angular.Radian
+        range_component = speed * math.cos(direction)
+        cross_component = speed * math.sin(direction) + error
+        result.append(Wind(Velocity.FPS(math.hypot(range_component, cross_component)),
+                           Angular.Radian(math.atan2(cross_component, range_component)), wind.until_distance))
+    return result
+
+
+def sample_impacts(calc: Calculator, shot: Shot, distance: [float, Distance], uncertainty: ShotUncertainty,
+                   samples: int = 1000, seed: int = None, workers: int = 1) -> ImpactDistribution:
+    """Monte Carlo impacts on a target at distance: each sample aims by the solution of shot for a ranged
+        distance, while its projectile flies with sampled muzzle velocity, BC and crosswind error
+    :param calc: Calculator to solve the trajectories
+    :param shot: zeroed shot in the estimated conditions
+    :param distance: true downrange distance of the target
+    :param uncertainty: errors to sample
+    :param samples: number of sampled shots
+    :param seed: seed of the random generator, for repeatable samples
+    :param workers: number of processes solving trajectories, as for Calculator.fire_batch()
+    """
+    if samples < 1:
+        raise ValueError("At least one sample is required")
+    distance = PreferredUnits.distance(distance)
+    rng = random.Random(seed)
+    target = distance >> Distance.Foot
+    mv_sd = uncertainty.mv_sd >> Velocity.FPS
+    wind_sd = uncertainty.wind_sd >> Velocity.FPS
+    range_sd = uncertainty.range_sd >> Distance.Foot
+    # Per-axis standard deviation of the circular normal distribution of precision
+    precision_sd = (uncertainty.precision >> Angular.Radian) / 2 / GROUP_SIGMAS
+
+    dm = shot.ammo.dm
+    sampled_shots, ranged, precision = [], [], []
+    for _ in range(samples):
+        ammo = shot.ammo.with_mv(Velocity.FPS((shot.ammo.mv >> Velocity.FPS) + rng.gauss(0, mv_sd)))
+        if uncertainty.bc_sd:
+            ammo.dm = dm.with_bc(dm.BC * (1 + rng.gauss(0, uncertainty.bc_sd)))
+        sampled_shots.append(replace(shot, ammo=ammo, winds=_with_crosswind(shot.winds, rng.gauss(0, wind_sd))))
+        ranged.append(max(target + rng.gauss(0, range_sd), 1.0))
+        precision.append((rng.gauss(0, precision_sd), rng.gauss(0, precision_sd)))
+
+    # Aim of each sample is the solution of the nominal shot at its ranged distance
+    order = sorted(range(samples), key=lambda i: ranged[i])
+    aimed = calc.fire_at_ranges(shot, [Distance.Foot(ranged[i]) for i in order])
+    rows = aimed.trajectory
+    if len(rows) < samples:
+        raise aimed.range_error(Distance.Foot(ranged[order[len(rows)]]) << PreferredUnits.distance)
+    aims = [None] * samples
+    for i, row in zip(order, rows):
+        aims[i] = (row.drop_adj >> Angular.Radian, row.windage_adj >> Angular.Radian)
+
+    flown = []
+    for result in calc.fire_batch((ShotScenario(sampled, distance, distance) for sampled in sampled_shots), workers):
+        row = result.trajectory[-1]
+        if (row.distance >> Distance.Foot) < target - 1e-6:
+            raise result.range_error(distance)
+        flown.append((row.drop_adj >> Angular.Radian, row.windage_adj >> Angular.Radian))
+
+    impacts = []
+    for (drop_adj, windage_adj), (aim_drop, aim_windage), (precision_v, precision_h) in zip(flown, aims, precision):
+        # Impact angle relative to the target center is the sampled correction the aim didn't make
+        vertical = math.tan(drop_adj - aim_drop + precision_v) * target
+        horizontal = math.tan(windage_adj - aim_windage + precision_h) * target
+        impacts.append(SampledImpact(Distance.Foot(vertical) << PreferredUnits.drop,
+                                     Distance.Foot(horizontal) << PreferredUnits.drop))
+    return ImpactDistribution(distance, impacts)
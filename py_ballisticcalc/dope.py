@@ -0,0 +1,329 @@
+"""DOPE book: SQLite persistence of shot scenarios, calculated solutions and observed impacts"""
+import json
+import sqlite3
+from dataclasses import fields, is_dataclass
+from datetime import datetime, timezone
+from enum import Enum
+from typing import NamedTuple, Optional
+
+from .conditions import Atmo, AtmosphereModel, Shot, Wind, WindLayer
+from .drag_model import BCReference, DragModel
+from .munition import AeroCoefficients, Ammo, ProjectilePhase, Sight, Weapon
+from .trajectory_data import HitResult, TrajectoryData
+from .unit import AbstractUnit, Angular, Distance, Velocity, PreferredUnits
+
+__all__ = ('DopeBook', 'DopeScenario', 'DopeSolution', 'DopeImpact', 'DopeEntry')
+
+cSolutionMatchDistance = 0.5  # Feet between an impact and the solution it is compared with
+
+SCHEMA = """
+CREATE TABLE IF NOT EXISTS scenarios (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    created TEXT NOT NULL,
+    shot TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS solutions (
+    id INTEGER PRIMARY KEY,
+    scenario_id INTEGER NOT NULL REFERENCES scenarios(id) ON DELETE CASCADE,
+    created TEXT NOT NULL,
+    distance REAL NOT NULL,
+    drop_adj REAL NOT NULL,
+    windage_adj REAL NOT NULL,
+    velocity REAL NOT NULL,
+    time REAL NOT NULL
+);
+CREATE TABLE IF NOT EXISTS impacts (
+    id INTEGER PRIMARY KEY,
+    scenario_id INTEGER NOT NULL REFERENCES scenarios(id) ON DELETE CASCADE,
+    created TEXT NOT NULL,
+    distance REAL NOT NULL,
+    elevation REAL NOT NULL,
+    windage REAL NOT NULL,
+    note TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS solutions_scenario ON solutions(scenario_id, distance);
+CREATE INDEX IF NOT EXISTS impacts_scenario ON impacts(scenario_id, distance);
+"""
+
+
+class DopeScenario(NamedTuple):
+    """Stored shot scenario"""
+    id: int
+    name: str
+    created: str
+    shot: Shot
+
+
+class DopeSolution(NamedTuple):
+    """
+    Stored calculated solution at one distance
+
+    Attributes:
+        drop_adj (Angular): elevation adjustment, as TrajectoryData.drop_adj
+        windage_adj (Angular): windage adjustment, as TrajectoryData.windage_adj
+        time (float): time of flight in seconds
+    """
+    id: int
+    scenario_id: int
+    created: str
+    distance: Distance
+    drop_adj: Angular
+    windage_adj: Angular
+    velocity: Velocity
+    time: float
+
+
+class DopeImpact(NamedTuple):
+    """
+    Stored observed impact: the adjustments that put a shot on target at distance
+
+    Attributes:
+        elevation (Angular): elevation adjustment that hit, in the sign convention of TrajectoryData.drop_adj
+        windage (Angular): windage adjustment that hit, in the sign convention of TrajectoryData.windage_adj
+    """
+    id: int
+    scenario_id: int
+    created: str
+    distance: Distance
+    elevation: Angular
+    windage: Angular
+    note: str
+
+
+class DopeEntry(NamedTuple):
+    """
+    Observed impact with the calculated solution at its distance
+
+    Attributes:
+        elevation_error (Angular): calculated drop_adj - observed elevation
+        windage_error (Angular): calculated windage_adj - observed windage
+    """
+    impact: DopeImpact
+    solution: DopeSolution
+    elevation_error: Angular
+    windage_error: Angular
+
+
+class DopeBook:
+    """
+    Persistent DOPE (data on previous engagements) book in a SQLite database.
+        Shots are stored as JSON, distances in feet, angles in radians and velocities in fps,
+        so the database can also be read by other tools.
+
+    Usage:
+        with DopeBook('dope.db') as book:
+            scenario_id = book.add_scenario('308 @ range', shot)
+            book.add_solutions(scenario_id, calc.fire(shot, 1000, 100))
+            book.add_impact(scenario_id, Distance.Yard(600), Angular.Mil(-4.3), Angular.Mil(0.2))
+            for entry in book.entries(scenario_id):
+                print(entry.impact.distance, entry.elevation_error)
+    """
+
+    def __init__(self, path: str = ':memory:'):
+        """:param path: SQLite database file, created if it doesn't exist"""
+        self.connection = sqlite3.connect(path)
+        self.connection.execute("PRAGMA foreign_keys = ON")
+        self.connection.executescript(SCHEMA)
+
+    def close(self) -> None:
+        """Closes the database"""
+        self.connection.close()
+
+    def __enter__(self) -> 'DopeBook':
+        return self
+
+    def __exit__(self, *args) -> None:
+        self.close()
+
+    def add_scenario(self, name: str, shot: Shot) -> int:
+        """Stores a copy of shot (weapon, ammo, atmosphere, winds and angles)
+        :return: scenario id
+        :raises TypeError: if shot.atmo_model is an AtmosphereModel other than Atmo, which can't be stored
+        """
+        with self.connection:
+            cursor = self.connection.execute(
+                "INSERT INTO scenarios (name, created, shot) VALUES (?, ?, ?)",
+                (name, _now(), json.dumps(_encode(shot))))
+        return cursor.lastrowid
+
+    def scenario(self, scenario_id: int) -> DopeScenario:
+        """:return: stored scenario, with a new Shot restored from the database"""
+        row = self.connection.execute(
+            "SELECT id, name, created, shot FROM scenarios WHERE id = ?", (scenario_id,)).fetchone()
+        if row is None:
+            raise KeyError(f"No scenario with id {scenario_id}")
+        return DopeScenario(row[0], row[1], row[2], _decode_shot(json.loads(row[3])))
+
+    def scenarios(self, name: str = None) -> list[tuple[int, str, str]]:
+        """:param name: Only scenarios with this name; None for all
+        :return: (id, name, created) of stored scenarios, oldest first
+        """
+        query = "SELECT id, name, created FROM scenarios"
+        parameters = ()
+        if name is not None:
+            query += " WHERE name = ?"
+            parameters = (name,)
+        return [tuple(row) for row in self.connection.execute(query + " ORDER BY id", parameters)]
+
+    def delete_scenario(self, scenario_id: int) -> None:
+        """Deletes scenario with its solutions and impacts"""
+        with self.connection:
+            self.connection.execute("DELETE FROM scenarios WHERE id = ?", (scenario_id,))
+
+    def add_solutions(self, scenario_id: int, rows: [HitResult, list[TrajectoryData]]) -> list[int]:
+        """Stores calculated rows (e.g. HitResult from Calculator.fire() of the scenario's shot)
+        :return: solution ids
+        """
+        created = _now()
+        ids = []
+        with self.connection:
+            for row in rows:
+                cursor = self.connection.execute(
+                    "INSERT INTO solutions (scenario_id, created, distance, drop_adj, windage_adj, velocity, time)"
+                    " VALUES (?, ?, ?, ?, ?, ?, ?)",
+                    (scenario_id, created, row.distance >> Distance.Foot, row.drop_adj >> Angular.Radian,
+                     row.windage_adj >> Angular.Radian, row.velocity >> Velocity.FPS, row.time))
+                ids.append(cursor.lastrowid)
+        return ids
+
+    def solutions(self, scenario_id: int, min_distance: [float, Distance] = None,
+                  max_distance: [float, Distance] = None) -> list[DopeSolution]:
+        """:return: stored solutions of scenario between min_distance and max_distance, nearest first"""
+        return [DopeSolution(
+            row[0], row[1], row[2],
+            Distance.Foot(row[3]) << PreferredUnits.distance,
+            Angular.Radian(row[4]) << PreferredUnits.adjustment,
+            Angular.Radian(row[5]) << PreferredUnits.adjustment,
+            Velocity.FPS(row[6]) << PreferredUnits.velocity,
+            row[7]
+        ) for row in self._select('solutions', 'drop_adj, windage_adj, velocity, time',
+                                  scenario_id, min_distance, max_distance)]
+
+    def add_impact(self, scenario_id: int, distance: [float, Distance], elevation: [float, Angular],
+                   windage: [float, Angular] = 0, note: str = '') -> int:
+        """Stores the adjustments that put a shot on target at distance
+        :return: impact id
+        """
+        with self.connection:
+            cursor = self.connection.execute(
+                "INSERT INTO impacts (scenario_id, created, distance, elevation, windage, note)"
+                " VALUES (?, ?, ?, ?, ?, ?)",
+                (scenario_id, _now(), PreferredUnits.distance(distance) >> Distance.Foot,
+                 PreferredUnits.adjustment(elevation) >> Angular.Radian,
+                 PreferredUnits.adjustment(windage) >> Angular.Radian, note))
+        return cursor.lastrowid
+
+    def impacts(self, scenario_id: int, min_distance: [float, Distance] = None,
+                max_distance: [float, Distance] = None) -> list[DopeImpact]:
+        """:return: stored impacts of scenario between min_distance and max_distance, nearest first"""
+        return [DopeImpact(
+            row[0], row[1], row[2],
+            Distance.Foot(row[3]) << PreferredUnits.distance,
+            Angular.Radian(row[4]) << PreferredUnits.adjustment,
+            Angular.Radian(row[5]) << PreferredUnits.adjustment,
+            row[6]
+        ) for row in self._select('impacts', 'elevation, windage, note', scenario_id, min_distance, max_distance)]
+
+    def entries(self, scenario_id: int) -> list[DopeEntry]:
+        """:return: impacts of scenario compared with the latest solution at the same distance
+            (impacts without a solution within half a foot are left out)"""
+        entries = []
+        solutions = self.solutions(scenario_id)
+        for impact in self.impacts(scenario_id):
+            x = impact.distance >> Distance.Foot
+            matches = [s for s in solutions if abs((s.distance >> Distance.Foot) - x) <= cSolutionMatchDistance]
+            if not matches:
+                continue
+            solution = max(matches, key=lambda s: s.id)
+            entries.append(DopeEntry(
+                impact, solution,
+                Angular.Radian((solution.drop_adj >> Angular.Radian) - (impact.elevation >> Angular.Radian))
+                << PreferredUnits.adjustment,
+                Angular.Radian((solution.windage_adj >> Angular.Radian) - (impact.windage >> Angular.Radian))
+                << PreferredUnits.adjustment
+            ))
+        return entries
+
+    def _select(self, table: str, columns: str, scenario_id: int,
+                min_distance: Optional[Distance], max_distance: Optional[Distance]) -> list[tuple]:
+        query = f"SELECT id, scenario_id, created, distance, {columns} FROM {table} WHERE scenario_id = ?"
+        parameters = [scenario_id]
+        if min_distance is not None:
+            query += " AND distance >= ?"
+            parameters.append(PreferredUnits.distance(min_distance) >> Distance.Foot)
+        if max_distance is not None:
+            query += " AND distance <= ?"
+            parameters.append(PreferredUnits.distance(max_distance) >> Distance.Foot)
+        return self.connection.execute(query + " ORDER BY distance, id", parameters).fetchall()
+
+
+def _now() -> str:
+    return datetime.now(timezone.utc).isoformat()
+
+
+def _encode(value):
+    """:return: JSON-compatible form of shot inputs: units as {value, units}, dataclasses as dicts"""
+    if isinstance(value, AbstractUnit):
+        return value.to_dict()
+    if isinstance(value, AtmosphereModel) and not isinstance(value, Atmo):
+        raise TypeError(f"Can't store {type(value).__name__}: only Atmo is supported as Shot.atmo_model")
+    if isinstance(value, DragModel):
+        return {'bc': value.BC, 'drag_table': [[p.Mach, p.CD] for p in value.drag_table],
+                'weight': _encode(value.weight), 'diameter': _encode(value.diameter),
+                'length': _encode(value.length), 'bc_reference': _encode(value.bc_reference)}
+    if is_dataclass(value):
+        return {f.name: _encode(getattr(value, f.name)) for f in fields(value) if f.init}
+    if isinstance(value, (list, tuple)):
+        return [_encode(v) for v in value]
+    if isinstance(value, Enum):
+        return value.value
+    return value
+
+
+def _decode(data: Optional[dict]) -> Optional[dict]:
+    """:return: data with {value, units} entries converted to units"""
+    if data is None:
+        return None
+    return {key: AbstractUnit.from_dict(value)
+            if isinstance(value, dict) and value.keys() == {'value', 'units'} else value
+            for key, value in data.items()}
+
+
+def _decode_drag_model(data: dict) -> DragModel:
+    dm = _decode(data)
+    reference = _decode(dm.pop('bc_reference'))
+    if reference is not None:
+        if reference['atmo'] is not None:
+            reference['atmo'] = Atmo(**_decode(reference['atmo']))
+        reference = BCReference(**reference)
+    return DragModel(dm.pop('bc'), [{'Mach': m, 'CD': cd} for m, cd in dm.pop('drag_table')],
+                     bc_reference=reference, **dm)
+
+
+def _decode_shot(data: dict) -> Shot:
+    weapon = _decode(data['weapon'])
+    if weapon.get('sight') is not None:
+        sight = _decode(weapon['sight'])
+        sight['focal_plane'] = Sight.FocalPlane(sight['focal_plane'])
+        weapon['sight'] = Sight(**sight)
+    ammo = _decode(data['ammo'])
+    ammo['dm'] = _decode_drag_model(ammo['dm'])
+    phases = []
+    for phase in ammo.get('phases', ()):
+        phase = _decode(phase)
+        if phase['dm'] is not None:
+            phase['dm'] = _decode_drag_model(phase['dm'])
+        phases.append(ProjectilePhase(**phase))
+    ammo['phases'] = phases
+    ammo['mv_by_temperature'] = [[AbstractUnit.from_dict(value) for value in point]
+                                 for point in ammo.get('mv_by_temperature', ())]
+    if ammo.get('aerodynamics') is not None:
+        ammo['aerodynamics'] = AeroCoefficients(**ammo['aerodynamics'])
+    shot = _decode({key: value for key, value in data.items()
+                    if key not in ('weapon', 'ammo', 'atmo', 'atmo_model', 'winds', 'wind_layers')})
+    if data.get('atmo_model') is not None:
+        shot['atmo_model'] = Atmo(**_decode(data['atmo_model']))
+    return Shot(weapon=Weapon(**weapon), ammo=Ammo(**ammo), atmo=Atmo(**_decode(data['atmo'])),
+                winds=[Wind(**_decode(w)) for w in data['winds']],
+                wind_layers=[WindLayer(**_decode(w)) for w in data.get('wind_layers', ())], **shot)
@@ -1,12 +1,18 @@
 """Drag model of projectile"""
 
 import math
+import numbers
 from dataclasses import dataclass, field
-from typing import Union
+from typing import Union, TYPE_CHECKING
 
+from .drag_tables import get_drag_table
 from .unit import Weight, Distance, Velocity, PreferredUnits, Dimension
 
-__all__ = ('DragModel', 'DragDataPoint', 'BCPoint', 'DragModelMultiBC')
+if TYPE_CHECKING:
+    from .conditions import Atmo
+
+__all__ = ('DragModel', 'DragDataPoint', 'BCPoint', 'DragModelMultiBC', 'DragModelCD', 'BCReference',
+           'sectional_density', 'form_factor', 'convert_bc')
 
 cSpeedOfSoundMetric = 340.0  # Speed of sound in standard atmosphere, in m/s
 
@@ -29,12 +35,44 @@ class BCPoint:
     def __post_init__(self):
         # If Mach not defined then convert V using standard atmosphere
         if self.Mach < 0:
+            if self.V is None:
+                raise ValueError('BCPoint requires Mach or V')
             self.Mach = (self.V >> Velocity.MPS) / cSpeedOfSoundMetric
-        if self.BC <= 0:
-            raise ValueError('Ballistic coefficient must be positive')
+        _validate_bc(self.BC)
+
+
+@dataclass
+class BCReference(PreferredUnits.Mixin):
+    """
+    Conditions under which a ballistic coefficient was measured, for BCs that were not
+        corrected to standard (ICAO sea-level) atmosphere by their publisher.
+
+    :param atmo: Atmosphere in which the BC was measured; None if already standard
+    :param velocity_low: Lowest velocity over which the BC was measured; None if unknown
+    :param velocity_high: Highest velocity over which the BC was measured; None if unknown
+    """
+    atmo: 'Atmo' = field(default=None)
+    velocity_low: [float, Velocity] = Dimension(prefer_units='velocity')
+    velocity_high: [float, Velocity] = Dimension(prefer_units='velocity')
+
+    def density_correction(self) -> float:
+        """:return: Factor converting a BC measured in .atmo to standard atmosphere.
+            The drag seen in thinner air makes an uncorrected BC look higher than it is.
+        """
+        if self.atmo is None:
+            return 1.0
+        return self.atmo.density_ratio
+
+    def in_band(self, velocity: Velocity) -> bool:
+        """:return: False if velocity is outside the band over which the BC was measured"""
+        if self.velocity_low is not None and velocity < self.velocity_low:
+            return False
+        if self.velocity_high is not None and velocity > self.velocity_high:
+            return False
+        return True
 
 
-DragTableDataType = [list[dict[str, float]], list[DragDataPoint]]
+DragTableDataType = [list[dict[str, float]], list[DragDataPoint], str]
 
 
 class DragModel:
@@ -44,9 +82,12 @@ class DragModel:
             is the bullet's form factor relative to the selected drag model.
     :param drag_table: If passed as List of {Mach, CD} dictionaries, this
             will be converted to a List of DragDataPoints.
+            A name (e.g. 'G7') refers to a table of register_drag_table().
     :param weight: Bullet weight in grains
     :param diameter: Bullet diameter in inches
     :param length: Bullet length in inches
+    :param bc_reference: Conditions under which bc was measured, if it was not corrected
+            to standard atmosphere.  The calculator corrects it using .standard_bc
     NOTE: .weight, .diameter, .length are only relevant for computing spin drift
     """
 
@@ -54,20 +95,19 @@ class DragModel:
                  drag_table: DragTableDataType,
                  weight: [float, Weight] = 0,
                  diameter: [float, Distance] = 0,
-                 length: [float, Distance] = 0):
-
-        if len(drag_table) <= 0:
-            # TODO: maybe have to require minimum size, cause few values don't give a valid result
-            raise ValueError('Received empty drag table')
-        elif bc <= 0:
-            raise ValueError('Ballistic coefficient must be positive')
+                 length: [float, Distance] = 0,
+                 bc_reference: BCReference = None):
 
+        _validate_bc(bc)
         self.drag_table = make_data_points(drag_table)
 
         self.BC = bc
+        self.bc_reference = bc_reference
         self.length = PreferredUnits.length(length)
         self.weight = PreferredUnits.weight(weight)
         self.diameter = PreferredUnits.diameter(diameter)
+        if min(self.length.raw_value, self.weight.raw_value, self.diameter.raw_value) < 0:
+            raise ValueError('Weight, diameter and length can\'t be negative')
         if weight > 0 and diameter > 0:
             self.sectional_density = self._get_sectional_density()
             self.form_factor = self._get_form_factor(self.BC)
@@ -75,6 +115,17 @@ class DragModel:
     def __repr__(self) -> str:
         return f"DragModel(bc={self.BC}, wgt={self.weight}, dia={self.diameter}, len={self.length})"
 
+    def with_bc(self, bc: float) -> 'DragModel':
+        """:return: copy of this DragModel with bc, sharing its drag table"""
+        return DragModel(bc, self.drag_table, self.weight, self.diameter, self.length, self.bc_reference)
+
+    @property
+    def standard_bc(self) -> float:
+        """:return: BC corrected to standard atmosphere from .bc_reference conditions"""
+        if self.bc_reference is None:
+            return self.BC
+        return self.BC * self.bc_reference.density_correction()
+
     def _get_form_factor(self, bc: float) -> float:
         return self.sectional_density / bc
 
@@ -84,11 +135,46 @@ class DragModel:
         return sectional_density(w, d)
 
 
+def _validate_bc(bc: float) -> None:
+    """:raise ValueError: unless bc is a finite positive number"""
+    if isinstance(bc, bool) or not isinstance(bc, numbers.Real) or not math.isfinite(bc) or bc <= 0:
+        raise ValueError(f'Ballistic coefficient must be positive, got {bc!r}')
+
+
 def make_data_points(drag_table: DragTableDataType) -> list[DragDataPoint]:
-    """Convert drag table from list of dictionaries to list of DragDataPoints"""
+    """Convert drag table from list of dictionaries, or the name of a registered drag table,
+        to list of DragDataPoints
+    :raise ValueError: for an unknown name, or a table the calculator can't interpolate: fewer than 2 points,
+        Mach not in ascending order, or a Mach or CD that is negative or not a finite number
+    """
+    if isinstance(drag_table, str):
+        try:
+            drag_table = get_drag_table(drag_table)
+        except KeyError as error:
+            raise ValueError(error.args[0]) from error
+    if not isinstance(drag_table, (list, tuple)) or not drag_table:
+        raise ValueError('Received empty drag table')
+    if len(drag_table) < 2:
+        raise ValueError('Drag table requires at least 2 points')
     if isinstance(drag_table[0], DragDataPoint):
-        return drag_table
-    return [DragDataPoint(point['Mach'], point['CD']) for point in drag_table]
+        points = drag_table
+    else:
+        try:
+            points = [DragDataPoint(point['Mach'], point['CD']) for point in drag_table]
+        except (KeyError, TypeError) as error:
+            raise ValueError('Drag table points require Mach and CD') from error
+    previous = None
+    for point in points:
+        if not isinstance(point, DragDataPoint):
+            raise ValueError(f'Invalid drag table point {point!r}')
+        for value in (point.Mach, point.CD):
+            if isinstance(value, bool) or not isinstance(value, numbers.Real) or not math.isfinite(value) \
+                    or value < 0:
+                raise ValueError(f'Invalid drag table point {point}')
+        if previous is not None and point.Mach <= previous:
+            raise ValueError(f'Mach of drag table must be in ascending order, got {point.Mach} after {previous}')
+        previous = point.Mach
+    return points
 
 
 def sectional_density(weight: float, diameter: float) -> float:
@@ -100,6 +186,41 @@ def sectional_density(weight: float, diameter: float) -> float:
     return weight / math.pow(diameter, 2) / 7000
 
 
+def form_factor(bc: float, weight: [float, Weight], diameter: [float, Distance]) -> float:
+    """
+    Form factor (e.g. i7 for a G7 BC, i1 for a G1 BC) = sectional density / bc:
+        drag of the bullet relative to the standard projectile of the bc's drag table
+    :param bc: Ballistic coefficient
+    :param weight: Bullet weight (float in PreferredUnits.weight)
+    :param diameter: Bullet diameter (float in PreferredUnits.diameter)
+    """
+    if bc <= 0:
+        raise ValueError('Ballistic coefficient must be positive')
+    return sectional_density(PreferredUnits.weight(weight) >> Weight.Grain,
+                             PreferredUnits.diameter(diameter) >> Distance.Inch) / bc
+
+
+def convert_bc(bc: float, from_table: DragTableDataType, to_table: DragTableDataType,
+               velocity: [float, Velocity]) -> float:
+    """
+    Converts a BC between drag tables, e.g. from G1 to G7, so that both give the same drag at velocity.
+        Since the tables have different shapes, the converted BC only holds near that velocity.
+    :param bc: Ballistic coefficient against from_table
+    :param from_table: Drag table of bc
+    :param to_table: Drag table of the converted BC
+    :param velocity: Velocity at which both BCs give the same drag (in standard atmosphere)
+    :return: Ballistic coefficient against to_table
+    """
+    if bc <= 0:
+        raise ValueError('Ballistic coefficient must be positive')
+    mach = (PreferredUnits.velocity(velocity) >> Velocity.MPS) / cSpeedOfSoundMetric
+    cds = []
+    for table in (from_table, to_table):
+        table = make_data_points(table)
+        cds.append(linear_interpolation([mach], [p.Mach for p in table], [p.CD for p in table])[0])
+    return bc * cds[1] / cds[0]
+
+
 def DragModelMultiBC(bc_points: list[BCPoint],
                      drag_table: DragTableDataType,
                      weight: [float, Weight] = 0,
@@ -115,6 +236,8 @@ def DragModelMultiBC(bc_points: list[BCPoint],
     :param diameter: Bullet diameter in inches
     :param length: Bullet length in inches
     """
+    if not bc_points:
+        raise ValueError('At least one BCPoint is required')
     weight = PreferredUnits.weight(weight)
     diameter = PreferredUnits.diameter(diameter)
     if weight > 0 and diameter > 0:
@@ -134,6 +257,27 @@ def DragModelMultiBC(bc_points: list[BCPoint],
     return DragModel(bc, drag_table, weight, diameter, length)
 
 
+def DragModelCD(drag_table: DragTableDataType,
+                weight: [float, Weight],
+                diameter: [float, Distance],
+                length: [float, Distance] = 0) -> DragModel:
+    """
+    Drag model of a projectile from its own drag coefficients (e.g. Doppler radar CDM) instead of a BC
+    against a standard projectile.  The BC is set to the sectional density, which makes the calculator's
+    deceleration V^2 * Cd * AirDensity * S / 2m, with S the cross-section of diameter and m the weight.
+    :param drag_table: list of dicts containing the projectile's Mach and CD
+    :param weight: Bullet weight in grains
+    :param diameter: Reference diameter in inches
+    :param length: Bullet length in inches
+    """
+    weight = PreferredUnits.weight(weight)
+    diameter = PreferredUnits.diameter(diameter)
+    if weight.raw_value <= 0 or diameter.raw_value <= 0:
+        raise ValueError('Weight and diameter are required for drag coefficients')
+    bc = sectional_density(weight >> Weight.Grain, diameter >> Distance.Inch)
+    return DragModel(bc, drag_table, weight, diameter, length)
+
+
 def linear_interpolation(x: Union[list[float], tuple[float]],
                          xp: Union[list[float], tuple[float]],
                          yp: Union[list[float], tuple[float]]) -> Union[list[float], tuple[float]]:
@@ -673,5 +673,49 @@ def get_drag_tables_names():
     return ["TableG%s" % n for n in (1, 7, 2, 5, 6, 8, 'I', 'S')]
 
 
-__all__ = ['get_drag_tables_names']
+# Drag tables by upper-case name, for reference by name, e.g. from profiles; see register_drag_table()
+_drag_table_registry = {name[len('Table'):]: globals()[name] for name in get_drag_tables_names()}
+
+
+def _registry_key(name: str) -> str:
+    key = name.strip().upper()
+    if key not in _drag_table_registry and key.startswith('TABLE'):
+        key = key[len('TABLE'):]
+    return key
+
+
+def register_drag_table(name: str, table: list, replace: bool = False) -> None:
+    """Registers a drag table so that DragModel and profiles can refer to it by name
+    :param name: case-insensitive name, e.g. 'G1-custom'
+    :param table: list of {Mach, CD} dictionaries or of DragDataPoints
+    :param replace: True => replace a table already registered under name
+    """
+    key = name.strip().upper()
+    if not key:
+        raise ValueError("Drag table name can't be empty")
+    if key in _drag_table_registry and not replace:
+        raise ValueError(f"Drag table {name!r} is already registered")
+    from .drag_model import make_data_points  # pylint: disable=import-outside-toplevel
+    make_data_points(list(table))  # Validates the table
+    # Store a copy, so later changes to table (or to the DragDataPoints made from it) don't affect the registry
+    _drag_table_registry[key] = [point.copy() if isinstance(point, dict) else {'Mach': point.Mach, 'CD': point.CD}
+                                 for point in table]
+
+
+def get_drag_table(name: str) -> list:
+    """:param name: registered name, case-insensitive; built-in tables also as 'TableG7'
+    :return: drag table registered under name
+    """
+    key = _registry_key(name)
+    if key not in _drag_table_registry:
+        raise KeyError(f"Unknown drag table {name!r}, use one of {registered_drag_tables()}")
+    return _drag_table_registry[key]
+
+
+def registered_drag_tables() -> list[str]:
+    """:return: names of the registered drag tables, built-in first"""
+    return list(_drag_table_registry)
+
+
+__all__ = ['get_drag_tables_names', 'register_drag_table', 'get_drag_table', 'registered_drag_tables']
 __all__ += get_drag_tables_names()
@@ -0,0 +1,130 @@
+"""Maximum reasonable engagement distance for a target size, combining trajectory, dispersion and wind error"""
+import math
+from dataclasses import replace
+from typing import NamedTuple, Optional
+
+from .conditions import Shot, Wind
+from .interface import Calculator
+from .unit import Angular, Distance, Velocity, PreferredUnits
+
+__all__ = ('EngagementPoint', 'EngagementLimit', 'max_engagement_distance')
+
+# Radius of a circle holding 95% of a circular normal distribution, in standard deviations per axis
+GROUP_SIGMAS = math.sqrt(-2 * math.log(0.05))
+
+
+class EngagementPoint(NamedTuple):
+    """
+    Hit odds at one trajectory row
+
+    Attributes:
+        distance (Distance): downrange distance
+        target_size (Distance): linear target size at distance
+        group_size (Distance): diameter holding 95% of shots, including transonic degradation dispersion
+        wind_error (Distance): horizontal drift from the wind uncertainty (one standard deviation),
+            or from half the wind bracket (worst case)
+        hit_probability (float): chance that a shot lands on the square target of target_size
+    """
+    distance: Distance
+    target_size: Distance
+    group_size: Distance
+    wind_error: Distance
+    hit_probability: float
+
+
+class EngagementLimit(NamedTuple):
+    """
+    Attributes:
+        distance (Distance): greatest distance at which hit_probability stays at the required level,
+            interpolated between trajectory rows; None if no row qualifies
+        limited_by_range (bool): True if the limit was not reached before the calculated trajectory ended
+        profile (list[EngagementPoint]): hit odds at each trajectory row
+    """
+    distance: Optional[Distance]
+    limited_by_range: bool
+    profile: list[EngagementPoint]
+
+
+def _normal_interval(low: float, high: float, sigma: float) -> float:
+    """:return: probability that a zero-mean normal variable with sigma falls between low and high"""
+    if sigma <= 0:
+        return 1.0 if low <= 0 <= high else 0.0
+    scale = sigma * math.sqrt(2)
+    return 0.5 * (math.erf(high / scale) - math.erf(low / scale))
+
+
+def max_engagement_distance(calc: Calculator, shot: Shot, target_size: [float, Distance, Angular],
+                            max_range: [float, Distance], *,
+                            hit_probability: float = 0.5,
+                            precision: [float, Angular] = Angular.MOA(1),
+                            wind_uncertainty: [float, Velocity] = Velocity.MPH(2),
+                            wind_bracket: Optional[Velocity] = None,
+                            trajectory_step: [float, Distance] = 0) -> EngagementLimit:
+    """Finds how far shot can reasonably engage a target: the point aim lands on the target center
+    and misses come from shot dispersion and from error in the crosswind estimate.
+
+    :param calc: Calculator to solve the trajectories
+    :param shot: zeroed shot; its winds are assumed to be held for
+    :param target_size: width and height of a square target, or its angular size as seen from the muzzle
+    :param max_range: downrange distance to search
+    :param hit_probability: required chance of a hit
+    :param precision: angular diameter holding 95% of shots (group size)
+    :param wind_uncertainty: standard deviation of the crosswind estimate
+    :param wind_bracket: full width of the crosswind bracket (e.g. 4mph for 8 +/- 2mph);
+        if given, every shot is assumed to be off by half the bracket instead of using wind_uncertainty
+    :param trajectory_step: distance between evaluated rows, default max_range / 100
+    """
+    if not 0 < hit_probability < 1:
+        raise ValueError(f"hit_probability has to be between 0 and 1, got {hit_probability}")
+    max_range = PreferredUnits.distance(max_range)
+    if not trajectory_step:
+        trajectory_step = max_range.unit_value / 100.0
+    step = PreferredUnits.distance(trajectory_step)
+    if not isinstance(target_size, Angular):
+        target_size = PreferredUnits.target_height(target_size)
+    precision = math.tan(PreferredUnits.adjustment(precision) >> Angular.Radian)
+    wind = PreferredUnits.velocity(wind_bracket if wind_bracket is not None else wind_uncertainty)
+    if wind_bracket is not None:
+        wind = Velocity.FPS((wind >> Velocity.FPS) / 2)
+
+    base = calc.fire(shot, max_range, step).trajectory
+    windy = calc.fire(replace(shot, winds=[Wind(wind, Angular.OClock(3))]), max_range, step).trajectory
+    calm = calc.fire(replace(shot, winds=[]), max_range, step).trajectory
+
+    profile = []
+    for row, w, c in zip(base, windy, calm):
+        x = row.distance >> Distance.Foot
+        if isinstance(target_size, Angular):
+            size = x * math.tan(target_size >> Angular.Radian)
+        else:
+            size = target_size >> Distance.Foot
+        group = precision * x + (row.dispersion >> Distance.Foot)
+        drift = abs((w.windage >> Distance.Foot) - (c.windage >> Distance.Foot))
+        sigma = group / (2 * GROUP_SIGMAS)
+        half = size / 2
+        if wind_bracket is not None:
+            horizontal = _normal_interval(-half - drift, half - drift, sigma)
+        else:
+            horizontal = _normal_interval(-half, half, math.hypot(sigma, drift))
+        probability = horizontal * _normal_interval(-half, half, sigma)
+        profile.append(EngagementPoint(
+            distance=row.distance << PreferredUnits.distance,
+            target_size=Distance.Foot(size) << PreferredUnits.target_height,
+            group_size=Distance.Foot(group) << PreferredUnits.drop,
+            wind_error=Distance.Foot(drift) << PreferredUnits.drop,
+            hit_probability=probability
+        ))
+
+    distance = None
+    for index, point in enumerate(profile):
+        if point.hit_probability < hit_probability:
+            if index > 0:
+                previous = profile[index - 1]
+                f = (previous.hit_probability - hit_probability) / (previous.hit_probability - point.hit_probability)
+                x0 = previous.distance >> Distance.Foot
+                x = x0 + f * ((point.distance >> Distance.Foot) - x0)
+                distance = Distance.Foot(x) << PreferredUnits.distance
+            return EngagementLimit(distance, False, profile)
+    if profile:
+        distance = profile[-1].distance
+    return EngagementLimit(distance, True, profile)
@@ -0,0 +1,128 @@
+"""Reachability envelope of a launcher swept over barrel elevation, with direct- and indirect-fire elevations"""
+from dataclasses import replace
+from typing import Iterable, NamedTuple, Optional
+
+from .conditions import Shot
+from .interface import Calculator, relative_angle_sweep
+from .trajectory_data import HitResult, TrajectoryData
+from .unit import Angular, Distance, PreferredUnits
+
+__all__ = ('ElevationSweep', 'ReachEnvelope', 'elevation_envelope')
+
+cImpactSubdivisions = 50  # Rows per trajectory_step when refining an impact beyond the last recorded row
+
+
+class ElevationSweep(NamedTuple):
+    """
+    Attributes:
+        elevation (Angular): barrel elevation above horizontal
+        result (HitResult): trajectory fired at elevation
+        apex (TrajectoryData): highest trajectory row
+        impact_distance (Distance): where the trajectory falls back to the launch level; None if beyond the range
+    """
+    elevation: Angular
+    result: HitResult
+    apex: TrajectoryData
+    impact_distance: Optional[Distance]
+
+
+class ReachEnvelope(NamedTuple):
+    """
+    Attributes:
+        sweeps (list[ElevationSweep]): trajectories in ascending order of elevation
+        envelope (list[tuple[Distance, Distance]]): (distance, greatest height reached by any elevation)
+            at each trajectory step
+    """
+    sweeps: list[ElevationSweep]
+    envelope: list[tuple[Distance, Distance]]
+
+    @property
+    def maximum(self) -> Optional[ElevationSweep]:
+        """:return: sweep with the farthest impact at launch level"""
+        impacts = [s for s in self.sweeps if s.impact_distance is not None]
+        return max(impacts, key=lambda s: s.impact_distance.raw_value) if impacts else None
+
+    def height_limit(self, distance: [float, Distance]) -> Optional[Distance]:
+        """:return: greatest height reachable at distance, interpolated along the envelope;
+            None if distance is outside it
+        """
+        x = PreferredUnits.distance(distance) >> Distance.Foot
+        for (d0, h0), (d1, h1) in zip(self.envelope, self.envelope[1:]):
+            x0, x1 = d0 >> Distance.Foot, d1 >> Distance.Foot
+            if x0 <= x <= x1:
+                f = (x - x0) / (x1 - x0) if x1 > x0 else 0
+                y = (h0 >> Distance.Foot) + f * ((h1 >> Distance.Foot) - (h0 >> Distance.Foot))
+                return Distance.Foot(y) << PreferredUnits.drop
+        return None
+
+    def elevation_for(self, distance: [float, Distance], high_angle: bool = False) -> Optional[Angular]:
+        """Elevation that lands at distance on the launch level, interpolated between sweeps
+        :param high_angle: False => direct fire, below the maximum-range elevation;
+            True => indirect fire, above it
+        :return: None if no pair of sweeps brackets distance
+        """
+        x = PreferredUnits.distance(distance) >> Distance.Foot
+        top = self.maximum
+        if top is None:
+            return None
+        split = self.sweeps.index(top)
+        branch = self.sweeps[split:] if high_angle else self.sweeps[:split + 1]
+        for a, b in zip(branch, branch[1:]):
+            if a.impact_distance is None or b.impact_distance is None:
+                continue
+            xa, xb = a.impact_distance >> Distance.Foot, b.impact_distance >> Distance.Foot
+            if min(xa, xb) <= x <= max(xa, xb):
+                f = (x - xa) / (xb - xa) if xb != xa else 0
+                ea, eb = a.elevation >> Angular.Radian, b.elevation >> Angular.Radian
+                return Angular.Radian(ea + f * (eb - ea)) << PreferredUnits.angular
+        return None
+
+
+def _impact_distance(rows: list[TrajectoryData]) -> Optional[Distance]:
+    """:return: distance of the last descent through height 0, interpolated between rows"""
+    for previous, current in zip(reversed(rows[:-1]), reversed(rows)):
+        y0, y1 = previous.height >> Distance.Foot, current.height >> Distance.Foot
+        if y0 >= 0 > y1:
+            x0, x1 = previous.distance >> Distance.Foot, current.distance >> Distance.Foot
+            return Distance.Foot(x0 + y0 / (y0 - y1) * (x1 - x0)) << PreferredUnits.distance
+    return None
+
+
+def elevation_envelope(calc: Calculator, shot: Shot, elevations: Iterable[[float, Angular]],
+                       trajectory_range: [float, Distance], trajectory_step: [float, Distance] = 0) -> ReachEnvelope:
+    """Fires shot at each of elevations above horizontal (look_angle and the weapon zero are ignored).
+        Near-vertical shots stop at CalculatorConfig.minimum_velocity at their apex unless it is set to 0.
+    :param calc: Calculator to solve the trajectories
+    :param shot: launcher, projectile and conditions
+    :param elevations: barrel elevations to sweep, e.g. every 5 degrees from 5 to 85
+    :param trajectory_range: downrange distance at which to stop each trajectory
+    :param trajectory_step: distance between recorded rows, default trajectory_range / 100
+    """
+    trajectory_range = PreferredUnits.distance(trajectory_range)
+    if not trajectory_step:
+        trajectory_step = trajectory_range.unit_value / 100.0
+    elevations = sorted((PreferredUnits.angular(e) for e in elevations), key=lambda e: e.raw_value)
+    if not elevations:
+        raise ValueError("At least one elevation is required")
+    level = replace(shot, look_angle=Angular.Radian(0), weapon=replace(shot.weapon, zero_elevation=Angular.Radian(0)))
+
+    sweeps = []
+    highest = {}  # Row distance => greatest height in feet
+    for elevation, result in zip(elevations, calc.fire_volley(relative_angle_sweep(level, elevations),
+                                                              trajectory_range, trajectory_step)):
+        rows = result.trajectory
+        for row in rows:
+            key = round(row.distance >> Distance.Foot, 6)
+            highest[key] = max(highest.get(key, -float('inf')), row.height >> Distance.Foot)
+        apex = max(rows, key=lambda row: row.height.raw_value)
+        impact = _impact_distance(rows)
+        if impact is None and result.incomplete and rows[-1].height.raw_value >= 0:
+            # Steep descent ended before the next row: look for the impact within the last step
+            x = rows[-1].distance >> Distance.Foot
+            step = PreferredUnits.distance(trajectory_step) >> Distance.Foot
+            fine = [Distance.Foot(x + step * i / cImpactSubdivisions) for i in range(cImpactSubdivisions + 1)]
+            impact = _impact_distance(calc.fire_at_ranges(result.shot, fine).trajectory)
+        sweeps.append(ElevationSweep(elevation, result, apex, impact))
+    envelope = [(Distance.Foot(x) << PreferredUnits.distance, Distance.Foot(y) << PreferredUnits.drop)
+                for x, y in sorted(highest.items())]
+    return ReachEnvelope(sweeps, envelope)
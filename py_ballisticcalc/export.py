@@ -0,0 +1,115 @@
+"""Apache Arrow and Parquet export of trajectories, for analysis in pandas, Polars or DuckDB,
+    and CSV export for spreadsheets"""
+import csv
+from typing import Iterable, TextIO, Union
+
+from .trajectory_data import HitResult, TrajectoryColumns, TrajectoryData
+from .unit import Unit
+
+try:
+    import pyarrow as pa
+except ImportError:
+    pa = None
+
+__all__ = ('to_record_batch', 'to_arrow_table', 'write_parquet', 'write_csv')
+
+
+def _require_pyarrow():
+    if pa is None:
+        raise ImportError("Install pyarrow to export trajectories to Arrow or Parquet")
+
+
+def _column(values) -> 'pa.Array':
+    """:return: Arrow array copied from an array.array column"""
+    if values.typecode == 'd':
+        return pa.Array.from_buffers(pa.float64(), len(values), [None, pa.py_buffer(values.tobytes())])
+    return pa.array(list(values), type=pa.int32())
+
+
+def to_record_batch(result: Union[HitResult, TrajectoryColumns], units: dict[str, Unit] = None,
+                    shot_index: int = None) -> 'pa.RecordBatch':
+    """Converts a trajectory to an Arrow record batch with a column for each field of TrajectoryData.
+        Field metadata 'units' holds the Unit name of each column with units ('s' for time).
+    :param result: HitResult or TrajectoryColumns
+    :param units: Units of fields (see TrajectoryColumns); ignored if result is TrajectoryColumns
+    :param shot_index: If given, adds columns 'shot' with this value and 'termination_reason',
+        so that batches of several shots can be combined into one table
+    :return: pyarrow.RecordBatch; schema metadata 'termination_reason' is set when shot_index is None
+    """
+    _require_pyarrow()
+    columns = result if isinstance(result, TrajectoryColumns) else result.columns(units)
+    fields, arrays = [], []
+    if shot_index is not None:
+        fields.append(pa.field('shot', pa.int32()))
+        arrays.append(pa.array([shot_index] * len(columns), type=pa.int32()))
+        fields.append(pa.field('termination_reason', pa.dictionary(pa.int32(), pa.string())))
+        arrays.append(pa.array([columns.termination_reason] * len(columns)).dictionary_encode())
+    for name in TrajectoryData._fields:
+        array = _column(getattr(columns, name))
+        metadata = None
+        if name in columns.units:
+            metadata = {'units': columns.units[name].name}
+        elif name == 'time':
+            metadata = {'units': 's'}
+        fields.append(pa.field(name, array.type, metadata=metadata))
+        arrays.append(array)
+    schema_metadata = {'termination_reason': columns.termination_reason} if shot_index is None else None
+    return pa.RecordBatch.from_arrays(arrays, schema=pa.schema(fields, metadata=schema_metadata))
+
+
+def to_arrow_table(results: Iterable[Union[HitResult, TrajectoryColumns]],
+                   units: dict[str, Unit] = None) -> 'pa.Table':
+    """Combines trajectories (e.g. from Calculator.fire_volley()) into one Arrow table,
+        with columns 'shot' (index of the result) and 'termination_reason' (see to_record_batch())
+    :param results: HitResults or TrajectoryColumns, all in the same units
+    :param units: Units of fields (see TrajectoryColumns) of HitResults
+    """
+    _require_pyarrow()
+    batches = [to_record_batch(result, units, i) for i, result in enumerate(results)]
+    if not batches:
+        raise ValueError("At least one trajectory is required")
+    return pa.Table.from_batches(batches)
+
+
+def write_parquet(results: Iterable[Union[HitResult, TrajectoryColumns]], path: str,
+                  units: dict[str, Unit] = None) -> None:
+    """Writes trajectories to a Parquet file, as the table of to_arrow_table()"""
+    _require_pyarrow()
+    import pyarrow.parquet as pq  # pylint: disable=import-outside-toplevel
+    pq.write_table(to_arrow_table(results, units), path)
+
+
+def write_csv(result: Union[HitResult, TrajectoryColumns, Iterable[TrajectoryData]], file: TextIO,
+              units: dict[str, Unit] = None, fields: Iterable[str] = None, rounded: bool = False) -> None:
+    """Writes a trajectory as CSV, with a header row naming each column and its units, e.g. 'distance (yd)'
+    :param result: HitResult, TrajectoryColumns or rows of TrajectoryData
+    :param file: Text file to write to, opened with newline=''
+    :param units: Units of fields (see TrajectoryColumns); ignored if result is TrajectoryColumns
+    :param fields: Names of TrajectoryData fields to write, in order; default all fields
+    :param rounded: True => round values to the accuracy of their units (see PreferredUnits)
+    """
+    if isinstance(result, TrajectoryColumns):
+        columns = result
+    elif isinstance(result, HitResult):
+        columns = result.columns(units)
+    else:
+        columns = TrajectoryColumns.from_rows(result, units)
+    fields = list(TrajectoryData._fields if fields is None else fields)
+    for name in fields:
+        if name not in TrajectoryData._fields:
+            raise KeyError(f"TrajectoryData has no field {name!r}")
+    header = []
+    for name in fields:
+        if name in columns.units:
+            header.append(f'{name} ({columns.units[name].symbol})')
+        elif name == 'time':
+            header.append(f'{name} (s)')
+        else:
+            header.append(name)
+    writer = csv.writer(file)
+    writer.writerow(header)
+    values = [getattr(columns, name) for name in fields]
+    accuracies = [columns.units[name].accuracy if rounded and name in columns.units else None for name in fields]
+    for row in zip(*values):
+        writer.writerow([value if accuracy is None else round(value, accuracy)
+                         for value, accuracy in zip(row, accuracies)])
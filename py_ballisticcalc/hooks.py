@@ -0,0 +1,104 @@
+"""Optional callbacks to observe the trajectory calculation loop"""
+import threading
+import time
+from dataclasses import dataclass
+from typing import Callable, NamedTuple, Optional
+
+from .trajectory_data import TrajectoryData
+from .vector import Vector
+
+__all__ = ('StepState', 'TrajectoryHooks', 'CancelContext', 'TrajectoryCancelledError')
+
+
+class StepState(NamedTuple):
+    """
+    Internal state of the integration loop, in calculation units (feet, fps, seconds)
+
+    Attributes:
+        time (float): time of flight
+        position (Vector): x = downrange distance, y = height, z = windage (without Litz spin drift)
+        velocity (Vector): ground velocity of the projectile
+        speed (float): magnitude of the velocity relative to air used for the last drag evaluation
+        mach (float): local speed of sound
+        density_factor (float): local air density relative to standard density
+        drag (float): drag term computed at the last step
+    """
+    time: float
+    position: Vector
+    velocity: Vector
+    speed: float
+    mach: float
+    density_factor: float
+    drag: float
+
+
+@dataclass
+class TrajectoryHooks:
+    """
+    Callbacks invoked by TrajectoryCalc; any of them may be None.
+    They are also invoked during zero-finding iterations.
+
+    :param on_step: called with a StepState at the start of every integration step;
+        returning True cancels the calculation
+    :param on_record: called with each TrajectoryData row as it is recorded
+    :param on_termination: called with the final StepState and a reason string
+        ('maximum_range', 'minimum_velocity', 'minimum_energy', 'maximum_drop', 'minimum_altitude',
+        'maximum_time' or 'cancelled') when the loop ends
+    """
+    on_step: Optional[Callable[[StepState], Optional[bool]]] = None
+    on_record: Optional[Callable[[TrajectoryData], None]] = None
+    on_termination: Optional[Callable[[StepState, str], None]] = None
+
+
+class CancelContext:
+    """
+    Cancellation and deadline of calculations, see Calculator.fire_with_context().
+    cancel() may be called from another thread.
+
+    :param timeout: seconds from creation after which calculations are cancelled; None for no deadline
+    """
+
+    def __init__(self, timeout: float = None):
+        self.deadline = None if timeout is None else time.monotonic() + timeout
+        self._cancelled = threading.Event()
+        self.interrupted = False  # True once a calculation has been stopped by this context
+
+    def cancel(self) -> None:
+        """Cancels running and later calculations of this context"""
+        self._cancelled.set()
+
+    @property
+    def reason(self) -> Optional[str]:
+        """:return: 'cancelled', 'deadline_exceeded', or None while calculations may run"""
+        if self._cancelled.is_set():
+            return 'cancelled'
+        if self.deadline is not None and time.monotonic() >= self.deadline:
+            return 'deadline_exceeded'
+        return None
+
+    def hooks(self, hooks: TrajectoryHooks = None) -> TrajectoryHooks:
+        """:return: hooks whose on_step also cancels the calculation when this context is done"""
+        on_step = None if hooks is None else hooks.on_step
+
+        def step(state: StepState) -> Optional[bool]:
+            if self.reason is not None:
+                self.interrupted = True
+                return True
+            return None if on_step is None else on_step(state)
+
+        return TrajectoryHooks(step, None if hooks is None else hooks.on_record,
+                               None if hooks is None else hooks.on_termination)
+
+
+class TrajectoryCancelledError(Exception):
+    """
+    Raised when a CancelContext stops a calculation
+
+    :param reason: CancelContext.reason
+    :param result: trajectory calculated until the cancellation (HitResult), None for zero finding
+    """
+
+    def __init__(self, reason: str, result=None):
+        super().__init__(f"Calculation stopped: {reason}")
+        self.reason = reason
+        self.result = result
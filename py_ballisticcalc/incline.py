@@ -0,0 +1,66 @@
+"""Rifleman's rule shortcuts for uphill and downhill shots, compared with the solved trajectory"""
+import math
+from dataclasses import replace
+from typing import NamedTuple
+
+from .conditions import Shot
+from .interface import Calculator
+from .unit import Angular, Distance, PreferredUnits
+
+__all__ = ('InclineHolds', 'incline_holds')
+
+
+class InclineHolds(NamedTuple):
+    """
+    Elevation adjustments (as TrajectoryData.drop_adj) for a target at slant_distance along shot.look_angle
+
+    Attributes:
+        slant_distance (Distance): sight-line distance to the target
+        exact (Angular): adjustment from the solved inclined trajectory
+        riflemans_rule (Angular): level-fire adjustment at the horizontal distance, slant_distance * cos(look_angle)
+        improved_riflemans_rule (Angular): level-fire adjustment at slant_distance, multiplied by cos(look_angle)
+    """
+    slant_distance: Distance
+    exact: Angular
+    riflemans_rule: Angular
+    improved_riflemans_rule: Angular
+
+    @property
+    def riflemans_rule_error(self) -> Angular:
+        """:return: riflemans_rule - exact"""
+        return _difference(self.riflemans_rule, self.exact)
+
+    @property
+    def improved_riflemans_rule_error(self) -> Angular:
+        """:return: improved_riflemans_rule - exact"""
+        return _difference(self.improved_riflemans_rule, self.exact)
+
+
+def _difference(a: Angular, b: Angular) -> Angular:
+    return Angular.Radian((a >> Angular.Radian) - (b >> Angular.Radian)) << PreferredUnits.adjustment
+
+
+def incline_holds(calc: Calculator, shot: Shot, slant_distance: [float, Distance]) -> InclineHolds:
+    """Calculates the quick incline corrections alongside the exact solution, to show how much the shortcuts err
+    :param calc: Calculator to solve the trajectories
+    :param shot: zeroed shot with look_angle of the target
+    :param slant_distance: sight-line distance to the target (what a rangefinder reads)
+    """
+    slant_distance = PreferredUnits.distance(slant_distance)
+    look_angle = shot.look_angle >> Angular.Radian
+    slant = slant_distance >> Distance.Foot
+    horizontal = Distance.Foot(slant * math.cos(look_angle))
+    exact_result = calc.fire_at_ranges(shot, [horizontal])
+    level_result = calc.fire_at_ranges(replace(shot, look_angle=Angular.Radian(0)), [horizontal, slant_distance])
+    exact, level = exact_result.trajectory, level_result.trajectory
+    if len(exact) < 1:
+        raise exact_result.range_error(horizontal)
+    if len(level) < 2:
+        raise level_result.range_error(slant_distance)
+    return InclineHolds(
+        slant_distance=slant_distance,
+        exact=exact[0].drop_adj,
+        riflemans_rule=level[0].drop_adj,
+        improved_riflemans_rule=Angular.Radian(
+            (level[1].drop_adj >> Angular.Radian) * math.cos(look_angle)) << PreferredUnits.adjustment
+    )
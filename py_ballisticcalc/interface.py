@@ -1,21 +1,100 @@
 """Implements basic interface for the ballistics calculator"""
-from dataclasses import dataclass, field
+import math
+import os
+import queue
+import threading
+from concurrent.futures import ProcessPoolExecutor
+from dataclasses import dataclass, field, fields, is_dataclass, replace
+from typing import Callable, Iterable, Iterator, NamedTuple, Optional
 
-from .conditions import Shot
+from .conditions import Atmo, Shot
+from .config import CalculatorConfig
+from .drag_model import DragModel
+from .hooks import CancelContext, TrajectoryCancelledError, TrajectoryHooks
+from .logger import logger
+from .munition import Ammo
+from .pejsa import PejsaCalc
 # pylint: disable=import-error,no-name-in-module,wildcard-import,unused-wildcard-import
 from .backend import *
-from .trajectory_data import HitResult
-from .unit import Angular, Distance, PreferredUnits
+from .trajectory_data import HitResult, RangeError, TrajectoryData, TrajectoryColumns, TrajFlag
+from .unit import AbstractUnit, Angular, Distance, Time, Velocity, Unit, PreferredUnits
 
 
-__all__ = ('Calculator',)
+__all__ = ('Calculator', 'ShotString', 'ShotScenario', 'relative_angle_sweep', 'muzzle_velocity_sweep', 'shot_string_sweep')
+
+cMaxCachedZeros = 64  # Sight angles and warm-start elevations kept by Calculator for repeated zeroing
+cStreamBuffer = 64  # Rows calculated ahead of the consumer of Calculator.fire_iter()
+
+
+class ShotString(NamedTuple):
+    """Impacts of a string of shots at one distance, see Calculator.fire_string()"""
+    shots: list[Shot]
+    impacts: list[TrajectoryData]
+
+    @property
+    def vertical_spread(self) -> Distance:
+        """:return: distance between the highest and the lowest impact"""
+        heights = [row.height >> Distance.Foot for row in self.impacts]
+        return Distance.Foot(max(heights) - min(heights)) << PreferredUnits.drop
+
+    @property
+    def mean_height(self) -> Distance:
+        """:return: average impact height relative to the sight line"""
+        heights = [row.height >> Distance.Foot for row in self.impacts]
+        return Distance.Foot(sum(heights) / len(heights)) << PreferredUnits.drop
+
+
+class ShotScenario(NamedTuple):
+    """Arguments of Calculator.fire() for one shot of Calculator.fire_batch()"""
+    shot: Shot
+    trajectory_range: [float, Distance]
+    trajectory_step: [float, Distance] = 0
+    extra_data: bool = False
 
 
 @dataclass
 class Calculator:
-    """Basic interface for the ballistics calculator"""
+    """Basic interface for the ballistics calculator.
+    Keeps the TrajectoryCalc (and its prepared drag curve) between calls
+        for as long as shots use an unchanged DragModel.
+    The config is frozen, and a Calculator created without one takes the global settings
+        (set_global_max_calc_step_size() etc.) at construction, so later changes of them don't apply to it.
+    Thread safety: clone() and reading config are safe from any thread.  Calculations (fire*, zeroing,
+        estimate_drop) update the TrajectoryCalc and caches a Calculator keeps, so they are not safe for
+        concurrent use of one instance: give each thread its own clone().  No locking is done.
+    :param hooks: Optional callbacks to observe the calculation loop (see TrajectoryHooks)
+    :param config: Optional CalculatorConfig; when None the global settings at construction are used
+    :param engine: Class with the interface of TrajectoryCalc used for calculations,
+        e.g. PejsaCalc for fast approximate solutions; None for TrajectoryCalc
+    """
 
+    hooks: TrajectoryHooks = field(default=None)
+    config: CalculatorConfig = field(default=None)
+    engine: type = field(default=None)
     _calc: TrajectoryCalc = field(init=False, repr=False, compare=False, default=None)
+    # (dm, dm.BC, dm.drag_table, dm.bc_reference, engine)
+    _calc_key: tuple = field(init=False, repr=False, compare=False, default=None)
+    # PejsaCalc for estimate_drop() and its (dm, dm.BC, dm.drag_table, dm.bc_reference)
+    _estimate_calc: PejsaCalc = field(init=False, repr=False, compare=False, default=None)
+    _estimate_key: tuple = field(init=False, repr=False, compare=False, default=None)
+    # Last converged barrel elevation for the latest cMaxCachedZeros (weapon, ammo) pairs, to warm-start zero_angle
+    _zero_cache: dict = field(init=False, repr=False, compare=False, default_factory=dict)
+    # Converged barrel elevation for each frozen set of inputs that determine it (see _sight_angle_key)
+    _sight_angle_cache: dict = field(init=False, repr=False, compare=False, default_factory=dict)
+
+    def __post_init__(self):
+        if self.config is None:
+            self.config = CalculatorConfig(max_calc_step_size=get_global_max_calc_step_size(),
+                                           gravity=get_global_gravity(),
+                                           use_powder_sensitivity=get_global_use_powder_sensitivity())
+
+    def clone(self) -> 'Calculator':
+        """:return: Calculator with the same hooks, engine and (frozen) config, and copies of the cached zeros,
+            but its own TrajectoryCalc, for use in another thread"""
+        calc = Calculator(self.hooks, self.config, self.engine)
+        calc._zero_cache = dict(self._zero_cache)
+        calc._sight_angle_cache = dict(self._sight_angle_cache)
+        return calc
 
     @property
     def cdm(self):
@@ -32,19 +111,114 @@ class Calculator:
                 on ballistic trajectory of shooting uphill or downhill.  Therefore:
                 For maximum accuracy, use the raw sight distance and look_angle as inputs here.
         """
-        self._calc = TrajectoryCalc(shot.ammo)
         target_distance = PreferredUnits.distance(target_distance)
-        total_elevation = self._calc.zero_angle(shot, target_distance)
+        key = self._sight_angle_key(shot, target_distance)
+        total_elevation = self._sight_angle_cache.get(key)
+        if total_elevation is None:
+            self._get_calc(shot.ammo)
+            total_elevation = self._warm_zero_angle(shot, target_distance)
+            if len(self._sight_angle_cache) >= cMaxCachedZeros:
+                del self._sight_angle_cache[next(iter(self._sight_angle_cache))]  # Oldest entry
+            self._sight_angle_cache[key] = total_elevation
         return Angular.Radian(
             (total_elevation >> Angular.Radian) - (shot.look_angle >> Angular.Radian)
         )
 
-    def set_weapon_zero(self, shot: Shot, zero_distance: [float, Distance]) -> Angular:
+    def invalidate_zero(self) -> None:
+        """Forgets cached sight angles, so the next barrel_elevation_for_target() runs zero finding.
+            Only needed if inputs changed in a way the cache key can't see (e.g. a subclass of Atmo
+            computing density from state that is not a dataclass field)."""
+        self._sight_angle_cache.clear()
+
+    def _sight_angle_key(self, shot: Shot, target_distance: Distance) -> tuple:
+        """:return: hashable values of every input that affects the barrel elevation to hit target_distance"""
+        weapon = tuple(_freeze(getattr(shot.weapon, f.name)) for f in fields(shot.weapon)
+                       if f.name != 'zero_elevation')
+        return (weapon, _freeze(shot.ammo), _freeze(shot.atmo), _freeze(shot.winds),
+                _freeze(shot.wind_layers), _freeze(shot.atmo_model),
+                shot.look_angle.raw_value, shot.cant_angle.raw_value, target_distance.raw_value,
+                _freeze(self.config), self.engine)
+
+    def _get_calc(self, ammo: Ammo) -> TrajectoryCalc:
+        """:return: TrajectoryCalc for ammo, reusing the current one if its DragModel is unchanged"""
+        dm = ammo.dm
+        key = self._calc_key
+        if (self._calc is None or key[0] is not dm or key[1] != dm.BC or key[2] is not dm.drag_table
+                or key[3] is not dm.bc_reference or key[4] is not self.engine):
+            self._calc = (self.engine or TrajectoryCalc)(ammo, self.hooks, self.config)
+            self._calc_key = (dm, dm.BC, dm.drag_table, dm.bc_reference, self.engine)
+        else:
+            self._calc.hooks = self.hooks
+            self._calc.config = self.config
+        return self._calc
+
+    @staticmethod
+    def _hit_result(calc: TrajectoryCalc, shot: Shot, data: list[TrajectoryData], extra: bool,
+                    step: Distance = None) -> HitResult:
+        """:return: HitResult of the trajectory just calculated by calc; HitResult.stability_warning reports
+            a projectile that is not stable, which is only logged at DEBUG level so that sweeps don't flood logs"""
+        result = HitResult(shot, data, extra, calc.termination_reason, calc.stability_coefficient,
+                           None if extra else step)
+        if result.stability_warning:
+            logger.debug(f"Projectile is {result.stability_warning}: stability factor {result.stability:.2f}")
+        return result
+
+    def estimate_drop(self, shot: Shot, target_range: [float, Distance]) -> TrajectoryData:
+        """Instant flat-fire estimate of the trajectory at one range, using the closed forms of PejsaCalc.
+            Drop and windage are typically within a few percent of fire() while the projectile is supersonic,
+            so UIs can show this while typing and refine with fire() afterwards.  Hooks are not called.
+        :param shot: shot parameters (initial position and barrel angle)
+        :param target_range: Downrange distance of the estimate
+        :return: TrajectoryData at target_range
+        """
+        dm = shot.ammo.dm
+        key = self._estimate_key
+        if (self._estimate_calc is None or key[0] is not dm or key[1] != dm.BC
+                or key[2] is not dm.drag_table or key[3] is not dm.bc_reference):
+            self._estimate_calc = PejsaCalc(shot.ammo, config=self.config)
+            self._estimate_key = (dm, dm.BC, dm.drag_table, dm.bc_reference)
+        else:
+            self._estimate_calc.config = self.config
+        target_range = PreferredUnits.distance(target_range)
+        data = self._estimate_calc.trajectory_at_ranges(shot, [target_range])
+        if not data:
+            raise RangeError(self._estimate_calc.termination_reason, f"distance {target_range}")
+        return data[0]
+
+    def _warm_zero_angle(self, shot: Shot, target_distance: Distance) -> Angular:
+        """Runs zero_angle starting from the last elevation converged for the same weapon and ammo.
+            Atmosphere, look angle and distance may differ between calls: the cached value is
+            only a first guess, and a cold start is used if the warm start fails to converge.
+        """
+        key = (id(shot.weapon), id(shot.ammo))
+        cached = self._zero_cache.get(key)
+        total_elevation = None
+        if cached is not None and cached[0] is shot.weapon and cached[1] is shot.ammo:
+            try:
+                total_elevation = self._calc.zero_angle(shot, target_distance, cached[2])
+            except Exception:  # pylint: disable=broad-exception-caught
+                total_elevation = None
+        if total_elevation is None:
+            total_elevation = self._calc.zero_angle(shot, target_distance)
+        if key not in self._zero_cache and len(self._zero_cache) >= cMaxCachedZeros:
+            del self._zero_cache[next(iter(self._zero_cache))]  # Oldest entry
+        # Keep references so that ids in the key cannot be reused by other objects
+        self._zero_cache[key] = (shot.weapon, shot.ammo, total_elevation)
+        return total_elevation
+
+    def set_weapon_zero(self, shot: Shot, zero_distance: [float, Distance],
+                        zero_atmo: Atmo = None, zero_ammo: Ammo = None) -> Angular:
         """Sets shot.weapon.zero_elevation so that it hits a target at zero_distance.
+            A weapon zeroed on one day keeps its zero_elevation, and shot then flies in its own conditions.
         :param shot: Shot instance from which we take a zero
         :param zero_distance: Look-distance to "zero," which is point we want to hit.
+        :param zero_atmo: Atmosphere in which the weapon was zeroed, default shot.atmo
+        :param zero_ammo: Ammunition with which the weapon was zeroed, default shot.ammo
         """
-        shot.weapon.zero_elevation = self.barrel_elevation_for_target(shot, zero_distance)
+        zero_shot = shot
+        if zero_atmo is not None or zero_ammo is not None:
+            zero_shot = replace(shot, atmo=zero_atmo or shot.atmo, ammo=zero_ammo or shot.ammo)
+        shot.weapon.zero_elevation = self.barrel_elevation_for_target(zero_shot, zero_distance)
         return shot.weapon.zero_elevation
 
     def fire(self, shot: Shot, trajectory_range: [float, Distance],
@@ -53,14 +227,320 @@ class Calculator:
         """Calculates trajectory
         :param shot: shot parameters (initial position and barrel angle)
         :param trajectory_range: Downrange distance at which to stop computing trajectory
+        :param trajectory_step: step between trajectory points to record, default a tenth of trajectory_range
+            (as HitResult.trajectory_step)
+        :param extra_data: True => store TrajectoryData for every calculation step;
+            False => store TrajectoryData only for each trajectory_step
+        """
+        trajectory_range, step = _range_and_step(trajectory_range, trajectory_step)
+        calc = self._get_calc(shot.ammo)
+        data = calc.trajectory(shot, trajectory_range, step, extra_data)
+        return self._hit_result(calc, shot, data, extra_data, step)
+
+    def fire_with_context(self, context: CancelContext, shot: Shot, trajectory_range: [float, Distance],
+                          trajectory_step: [float, Distance] = 0, extra_data: bool = False) -> HitResult:
+        """fire() that stops when context is cancelled or its deadline passes
+        :param context: CancelContext of the calculation
+        :raises TrajectoryCancelledError: with the trajectory calculated until the cancellation
+        """
+        hooks = self.hooks
+        self.hooks = context.hooks(hooks)
+        try:
+            result = self.fire(shot, trajectory_range, trajectory_step, extra_data)
+        finally:
+            self.hooks = hooks
+        if result.termination_reason == 'cancelled' and context.reason is not None:
+            raise TrajectoryCancelledError(context.reason, result)
+        return result
+
+    def set_weapon_zero_with_context(self, context: CancelContext, shot: Shot, zero_distance: [float, Distance],
+                                     zero_atmo: Atmo = None, zero_ammo: Ammo = None) -> Angular:
+        """set_weapon_zero() that stops when context is cancelled or its deadline passes
+        :param context: CancelContext of the calculation
+        :raises TrajectoryCancelledError: without result; shot.weapon.zero_elevation is unchanged
+        """
+        zero_distance = PreferredUnits.distance(zero_distance)
+        zero_shot = replace(shot, weapon=replace(shot.weapon))
+        hooks = self.hooks
+        self.hooks = context.hooks(hooks)
+        try:
+            zero = self.set_weapon_zero(zero_shot, zero_distance, zero_atmo, zero_ammo)
+        except Exception as error:
+            if not context.interrupted:
+                raise
+            raise TrajectoryCancelledError(context.reason) from error
+        finally:
+            self.hooks = hooks
+        if context.interrupted:
+            # Zero finding didn't converge on complete trajectories
+            if zero_atmo is not None or zero_ammo is not None:
+                zero_shot = replace(zero_shot, atmo=zero_atmo or shot.atmo, ammo=zero_ammo or shot.ammo)
+            self._sight_angle_cache.pop(self._sight_angle_key(zero_shot, zero_distance), None)
+            raise TrajectoryCancelledError(context.reason)
+        shot.weapon.zero_elevation = zero
+        return zero
+
+    def fire_columns(self, shot: Shot, trajectory_range: [float, Distance],
+                     trajectory_step: [float, Distance] = 0,
+                     extra_data: bool = False, units: dict[str, Unit] = None) -> TrajectoryColumns:
+        """Calculates trajectory into TrajectoryColumns, without keeping TrajectoryData rows
+        :param shot: shot parameters (initial position and barrel angle)
+        :param trajectory_range: Downrange distance at which to stop computing trajectory
+        :param trajectory_step: step between trajectory points to record
+        :param extra_data: True => store every calculation step; False => store only each trajectory_step
+        :param units: Units of fields (see TrajectoryColumns); others use PreferredUnits
+        """
+        trajectory_range, step = _range_and_step(trajectory_range, trajectory_step)
+        calc = self._get_calc(shot.ammo)
+        columns = TrajectoryColumns(units)
+        calc.trajectory(shot, trajectory_range, step, extra_data, out=columns)
+        columns.termination_reason = calc.termination_reason
+        return columns
+
+    def fire_stream(self, shot: Shot, trajectory_range: [float, Distance],
+                    on_row: Callable[[TrajectoryData], Optional[bool]], trajectory_step: [float, Distance] = 0,
+                    extra_data: bool = False) -> str:
+        """Calculates trajectory passing each row to on_row as it is calculated, without keeping the rows
+        :param shot: shot parameters (initial position and barrel angle)
+        :param trajectory_range: Downrange distance at which to stop computing trajectory
+        :param on_row: called with each TrajectoryData row; returning True stops the calculation
+        :param trajectory_step: step between trajectory points to record
+        :param extra_data: True => pass every calculation step; False => pass only each trajectory_step
+        :return: termination reason of the trajectory (see TrajectoryHooks), 'cancelled' if stopped by on_row
+        """
+        trajectory_range, step = _range_and_step(trajectory_range, trajectory_step)
+        sink = _RowSink(on_row)
+        hooks = self.hooks
+        on_step = None if hooks is None else hooks.on_step
+        self.hooks = TrajectoryHooks(lambda state: sink.stopped or (on_step is not None and on_step(state)),
+                                     None if hooks is None else hooks.on_record,
+                                     None if hooks is None else hooks.on_termination)
+        try:
+            calc = self._get_calc(shot.ammo)
+            calc.trajectory(shot, trajectory_range, step, extra_data, out=sink)
+        finally:
+            self.hooks = hooks
+        return calc.termination_reason
+
+    def fire_iter(self, shot: Shot, trajectory_range: [float, Distance],
+                  trajectory_step: [float, Distance] = 0, extra_data: bool = False) -> Iterator[TrajectoryData]:
+        """Lazily calculates trajectory, yielding rows while the calculation runs in another thread.
+            Closing the iterator (or leaving a for loop early) stops the calculation.
+            The Calculator must not be used by other calls until the iterator is exhausted or closed.
+        :param shot: shot parameters (initial position and barrel angle)
+        :param trajectory_range: Downrange distance at which to stop computing trajectory
+        :param trajectory_step: step between trajectory points to record
+        :param extra_data: True => yield every calculation step; False => yield only each trajectory_step
+        """
+        trajectory_range, trajectory_step = _range_and_step(trajectory_range, trajectory_step)
+        rows = queue.Queue(maxsize=cStreamBuffer)
+        done = object()
+        closed = threading.Event()
+        errors = []
+
+        def on_row(row: TrajectoryData) -> bool:
+            if not closed.is_set():
+                rows.put(row)
+            return closed.is_set()
+
+        def produce():
+            try:
+                self.fire_stream(shot, trajectory_range, on_row, trajectory_step, extra_data)
+            except Exception as error:  # pylint: disable=broad-except
+                errors.append(error)
+            finally:
+                rows.put(done)
+
+        producer = threading.Thread(target=produce, daemon=True)
+        producer.start()
+        row = None
+        try:
+            while (row := rows.get()) is not done:
+                yield row
+        finally:
+            if row is not done:
+                # Unblock the producer and wait for it to stop at its next step
+                closed.set()
+                while rows.get() is not done:
+                    pass
+            producer.join()
+        if errors:
+            raise errors[0]
+
+    def fire_at_ranges(self, shot: Shot, ranges: Iterable[[float, Distance]]) -> HitResult:
+        """Calculates trajectory with records interpolated to exactly the requested distances
+        :param shot: shot parameters (initial position and barrel angle)
+        :param ranges: Downrange distances at which to record TrajectoryData, e.g. [100, 230, 575, 840]
+        """
+        calc = self._get_calc(shot.ammo)
+        data = calc.trajectory_at_ranges(shot, [PreferredUnits.distance(r) for r in ranges])
+        return self._hit_result(calc, shot, data, False)
+
+    def fire_terminal(self, shot: Shot, target_distance: [float, Distance], zero_crossings: bool = False) -> HitResult:
+        """Calculates only the trajectory row at target_distance, skipping the bookkeeping of intermediate rows,
+            for fire-control applications that solve at high frequency and only need the final hold
+        :param shot: shot parameters (initial position and barrel angle)
+        :param target_distance: Downrange distance of the row to calculate
+        :param zero_crossings: True => also record rows where the trajectory crosses the sight line
+        :return: HitResult whose last row is at target_distance, preceded by any zero crossings.
+            Its rows are not every step, so find zero crossings by their flags (TrajFlag.ZERO_UP, ZERO_DOWN),
+            e.g. with HitResult.flag_at(), rather than with HitResult.zeros()
+        :raises RangeError: if the trajectory stops before target_distance
+        """
+        target_distance = PreferredUnits.distance(target_distance)
+        calc = self._get_calc(shot.ammo)
+        data = calc.trajectory_terminal(shot, target_distance, zero_crossings)
+        result = self._hit_result(calc, shot, data, False)
+        if not data or not data[-1].flag & TrajFlag.RANGE.value:
+            raise result.range_error(target_distance)
+        return result
+
+    def fire_by_time(self, shot: Shot, maximum_time: [float, Time], time_step: [float, Time] = 0) -> HitResult:
+        """Calculates trajectory with records at fixed intervals of time of flight, e.g. for animation
+            or comparison with radar data
+        :param shot: shot parameters (initial position and barrel angle)
+        :param maximum_time: Time (float in seconds) of flight at which to stop computing trajectory
+        :param time_step: Time (float in seconds) between trajectory points to record, default maximum_time / 10
+        """
+        maximum_time = Time.Second(maximum_time) >> Time.Second
+        time_step = Time.Second(time_step) >> Time.Second
+        if maximum_time <= 0:
+            raise ValueError("maximum_time have to be > 0")
+        if not time_step:
+            time_step = maximum_time / 10.0
+        if time_step < 0:
+            raise ValueError("time_step have to be > 0")
+        # Rounded so that floating point error doesn't drop the last step
+        times = [i * time_step for i in range(int(round(maximum_time / time_step, 9)) + 1)]
+        calc = self._get_calc(shot.ammo)
+        data = calc.trajectory_at_times(shot, times)
+        return self._hit_result(calc, shot, data, False)
+
+    def fire_volley(self, shots: Iterable[Shot], trajectory_range: [float, Distance],
+                    trajectory_step: [float, Distance] = 0,
+                    extra_data: bool = False) -> Iterator[HitResult]:
+        """Lazily calculates trajectories for a sequence of shots, yielding each HitResult as soon as
+            it is ready.  Consecutive shots sharing the same DragModel reuse one TrajectoryCalc,
+            so the drag curve is only prepared once.  Use with relative_angle_sweep() or
+            muzzle_velocity_sweep(), or any other iterable of shots sharing a zeroed weapon.
+        :param shots: shots to calculate
+        :param trajectory_range: Downrange distance at which to stop computing trajectory
         :param trajectory_step: step between trajectory points to record
         :param extra_data: True => store TrajectoryData for every calculation step;
             False => store TrajectoryData only for each trajectory_step
         """
-        trajectory_range = PreferredUnits.distance(trajectory_range)
-        if not trajectory_step:
-            trajectory_step = trajectory_range.unit_value / 10.0
-        step = PreferredUnits.distance(trajectory_step)
-        self._calc = TrajectoryCalc(shot.ammo)
-        data = self._calc.trajectory(shot, trajectory_range, step, extra_data)
-        return HitResult(shot, data, extra_data)
+        trajectory_range, step = _range_and_step(trajectory_range, trajectory_step)
+        for shot in shots:
+            calc = self._get_calc(shot.ammo)
+            data = calc.trajectory(shot, trajectory_range, step, extra_data)
+            yield self._hit_result(calc, shot, data, extra_data, step)
+
+    def fire_batch(self, scenarios: Iterable[ShotScenario], workers: int = None) -> list[HitResult]:
+        """Calculates trajectories of scenarios in a pool of worker processes
+        :param scenarios: shots with the arguments of fire()
+        :param workers: number of processes, default the number of CPUs; 1 => calculate in this process.
+            TrajectoryHooks are only called in this process
+        :return: HitResult of each scenario, in the order of scenarios
+        """
+        scenarios = [ShotScenario(*scenario) for scenario in scenarios]
+        if workers is None:
+            workers = os.cpu_count() or 1
+        if workers < 1:
+            raise ValueError("At least one worker is required")
+        workers = min(workers, len(scenarios))
+        if workers <= 1:
+            return _fire_scenarios(self, scenarios)
+        # Contiguous chunks keep consecutive shots with one DragModel on one prepared TrajectoryCalc
+        size = math.ceil(len(scenarios) / workers)
+        chunks = [scenarios[i:i + size] for i in range(0, len(scenarios), size)]
+        worker_calc = Calculator(config=self.config, engine=self.engine)
+        with ProcessPoolExecutor(max_workers=workers) as executor:
+            return [result for chunk in executor.map(_fire_scenarios, [worker_calc] * len(chunks), chunks)
+                    for result in chunk]
+
+    def fire_string(self, shot: Shot, count: int, target_distance: [float, Distance]) -> ShotString:
+        """Calculates where each shot of a string lands, with muzzle velocities from Ammo.mv_for_shot()
+        :param shot: zeroed shot whose ammo has cold_bore_offset and shot_mv_drift
+        :param count: number of shots in the string
+        :param target_distance: distance at which to compare the impacts
+        """
+        target_distance = PreferredUnits.distance(target_distance)
+        shots = list(shot_string_sweep(shot, count))
+        impacts = []
+        for string_shot in shots:
+            result = self.fire_at_ranges(string_shot, [target_distance])
+            if not result.trajectory:
+                raise result.range_error(target_distance)
+            impacts.append(result.trajectory[0])
+        return ShotString(shots, impacts)
+
+
+class _RowSink:
+    """Output of TrajectoryCalc.trajectory() passing rows to a callback and keeping only the last one"""
+
+    def __init__(self, on_row: Callable[[TrajectoryData], Optional[bool]]):
+        self.on_row = on_row
+        self.last = None
+        self.stopped = False
+
+    def append(self, row: TrajectoryData) -> None:
+        if self.stopped:
+            return
+        self.last = row
+        if self.on_row(row):
+            self.stopped = True
+
+    def __getitem__(self, index: int) -> TrajectoryData:
+        return self.last
+
+
+def _fire_scenarios(calc: Calculator, scenarios: list[ShotScenario]) -> list[HitResult]:
+    """:return: Calculator.fire() of each of scenarios"""
+    return [calc.fire(*scenario) for scenario in scenarios]
+
+
+def _range_and_step(trajectory_range: [float, Distance], trajectory_step: [float, Distance]) -> tuple:
+    """:return: trajectory_range and trajectory_step as Distance, the step defaulting to a tenth of the range
+    :raise ValueError: if trajectory_range isn't positive or trajectory_step is negative
+    """
+    trajectory_range = PreferredUnits.distance(trajectory_range)
+    if not trajectory_range.raw_value > 0:
+        raise ValueError(f"trajectory_range have to be > 0, got {trajectory_range}")
+    if not trajectory_step:
+        trajectory_step = trajectory_range.unit_value / 10.0
+    step = PreferredUnits.distance(trajectory_step)
+    if not step.raw_value > 0:
+        raise ValueError(f"trajectory_step have to be > 0, got {step}")
+    return trajectory_range, step
+
+
+def _freeze(value):
+    """:return: hashable snapshot of the values of dataclasses, units, drag models and lists"""
+    if isinstance(value, AbstractUnit):
+        return value.raw_value
+    if isinstance(value, DragModel):
+        return (value.BC, value.weight.raw_value, value.diameter.raw_value, value.length.raw_value,
+                tuple((p.Mach, p.CD) for p in value.drag_table), _freeze(value.bc_reference))
+    if is_dataclass(value):
+        return (type(value),) + tuple(_freeze(getattr(value, f.name)) for f in fields(value))
+    if isinstance(value, (list, tuple)):
+        return tuple(_freeze(v) for v in value)
+    return value
+
+
+def relative_angle_sweep(shot: Shot, relative_angles: Iterable[[float, Angular]]) -> Iterator[Shot]:
+    """:return: copies of shot with each of relative_angles (elevation sweep)"""
+    for angle in relative_angles:
+        yield replace(shot, relative_angle=PreferredUnits.angular(angle))
+
+
+def muzzle_velocity_sweep(shot: Shot, velocities: Iterable[[float, Velocity]]) -> Iterator[Shot]:
+    """:return: copies of shot whose ammo has each of velocities as muzzle velocity"""
+    for velocity in velocities:
+        yield replace(shot, ammo=replace(shot.ammo, mv=PreferredUnits.velocity(velocity)))
+
+
+def shot_string_sweep(shot: Shot, count: int) -> Iterator[Shot]:
+    """:return: copies of shot for each of count shots fired from a cold barrel (see Ammo.mv_for_shot)"""
+    for shot_number in range(1, count + 1):
+        yield replace(shot, ammo=replace(shot.ammo, mv=shot.ammo.mv_for_shot(shot_number)))
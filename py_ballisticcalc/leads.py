@@ -0,0 +1,85 @@
+"""Lead holds for targets crossing the line of sight, from the calculated time of flight"""
+import math
+from typing import Iterable, Iterator, Mapping, NamedTuple
+
+from .conditions import Shot
+from .interface import Calculator
+from .localization import Localization, Locales
+from .unit import Angular, Distance, Velocity, Unit, PreferredUnits
+
+__all__ = ('TARGET_SPEEDS', 'LeadRow', 'lead_holds', 'lead_card')
+
+# Typical crossing speeds of targets
+TARGET_SPEEDS = {
+    'walk': Velocity.MPH(3),
+    'trot': Velocity.MPH(6),
+    'run': Velocity.MPH(12),
+    'vehicle': Velocity.MPH(20),
+    'fast vehicle': Velocity.MPH(40),
+}
+
+
+class LeadRow(NamedTuple):
+    """
+    Leads at one range
+
+    Attributes:
+        distance (Distance): sight-line distance to the target
+        time (float): time of flight in seconds
+        leads (dict[str, Distance]): distance each target moves during the time of flight, by speed name
+        holds (dict[str, Angular]): angle to hold ahead of each target, by speed name
+    """
+    distance: Distance
+    time: float
+    leads: dict[str, Distance]
+    holds: dict[str, Angular]
+
+    def formatted(self, localization: Localization = None, units: Unit = None) -> tuple:
+        """
+        :param localization: Number and unit label formatting (default: Locales.English)
+        :param units: Units of the holds, default PreferredUnits.adjustment
+        :return: distance, time of flight, then the holds in the order of the speeds
+        """
+        loc = Locales.English if localization is None else localization
+        units = units or PreferredUnits.adjustment
+        return (loc.format(self.distance, PreferredUnits.distance),
+                f'{loc.number(self.time, 3)} {loc.label("s")}',
+                *(loc.format(hold, units) for hold in self.holds.values()))
+
+
+def lead_holds(calc: Calculator, shot: Shot, ranges: Iterable[[float, Distance]],
+               speeds: Mapping[str, Velocity] = None) -> Iterator[LeadRow]:
+    """Yields leads for each of ranges, for targets crossing perpendicular to the line of sight
+    :param calc: Calculator to solve the trajectory
+    :param shot: zeroed shot
+    :param ranges: downrange distances, as for Calculator.fire_at_ranges()
+    :param speeds: target speed by name, default TARGET_SPEEDS
+    """
+    speeds = TARGET_SPEEDS if speeds is None else speeds
+    speeds = {name: PreferredUnits.velocity(speed) for name, speed in speeds.items()}
+    for row in calc.fire_at_ranges(shot, ranges).trajectory:
+        look_distance = row.look_distance >> Distance.Foot
+        leads, holds = {}, {}
+        for name, speed in speeds.items():
+            lead = (speed >> Velocity.FPS) * row.time
+            leads[name] = Distance.Foot(lead) << PreferredUnits.drop
+            holds[name] = Angular.Radian(math.atan2(lead, look_distance)) << PreferredUnits.adjustment
+        yield LeadRow(row.look_distance << PreferredUnits.distance, row.time, leads, holds)
+
+
+def lead_card(calc: Calculator, shot: Shot, ranges: Iterable[[float, Distance]],
+              speeds: Mapping[str, Velocity] = None, localization: Localization = None,
+              units: Unit = None) -> list[tuple]:
+    """Lead holds as rows of strings for a range card
+    :param units: Units of the holds, default PreferredUnits.adjustment
+    :return: header row ('range', 'time', then each speed name with its speed, all translated by
+        Localization.label), then LeadRow.formatted() rows
+    """
+    loc = Locales.English if localization is None else localization
+    speeds = TARGET_SPEEDS if speeds is None else speeds
+    header = [loc.label('range'), loc.label('time')]
+    for name, speed in speeds.items():
+        # Speeds are labeled in the units they were given in
+        units_of_speed = speed.units if isinstance(speed, Velocity) else PreferredUnits.velocity
+        header.append(f'{loc.label(name)} {loc.format(PreferredUnits.velocity(speed), units_of_speed)}')
+    return [tuple(header)] + [row.formatted(loc, units) for row in lead_holds(calc, shot, ranges, speeds)]
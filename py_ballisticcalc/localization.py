@@ -0,0 +1,69 @@
+"""Localized number and unit formatting of formatted outputs"""
+from dataclasses import dataclass, field
+from typing import Mapping
+
+from .unit import AbstractUnit, Unit
+
+__all__ = ('Localization', 'Locales')
+
+
+@dataclass(frozen=True)
+class Localization:
+    """
+    Formatting of numbers and unit labels, passed to formatters such as TrajectoryData.formatted()
+
+    :param decimal_separator: Separator of the fractional part, e.g. ',' for most European locales
+    :param thousands_separator: Separator of groups of thousands ('' for no grouping)
+    :param unit_labels: Labels replacing Unit.symbol for some units, e.g. {Unit.Meter: 'м'}
+    :param labels: Translations of other labels: 's' (seconds), 'mach' and 'rps' (revolutions per second),
+        and the column names of lead_card()
+    """
+    decimal_separator: str = '.'
+    thousands_separator: str = ''
+    unit_labels: Mapping[Unit, str] = field(default_factory=dict)
+    labels: Mapping[str, str] = field(default_factory=dict)
+
+    def number(self, value: float, accuracy: int, exponent: bool = False) -> str:
+        """:return: value rounded to accuracy digits after the decimal separator
+            (in scientific notation if exponent)"""
+        grouping = ',' if self.thousands_separator and not exponent else ''
+        text = f"{value:{grouping}.{accuracy}{'e' if exponent else 'f'}}"
+        return ''.join(self.thousands_separator if c == ',' else self.decimal_separator if c == '.' else c
+                       for c in text)
+
+    def symbol(self, units: Unit) -> str:
+        """:return: label of units"""
+        return self.unit_labels.get(units, units.symbol)
+
+    def label(self, text: str) -> str:
+        """:return: translation of a label that is not a unit"""
+        return self.labels.get(text, text)
+
+    def format(self, value: AbstractUnit, units: Unit) -> str:
+        """:return: value converted to units, with their accuracy and label"""
+        return f"{self.number(value >> units, units.accuracy)} {self.symbol(units)}"
+
+
+class Locales:  # pylint: disable=too-few-public-methods
+    """Localization presets"""
+    English = Localization()
+    German = Localization(decimal_separator=',', thousands_separator='.')
+    Ukrainian = Localization(
+        decimal_separator=',',
+        thousands_separator='\u00a0',  # No-break space
+        unit_labels={
+            Unit.Radian: 'рад', Unit.Degree: '°', Unit.Mil: 'тис.', Unit.MRad: 'мрад', Unit.OClock: 'год',
+            Unit.Inch: 'дюйм', Unit.Foot: 'фут', Unit.Yard: 'ярд', Unit.Mile: 'миля', Unit.NauticalMile: 'мор. миля',
+            Unit.Millimeter: 'мм', Unit.Centimeter: 'см', Unit.Meter: 'м', Unit.Kilometer: 'км', Unit.Line: 'лн',
+            Unit.Second: 'с', Unit.Millisecond: 'мс', Unit.Minute: 'хв',
+            Unit.FootPound: 'фут·фунт', Unit.Joule: 'Дж', Unit.Kilojoule: 'кДж', Unit.Megajoule: 'МДж',
+            Unit.KgfMeter: 'кгс·м',
+            Unit.MmHg: 'мм рт. ст.', Unit.InHg: 'дюйм рт. ст.', Unit.Bar: 'бар', Unit.hPa: 'гПа', Unit.PSI: 'psi',
+            Unit.Pascal: 'Па', Unit.kPa: 'кПа', Unit.Millibar: 'мбар',
+            Unit.MPS: 'м/с', Unit.KMH: 'км/год', Unit.FPS: 'фут/с', Unit.MPH: 'миль/год', Unit.KT: 'вуз.',
+            Unit.Grain: 'гран', Unit.Ounce: 'унц.', Unit.Gram: 'г', Unit.Pound: 'фунт', Unit.Kilogram: 'кг',
+            Unit.Newton: 'Н', Unit.KgPerCubicMeter: 'кг/м³', Unit.LbPerCubicFoot: 'фунт/фут³', Unit.GramPerLiter: 'г/л',
+            Unit.FootPerSecondSquared: 'фут/с²', Unit.MeterPerSecondSquared: 'м/с²', Unit.StandardGravity: 'g₀',
+        },
+        labels={'s': 'с', 'mach': 'Мах', 'rps': 'об/с'}
+    )
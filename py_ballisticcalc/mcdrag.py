@@ -0,0 +1,222 @@
+"""Drag estimation from projectile geometry, after McCoy's MC DRAG (BRL-MR-2474),
+    for projectiles without a published BC
+"""
+import math
+from dataclasses import dataclass, field
+from enum import Enum
+from typing import Iterable, NamedTuple
+
+from .drag_model import DragDataPoint, DragModel, DragModelCD
+from .unit import Distance, Weight, PreferredUnits, Dimension
+
+__all__ = ('BoundaryLayer', 'ProjectileGeometry', 'DragBreakdown', 'drag_breakdown',
+           'estimate_drag_table', 'estimate_drag_model')
+
+cReynoldsPerMachCaliberMm = 23296.3  # Reynolds number per Mach, caliber of length and mm of diameter at sea level
+cMinimumMach = 0.5  # Below this Mach the estimate is held constant
+cTransonicMach = 1.2  # Wave drag between the critical Mach and this Mach is held at its value here
+cMeplatPressure = 0.8  # Mean pressure on a flat meplat as a fraction of stagnation pressure
+
+# Mach numbers of estimate_drag_table(), denser through the transonic drag rise
+MCDRAG_MACHS = tuple(
+    [0.0] + [round(0.5 + 0.05 * i, 3) for i in range(7)]
+    + [round(0.825 + 0.025 * i, 3) for i in range(16)]
+    + [round(1.25 + 0.05 * i, 3) for i in range(15)]
+    + [round(2.0 + 0.1 * i, 3) for i in range(21)])
+
+
+class BoundaryLayer(Enum):
+    """State of the boundary layer over the projectile"""
+    LAMINAR = 'L/L'  # Laminar over the whole body
+    LAMINAR_NOSE = 'L/T'  # Laminar over the nose, turbulent behind it
+    TURBULENT = 'T/T'  # Turbulent over the whole body, typical of bullets with a cannelure or engraving
+
+
+@dataclass
+class ProjectileGeometry(PreferredUnits.Mixin):
+    """
+    Projectile profile, as for MC DRAG
+
+    :param diameter: Reference (bore-riding) diameter
+    :param length: Overall length
+    :param nose_length: Length of the ogive or cone, from the meplat to the start of the cylinder
+    :param ogive_ratio: Tangent radius over the actual ogive radius (RT/R): 0 = cone, 1 = tangent ogive,
+        between them a secant ogive
+    :param meplat_diameter: Diameter of the flat tip, default 0 (pointed)
+    :param boattail_length: Length of the conical boattail, default 0 (flat base)
+    :param base_diameter: Diameter at the base, default the diameter
+    :param band_diameter: Diameter of a driving band, default the diameter (no band)
+    :param boundary_layer: State of the boundary layer, default turbulent
+    """
+    diameter: [float, Distance] = Dimension(prefer_units='diameter')
+    length: [float, Distance] = Dimension(prefer_units='length')
+    nose_length: [float, Distance] = Dimension(prefer_units='length')
+    ogive_ratio: float = field(default=1.0)
+    meplat_diameter: [float, Distance] = Dimension(prefer_units='diameter')
+    boattail_length: [float, Distance] = Dimension(prefer_units='length')
+    base_diameter: [float, Distance] = Dimension(prefer_units='diameter')
+    band_diameter: [float, Distance] = Dimension(prefer_units='diameter')
+    boundary_layer: BoundaryLayer = field(default=BoundaryLayer.TURBULENT)
+
+    def __post_init__(self):
+        if not self.diameter or not self.length or not self.nose_length:
+            raise ValueError("Diameter, length and nose length are required")
+        if not self.meplat_diameter:
+            self.meplat_diameter = 0
+        if not self.boattail_length:
+            self.boattail_length = 0
+        if not self.base_diameter:
+            self.base_diameter = self.diameter
+        if not self.band_diameter:
+            self.band_diameter = self.diameter
+        if self.diameter.raw_value <= 0 or self.nose_length.raw_value <= 0:
+            raise ValueError("Diameter and nose length have to be positive")
+        if (self.nose_length >> Distance.Inch) + (self.boattail_length >> Distance.Inch) > \
+                self.length >> Distance.Inch:
+            raise ValueError("Nose and boattail are longer than the projectile")
+        if not (0 <= self.meplat_diameter.raw_value < self.diameter.raw_value):
+            raise ValueError("Meplat diameter has to be smaller than the diameter")
+        if not (0 < self.base_diameter.raw_value <= self.diameter.raw_value):
+            raise ValueError("Base diameter has to be positive and no larger than the diameter")
+        if self.band_diameter.raw_value < self.diameter.raw_value:
+            raise ValueError("Band diameter can't be smaller than the diameter")
+        if not 0 <= self.ogive_ratio <= 1:
+            raise ValueError("Ogive ratio has to be from 0 (cone) to 1 (tangent ogive)")
+
+    def calibers(self, dimension: Distance) -> float:
+        """:return: dimension in calibers of the diameter"""
+        return (dimension >> Distance.Inch) / (self.diameter >> Distance.Inch)
+
+
+class DragBreakdown(NamedTuple):
+    """
+    Drag coefficient components at one Mach number
+
+    Attributes:
+        mach (float): Mach number
+        skin_friction (float): viscous drag over the wetted surface
+        nose_wave (float): pressure (wave) drag of the ogive or cone
+        meplat (float): pressure drag of the flat tip
+        boattail_wave (float): pressure drag of the boattail expansion
+        band (float): drag of a driving band
+        base (float): drag of the low pressure behind the base
+    """
+    mach: float
+    skin_friction: float
+    nose_wave: float
+    meplat: float
+    boattail_wave: float
+    band: float
+    base: float
+
+    @property
+    def total(self) -> float:
+        """:return: drag coefficient of the projectile"""
+        return sum(self[1:])
+
+
+def _transonic_ramp(mach: float, critical_mach: float) -> float:
+    """:return: Fraction of the wave drag at cTransonicMach reached by mach on the transonic drag rise"""
+    if mach <= critical_mach:
+        return 0.0
+    if mach >= 1:
+        return 1.0
+    x = (mach - critical_mach) / (1 - critical_mach)
+    return x * x * (3 - 2 * x)
+
+
+def _base_pressure(mach: float, m2: float, l1: float, l2: float, d2: float) -> float:
+    """:return: Ratio of base pressure to free-stream pressure"""
+    if mach < 1:
+        pb2 = 1 / (1 + 0.1875 * m2 + 0.0531 * m2 * m2)
+    else:
+        pb2 = 1 / (1 + 0.2477 * m2 + 0.0345 * m2 * m2)
+    pb4 = (1 + 0.09 * m2 * (1 - math.exp(l2 - l1))) * (1 + 0.25 * m2 * (1 - d2))
+    return pb2 * pb4
+
+
+def _stagnation_pressure_coefficient(mach: float, m2: float) -> float:
+    """:return: Pressure coefficient at the stagnation point (behind the normal shock when supersonic)"""
+    if mach < 1:
+        ratio = math.pow(1 + 0.2 * m2, 3.5)
+    else:
+        ratio = math.pow(1.2 * m2, 3.5) * math.pow(6 / (7 * m2 - 1), 2.5)
+    return (ratio - 1) / (0.7 * m2)
+
+
+def drag_breakdown(geometry: ProjectileGeometry, mach: float) -> DragBreakdown:
+    """Estimates the drag coefficient of geometry at mach in standard sea-level air
+    :param mach: Mach number, held at cMinimumMach below it
+    """
+    mach = max(mach, cMinimumMach)
+    m2 = mach * mach
+    l1 = geometry.calibers(geometry.length)
+    l2 = geometry.calibers(geometry.nose_length)
+    l3 = geometry.calibers(geometry.boattail_length)
+    d2 = geometry.calibers(geometry.base_diameter)
+    d3 = geometry.calibers(geometry.meplat_diameter)
+    d4 = geometry.calibers(geometry.band_diameter)
+    r1 = geometry.ogive_ratio
+
+    # Skin friction of flat plates with compressibility corrections, over the wetted area in calibers squared
+    reynolds = cReynoldsPerMachCaliberMm * mach * l1 * (geometry.diameter >> Distance.Millimeter)
+    cf_turbulent = 0.455 / math.pow(math.log10(reynolds), 2.58) * math.pow(1 + 0.21 * m2, -0.32)
+    cf_laminar = 1.328 / math.sqrt(reynolds) * math.pow(1 + 0.12 * m2, -0.12)
+    nose_area = (math.pi / 2 * (1 + d3) * math.sqrt(l2 * l2 + math.pow((1 - d3) / 2, 2))
+                 * (1 + (0.333 + 0.02 / (l2 * l2)) * r1))
+    body_area = math.pi * (l1 - l2 - l3)
+    tail_area = math.pi / 2 * (1 + d2) * math.sqrt(l3 * l3 + math.pow((1 - d2) / 2, 2)) if l3 else 0
+    if geometry.boundary_layer == BoundaryLayer.LAMINAR:
+        friction = (nose_area + body_area + tail_area) * cf_laminar
+    elif geometry.boundary_layer == BoundaryLayer.LAMINAR_NOSE:
+        friction = nose_area * cf_laminar + (body_area + tail_area) * cf_turbulent
+    else:
+        friction = (nose_area + body_area + tail_area) * cf_turbulent
+    skin_friction = friction * 4 / math.pi
+
+    # Wave drag rises from the critical Mach, and is held from Mach 1 to cTransonicMach
+    beta = math.sqrt(max(m2, cTransonicMach * cTransonicMach) - 1)
+    taper = (1 - d3) / l2
+    critical_mach = max(0.75, 1 - 0.3 * taper)
+    ramp = _transonic_ramp(mach, critical_mach)
+    c1 = 0.7156 - 0.5313 * r1 + 0.595 * r1 * r1
+    c2 = 0.0796 + 0.0779 * r1
+    c3 = 1.587 + 0.049 * r1
+    c4 = 0.1122 + 0.1658 * r1
+    nose_wave = ramp * (c1 - c2 * taper * taper) / (beta * beta) * math.pow(taper * beta, c3 + c4 * taper)
+    meplat = ramp * cMeplatPressure * _stagnation_pressure_coefficient(mach, m2) * d3 * d3
+    # Slender-body expansion over the boattail annulus
+    boattail_wave = 0.0
+    if l3:
+        tail_slope = (1 - d2) / (2 * l3)
+        boattail_wave = ramp * 4 * tail_slope * tail_slope / beta * (1 - d2 * d2)
+
+    if mach < 0.95:
+        band = math.pow(mach, 12.5) * (d4 - 1)
+    else:
+        band = (0.21 + 0.28 / m2) * (d4 - 1)
+
+    # Base pressure, blended across Mach 1 between its subsonic and supersonic correlations
+    def base_drag(m: float) -> float:
+        return 1.4286 * (1 - _base_pressure(m, m * m, l1, l2, d2)) * d2 * d2 / (m * m)
+
+    if 0.95 < mach < 1.05:
+        f = (mach - 0.95) / 0.1
+        base = (1 - f) * base_drag(0.95) + f * base_drag(1.05)
+    else:
+        base = base_drag(mach)
+    return DragBreakdown(mach, skin_friction, nose_wave, meplat, boattail_wave, band, base)
+
+
+def estimate_drag_table(geometry: ProjectileGeometry, machs: Iterable[float] = None) -> list[DragDataPoint]:
+    """:param machs: Mach numbers of the table, default MCDRAG_MACHS
+    :return: Drag table of geometry, for DragModel with bc = sectional density
+    """
+    machs = MCDRAG_MACHS if machs is None else sorted(machs)
+    return [DragDataPoint(mach, drag_breakdown(geometry, mach).total) for mach in machs]
+
+
+def estimate_drag_model(geometry: ProjectileGeometry, weight: [float, Weight],
+                        machs: Iterable[float] = None) -> DragModel:
+    """:return: DragModelCD of a projectile with geometry and weight, on its estimated drag table"""
+    return DragModelCD(estimate_drag_table(geometry, machs), weight, geometry.diameter, geometry.length)
@@ -0,0 +1,90 @@
+"""Modified point-mass (4-DOF) trajectory model: point mass with the yaw of repose of a spinning projectile"""
+import math
+
+from . import backend
+from .conditions import Shot
+from .config import CalculatorConfig
+from .hooks import TrajectoryHooks
+from .logger import logger
+from .munition import Ammo
+from .trajectory_calc import TrajectoryCalc, Vector, cStandardDensity, cRollDampingCoefficient
+
+__all__ = ('ModifiedPointMassCalc',)
+
+
+class ModifiedPointMassCalc(TrajectoryCalc):
+    """
+    Modified point-mass model (McCoy, Modern Exterior Ballistics, ch. 9; STANAG 4355): the point-mass
+        equations with the yaw of repose of the spinning projectile, which adds lift, Magnus force
+        and yaw drag, and with spin damped by the roll damping coefficient.
+        Spin drift follows from the lift of the yaw of repose instead of an empirical formula,
+        and coefficients given by Mach follow the projectile through the transonic region.
+
+    Use as Calculator(engine=ModifiedPointMassCalc) with Ammo.aerodynamics.
+    Requirements and limits:
+        * Ammo.aerodynamics (AeroCoefficients), and DragModel weight and diameter
+        * CalculatorConfig.use_spin_drift and spin_drift_model are ignored
+        * The yaw is the steady yaw of repose: epicyclic swerve (nutation and precession) is not modeled
+        * Always runs in pure python: the binary backend (py_ballisticcalc.exts) has no hook for
+          the forces of the yaw of repose, so a warning is logged if it is installed
+    """
+    _aerodynamic_forces = True
+
+    def __init__(self, ammo: Ammo, hooks: TrajectoryHooks = None, config: CalculatorConfig = None):
+        super().__init__(ammo, hooks, config)
+        if backend.TrajectoryCalc is not TrajectoryCalc:
+            logger.warning("ModifiedPointMassCalc runs in pure python, not in the binary backend")
+
+    def _init_trajectory(self, shot_info: Shot):
+        super()._init_trajectory(shot_info)
+        self.aero = shot_info.ammo.aerodynamics
+        if self.aero is None:
+            raise ValueError("ModifiedPointMassCalc requires Ammo.aerodynamics")
+        if not self.weight or not self.diameter:
+            raise ValueError("ModifiedPointMassCalc requires DragModel weight and diameter")
+        # Spin drift is integrated from the yaw of repose
+        self.use_spin_drift = False
+        self.spin_lift = 0
+        self.diameter_feet = self.diameter / 12
+        mass = self.weight / 7000  # Pounds
+        # Axial moment of inertia in lb*ft^2
+        if self.aero.axial_inertia is None:
+            self.axial_inertia = mass * self.diameter_feet * self.diameter_feet / 10
+        else:
+            self.axial_inertia = self.aero.axial_inertia / 7000 / 144
+        # Air density times this is rho * S / 2m, per foot
+        self.force_factor = cStandardDensity * math.pi * self.diameter_feet * self.diameter_feet / (8 * mass)
+        self.spin_sign = math.copysign(1, self.twist) if self.twist else 0
+
+    def _aerodynamic_acceleration(self, velocity_adjusted: Vector, velocity: float, drag: float,
+                                  gravity_vector: Vector, density_factor: float, mach: float,
+                                  spin_rate: float) -> Vector:
+        """:return: Acceleration by the lift, Magnus force and yaw drag of the yaw of repose
+        :param velocity_adjusted: Velocity relative to the air
+        :param velocity: Magnitude of velocity_adjusted
+        :param drag: Drag of TrajectoryCalc, as acceleration per velocity
+        :param gravity_vector: Gravitational acceleration
+        :param density_factor: Air density relative to cStandardDensity
+        :param mach: Mach number
+        :param spin_rate: Spin rate in revolutions per second
+        """
+        aero = self.aero
+        d = self.diameter_feet
+        roll_damping = aero.at_mach('roll_damping', mach)
+        # Spin damping at this Mach, used by TrajectoryCalc from the next step
+        self.spin_decay = (math.pi * cStandardDensity * math.pow(d, 4) / (16 * self.axial_inertia)
+                           * (cRollDampingCoefficient if roll_damping is None else roll_damping))
+        if not spin_rate or not velocity:
+            return Vector(.0, .0, .0)
+        spin = self.spin_sign * 2 * math.pi * spin_rate  # Radians per second, positive for right-hand twist
+        density = cStandardDensity * density_factor
+        # Yaw of repose in radians: to the right of the trajectory for right-hand twist
+        acceleration = velocity_adjusted * -drag + gravity_vector
+        yaw = acceleration.cross(velocity_adjusted) * (
+            8 * self.axial_inertia * spin
+            / (math.pi * density * d * d * d * aero.at_mach('overturning_slope', mach) * math.pow(velocity, 4)))
+        k = self.force_factor * density_factor
+        lift = yaw * (k * aero.at_mach('lift_slope', mach) * velocity * velocity)
+        magnus = yaw.cross(velocity_adjusted) * (k * d * aero.at_mach('magnus_slope', mach) * spin)
+        yaw_drag = velocity_adjusted * (-k * aero.at_mach('yaw_drag', mach) * yaw.dot(yaw) * velocity)
+        return lift + magnus + yaw_drag
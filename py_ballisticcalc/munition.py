@@ -1,13 +1,16 @@
 """Module for Weapon and Ammo properties definitions"""
 import math
-from dataclasses import dataclass, field
+from dataclasses import dataclass, field, replace
 from enum import IntEnum
 from typing import NamedTuple
 
 from .drag_model import DragModel
-from .unit import Velocity, Temperature, Distance, Angular, PreferredUnits, Dimension, AbstractUnitType
+from .unit import (Velocity, Temperature, Distance, Angular, Acceleration, Unit, PreferredUnits, Dimension,
+                   AbstractUnitType, UnitTypeError)
 
-__all__ = ('Weapon', 'Ammo', 'Sight')
+__all__ = ('Weapon', 'Ammo', 'Sight', 'ProjectilePhase', 'PowderTemperaturePoint', 'AeroCoefficients')
+
+cVelocityPerBarrelInch = 25.0  # Typical change of rifle muzzle velocity with barrel length, fps per inch
 
 
 @dataclass
@@ -26,6 +29,19 @@ class Sight(PreferredUnits.Mixin):
         vertical: float
         horizontal: float
 
+    class RoundedClicks(NamedTuple):
+        """
+        Attributes:
+            clicks (int): whole clicks nearest to the adjustment
+            residual (Angular): part of the adjustment the whole clicks leave uncorrected
+        """
+        clicks: int
+        residual: Angular
+
+    class RoundedAdjustment(NamedTuple):
+        vertical: 'Sight.RoundedClicks'
+        horizontal: 'Sight.RoundedClicks'
+
     focal_plane: FocalPlane = field(default=FocalPlane.FFP)
     scale_factor: [float, Distance] = Dimension(prefer_units='distance')
     h_click_size: [float, Angular] = Dimension(prefer_units='adjustment')
@@ -91,6 +107,25 @@ class Sight(PreferredUnits.Mixin):
                                    trajectory_point.windage_adj,
                                    magnification)
 
+    @staticmethod
+    def round_clicks(adjustment: Angular, clicks: float) -> RoundedClicks:
+        """
+        :param adjustment: angle to adjust
+        :param clicks: clicks of the adjustment, as from get_adjustment()
+        :return: whole clicks nearest to clicks (half away from zero), and the residual angle
+        """
+        whole = int(math.copysign(math.floor(abs(clicks) + 0.5), clicks))
+        residual = adjustment.unit_value * (1 - whole / clicks) if clicks else 0
+        return Sight.RoundedClicks(whole, adjustment.units(residual))
+
+    def get_rounded_adjustment(self, target_distance: Distance,
+                               drop_adj: Angular, windage_adj: Angular,
+                               magnification: float) -> RoundedAdjustment:
+        """:return: get_adjustment() rounded to whole clicks, with the residual angles"""
+        clicks = self.get_adjustment(target_distance, drop_adj, windage_adj, magnification)
+        return Sight.RoundedAdjustment(self.round_clicks(drop_adj, clicks.vertical),
+                                       self.round_clicks(windage_adj, clicks.horizontal))
+
 
 @dataclass
 class Weapon(PreferredUnits.Mixin):
@@ -100,11 +135,14 @@ class Weapon(PreferredUnits.Mixin):
         Positive value => right-hand twist, negative value => left-hand twist.
     :param zero_elevation: Angle of barrel relative to sight line when sight is set to "zero."
         (Typically computed by ballistic Calculator.)
+    :param barrel_length: Length of the barrel (None = unknown), to adjust the muzzle velocity of Ammo
+        measured in a barrel of another length (see Ammo.barrel_length)
     """
     sight_height: [float, Distance] = Dimension(prefer_units='sight_height')
     twist: [float, Distance] = Dimension(prefer_units='twist')
     zero_elevation: [float, Angular] = Dimension(prefer_units='angular')
     sight: [Sight, None] = field(default=None)
+    barrel_length: [float, Distance] = Dimension(prefer_units='length')
 
     def __post_init__(self):
         if not self.sight_height:
@@ -114,6 +152,97 @@ class Weapon(PreferredUnits.Mixin):
         if not self.zero_elevation:
             self.zero_elevation = 0
 
+    def with_sight_height(self, sight_height: [float, Distance]) -> 'Weapon':
+        """:return: copy of this Weapon with sight_height"""
+        return replace(self, sight_height=PreferredUnits.sight_height(sight_height))
+
+    def with_twist(self, twist: [float, Distance]) -> 'Weapon':
+        """:return: copy of this Weapon with twist"""
+        return replace(self, twist=PreferredUnits.twist(twist))
+
+
+@dataclass
+class ProjectilePhase(PreferredUnits.Mixin):
+    """
+    Change of the projectile in flight, such as a discarded sabot or a rocket motor firing.
+        The phase starts at start_time or at start_distance, whichever comes first.
+
+    :param start_time: Time of flight in seconds at which the phase starts (None = not by time)
+    :param start_distance: Downrange distance at which the phase starts (None = not by distance)
+    :param dm: DragModel (including weight) from the start of the phase; None keeps the previous one
+    :param thrust: Acceleration along the velocity vector (float in PreferredUnits.acceleration)
+    :param burn_time: Seconds of thrust from the start of the phase
+    """
+    start_time: float = field(default=None)
+    start_distance: [float, Distance] = Dimension(prefer_units='distance')
+    dm: DragModel = field(default=None)
+    thrust: [float, Acceleration] = Dimension(prefer_units='acceleration')
+    burn_time: float = field(default=0)
+
+    def __post_init__(self):
+        if self.start_time is None and self.start_distance is None:
+            raise ValueError("ProjectilePhase needs start_time or start_distance")
+        if not self.thrust:
+            self.thrust = 0
+        if not isinstance(self.thrust, Acceleration):
+            raise UnitTypeError(f"thrust has to be an Acceleration, not {type(self.thrust).__name__}")
+
+
+@dataclass
+class AeroCoefficients:
+    """
+    Aerodynamic coefficients of a spinning projectile for the modified point-mass model
+        (see ModifiedPointMassCalc).  Each coefficient is a constant or a list of (mach, value)
+        points, interpolated linearly and held beyond the first and last Mach.
+
+    :param lift_slope: Lift force coefficient slope CLa, per radian of yaw
+    :param overturning_slope: Overturning moment coefficient slope CMa, per radian of yaw,
+        positive for spin-stabilized projectiles
+    :param magnus_slope: Magnus force coefficient slope CNpa, per radian of yaw and p*d/V of spin
+    :param yaw_drag: Quadratic yaw drag coefficient CDa2, per radian squared of yaw
+    :param roll_damping: Magnitude of spin damping moment coefficient Clp, per p*d/2V of spin
+        (None = the typical value used by TrajectoryCalc)
+    :param axial_inertia: Axial moment of inertia in grain*inch^2 (None = weight * diameter^2 / 10)
+    """
+    lift_slope: [float, list]
+    overturning_slope: [float, list]
+    magnus_slope: [float, list] = field(default=0)
+    yaw_drag: [float, list] = field(default=0)
+    roll_damping: [float, list] = field(default=None)
+    axial_inertia: float = field(default=None)
+
+    def __post_init__(self):
+        for name in ('lift_slope', 'overturning_slope', 'magnus_slope', 'yaw_drag', 'roll_damping'):
+            value = getattr(self, name)
+            if isinstance(value, (list, tuple)):
+                points = sorted((float(mach), float(v)) for mach, v in value)
+                if not points:
+                    raise ValueError(f"{name} needs at least one (mach, value) point")
+                setattr(self, name, points)
+        if any(v <= 0 for v in ([self.overturning_slope] if not isinstance(self.overturning_slope, list)
+                                else [v for _, v in self.overturning_slope])):
+            raise ValueError("overturning_slope have to be > 0")
+        if self.axial_inertia is not None and self.axial_inertia <= 0:
+            raise ValueError("axial_inertia have to be > 0")
+
+    def at_mach(self, name: str, mach: float) -> float:
+        """:return: Coefficient name at mach"""
+        value = getattr(self, name)
+        if not isinstance(value, list):
+            return value
+        if mach <= value[0][0]:
+            return value[0][1]
+        for (m0, v0), (m1, v1) in zip(value, value[1:]):
+            if mach <= m1:
+                return v0 + (v1 - v0) * (mach - m0) / (m1 - m0)
+        return value[-1][1]
+
+
+class PowderTemperaturePoint(NamedTuple):
+    """Muzzle velocity measured at a powder temperature, for Ammo.mv_by_temperature"""
+    temperature: Temperature
+    velocity: Velocity
+
 
 @dataclass
 class Ammo(PreferredUnits.Mixin):
@@ -122,17 +251,83 @@ class Ammo(PreferredUnits.Mixin):
     :param mv: Muzzle Velocity
     :param powder_temp: Baseline temperature that produces the given mv
     :param temp_modifier: Change in velocity w temperature: % per 15°C.
-        Can be computed with .calc_powder_sens().  Only applies if:
+        Can be computed with .calc_powder_sens() or .set_powder_sensitivity().  Only applies if:
             Settings.USE_POWDER_SENSITIVITY = True
+    :param mv_by_temperature: Muzzle velocities measured at two or more powder temperatures,
+        as PowderTemperaturePoint or (temperature, velocity).  When given, replaces temp_modifier:
+        velocity is interpolated between the points, and extrapolated from the nearest two beyond them.
+        The table sets the change of velocity from powder_temp, at which the velocity is mv,
+        so copies with another mv (with_mv(), mv_for_shot()) shift the whole table.
+    :param cold_bore_offset: Change in velocity of the first shot from a cold barrel
+    :param shot_mv_drift: Change in velocity with each following shot of a string, as the barrel heats
+    :param phases: ProjectilePhase changes in flight, in the order they happen
+    :param barrel_length: Length of the barrel in which mv was measured (None = mv is used with any barrel)
+    :param velocity_per_barrel_inch: Change of muzzle velocity per inch of Weapon.barrel_length
+        longer than barrel_length, default 25 fps
+    :param aerodynamics: AeroCoefficients of the projectile, required by ModifiedPointMassCalc
     """
     dm: DragModel = field(default=None)
     mv: [float, Velocity] = Dimension(prefer_units='velocity')
     powder_temp: [float, Temperature] = Dimension(prefer_units='temperature')
     temp_modifier: float = field(default=0)
+    cold_bore_offset: [float, Velocity] = Dimension(prefer_units='velocity')
+    shot_mv_drift: [float, Velocity] = Dimension(prefer_units='velocity')
+    phases: list[ProjectilePhase] = field(default_factory=list)
+    mv_by_temperature: list[PowderTemperaturePoint] = field(default_factory=list)
+    barrel_length: [float, Distance] = Dimension(prefer_units='length')
+    velocity_per_barrel_inch: [float, Velocity] = Dimension(prefer_units='velocity')
+    aerodynamics: AeroCoefficients = field(default=None)
 
     def __post_init__(self):
         if not self.powder_temp:
             self.powder_temp = Temperature.Celsius(15)
+        if self.velocity_per_barrel_inch is None:
+            self.velocity_per_barrel_inch = Velocity.FPS(cVelocityPerBarrelInch)
+        self.mv_by_temperature = sorted(
+            (PowderTemperaturePoint(PreferredUnits.temperature(t), PreferredUnits.velocity(v))
+             for t, v in self.mv_by_temperature),
+            key=lambda point: point.temperature >> Temperature.Celsius)
+        if len(self.mv_by_temperature) == 1:
+            raise ValueError("mv_by_temperature needs at least two points")
+        temperatures = [point.temperature >> Temperature.Celsius for point in self.mv_by_temperature]
+        if any(a == b for a, b in zip(temperatures, temperatures[1:])):
+            raise ValueError("mv_by_temperature has two points at the same temperature")
+        if not self.cold_bore_offset:
+            self.cold_bore_offset = 0
+        if not self.shot_mv_drift:
+            self.shot_mv_drift = 0
+
+    def with_mv(self, mv: [float, Velocity]) -> 'Ammo':
+        """:return: copy of this Ammo with muzzle velocity mv"""
+        return replace(self, mv=PreferredUnits.velocity(mv))
+
+    def with_bc(self, bc: float) -> 'Ammo':
+        """:return: copy of this Ammo with a copy of its DragModel with bc (see DragModel.with_bc())"""
+        return replace(self, dm=self.dm.with_bc(bc))
+
+    def mv_for_shot(self, shot_number: int) -> Velocity:
+        """Muzzle velocity of a shot in a string fired from a cold barrel
+        :param shot_number: 1 for the cold-bore shot, 2 for the next one, etc.
+        :return: mv + cold_bore_offset for the first shot, mv + (shot_number - 1) * shot_mv_drift after it
+        """
+        if shot_number < 1:
+            raise ValueError(f"Shot number starts from 1, got {shot_number}")
+        mv = self.mv >> Velocity.FPS
+        if shot_number == 1:
+            mv += self.cold_bore_offset >> Velocity.FPS
+        else:
+            mv += (shot_number - 1) * (self.shot_mv_drift >> Velocity.FPS)
+        return Velocity.FPS(mv) << PreferredUnits.velocity
+
+    def barrel_velocity_change(self, barrel_length: [float, Distance, None]) -> Velocity:
+        """:param barrel_length: Length of the barrel firing this ammo, as Weapon.barrel_length
+        :return: Change of muzzle velocity from mv, by velocity_per_barrel_inch;
+            zero if either barrel length is unknown
+        """
+        if barrel_length is None or self.barrel_length is None:
+            return Velocity.FPS(0)
+        inches = (PreferredUnits.length(barrel_length) >> Distance.Inch) - (self.barrel_length >> Distance.Inch)
+        return Velocity.FPS(inches * (self.velocity_per_barrel_inch >> Velocity.FPS))
 
     def calc_powder_sens(self, other_velocity: [float, Velocity],
                          other_temperature: [float, Temperature]) -> float:
@@ -158,14 +353,40 @@ class Ammo(PreferredUnits.Mixin):
         self.temp_modifier = v_delta / t_delta * (15 / v_lower)  # * 100
         return self.temp_modifier
 
+    def set_powder_sensitivity(self, velocity_per_degree: [float, Velocity],
+                               temperature_units: Unit = None) -> float:
+        """Sets temp_modifier from a linear sensitivity, e.g. 1.2 fps per °F:
+            set_powder_sensitivity(Velocity.FPS(1.2), Unit.Fahrenheit)
+        :param velocity_per_degree: change of muzzle velocity per degree of powder temperature
+        :param temperature_units: units of the degree, default PreferredUnits.temperature
+        :return: temperature modifier in terms %v_delta/15°C
+        """
+        temperature_units = temperature_units or PreferredUnits.temperature
+        degree = (temperature_units(1) >> Temperature.Celsius) - (temperature_units(0) >> Temperature.Celsius)
+        dv = PreferredUnits.velocity(velocity_per_degree) >> Velocity.MPS
+        self.temp_modifier = dv / degree * (15 / (self.mv >> Velocity.MPS))
+        return self.temp_modifier
+
     def get_velocity_for_temp(self, current_temp: [float, Temperature]) -> Velocity:
-        """Calculates muzzle velocity at temperature, based on temp_modifier.
+        """Calculates muzzle velocity at temperature, based on mv_by_temperature or temp_modifier.
         :param current_temp: Temperature of cartridge powder
         :return: Muzzle velocity corrected to current_temp
         """
+        t1 = PreferredUnits.temperature(current_temp) >> Temperature.Celsius
         v0 = self.mv >> Velocity.MPS
         t0 = self.powder_temp >> Temperature.Celsius
-        t1 = PreferredUnits.temperature(current_temp) >> Temperature.Celsius
+        if self.mv_by_temperature:
+            return Velocity.MPS(v0 + self._table_velocity(t1) - self._table_velocity(t0))
         t_delta = t1 - t0
         muzzle_velocity = self.temp_modifier / (15 / v0) * t_delta + v0
         return Velocity.MPS(muzzle_velocity)
+
+    def _table_velocity(self, celsius: float) -> float:
+        """:return: velocity in m/s of mv_by_temperature at temperature celsius"""
+        points = self.mv_by_temperature
+        i = 1
+        while i < len(points) - 1 and celsius > points[i].temperature >> Temperature.Celsius:
+            i += 1
+        ta, tb = points[i - 1].temperature >> Temperature.Celsius, points[i].temperature >> Temperature.Celsius
+        va, vb = points[i - 1].velocity >> Velocity.MPS, points[i].velocity >> Velocity.MPS
+        return va + (vb - va) * (celsius - ta) / (tb - ta)
@@ -0,0 +1,276 @@
+"""Pejsa analytic trajectory model: fast approximate alternative to TrajectoryCalc for flat fire"""
+import math
+
+from .conditions import Shot
+from .hooks import StepState
+from .trajectory_calc import TrajectoryCalc, Vector, create_trajectory_row, wind_to_vector
+from .trajectory_data import TrajectoryData, TrajFlag
+from .unit import Angular, Distance
+
+__all__ = ('PejsaCalc',)
+
+cMinimumSlope = 0.05  # Limits of fitted slope n of the retardation coefficient,
+cMaximumSlope = 0.95  # which keep the closed forms away from their singular points
+cFitIterations = 4
+
+
+class PejsaCalc(TrajectoryCalc):
+    """
+    Pejsa model: the retardation coefficient F = -V / (dV/dx) varies linearly with range,
+        F = F0 - n * x, so velocity, time of flight and drop have closed forms.
+        F0 is taken from the drag model at the muzzle, and n is fitted to the drag model
+        over the velocities reached.  Drop uses the flat-fire approximation, wind drift
+        uses the lag rule, and air density and speed of sound are taken at the shooter's altitude.
+
+    Use as Calculator(engine=PejsaCalc) for UI sliders and coarse searches.
+    Accuracy limits versus TrajectoryCalc:
+        * Intended for flat fire (look angles within a few degrees) at supersonic velocity:
+          for typical rifle bullets within about 1% in velocity, time of flight, drop and wind drift
+          while supersonic
+        * Degrades through the transonic region, where drag is not close to a power of velocity
+        * Ignores head and tail wind and changes of air density with height
+        * TrajectoryHooks.on_step is not called, since there are no integration steps
+        * Ammo.phases are not supported
+        * SpinDriftModel.YAW_OF_REPOSE is replaced by Litz's approximation of spin drift
+        * Shot.wind_layers are ignored
+        * CalculatorConfig.extreme_range is ignored, being the opposite of flat fire
+    """
+
+    def _init_trajectory(self, shot_info: Shot):
+        if shot_info.ammo.phases:
+            raise ValueError(f"{type(self).__name__} doesn't support Ammo.phases, use TrajectoryCalc")
+        super()._init_trajectory(shot_info)
+        self.use_spin_drift = self.use_spin_drift or self.use_yaw_of_repose
+        self.density_factor, self.mach = self.atmo_model.get_density_factor_and_mach_for_altitude(self.alt0)
+        self.gravity = -self.gravity_vector.y
+        self.f0 = self._retardation_coefficient(self.muzzle_velocity)
+        self.n = 0.5
+
+    def _retardation_coefficient(self, velocity: float) -> float:
+        """:return: F = -V / (dV/dx) in feet"""
+        return 1 / (self.density_factor * self.drag_by_mach(velocity / self.mach))
+
+    def _fit(self, maximum_range: float):
+        """Fits slope n of the retardation coefficient to the drag model between the muzzle
+            and maximum_range (feet)"""
+        for _ in range(cFitIterations):
+            velocity = self._velocity(maximum_range)
+            if velocity <= 0 or velocity >= self.muzzle_velocity:
+                return
+            n = (math.log(self.f0 / self._retardation_coefficient(velocity))
+                 / math.log(self.muzzle_velocity / velocity))
+            self.n = min(max(n, cMinimumSlope), cMaximumSlope)
+
+    def _u(self, x: float) -> float:
+        return 1 - self.n * x / self.f0
+
+    def _velocity(self, x: float) -> float:
+        u = self._u(x)
+        return self.muzzle_velocity * math.pow(u, 1 / self.n) if u > 0 else 0
+
+    def _time(self, x: float) -> float:
+        if x <= 0:
+            return .0
+        k = 1 - 1 / self.n
+        return self.f0 / (self.n * self.muzzle_velocity) * (1 - math.pow(self._u(x), k)) / k
+
+    def _distance_at_time(self, time: float) -> float:
+        """:return: Feet down range at time of flight, inverting _time()"""
+        k = 1 - 1 / self.n
+        u = math.pow(1 - time * k * self.n * self.muzzle_velocity / self.f0, 1 / k)
+        return self.f0 * (1 - u) / self.n
+
+    def _slope_integral(self, x: float) -> float:
+        """:return: Integral of 1/V^2 from 0 to x"""
+        k = 1 - 2 / self.n
+        return (self.f0 / (self.n * self.muzzle_velocity ** 2)) * (1 - math.pow(self._u(x), k)) / k
+
+    def _drop_integral(self, x: float) -> float:
+        """:return: Double integral of 1/V^2 from 0 to x"""
+        k = 1 - 2 / self.n
+        return (self.f0 / (self.n * self.muzzle_velocity ** 2 * k)
+                * (x - (self.f0 / self.n) * (1 - math.pow(self._u(x), k + 1)) / (k + 1)))
+
+    def _lag(self, a: float, x: float) -> float:
+        """:return: Lag time at x of a wind starting at a"""
+        if x <= a:
+            return 0
+        return self._time(x) - self._time(a) - (x - a) / self._velocity(a)
+
+    def _wind_drift(self, shot_info: Shot, x: float) -> float:
+        """:return: Crosswind drift at x (feet) by the lag rule"""
+        drift = 0
+        start = .0
+        for wind in shot_info.winds:
+            if start >= x:
+                break
+            end = wind.until_distance >> Distance.Foot
+            cross = wind_to_vector(wind).z
+            if cross:
+                drift += cross * (self._lag(start, x) - self._lag(end, x))
+            start = end
+        return drift
+
+    def zero_angle(self, shot_info: Shot, distance: Distance, initial_elevation: Angular = None) -> Angular:
+        """Closed-form barrel elevation needed for a particular zero
+        :param shot_info: Shot parameters
+        :param distance: Zero distance
+        :param initial_elevation: Unused; accepted for compatibility with TrajectoryCalc
+        :return: Barrel elevation to hit height zero at zero distance
+        """
+        self._init_trajectory(shot_info)
+        zero_distance = math.cos(self.look_angle) * (distance >> Distance.Foot)
+        height_at_zero = math.sin(self.look_angle) * (distance >> Distance.Foot)
+        self._fit(zero_distance)
+        if self._velocity(zero_distance) < self.min_velocity:
+            raise Exception(f'Velocity at zero distance {distance} is below minimum velocity.')
+        return Angular.Radian(math.atan(
+            (height_at_zero + self.cant_cosine * self.sight_height
+             + self.gravity * self._drop_integral(zero_distance)) / zero_distance
+        ))
+
+    def trajectory(self, shot_info: Shot, max_range: Distance, dist_step: Distance,
+                   extra_data: bool = False, out: list[TrajectoryData] = None) -> list[TrajectoryData]:
+        """Calculate trajectory for specified shot
+        :param extra_data: True => record rows every max_calc_step_size and flag zero and Mach crossings
+        :param out: Optional list (or TrajectoryColumns) to which rows are appended instead of a new list.
+        :return: list of TrajectoryData (the `out` list if it was provided)
+        """
+        filter_flags = TrajFlag.RANGE
+        step = dist_step >> Distance.Foot
+        self._init_trajectory(shot_info)
+        if extra_data:
+            step = self.calc_step * 2
+            filter_flags = TrajFlag.ALL
+        distances = [i * step for i in range(int((max_range >> Distance.Foot) / step) + 1)]
+        return self._rows(shot_info, distances, filter_flags, out)
+
+    def trajectory_at_ranges(self, shot_info: Shot, distances: list[Distance],
+                             out: list[TrajectoryData] = None) -> list[TrajectoryData]:
+        """Calculate trajectory with rows at exactly the requested downrange distances
+        :param distances: Downrange distances at which to record TrajectoryData, in any order
+        :param out: Optional list (or TrajectoryColumns) to which rows are appended instead of a new list.
+        :return: list of TrajectoryData sorted by distance (the `out` list if it was provided)
+        """
+        feet = sorted(d >> Distance.Foot for d in distances)
+        if not feet:
+            raise ValueError("At least one distance is required")
+        if feet[0] < 0:
+            raise ValueError("Distances have to be >= 0")
+        self._init_trajectory(shot_info)
+        return self._rows(shot_info, feet, TrajFlag.RANGE, out)
+
+    def trajectory_terminal(self, shot_info: Shot, distance: Distance, zero_crossings: bool = False,
+                            out: list[TrajectoryData] = None) -> list[TrajectoryData]:
+        """Calculate only the trajectory row at one distance
+        :param zero_crossings: Ignored: closed-form rows are only at requested distances,
+            so zero crossings are not located
+        :param out: Optional list (or TrajectoryColumns) to which rows are appended instead of a new list.
+        :return: list with the TrajectoryData at distance (the `out` list if it was provided)
+        """
+        feet = distance >> Distance.Foot
+        if feet < 0:
+            raise ValueError("Distance has to be >= 0")
+        self._init_trajectory(shot_info)
+        return self._rows(shot_info, [feet], TrajFlag.RANGE, out)
+
+    def trajectory_at_times(self, shot_info: Shot, times: list[float],
+                            out: list[TrajectoryData] = None) -> list[TrajectoryData]:
+        """Calculate trajectory with rows at exactly the requested times of flight
+        :param times: Seconds of flight at which to record TrajectoryData, in any order
+        :param out: Optional list (or TrajectoryColumns) to which rows are appended instead of a new list.
+        :return: list of TrajectoryData sorted by time (the `out` list if it was provided)
+        """
+        seconds = sorted(times)
+        if not seconds:
+            raise ValueError("At least one time is required")
+        if seconds[0] < 0:
+            raise ValueError("Times have to be >= 0")
+        self._init_trajectory(shot_info)
+        # The fit depends on the range reached at the last time, which depends on the fit
+        for _ in range(cFitIterations):
+            self._fit(self._distance_at_time(seconds[-1]))
+        return self._rows(shot_info, [self._distance_at_time(t) for t in seconds], TrajFlag.RANGE, out)
+
+    def _rows(self, shot_info: Shot, distances: list[float], filter_flags: TrajFlag,
+              ranges: list[TrajectoryData] = None) -> list[TrajectoryData]:
+        """:return: TrajectoryData at each of distances (sorted feet down range) until a stop condition"""
+        if ranges is None:
+            ranges = []
+        self._fit(distances[-1])
+        hooks = self.hooks
+        termination_reason = 'maximum_range'
+        adjustment_reference = self._get_adjustment_reference()
+        tan_elevation = math.tan(self.barrel_elevation)
+        tan_azimuth = math.tan(self.barrel_azimuth)
+        y0 = -self.cant_cosine * self.sight_height
+        z0 = -self.cant_sine * self.sight_height
+
+        seen_zero = TrajFlag.NONE
+        if y0 >= 0:
+            seen_zero |= TrajFlag.ZERO_UP
+        elif self.barrel_elevation < self.look_angle:
+            seen_zero |= TrajFlag.ZERO_DOWN
+        previous_mach = .0
+        previous_slope = .0
+        state = None
+
+        for x in distances:
+            velocity = self._velocity(x)
+            if velocity < self.min_velocity:
+                termination_reason = 'minimum_velocity'
+                break
+            if velocity < self.min_energy_velocity:
+                termination_reason = 'minimum_energy'
+                break
+            time = self._time(x)
+            if time > self.max_time:
+                termination_reason = 'maximum_time'
+                break
+            y = y0 + x * tan_elevation - self.gravity * self._drop_integral(x)
+            if y < self.max_drop:
+                termination_reason = 'maximum_drop'
+                break
+            if self.alt0 + y < self.min_altitude:
+                termination_reason = 'minimum_altitude'
+                break
+            slope = tan_elevation - self.gravity * self._slope_integral(x)
+            range_vector = Vector(x, y, z0 + x * tan_azimuth + self._wind_drift(shot_info, x))
+            velocity_vector = Vector(1, slope, tan_azimuth) * (velocity / math.sqrt(1 + slope * slope))
+            drag = self.density_factor * velocity * self.drag_by_mach(velocity / self.mach)
+            state = (time, range_vector, velocity_vector, velocity, drag)
+
+            _flag = TrajFlag.RANGE
+            if x > 0:
+                reference_height = x * math.tan(self.look_angle)
+                if not seen_zero & TrajFlag.ZERO_UP:
+                    if y >= reference_height:
+                        _flag |= TrajFlag.ZERO_UP
+                        seen_zero |= TrajFlag.ZERO_UP
+                elif not seen_zero & TrajFlag.ZERO_DOWN:
+                    if y < reference_height:
+                        _flag |= TrajFlag.ZERO_DOWN
+                        seen_zero |= TrajFlag.ZERO_DOWN
+            if velocity / self.mach <= 1 < previous_mach:
+                _flag |= TrajFlag.MACH
+            previous_mach = velocity / self.mach
+            if slope <= 0 < previous_slope:
+                _flag |= TrajFlag.APEX
+            previous_slope = slope
+
+            spin_rate = self.spin_rate0 * math.exp(-self.spin_decay * self.density_factor * x)
+            ranges.append(create_trajectory_row(
+                time, range_vector, velocity_vector, velocity, self.mach, self.spin_drift(time),
+                self.look_angle, self.density_factor, drag, self.weight, (_flag & filter_flags).value,
+                adjustment_reference, spin_rate, self.stability_at(velocity, self.density_factor, spin_rate)
+            ))
+            if hooks is not None and hooks.on_record is not None:
+                hooks.on_record(ranges[-1])
+
+        if hooks is not None and hooks.on_termination is not None and state is not None:
+            time, range_vector, velocity_vector, velocity, drag = state
+            hooks.on_termination(StepState(time, range_vector, velocity_vector, velocity,
+                                           self.mach, self.density_factor, drag),
+                                 termination_reason)
+        self.termination_reason = termination_reason
+        return ranges
@@ -1,5 +1,4 @@
 import logging
-import re
 from math import isinf
 from typing import Any
 import os
@@ -9,10 +8,10 @@ try:
 except ImportError:
     import tomli as tomllib
 
-import py_ballisticcalc
 from py_ballisticcalc import (
     basicConfig, Unit, Weapon, logger, Atmo, AbstractUnitType, Ammo, DragModel,
-    get_drag_tables_names, BCPoint, DragModelMultiBC, Wind, DragDataPoint, Distance
+    get_drag_table, registered_drag_tables, BCPoint, DragModelMultiBC, Wind, DragDataPoint, Distance,
+    BoundaryLayer, ProjectileGeometry, estimate_drag_model
 )
 
 __all__ = ('ProfileLoadingError', 'load_multiple_toml', 'load_profile')
@@ -162,26 +161,21 @@ def parse_drag(drag: dict) -> DragModel:
     _model = get_prop(drag, 'model', section="drag")
     _bc = get_prop(drag, 'bc', section="drag")
     _custom_table = get_prop(drag, 'custom_table', section="drag")
+    _geometry = drag.get('geometry')
 
-    if any((_model, _bc)) and _custom_table:
+    if sum(map(bool, (any((_model, _bc)), _custom_table, _geometry))) > 1:
         raise ValueError(
-            "You cannot specify all at same time: bc, model and custom_table "
-            "Please use (model + bc) or custom_table instead"
+            "You cannot specify all at same time: bc, model, custom_table and geometry "
+            "Please use (model + bc), custom_table or geometry instead"
         )
 
     if all((_model, _bc)):
 
-        model_match = ''
-        for pattern in (r"^table(g\w)$", r"^(g\w)$"):
-            if _model_match := re.match(pattern, _model, re.IGNORECASE):
-                model_match = _model_match.group(1).upper()
-                break
-
-        if not hasattr(py_ballisticcalc, f"Table{model_match}"):
+        try:
+            drag_kwargs['drag_table'] = get_drag_table(_model)
+        except KeyError:
             raise ValueError(f"Unrecognized model: {_model}, "
-                             f"use one of the following: {get_drag_tables_names()}")
-
-        drag_kwargs['drag_table'] = getattr(py_ballisticcalc, f"Table{model_match}")
+                             f"use one of the following: {registered_drag_tables()}")
         bc = parse_bc(_bc)
 
         if isinstance(bc, float):
@@ -199,10 +193,30 @@ def parse_drag(drag: dict) -> DragModel:
         else:
             raise ValueError("Wrong custom drag table")
 
+    elif _geometry:
+        return estimate_drag_model(parse_geometry(_geometry, drag_kwargs), drag_kwargs['weight'])
+
     else:
         raise TypeError("Unrecognized drag data provided")
 
 
+def parse_geometry(geometry: dict, drag_kwargs: dict) -> ProjectileGeometry:
+    """Projectile profile for estimate_drag_model(), with diameter and length of the bullet"""
+    check_expected_props(geometry, ('nose_length',), 'drag.geometry', required=True)
+    geometry_kwargs = {'diameter': drag_kwargs['diameter'], 'length': drag_kwargs['length']}
+    for key in ('nose_length', 'boattail_length'):
+        if key in geometry:
+            geometry_kwargs[key] = load_dimension(geometry[key], 'length', f'drag.geometry.{key}')
+    for key in ('meplat_diameter', 'base_diameter', 'band_diameter'):
+        if key in geometry:
+            geometry_kwargs[key] = load_dimension(geometry[key], 'diameter', f'drag.geometry.{key}')
+    if 'ogive_ratio' in geometry:
+        geometry_kwargs['ogive_ratio'] = float(geometry['ogive_ratio'])
+    if 'boundary_layer' in geometry:
+        geometry_kwargs['boundary_layer'] = BoundaryLayer(geometry['boundary_layer'])
+    return ProjectileGeometry(**geometry_kwargs)
+
+
 def parse_ammo(ammo: dict) -> Ammo:
     required = ('muzzle_velocity', 'drag', 'powder_temp', 'powder_temp_modifier')
 
@@ -225,6 +239,10 @@ def parse_ammo(ammo: dict) -> Ammo:
             logger.warning(f"Powder temp modifier load "
                            f"warning for value={_powder_temp_modifier}: {err}")
 
+    for _key in ('cold_bore_offset', 'shot_mv_drift'):  # Optional shot string properties
+        if (_mv_offset := ammo.get(_key)) is not None:
+            ammo_kwargs[_key] = load_dimension(_mv_offset, 'velocity', f'ammo.{_key}')
+
     if _drag := get_prop(ammo, 'drag', section="ammo", required=True):
         ammo_kwargs['dm'] = parse_drag(_drag)
         logger.debug(f"Loaded: dm={ammo_kwargs['dm']}")
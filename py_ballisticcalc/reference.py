@@ -0,0 +1,198 @@
+"""Comparison of calculated trajectories with external reference data (published tables, radar measurements)"""
+import csv
+import math
+import re
+from typing import Iterable, NamedTuple, Optional
+
+from .conditions import Shot
+from .interface import Calculator
+from .unit import Distance, Velocity, Unit, PreferredUnits, AbstractUnit
+
+__all__ = ('ReferenceRow', 'ReferenceTrajectory', 'Residual', 'ResidualStats', 'ReferenceComparison')
+
+# Column name => (TrajectoryData attribute, PreferredUnits attribute of default units)
+REFERENCE_COLUMNS = {
+    'distance': ('distance', 'distance'),
+    'drop': ('target_drop', 'drop'),
+    'windage': ('windage', 'drop'),
+    'velocity': ('velocity', 'velocity'),
+    'time': ('time', None),
+}
+REFERENCE_ALIASES = {'range': 'distance'}
+
+
+class ReferenceRow(NamedTuple):
+    """
+    One row of a reference trajectory.  Quantities the reference doesn't provide are None.
+
+    Attributes:
+        distance (Distance): downrange distance
+        drop (Distance): height relative to the sight line, negative below it (as TrajectoryData.target_drop)
+        windage (Distance): windage, positive to the right
+        velocity (Velocity): velocity
+        time (float): time of flight in seconds
+    """
+    distance: Distance
+    drop: Optional[Distance] = None
+    windage: Optional[Distance] = None
+    velocity: Optional[Velocity] = None
+    time: Optional[float] = None
+
+
+class Residual(NamedTuple):
+    """
+    Calculated minus reference value of each quantity at one distance (None where the reference has no value)
+
+    Attributes:
+        distance (Distance): downrange distance
+        drop (Distance):
+        windage (Distance):
+        velocity (Velocity):
+        time (float): seconds
+    """
+    distance: Distance
+    drop: Optional[Distance]
+    windage: Optional[Distance]
+    velocity: Optional[Velocity]
+    time: Optional[float]
+
+
+class ResidualStats(NamedTuple):
+    """
+    Statistics of the residuals of one quantity, in the units of that quantity
+        (PreferredUnits.drop for drop and windage, PreferredUnits.velocity for velocity, seconds for time)
+
+    Attributes:
+        count (int): number of rows with a reference value
+        mean (float): mean residual (bias)
+        rms (float): root mean square residual
+        max_abs (float): largest absolute residual
+    """
+    count: int
+    mean: float
+    rms: float
+    max_abs: float
+
+
+class ReferenceComparison(NamedTuple):
+    """
+    Residuals of a calculated trajectory against a ReferenceTrajectory
+
+    Attributes:
+        reference (ReferenceTrajectory): compared reference
+        residuals (list[Residual]): residuals at each reference distance
+    """
+    reference: 'ReferenceTrajectory'
+    residuals: list[Residual]
+
+    def stats(self) -> dict[str, ResidualStats]:
+        """:return: ResidualStats of each quantity provided by the reference ('drop', 'windage', 'velocity', 'time')"""
+        result = {}
+        for name, (_, preferred) in REFERENCE_COLUMNS.items():
+            if name == 'distance':
+                continue
+            values = [getattr(r, name) for r in self.residuals if getattr(r, name) is not None]
+            if not values:
+                continue
+            if preferred is not None:
+                values = [v >> getattr(PreferredUnits, preferred) for v in values]
+            result[name] = ResidualStats(
+                count=len(values),
+                mean=sum(values) / len(values),
+                rms=math.sqrt(sum(v * v for v in values) / len(values)),
+                max_abs=max(math.fabs(v) for v in values)
+            )
+        return result
+
+
+class ReferenceTrajectory:
+    """
+    External trajectory for the same shot, e.g. a published table or radar truth data.
+
+    Data format (see from_csv()): a table with a header row naming columns distance (or range), drop,
+        windage, velocity and time, in any order; only distance is required.
+        Units may follow a column name in square brackets, e.g. "range[yd],drop[in],velocity[fps],time[s]",
+        or follow each value, e.g. "100yd"; otherwise PreferredUnits are assumed.
+        Empty cells mean the reference has no value for that quantity at that distance.
+    """
+
+    def __init__(self, rows: Iterable[ReferenceRow], name: str = ''):
+        self.rows = sorted(rows, key=lambda r: r.distance.raw_value)
+        self.name = name
+        if not self.rows:
+            raise ValueError("Reference trajectory has no rows")
+
+    def __repr__(self) -> str:
+        return f"ReferenceTrajectory(name={self.name!r}, rows={len(self.rows)})"
+
+    @classmethod
+    def from_dicts(cls, rows: Iterable[dict], name: str = '') -> 'ReferenceTrajectory':
+        """Creates reference from mappings of column names (see class docstring) to values"""
+        result = []
+        for data in rows:
+            values = {}
+            for key, value in data.items():
+                if key is None or value is None or (isinstance(value, str) and not value.strip()):
+                    continue
+                column, units = _parse_column(key)
+                preferred = REFERENCE_COLUMNS[column][1]
+                if preferred is None:
+                    values[column] = float(value)
+                elif isinstance(value, AbstractUnit):
+                    values[column] = value
+                else:
+                    values[column] = Unit.parse_value(value, units or getattr(PreferredUnits, preferred))
+            if 'distance' not in values:
+                raise ValueError(f"Reference row without distance: {data}")
+            result.append(ReferenceRow(**values))
+        return cls(result, name)
+
+    @classmethod
+    def from_csv(cls, path: str, name: str = None) -> 'ReferenceTrajectory':
+        """Loads reference from a CSV file with a header row (see class docstring)"""
+        with open(path, 'r', encoding='utf-8', newline='') as fp:
+            return cls.from_dicts(csv.DictReader(fp), path if name is None else name)
+
+    def compare(self, calc: Calculator, shot: Shot) -> ReferenceComparison:
+        """Calculates shot at the reference distances
+        :param calc: Calculator to check
+        :param shot: shot matching the conditions of the reference
+        :return: ReferenceComparison with calculated minus reference values
+        """
+        data = calc.fire_at_ranges(shot, [r.distance for r in self.rows]).trajectory
+        if len(data) < len(self.rows):
+            raise ArithmeticError(
+                f"Calculated trajectory doesn't reach reference distance {self.rows[len(data)].distance}")
+        residuals = []
+        for row, calculated in zip(self.rows, data):
+            values = {}
+            for column, (attribute, preferred) in REFERENCE_COLUMNS.items():
+                if column == 'distance' or (expected := getattr(row, column)) is None:
+                    values[column] = None
+                elif preferred is None:
+                    values[column] = getattr(calculated, attribute) - expected
+                else:
+                    values[column] = _difference(getattr(calculated, attribute), expected)
+            values['distance'] = row.distance
+            residuals.append(Residual(**values))
+        return ReferenceComparison(self, residuals)
+
+
+def _difference(a: AbstractUnit, b: AbstractUnit) -> AbstractUnit:
+    """:return: a - b in the units of b"""
+    return b.units((a >> b.units) - b.unit_value)
+
+
+def _parse_column(key: str) -> tuple[str, Optional[Unit]]:
+    """:return: (column name, units) from a header like "Range [yd]" """
+    if not (match := re.match(r'^\s*([A-Za-z_]+)\s*(?:\[(.*)])?\s*$', key)):
+        raise KeyError(f"Unknown reference column {key!r}")
+    column = match.group(1).lower()
+    column = REFERENCE_ALIASES.get(column, column)
+    if column not in REFERENCE_COLUMNS:
+        raise KeyError(f"Unknown reference column {key!r}")
+    units = None
+    if match.group(2) and REFERENCE_COLUMNS[column][1] is not None:
+        if (units := Unit.parse_unit(match.group(2))) is None:
+            raise KeyError(f"Unknown units in reference column {key!r}")
+    return column, units
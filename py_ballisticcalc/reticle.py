@@ -0,0 +1,113 @@
+"""Holdover chart of a ballistic reticle: the distance each reticle mark holds for"""
+from dataclasses import dataclass, field
+from typing import NamedTuple, Optional
+
+from .conditions import Shot
+from .interface import Calculator
+from .localization import Localization, Locales
+from .munition import Sight
+from .trajectory_data import TrajectoryData
+from .unit import Angular, Distance, Unit, PreferredUnits
+
+__all__ = ('Reticle', 'HoldoverMark', 'reticle_holdovers', 'holdover_card')
+
+
+@dataclass
+class Reticle:
+    """
+    Holdover marks of a ballistic reticle
+
+    :param marks: Subtensions of the marks below the center of the reticle, as float in PreferredUnits.adjustment
+        or Angular (e.g. the 1..10 mil hash marks of a mil reticle)
+    :param focal_plane: Sight.FocalPlane.FFP or SFP
+    :param magnification: Magnification at which an SFP reticle has the subtensions of marks
+    :param name: Name of the reticle
+    """
+    marks: list[[float, Angular]]
+    focal_plane: Sight.FocalPlane = field(default=Sight.FocalPlane.FFP)
+    magnification: float = field(default=None)
+    name: str = field(default='')
+
+    def __post_init__(self):
+        self.marks = sorted((PreferredUnits.adjustment(mark) for mark in self.marks), key=lambda m: m.raw_value)
+        if not self.marks:
+            raise ValueError("Reticle has no marks")
+        if self.focal_plane == Sight.FocalPlane.SFP:
+            if not self.magnification or self.magnification <= 0:
+                raise ValueError("Magnification of the subtensions required for SFP reticles")
+        elif self.focal_plane != Sight.FocalPlane.FFP:
+            raise ValueError("Reticle focal plane must be FFP or SFP")
+
+    def subtension(self, mark: Angular, magnification: float = None) -> Angular:
+        """:return: angle a mark subtends at magnification
+        :param mark: subtension of the mark
+        :param magnification: magnification of the scope, required for SFP reticles
+        """
+        if self.focal_plane == Sight.FocalPlane.FFP:
+            return mark
+        if not magnification or magnification <= 0:
+            raise ValueError("Magnification required for SFP reticles")
+        # Reticle of the second focal plane keeps its size in the eyepiece as the target is magnified
+        return mark.units(mark.unit_value * self.magnification / magnification)
+
+
+class HoldoverMark(NamedTuple):
+    """
+    Attributes:
+        mark (Angular): subtension of the reticle mark
+        hold (Angular): angle the mark holds at the magnification
+        distance (Distance): sight-line distance the mark holds for, None if beyond the trajectory
+        point (TrajectoryData): trajectory at the distance, None if beyond the trajectory
+    """
+    mark: Angular
+    hold: Angular
+    distance: Optional[Distance]
+    point: Optional[TrajectoryData]
+
+
+def reticle_holdovers(calc: Calculator, shot: Shot, reticle: Reticle, max_range: [float, Distance],
+                      magnification: float = None) -> list[HoldoverMark]:
+    """Maps each mark of reticle to the distance beyond the zero where the bullet falls by its hold
+    :param calc: Calculator to solve the trajectory
+    :param shot: zeroed shot
+    :param reticle: Reticle of the sight
+    :param max_range: downrange distance to compute the trajectory to
+    :param magnification: magnification of the scope, required for SFP reticles
+    """
+    result = calc.fire(shot, max_range, extra_data=True)
+    rows = result.trajectory[1:]
+    # Holds grow monotonically beyond the highest point of the trajectory above the sight line
+    start = max(range(len(rows)), key=lambda i: rows[i].drop_adj >> Angular.Radian, default=0)
+    holds = [-(row.drop_adj >> Angular.Radian) for row in rows]
+    marks = []
+    for mark in reticle.marks:
+        hold = reticle.subtension(mark, magnification)
+        angle = hold >> Angular.Radian
+        point = None
+        for i in range(start, len(rows) - 1):
+            if holds[i] <= angle <= holds[i + 1]:
+                f = (angle - holds[i]) / (holds[i + 1] - holds[i]) if holds[i + 1] > holds[i] else 0
+                x0 = rows[i].distance >> Distance.Foot
+                point = result.at(Distance.Foot(x0 + f * ((rows[i + 1].distance >> Distance.Foot) - x0)))
+                break
+        marks.append(HoldoverMark(mark, hold << PreferredUnits.adjustment,
+                                  None if point is None else point.look_distance << PreferredUnits.distance,
+                                  point))
+    return marks
+
+
+def holdover_card(calc: Calculator, shot: Shot, reticle: Reticle, max_range: [float, Distance],
+                  magnification: float = None, localization: Localization = None,
+                  units: Unit = None) -> list[tuple]:
+    """Holdover chart as rows of strings
+    :param units: Units of the marks, default PreferredUnits.adjustment
+    :return: header row ('mark', 'range', translated by Localization.label), then a row of each mark
+        with its distance, or '-' for marks beyond the trajectory
+    """
+    loc = Locales.English if localization is None else localization
+    units = units or PreferredUnits.adjustment
+    rows = [(loc.label('mark'), loc.label('range'))]
+    for mark in reticle_holdovers(calc, shot, reticle, max_range, magnification):
+        rows.append((loc.format(mark.mark, units),
+                     '-' if mark.distance is None else loc.format(mark.distance, PreferredUnits.distance)))
+    return rows
@@ -0,0 +1,159 @@
+# pylint: disable=invalid-name
+"""Quaternion and rotation matrix helpers for trajectory geometry
+
+Axes follow the trajectory calculation: x = downrange, y = up, z = right (windage).
+Euler angles (all in radians) are applied in the order used for a rifle:
+    cant (roll about x), then elevation (pitch about z), then azimuth (yaw about y),
+with positive azimuth turning the bore towards +z, so that rotating the unit x vector gives
+    (cos(elevation) * cos(azimuth), sin(elevation), cos(elevation) * sin(azimuth))
+"""
+
+import math
+from typing import NamedTuple
+
+from .vector import Vector
+
+__all__ = ('Quaternion', 'Matrix3',
+           'rotation_matrix_x', 'rotation_matrix_y', 'rotation_matrix_z',
+           'matrix_multiply', 'matrix_vector', 'matrix_transpose')
+
+Matrix3 = tuple[tuple[float, float, float], tuple[float, float, float], tuple[float, float, float]]
+
+
+def rotation_matrix_x(angle: float) -> Matrix3:
+    """:return: matrix rotating by angle (radians) about the x axis"""
+    c, s = math.cos(angle), math.sin(angle)
+    return ((1.0, 0.0, 0.0),
+            (0.0, c, -s),
+            (0.0, s, c))
+
+
+def rotation_matrix_y(angle: float) -> Matrix3:
+    """:return: matrix rotating by angle (radians) about the y axis"""
+    c, s = math.cos(angle), math.sin(angle)
+    return ((c, 0.0, s),
+            (0.0, 1.0, 0.0),
+            (-s, 0.0, c))
+
+
+def rotation_matrix_z(angle: float) -> Matrix3:
+    """:return: matrix rotating by angle (radians) about the z axis"""
+    c, s = math.cos(angle), math.sin(angle)
+    return ((c, -s, 0.0),
+            (s, c, 0.0),
+            (0.0, 0.0, 1.0))
+
+
+def matrix_multiply(a: Matrix3, b: Matrix3) -> Matrix3:
+    """:return: matrix product a·b (apply b first, then a)"""
+    return tuple(tuple(sum(a[i][k] * b[k][j] for k in range(3)) for j in range(3)) for i in range(3))
+
+
+def matrix_vector(m: Matrix3, v: Vector) -> Vector:
+    """:return: vector v rotated by matrix m"""
+    return Vector(m[0][0] * v.x + m[0][1] * v.y + m[0][2] * v.z,
+                  m[1][0] * v.x + m[1][1] * v.y + m[1][2] * v.z,
+                  m[2][0] * v.x + m[2][1] * v.y + m[2][2] * v.z)
+
+
+def matrix_transpose(m: Matrix3) -> Matrix3:
+    """:return: transpose of m, which is the inverse of a rotation matrix"""
+    return tuple(tuple(m[j][i] for j in range(3)) for i in range(3))
+
+
+class Quaternion(NamedTuple):
+    """Rotation quaternion w + xi + yj + zk"""
+    w: float
+    x: float
+    y: float
+    z: float
+
+    @staticmethod
+    def identity() -> 'Quaternion':
+        return Quaternion(1.0, 0.0, 0.0, 0.0)
+
+    @staticmethod
+    def from_axis_angle(axis: Vector, angle: float) -> 'Quaternion':
+        """:return: rotation by angle (radians) about axis, right-hand rule"""
+        m = axis.magnitude()
+        if m < 1e-10:
+            return Quaternion.identity()
+        s = math.sin(angle / 2) / m
+        return Quaternion(math.cos(angle / 2), axis.x * s, axis.y * s, axis.z * s)
+
+    @staticmethod
+    def from_euler(azimuth: float, elevation: float, cant: float) -> 'Quaternion':
+        """:return: rotation for barrel azimuth, elevation and cant (radians); see module docstring"""
+        return (Quaternion.from_axis_angle(Vector(0, 1, 0), -azimuth)
+                * Quaternion.from_axis_angle(Vector(0, 0, 1), elevation)
+                * Quaternion.from_axis_angle(Vector(1, 0, 0), cant))
+
+    @staticmethod
+    def from_matrix(m: Matrix3) -> 'Quaternion':
+        """:return: unit quaternion for rotation matrix m"""
+        trace = m[0][0] + m[1][1] + m[2][2]
+        if trace > 0:
+            s = 2 * math.sqrt(trace + 1.0)
+            q = Quaternion(0.25 * s, (m[2][1] - m[1][2]) / s,
+                           (m[0][2] - m[2][0]) / s, (m[1][0] - m[0][1]) / s)
+        elif m[0][0] > m[1][1] and m[0][0] > m[2][2]:
+            s = 2 * math.sqrt(1.0 + m[0][0] - m[1][1] - m[2][2])
+            q = Quaternion((m[2][1] - m[1][2]) / s, 0.25 * s,
+                           (m[0][1] + m[1][0]) / s, (m[0][2] + m[2][0]) / s)
+        elif m[1][1] > m[2][2]:
+            s = 2 * math.sqrt(1.0 + m[1][1] - m[0][0] - m[2][2])
+            q = Quaternion((m[0][2] - m[2][0]) / s, (m[0][1] + m[1][0]) / s,
+                           0.25 * s, (m[1][2] + m[2][1]) / s)
+        else:
+            s = 2 * math.sqrt(1.0 + m[2][2] - m[0][0] - m[1][1])
+            q = Quaternion((m[1][0] - m[0][1]) / s, (m[0][2] + m[2][0]) / s,
+                           (m[1][2] + m[2][1]) / s, 0.25 * s)
+        return q.normalize()
+
+    def norm(self) -> float:
+        return math.sqrt(self.w * self.w + self.x * self.x + self.y * self.y + self.z * self.z)
+
+    def normalize(self) -> 'Quaternion':
+        n = self.norm()
+        if n < 1e-10:
+            return Quaternion.identity()
+        return Quaternion(self.w / n, self.x / n, self.y / n, self.z / n)
+
+    def conjugate(self) -> 'Quaternion':
+        return Quaternion(self.w, -self.x, -self.y, -self.z)
+
+    def inverse(self) -> 'Quaternion':
+        n2 = self.w * self.w + self.x * self.x + self.y * self.y + self.z * self.z
+        return Quaternion(self.w / n2, -self.x / n2, -self.y / n2, -self.z / n2)
+
+    def __mul__(self, other: 'Quaternion') -> 'Quaternion':
+        """Hamilton product: (a * b).rotate(v) == a.rotate(b.rotate(v))"""
+        if not isinstance(other, Quaternion):
+            return NotImplemented
+        return Quaternion(
+            self.w * other.w - self.x * other.x - self.y * other.y - self.z * other.z,
+            self.w * other.x + self.x * other.w + self.y * other.z - self.z * other.y,
+            self.w * other.y - self.x * other.z + self.y * other.w + self.z * other.x,
+            self.w * other.z + self.x * other.y - self.y * other.x + self.z * other.w
+        )
+
+    def rotate(self, v: Vector) -> Vector:
+        """:return: vector v rotated by this (unit) quaternion"""
+        u = Vector(self.x, self.y, self.z)
+        t = u.cross(v) * 2.0
+        return v + t * self.w + u.cross(t)
+
+    def to_matrix(self) -> Matrix3:
+        """:return: rotation matrix equivalent to this (unit) quaternion"""
+        w, x, y, z = self
+        return ((1 - 2 * (y * y + z * z), 2 * (x * y - z * w), 2 * (x * z + y * w)),
+                (2 * (x * y + z * w), 1 - 2 * (x * x + z * z), 2 * (y * z - x * w)),
+                (2 * (x * z - y * w), 2 * (y * z + x * w), 1 - 2 * (x * x + y * y)))
+
+    def to_euler(self) -> tuple[float, float, float]:
+        """:return: (azimuth, elevation, cant) in radians; inverse of from_euler()"""
+        m = self.to_matrix()
+        elevation = math.asin(max(-1.0, min(1.0, m[1][0])))
+        azimuth = math.atan2(m[2][0], m[0][0])
+        cant = math.atan2(-m[1][2], m[1][1])
+        return azimuth, elevation, cant
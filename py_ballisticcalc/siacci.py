@@ -0,0 +1,162 @@
+"""Siacci method: classic table-based solution of flat-fire trajectories"""
+import math
+from bisect import bisect_right
+
+from .conditions import Shot
+from .pejsa import PejsaCalc
+from .unit import Angular, Distance
+
+__all__ = ('SiacciCalc',)
+
+cTableVelocityStep = 1.0  # fps between entries of the S, T, A, I tables
+cTableMinimumVelocity = 10.0  # fps
+cTableMaximumVelocity = 5000.0  # fps; tables are extended to cover faster muzzle velocities
+cZeroIterations = 10
+
+
+class SiacciTable:
+    """
+    Siacci's space S(u), time T(u), altitude A(u) and inclination I(u) functions of pseudo-velocity u
+        for the standard projectile (BC = 1) of a drag table at a given speed of sound.
+        G(u) = u^2 * Cd(u / mach) * 2.08551e-04 is the standard retardation in fps^2, and the functions
+        are integrated from the top of the table down to u:
+            S = int(u / G du), T = int(1 / G du), I = int(2 / (u * G) du), A = int(I dS)
+    Entries are ordered by decreasing pseudo-velocity, so S, T, A and I increase with index.
+    """
+
+    def __init__(self, standard_retardation, mach: float, top_velocity: float):
+        """
+        :param standard_retardation: Function of Mach number returning G(u) / u^2 for BC = 1
+        :param mach: Speed of sound in fps
+        :param top_velocity: Highest pseudo-velocity in the table, fps
+        """
+        count = int(math.ceil((top_velocity - cTableMinimumVelocity) / cTableVelocityStep)) + 1
+        self.top_velocity = cTableMinimumVelocity + (count - 1) * cTableVelocityStep
+        self.u = [self.top_velocity - i * cTableVelocityStep for i in range(count)]
+        g = [u * u * standard_retardation(u / mach) for u in self.u]
+        self.s, self.t, self.a, self.i = [.0], [.0], [.0], [.0]
+        for k in range(1, count):
+            du = self.u[k - 1] - self.u[k]
+            self.s.append(self.s[-1] + du * (self.u[k - 1] / g[k - 1] + self.u[k] / g[k]) / 2)
+            self.t.append(self.t[-1] + du * (1 / g[k - 1] + 1 / g[k]) / 2)
+            self.i.append(self.i[-1] + du * (1 / (self.u[k - 1] * g[k - 1]) + 1 / (self.u[k] * g[k])))
+            self.a.append(self.a[-1] + (self.s[k] - self.s[k - 1]) * (self.i[k - 1] + self.i[k]) / 2)
+
+    def _interpolate(self, k: int, f: float) -> tuple[float, float, float, float, float]:
+        if k >= len(self.u) - 1:
+            k, f = len(self.u) - 2, 1.0
+        return tuple(column[k] + f * (column[k + 1] - column[k])
+                     for column in (self.u, self.s, self.t, self.a, self.i))
+
+    def at_velocity(self, u: float) -> tuple[float, float, float, float, float]:
+        """:return: (u, S, T, A, I) at pseudo-velocity u"""
+        position = max((self.top_velocity - u) / cTableVelocityStep, 0)
+        k = int(position)
+        return self._interpolate(k, position - k)
+
+    def at_space(self, s: float) -> tuple[float, float, float, float, float]:
+        """:return: (u, S, T, A, I) where the space function equals s"""
+        k = max(bisect_right(self.s, s) - 1, 0)
+        if k >= len(self.s) - 1:
+            return self._interpolate(k, 1.0)
+        return self._interpolate(k, (s - self.s[k]) / (self.s[k + 1] - self.s[k]))
+
+
+class SiacciCalc(PejsaCalc):
+    """
+    Siacci's method: the horizontal component of velocity divided by the cosine of the departure angle
+        (the pseudo-velocity u) is assumed to be retarded as if the projectile flew along the line of departure,
+        so range, time, drop and inclination follow from the standard S, T, A, I functions (see SiacciTable):
+            x = C cos(phi) (S(u) - S(U0)),  t = C (T(u) - T(U0)),
+            tan(theta) = tan(phi) - g C / (2 cos(phi)) (I(u) - I(U0)),
+            y = x tan(phi) - g (C^2 / 2 (A(u) - A(U0)) - x C I(U0) / (2 cos(phi)))
+        where pseudo-velocity u = v cos(theta) / cos(phi), U0 = muzzle velocity and C = BC / density factor.
+        Tables are calculated from the drag model at the shooter's speed of sound,
+        and air density is taken at the shooter's altitude.
+
+    Use as Calculator(engine=SiacciCalc) to check TrajectoryCalc against classic published solutions,
+        and for low velocity (e.g. blackpowder) loads.  Accuracy limits versus TrajectoryCalc:
+        * Exact in the limit of flat fire; error grows with departure angle
+          (for typical rifle bullets about 1% of drop at 15 degrees and 2% at 30 degrees)
+        * Ignores changes of air density with height
+        * Wind drift uses the lag rule, and head and tail wind are ignored
+        * TrajectoryHooks.on_step is not called, since there are no integration steps
+    """
+
+    def __init__(self, *args, **kwargs):
+        super().__init__(*args, **kwargs)
+        self._tables: dict[tuple[float, float], SiacciTable] = {}
+
+    def _init_trajectory(self, shot_info: Shot):
+        super()._init_trajectory(shot_info)
+        top = max(cTableMaximumVelocity, self.muzzle_velocity)
+        key = (self.mach, top)
+        if (table := self._tables.get(key)) is None:
+            table = self._tables[key] = SiacciTable(lambda mach: self.drag_by_mach(mach) * self._bc,
+                                                    self.mach, top)
+        self.table = table
+        self.ballistic_coefficient = self._bc / self.density_factor
+        self._departure(self.barrel_elevation)
+
+    def _departure(self, angle: float):
+        """Prepares the terms that depend on the departure angle (radians)"""
+        self.cos_departure = math.cos(angle)
+        self.tan_departure = math.tan(angle)
+        _, self.s0, self.t0, self.a0, self.i0 = self.table.at_velocity(self.muzzle_velocity)
+        self._last = (None, None)
+
+    def _fit(self, maximum_range: float):
+        """Siacci's functions are tabulated for the whole drag curve, so no fit is needed"""
+
+    def _functions(self, x: float) -> tuple[float, float, float, float, float]:
+        """:return: (u, S, T, A, I) at x feet down range"""
+        if self._last[0] != x:
+            s = self.s0 + x / (self.ballistic_coefficient * self.cos_departure)
+            self._last = (x, self.table.at_space(s))
+        return self._last[1]
+
+    def _velocity(self, x: float) -> float:
+        u = self._functions(x)[0]
+        if u <= cTableMinimumVelocity:
+            return 0
+        tan_inclination = self.tan_departure - self.gravity * self._slope_integral(x)
+        return u * self.cos_departure * math.sqrt(1 + tan_inclination * tan_inclination)
+
+    def _time(self, x: float) -> float:
+        return self.ballistic_coefficient * (self._functions(x)[2] - self.t0)
+
+    def _slope_integral(self, x: float) -> float:
+        """:return: Drop of tan(inclination) divided by gravity"""
+        return self.ballistic_coefficient / (2 * self.cos_departure) * (self._functions(x)[4] - self.i0)
+
+    def _drop_integral(self, x: float) -> float:
+        """:return: Drop below the line of departure divided by gravity"""
+        c = self.ballistic_coefficient
+        return c * c / 2 * (self._functions(x)[3] - self.a0) - x * c * self.i0 / (2 * self.cos_departure)
+
+    def _lag(self, a: float, x: float) -> float:
+        """:return: Lag time at x of a wind starting at a, using the horizontal component of velocity"""
+        if x <= a:
+            return 0
+        return self._time(x) - self._time(a) - (x - a) / (self._functions(a)[0] * self.cos_departure)
+
+    def zero_angle(self, shot_info: Shot, distance: Distance, initial_elevation: Angular = None) -> Angular:
+        """Barrel elevation needed for a particular zero, by fixed-point iteration on the departure angle
+        :param shot_info: Shot parameters
+        :param distance: Zero distance
+        :param initial_elevation: Unused; accepted for compatibility with TrajectoryCalc
+        :return: Barrel elevation to hit height zero at zero distance
+        """
+        self._init_trajectory(shot_info)
+        zero_distance = math.cos(self.look_angle) * (distance >> Distance.Foot)
+        height_at_zero = math.sin(self.look_angle) * (distance >> Distance.Foot) + self.cant_cosine * self.sight_height
+        angle = math.atan(height_at_zero / zero_distance)
+        for _ in range(cZeroIterations):
+            self._departure(angle)
+            if self._velocity(zero_distance) < self.min_velocity:
+                raise Exception(f'Velocity at zero distance {distance} is below minimum velocity.')
+            previous, angle = angle, math.atan(
+                (height_at_zero + self.gravity * self._drop_integral(zero_distance)) / zero_distance)
+            if math.fabs(angle - previous) < 1e-12:
+                break
+        return Angular.Radian(angle)
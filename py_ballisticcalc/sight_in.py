@@ -0,0 +1,88 @@
+"""Turret corrections to move an observed group onto the point of aim"""
+import math
+from typing import NamedTuple, Optional
+
+from .conditions import Shot
+from .interface import Calculator
+from .munition import Sight
+from .trajectory_data import TrajectoryData
+from .unit import Angular, Distance, PreferredUnits
+
+__all__ = ('SightInCorrection', 'sight_in_correction', 'rezero_correction')
+
+
+class SightInCorrection(NamedTuple):
+    """
+    Attributes:
+        elevation (Angular): turret correction, positive => up
+        windage (Angular): turret correction, positive => right
+        clicks (Sight.Clicks): signed clicks of elevation and windage; None without a sight
+        zero_elevation (Angular): Weapon.zero_elevation that makes the same correction in the barrel;
+            None if the weapon is unknown
+    """
+    elevation: Angular
+    windage: Angular
+    clicks: Optional[Sight.Clicks]
+    zero_elevation: Optional[Angular]
+
+
+def _offset_angle(offset: [float, Distance], distance: Distance) -> float:
+    """:return: Angle in radians of offset seen at distance"""
+    return math.atan2(PreferredUnits.drop(offset) >> Distance.Foot, distance >> Distance.Foot)
+
+
+def _row_at(calc: Calculator, shot: Shot, distance: Distance) -> TrajectoryData:
+    result = calc.fire_at_ranges(shot, [distance])
+    if not result.trajectory:
+        raise result.range_error(distance)
+    return result.trajectory[0]
+
+
+def _correction(elevation: float, windage: float, distance: Distance, sight: Optional[Sight],
+                zero_elevation: Optional[Angular], magnification: float) -> SightInCorrection:
+    elevation = Angular.Radian(elevation) << PreferredUnits.adjustment
+    windage = Angular.Radian(windage) << PreferredUnits.adjustment
+    clicks = None if sight is None else sight.get_adjustment(distance, elevation, windage, magnification)
+    if zero_elevation is not None:
+        zero_elevation = Angular.Radian((zero_elevation >> Angular.Radian) + (elevation >> Angular.Radian)) \
+            << PreferredUnits.angular
+    return SightInCorrection(elevation, windage, clicks, zero_elevation)
+
+
+def sight_in_correction(offset_vertical: [float, Distance], offset_horizontal: [float, Distance],
+                        distance: [float, Distance], sight: Sight = None,
+                        magnification: float = 1) -> SightInCorrection:
+    """Correction to zero at the distance of the group, e.g. 4 cm low and 6 cm left at 100 m => up and right
+    :param offset_vertical: center of the group relative to the point of aim, positive => high
+    :param offset_horizontal: center of the group relative to the point of aim, positive => right
+    :param distance: distance at which the group was shot
+    :param sight: sight whose click values count the clicks
+    :param magnification: magnification of a SFP or LWIR sight
+    """
+    distance = PreferredUnits.distance(distance)
+    return _correction(-_offset_angle(offset_vertical, distance), -_offset_angle(offset_horizontal, distance),
+                       distance, sight, None, magnification)
+
+
+def rezero_correction(calc: Calculator, shot: Shot, offset_vertical: [float, Distance],
+                      offset_horizontal: [float, Distance], distance: [float, Distance],
+                      zero_distance: [float, Distance] = None, magnification: float = 1) -> SightInCorrection:
+    """Correction to zero at zero_distance from a group shot at another distance:
+        the difference between the observed and the calculated impact is the sight error,
+        which is removed together with the calculated drop at zero_distance.
+    :param calc: Calculator to solve the trajectory
+    :param shot: shot with the weapon as it was when the group was fired; weapon.sight counts the clicks
+    :param offset_vertical: center of the group relative to the point of aim, positive => high
+    :param offset_horizontal: center of the group relative to the point of aim, positive => right
+    :param distance: distance at which the group was shot
+    :param zero_distance: distance at which to zero, default the distance of the group
+    :param magnification: magnification of a SFP or LWIR sight
+    """
+    distance = PreferredUnits.distance(distance)
+    zero_distance = distance if zero_distance is None else PreferredUnits.distance(zero_distance)
+    observed, zero = (_row_at(calc, shot, distance), _row_at(calc, shot, zero_distance))
+    error_vertical = _offset_angle(offset_vertical, distance) - (observed.drop_adj >> Angular.Radian)
+    error_horizontal = _offset_angle(offset_horizontal, distance) - (observed.windage_adj >> Angular.Radian)
+    return _correction(-((zero.drop_adj >> Angular.Radian) + error_vertical),
+                       -((zero.windage_adj >> Angular.Radian) + error_horizontal),
+                       zero_distance, shot.weapon.sight, shot.weapon.zero_elevation, magnification)
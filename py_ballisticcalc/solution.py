@@ -0,0 +1,66 @@
+"""Firing solution at one target: turret corrections, clicks, time of flight and remaining energy"""
+import math
+from dataclasses import replace
+from typing import NamedTuple, Optional
+
+from .conditions import Shot
+from .interface import Calculator
+from .munition import Sight
+from .trajectory_data import TrajectoryData
+from .unit import Angular, Distance, Energy, Velocity, PreferredUnits
+
+__all__ = ('FiringSolution', 'firing_solution')
+
+
+class FiringSolution(NamedTuple):
+    """
+    Attributes:
+        distance (Distance): line-of-sight distance of the target
+        look_angle (Angular): incline of the line of sight to the target
+        elevation (Angular): turret correction, positive => up (the negative of TrajectoryData.drop_adj)
+        windage (Angular): turret correction, positive => right (the negative of TrajectoryData.windage_adj)
+        clicks (Sight.Clicks): signed clicks of elevation and windage on weapon.sight; None without a sight
+        time (float): time of flight in seconds
+        velocity (Velocity): remaining velocity at the target
+        energy (Energy): remaining energy at the target; zero if DragModel.weight is unknown
+        trajectory (TrajectoryData): calculated row at the target
+        rounded_clicks (Sight.RoundedAdjustment): clicks rounded to whole clicks of weapon.sight, with the
+            residual angles; None without a sight
+    """
+    distance: Distance
+    look_angle: Angular
+    elevation: Angular
+    windage: Angular
+    clicks: Optional[Sight.Clicks]
+    time: float
+    velocity: Velocity
+    energy: Energy
+    trajectory: TrajectoryData
+    rounded_clicks: Optional[Sight.RoundedAdjustment] = None
+
+
+def firing_solution(calc: Calculator, shot: Shot, distance: [float, Distance],
+                    look_angle: [float, Angular] = None, magnification: float = 1) -> FiringSolution:
+    """Solves the shot at a target, e.g. for a rangefinder reading
+    :param calc: Calculator to solve the trajectory
+    :param shot: zeroed shot; shot.weapon.sight counts the clicks
+    :param distance: line-of-sight distance of the target
+    :param look_angle: incline to the target, default shot.look_angle
+    :param magnification: magnification of a SFP or LWIR sight
+    """
+    distance = PreferredUnits.distance(distance)
+    if look_angle is not None:
+        shot = replace(shot, look_angle=PreferredUnits.angular(look_angle))
+    # Rows are recorded at horizontal distances
+    horizontal = Distance.Foot(math.cos(shot.look_angle >> Angular.Radian) * (distance >> Distance.Foot))
+    result = calc.fire_at_ranges(shot, [horizontal])
+    if not result.trajectory:
+        raise result.range_error(distance)
+    row = result.trajectory[0]
+    elevation = Angular.Radian(-(row.drop_adj >> Angular.Radian)) << PreferredUnits.adjustment
+    windage = Angular.Radian(-(row.windage_adj >> Angular.Radian)) << PreferredUnits.adjustment
+    sight = shot.weapon.sight
+    clicks = None if sight is None else sight.get_adjustment(distance, elevation, windage, magnification)
+    rounded = None if sight is None else sight.get_rounded_adjustment(distance, elevation, windage, magnification)
+    return FiringSolution(distance, shot.look_angle, elevation, windage, clicks, row.time,
+                          row.velocity, row.energy, row, rounded)
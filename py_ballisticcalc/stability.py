@@ -0,0 +1,96 @@
+"""Miller gyroscopic stability, and the twist rate it takes to reach a required stability"""
+import math
+from typing import Iterable, NamedTuple
+
+from .conditions import Atmo
+from .unit import Distance, Pressure, Temperature, Velocity, Weight, PreferredUnits
+
+__all__ = ('miller_stability', 'TwistRequirement', 'TwistRecommendation', 'recommend_twist')
+
+
+def _miller_factor(weight: [float, Weight], diameter: [float, Distance], length: [float, Distance],
+                   velocity: [float, Velocity], atmo: Atmo) -> float:
+    """:return: Miller stability multiplied by the squared twist in calibers"""
+    weight = PreferredUnits.weight(weight) >> Weight.Grain
+    diameter = PreferredUnits.diameter(diameter) >> Distance.Inch
+    length = PreferredUnits.length(length) >> Distance.Inch
+    if not (weight and diameter and length):
+        raise ValueError("Bullet weight, diameter and length are required for stability")
+    length /= diameter
+    sd = 30 * weight / (math.pow(diameter, 3) * length * (1 + math.pow(length, 2)))
+    # Velocity correction factor
+    fv = math.pow((PreferredUnits.velocity(velocity) >> Velocity.FPS) / 2800, 1.0 / 3.0)
+    # Atmospheric correction
+    ft = atmo.temperature >> Temperature.Fahrenheit
+    pt = atmo.pressure >> Pressure.InHg
+    ftp = ((ft + 460) / (59 + 460)) * (29.92 / pt)
+    return sd * fv * ftp
+
+
+def miller_stability(weight: [float, Weight], diameter: [float, Distance], length: [float, Distance],
+                     twist: [float, Distance], velocity: [float, Velocity], atmo: Atmo = None) -> float:
+    """Miller gyroscopic stability factor (SG) at the muzzle, as used by TrajectoryCalc for spin drift
+    :param twist: barrel twist rate (distance per turn); its sign (hand) doesn't matter
+    :param atmo: atmosphere at the muzzle, default standard ICAO at sea level
+    """
+    atmo = atmo or Atmo.icao()
+    twist = math.fabs(PreferredUnits.twist(twist) >> Distance.Inch)
+    if not twist:
+        return 0
+    calibers = twist / (PreferredUnits.diameter(diameter) >> Distance.Inch)
+    return _miller_factor(weight, diameter, length, velocity, atmo) / math.pow(calibers, 2)
+
+
+class TwistRequirement(NamedTuple):
+    """Slowest twist that gives the required stability under one condition"""
+    velocity: Velocity
+    atmo: Atmo
+    twist: Distance
+
+
+class TwistRecommendation(NamedTuple):
+    """
+    Attributes:
+        twist (Distance): slowest twist (longest distance per turn) that reaches required_stability
+            in every condition
+        required_stability (float): requested gyroscopic stability
+        limiting (TwistRequirement): the condition that needs the fastest twist
+        requirements (list[TwistRequirement]): twist needed for each velocity and atmosphere
+    """
+    twist: Distance
+    required_stability: float
+    limiting: TwistRequirement
+    requirements: list[TwistRequirement]
+
+    def stability(self, twist: [float, Distance] = None) -> float:
+        """:return: stability in the limiting condition with twist, default the recommended one"""
+        twist = PreferredUnits.twist(twist if twist is not None else self.twist)
+        return self.required_stability * math.pow((self.twist >> Distance.Inch) / (twist >> Distance.Inch), 2)
+
+
+def recommend_twist(weight: [float, Weight], diameter: [float, Distance], length: [float, Distance],
+                    velocities: Iterable[[float, Velocity]],
+                    altitudes: Iterable[[float, Distance, Atmo]] = (0,),
+                    required_stability: float = 1.5) -> TwistRecommendation:
+    """Inverts the Miller stability formula over the expected conditions:
+        stability is inversely proportional to the squared twist, so each condition has a slowest
+        acceptable twist, and the recommendation is the fastest of these.
+    :param velocities: expected muzzle velocities
+    :param altitudes: expected altitudes (standard ICAO atmosphere at each) or Atmo instances
+    :param required_stability: minimum gyroscopic stability, default 1.5
+    """
+    if required_stability <= 0:
+        raise ValueError(f"Required stability has to be positive, got {required_stability}")
+    atmos = [a if isinstance(a, Atmo) else Atmo.icao(a) for a in altitudes]
+    calibers_per_inch = 1 / (PreferredUnits.diameter(diameter) >> Distance.Inch)
+    requirements = []
+    for velocity in velocities:
+        velocity = PreferredUnits.velocity(velocity)
+        for atmo in atmos:
+            factor = _miller_factor(weight, diameter, length, velocity, atmo)
+            twist = math.sqrt(factor / required_stability) / calibers_per_inch
+            requirements.append(TwistRequirement(velocity, atmo, Distance.Inch(twist) << PreferredUnits.twist))
+    if not requirements:
+        raise ValueError("At least one velocity and one altitude are required")
+    limiting = min(requirements, key=lambda r: r.twist.raw_value)
+    return TwistRecommendation(limiting.twist, required_stability, limiting, requirements)
@@ -3,14 +3,18 @@
 """pure python trajectory calculation backend"""
 
 import math
-from dataclasses import dataclass
 from typing import NamedTuple
 
+from .config import CalculatorConfig, AdjustmentReference, SpinDriftModel
 from .drag_model import DragDataPoint
+from .hooks import StepState, TrajectoryHooks
+from .logger import logger
 from .conditions import Atmo, Shot, Wind
 from .munition import Ammo
 from .trajectory_data import TrajectoryData, TrajFlag
-from .unit import Distance, Angular, Velocity, Weight, Energy, Pressure, Temperature, PreferredUnits
+from .unit import (Distance, Angular, Velocity, Weight, Energy, Pressure, Temperature, Acceleration, Time,
+                   PreferredUnits)
+from .vector import Vector
 
 __all__ = (
     'TrajectoryCalc',
@@ -18,6 +22,8 @@ __all__ = (
     'get_global_use_powder_sensitivity',
     'set_global_max_calc_step_size',
     'set_global_use_powder_sensitivity',
+    'get_global_gravity',
+    'set_global_gravity',
     'reset_globals'
 )
 
@@ -26,9 +32,16 @@ cMinimumVelocity = 50.0
 cMaximumDrop = -15000
 cMaxIterations = 20
 cGravityConstant = -32.17405
+cStandardDensity = 0.076474  # lb/ft^3
+cRollDampingCoefficient = 0.005  # Magnitude of spin damping moment coefficient (Clp) typical of bullets
+cMarginalStability = 1.4  # Minimum gyroscopic stability recommended for transonic flight
+cLiftToOverturningRatio = 1.0  # Lift over overturning moment coefficient slope (CLa/CMa) typical of bullets
+cAdaptiveStepRatio = 16  # Limit of adaptive steps relative to the fixed step, both ways
+cEarthRadius = 20902231.0  # Mean radius of the Earth in feet, 6371 km
 
 _globalUsePowderSensitivity = False
 _globalMaxCalcStepSize = Distance.Foot(0.5)
+_globalGravity = Acceleration.FootPerSecondSquared(-cGravityConstant)
 
 
 def get_global_max_calc_step_size() -> Distance:
@@ -39,10 +52,15 @@ def get_global_use_powder_sensitivity() -> bool:
     return _globalUsePowderSensitivity
 
 
+def get_global_gravity() -> Acceleration:
+    return _globalGravity
+
+
 def reset_globals() -> None:
-    global _globalUsePowderSensitivity, _globalMaxCalcStepSize
+    global _globalUsePowderSensitivity, _globalMaxCalcStepSize, _globalGravity
     _globalUsePowderSensitivity = False
     _globalMaxCalcStepSize = Distance.Foot(0.5)
+    _globalGravity = Acceleration.FootPerSecondSquared(-cGravityConstant)
 
 
 def set_global_max_calc_step_size(value: [float, Distance]) -> None:
@@ -52,6 +70,16 @@ def set_global_max_calc_step_size(value: [float, Distance]) -> None:
     _globalMaxCalcStepSize = PreferredUnits.distance(value)
 
 
+def set_global_gravity(value: [float, Acceleration]) -> None:
+    """Set magnitude of gravitational acceleration used by new TrajectoryCalc instances
+    :param value: Acceleration (float in PreferredUnits.acceleration), e.g. Gravity.Moon
+    """
+    global _globalGravity
+    if (_value := PreferredUnits.acceleration(value)).raw_value < 0:
+        raise ValueError("_globalGravity have to be >= 0")
+    _globalGravity = _value
+
+
 def set_global_use_powder_sensitivity(value: bool) -> None:
     global _globalUsePowderSensitivity
     if not isinstance(value, bool):
@@ -66,80 +94,28 @@ class CurvePoint(NamedTuple):
     c: float
 
 
-@dataclass
-class Vector:
-    x: float
-    y: float
-    z: float
-
-    def magnitude(self):
-        return math.sqrt(self.x * self.x + self.y * self.y + self.z * self.z)
-
-    def mul_by_const(self, a: float):
-        return Vector(self.x * a, self.y * a, self.z * a)
-
-    def mul_by_vector(self, b: 'Vector'):
-        return self.x * b.x + self.y * b.y + self.z * b.z
-
-    def add(self, b: 'Vector'):
-        return Vector(self.x + b.x, self.y + b.y, self.z + b.z)
-
-    def subtract(self, b: 'Vector'):
-        return Vector(self.x - b.x, self.y - b.y, self.z - b.z)
-
-    def negate(self):
-        return Vector(-self.x, -self.y, -self.z)
-
-    def normalize(self):
-        m = self.magnitude()
-        if math.fabs(m) < 1e-10:
-            return Vector(self.x, self.y, self.z)
-        return self.mul_by_const(1.0 / m)
-
-    def __add__(self, other: 'Vector'):
-        return self.add(other)
-
-    def __radd__(self, other: 'Vector'):
-        return self.add(other)
-
-    def __iadd__(self, other: 'Vector'):
-        return self.add(other)
-
-    def __sub__(self, other: 'Vector'):
-        return self.subtract(other)
-
-    def __rsub__(self, other: 'Vector'):
-        return self.subtract(other)
-
-    def __isub__(self, other: 'Vector'):
-        return self.subtract(other)
-
-    def __mul__(self, other: [int, float, 'Vector']):
-        if isinstance(other, (int, float)):
-            return self.mul_by_const(other)
-        if isinstance(other, Vector):
-            return self.mul_by_vector(other)
-        raise TypeError(other)
-
-    def __rmul__(self, other: [int, float, 'Vector']):
-        return self.__mul__(other)
-
-    def __imul__(self, other):
-        return self.__mul__(other)
-
-    def __neg__(self):
-        return self.negate()
-
-
 class TrajectoryCalc:
     """All calculations are done in units of feet and fps"""
 
-    def __init__(self, ammo: Ammo):
+    # Engines with more forces than drag and gravity set this and implement _aerodynamic_acceleration()
+    _aerodynamic_forces = False
+
+    def __init__(self, ammo: Ammo, hooks: TrajectoryHooks = None, config: CalculatorConfig = None):
+        """
+        :param ammo: Ammo whose DragModel is prepared for the calculation
+        :param hooks: Optional callbacks to observe the calculation loop
+        :param config: Optional settings; when None the global settings are used
+        """
         self.ammo = ammo
-        self._bc = self.ammo.dm.BC
+        self.hooks = hooks
+        self.config = config
+        self._bc = self.ammo.dm.standard_bc
         self._table_data = ammo.dm.drag_table
         self._curve = calculate_curve(self._table_data)
-        self.gravity_vector = Vector(.0, cGravityConstant, .0)
+        self._curve_index = -1  # Hint for find_curve_index(), carried between integration steps
+        self.termination_reason = None  # Why the last calculated trajectory ended
+        self._drag0 = (self._bc, self._table_data, self._curve)  # Drag before any of Ammo.phases
+        self._phases = []  # (start_time, start_x, bc, table, curve, weight, thrust, burn_time) of Ammo.phases
 
     @staticmethod
     def get_calc_step(step: float = 0):
@@ -153,7 +129,12 @@ class TrajectoryCalc:
         return min(step, preferred_step) / 2.0
 
     def trajectory(self, shot_info: Shot, max_range: Distance, dist_step: Distance,
-                   extra_data: bool = False):
+                   extra_data: bool = False, out: list[TrajectoryData] = None):
+        """Calculate trajectory for specified shot
+        :param out: Optional list (or TrajectoryColumns) to which rows are appended instead of a new list.
+            Lets high-frequency callers reuse one buffer: call out.clear() between solves.
+        :return: list of TrajectoryData (the `out` list if it was provided)
+        """
         filter_flags = TrajFlag.RANGE
 
         if extra_data:
@@ -161,9 +142,115 @@ class TrajectoryCalc:
             filter_flags = TrajFlag.ALL
 
         self._init_trajectory(shot_info)
-        return self._trajectory(shot_info, max_range >> Distance.Foot, dist_step >> Distance.Foot, filter_flags)
+        if filter_flags == TrajFlag.RANGE:
+            # Interpolate rows onto exact multiples of dist_step rather than the first step past each
+            step = dist_step >> Distance.Foot
+            distances = [i * step for i in range(int((max_range >> Distance.Foot) / step) + 1)]
+            return self._trajectory(shot_info, distances[-1], step, filter_flags, out, distances)
+        return self._trajectory(shot_info, max_range >> Distance.Foot, dist_step >> Distance.Foot,
+                                filter_flags, out)
+
+    def trajectory_at_ranges(self, shot_info: Shot, distances: list[Distance],
+                             out: list[TrajectoryData] = None) -> list[TrajectoryData]:
+        """Calculate trajectory with rows interpolated to exactly the requested downrange distances
+        :param distances: Downrange distances at which to record TrajectoryData, in any order
+        :param out: Optional list (or TrajectoryColumns) to which rows are appended instead of a new list.
+        :return: list of TrajectoryData sorted by distance (the `out` list if it was provided);
+            shorter than distances if the calculation stopped early
+        """
+        feet = sorted(d >> Distance.Foot for d in distances)
+        if not feet:
+            raise ValueError("At least one distance is required")
+        if feet[0] < 0:
+            raise ValueError("Distances have to be >= 0")
+        self._init_trajectory(shot_info)
+        return self._trajectory(shot_info, feet[-1], feet[-1], TrajFlag.RANGE, out, feet)
+
+    def trajectory_terminal(self, shot_info: Shot, distance: Distance, zero_crossings: bool = False,
+                            out: list[TrajectoryData] = None) -> list[TrajectoryData]:
+        """Calculate only the trajectory row at one distance, without recording rows at intermediate steps,
+            for fire-control loops that call the solver at high frequency and only need the final hold
+        :param distance: Downrange distance at which to record TrajectoryData
+        :param zero_crossings: True => also record the rows where the trajectory crosses the sight line
+        :param out: Optional list (or TrajectoryColumns) to which rows are appended instead of a new list.
+        :return: list of TrajectoryData ending with the row at distance (the `out` list if it was provided);
+            without that row if the calculation stopped early
+        """
+        feet = distance >> Distance.Foot
+        if feet < 0:
+            raise ValueError("Distance has to be >= 0")
+        self._init_trajectory(shot_info)
+        filter_flags = TrajFlag.RANGE | TrajFlag.ZERO if zero_crossings else TrajFlag.RANGE
+        return self._trajectory(shot_info, feet, feet, filter_flags, out, [feet])
+
+    def trajectory_at_times(self, shot_info: Shot, times: list[float],
+                            out: list[TrajectoryData] = None) -> list[TrajectoryData]:
+        """Calculate trajectory with rows interpolated to exactly the requested times of flight
+        :param times: Seconds of flight at which to record TrajectoryData, in any order
+        :param out: Optional list (or TrajectoryColumns) to which rows are appended instead of a new list.
+        :return: list of TrajectoryData sorted by time (the `out` list if it was provided);
+            shorter than times if the calculation stopped early
+        """
+        seconds = sorted(times)
+        if not seconds:
+            raise ValueError("At least one time is required")
+        if seconds[0] < 0:
+            raise ValueError("Times have to be >= 0")
+        self._init_trajectory(shot_info)
+        # Projectile can't get farther down range than at its muzzle velocity plus all of its thrust
+        speed = self.muzzle_velocity + sum(phase[6] * phase[7] for phase in self._phases)
+        maximum_range = speed * seconds[-1]
+        return self._trajectory(shot_info, maximum_range, maximum_range, TrajFlag.RANGE, out, times=seconds)
+
+    def _init_config(self):
+        config = self.config
+        if config is None:
+            config = CalculatorConfig(max_calc_step_size=_globalMaxCalcStepSize,
+                                      gravity=_globalGravity,
+                                      use_powder_sensitivity=_globalUsePowderSensitivity)
+        self.calc_step = (config.max_calc_step_size >> Distance.Foot) / 2.0
+        self.step_tolerance = 0 if config.step_tolerance is None else config.step_tolerance >> Distance.Foot
+        self.gravity_vector = Vector(.0, -(config.gravity >> Acceleration.FootPerSecondSquared), .0)
+        self.extreme_range = config.extreme_range
+        self.min_velocity = config.minimum_velocity >> Velocity.FPS
+        self.max_drop = config.maximum_drop >> Distance.Foot
+        if config.minimum_altitude is None:
+            self.min_altitude = -math.inf
+        else:
+            self.min_altitude = config.minimum_altitude >> Distance.Foot
+        self.min_energy = 0 if config.minimum_energy is None else config.minimum_energy >> Energy.FootPound
+        self.max_time = math.inf if config.maximum_time is None else config.maximum_time >> Time.Second
+        self.zero_finding_accuracy = config.zero_finding_accuracy >> Distance.Foot
+        self.max_iterations = config.max_iterations
+        self.use_powder_sensitivity = config.use_powder_sensitivity
+        # Litz spin drift is added to rows, yaw of repose is integrated with the trajectory
+        self.use_spin_drift = config.use_spin_drift and config.spin_drift_model == SpinDriftModel.LITZ
+        self.use_yaw_of_repose = config.use_spin_drift and config.spin_drift_model == SpinDriftModel.YAW_OF_REPOSE
+        self.adjustment_reference = config.adjustment_reference
+        degradation = config.transonic_degradation
+        if degradation is None:
+            self.degrade_stability = 0
+            self.degrade_mach = 0
+            self.degrade_drag_factor = 1
+            self.degrade_dispersion = 0
+        else:
+            self.degrade_stability = degradation.stability_threshold
+            self.degrade_mach = degradation.mach_threshold
+            self.degrade_drag_factor = 1 + degradation.drag_increase
+            self.degrade_dispersion = math.tan(degradation.dispersion >> Angular.Radian)
+
+    def _get_adjustment_reference(self) -> tuple[float, float, float, float]:
+        """:return: (height, windage, elevation, azimuth) of the origin and direction of the line
+            from which drop and windage adjustments are measured"""
+        if self.adjustment_reference == AdjustmentReference.BORE:
+            return (-self.cant_cosine * self.sight_height, -self.cant_sine * self.sight_height,
+                    self.barrel_elevation, self.barrel_azimuth)
+        if self.adjustment_reference == AdjustmentReference.HORIZONTAL:
+            return .0, .0, .0, .0
+        return .0, .0, self.look_angle, .0
 
     def _init_trajectory(self, shot_info: Shot):
+        self._init_config()
         self.look_angle = shot_info.look_angle >> Angular.Radian
         self.twist = shot_info.weapon.twist >> Distance.Inch
         self.length = shot_info.ammo.dm.length >> Distance.Inch
@@ -175,18 +262,58 @@ class TrajectoryCalc:
         self.cant_cosine = math.cos(shot_info.cant_angle >> Angular.Radian)
         self.cant_sine = math.sin(shot_info.cant_angle >> Angular.Radian)
         self.alt0 = shot_info.atmo.altitude >> Distance.Foot
-        self.calc_step = self.get_calc_step()
-        if _globalUsePowderSensitivity:
+        self.atmo_model = shot_info.atmo_model or shot_info.atmo
+        if self.use_powder_sensitivity:
             self.muzzle_velocity = shot_info.ammo.get_velocity_for_temp(shot_info.atmo.temperature) >> Velocity.FPS
         else:
             self.muzzle_velocity = shot_info.ammo.mv >> Velocity.FPS
+        self.muzzle_velocity += shot_info.ammo.barrel_velocity_change(shot_info.weapon.barrel_length) >> Velocity.FPS
+        bc_reference = shot_info.ammo.dm.bc_reference
+        if bc_reference is not None and not bc_reference.in_band(Velocity.FPS(self.muzzle_velocity)):
+            logger.warning("Muzzle velocity %.0f fps is outside the velocity band of the BC measurement",
+                           self.muzzle_velocity)
         self.stability_coefficient = self.calc_stability_coefficient(shot_info.atmo)
-
-    def zero_angle(self, shot_info: Shot, distance: Distance) -> Angular:
+        self._phases = []
+        for phase in shot_info.ammo.phases:
+            dm = phase.dm
+            self._phases.append((
+                math.inf if phase.start_time is None else phase.start_time,
+                math.inf if phase.start_distance is None else phase.start_distance >> Distance.Foot,
+                None if dm is None else dm.standard_bc,
+                None if dm is None else dm.drag_table,
+                None if dm is None else calculate_curve(dm.drag_table),
+                None if dm is None or not dm.weight else dm.weight >> Weight.Grain,
+                phase.thrust >> Acceleration.FootPerSecondSquared,
+                phase.burn_time))
+        self.density_factor0 = self.atmo_model.get_density_factor_and_mach_for_altitude(self.alt0)[0]
+        # Spin rate in revolutions per second
+        self.spin_rate0 = self.muzzle_velocity * 12 / math.fabs(self.twist) if self.twist else 0
+        self.spin_decay = 0  # Per foot of travel through standard density air
+        if self.weight and self.diameter:
+            # Roll damping moment with axial moment of inertia approximated as m*d^2/10
+            self.spin_decay = (10 * math.pi * cStandardDensity * math.pow(self.diameter / 12, 2)
+                               * cRollDampingCoefficient / (16 * self.weight / 7000))
+        # Side acceleration per spin rate of the lift of the yaw of repose: with axial moment of inertia m*d^2/10,
+        #   CLa/CMa * d * 2 pi * spin rate * g * cos(trajectory angle) / (10 * velocity)
+        self.spin_lift = 0
+        if self.use_yaw_of_repose and self.twist and self.diameter:
+            self.spin_lift = (math.copysign(1, self.twist) * cLiftToOverturningRatio * (self.diameter / 12)
+                              * 2 * math.pi * -self.gravity_vector.y / 10)
+        # Velocity at which kinetic energy falls to min_energy
+        self.min_energy_velocity = 0
+        if self.min_energy > 0:
+            if self.weight <= 0:
+                raise ValueError("minimum_energy requires DragModel.weight")
+            self.min_energy_velocity = math.sqrt(self.min_energy * 450400 / self.weight)
+
+    def zero_angle(self, shot_info: Shot, distance: Distance, initial_elevation: Angular = None) -> Angular:
         """Iterative algorithm to find barrel elevation needed for a particular zero
         :param shot_info: Shot parameters
         :param distance: Zero distance
-        :return: Barrel elevation to hit height zero at zero distance
+        :param initial_elevation: Optional first guess of barrel elevation (e.g., a previous solution)
+        :return: Barrel elevation to hit height zero at zero distance, found without the effects of spin
+            (spin drift, spin lift, aerodynamic forces of yaw and transonic degradation);
+            trajectories fired at this elevation include them
         """
         self._init_trajectory(shot_info)
 
@@ -194,53 +321,92 @@ class TrajectoryCalc:
         height_at_zero = math.sin(self.look_angle) * (distance >> Distance.Foot)
         maximum_range = zero_distance - 1.5 * self.calc_step
         self.barrel_azimuth = 0.0
-        self.barrel_elevation = math.atan(height_at_zero / zero_distance)
+        if initial_elevation is None:
+            self.barrel_elevation = math.atan(height_at_zero / zero_distance)
+        else:
+            self.barrel_elevation = initial_elevation >> Angular.Radian
         self.twist = 0
+        self.spin_rate0 = 0
+        self.spin_lift = 0
+        self.degrade_stability = 0
 
         iterations_count = 0
-        zero_finding_error = cZeroFindingAccuracy * 2
+        zero_finding_error = self.zero_finding_accuracy * 2
         # x = horizontal distance down range, y = drop, z = windage
-        while zero_finding_error > cZeroFindingAccuracy and iterations_count < cMaxIterations:
+        while zero_finding_error > self.zero_finding_accuracy and iterations_count < self.max_iterations:
             # Check height of trajectory at the zero distance (using current self.barrel_elevation)
-            t = self._trajectory(shot_info, maximum_range, zero_distance, TrajFlag.NONE)[0]
+            if self.step_tolerance:
+                # Adaptive steps don't end next to the zero distance, so land exactly on it
+                t = self._trajectory(shot_info, zero_distance, zero_distance, TrajFlag.RANGE,
+                                     distances=[zero_distance])[0]
+            else:
+                t = self._trajectory(shot_info, maximum_range, zero_distance, TrajFlag.NONE)[0]
             height = t.height >> Distance.Foot
             zero_finding_error = math.fabs(height - height_at_zero)
-            if zero_finding_error > cZeroFindingAccuracy:
+            if zero_finding_error > self.zero_finding_accuracy:
                 # Adjust barrel elevation to close height at zero distance
                 self.barrel_elevation -= (height - height_at_zero) / zero_distance
             else:  # last barrel_elevation hit zero!
                 break
             iterations_count += 1
 
-        if zero_finding_error > cZeroFindingAccuracy:
+        if zero_finding_error > self.zero_finding_accuracy:
             # TODO: Don't raise exception; return a tuple that contains the error so caller can check how close zero is
             raise Exception(f'Zero vertical error {zero_finding_error} feet, after {iterations_count} iterations.')
         return Angular.Radian(self.barrel_elevation)
 
     def _trajectory(self, shot_info: Shot, maximum_range: float, step: float,
-                    filter_flags: TrajFlag) -> list[TrajectoryData]:
+                    filter_flags: TrajFlag, ranges: list[TrajectoryData] = None,
+                    distances: list[float] = None, times: list[float] = None) -> list[TrajectoryData]:
         """Calculate trajectory for specified shot
         :param maximum_range: Feet down range to stop calculation
         :param step: Frequency (in feet down range) to record TrajectoryData
+        :param ranges: Optional caller-supplied list to append TrajectoryData rows to
+        :param distances: Optional sorted feet down range at which to record interpolated
+            TrajectoryData instead of every step
+        :param times: Optional sorted seconds of flight at which to record interpolated TrajectoryData,
+            instead of distances
         :return: list of TrajectoryData, one for each dist_step, out to max_range
         """
-        ranges = []  # Record of TrajectoryData points to return
-        ranges_length = int(maximum_range / step) + 1
+        if ranges is None:
+            ranges = []  # Record of TrajectoryData points to return
+        ranges_length = int(maximum_range / step) + 1 if step else 1
+        records = distances if times is None else times  # Distances or times at which to interpolate rows
+        current_distance = 0  # Index of the next of records to record
+        previous_state = None  # State at the previous step, to interpolate rows at distances
         time = 0
         previous_mach = .0
+        previous_velocity_y = .0
         drag = 0
+        spin_rate = self.spin_rate0
+        degrade_onset = -1.0  # Feet down range where transonic degradation began
+        calc_step = self.calc_step
+        acceleration = None  # Acceleration at the previous step, for adaptive steps
+        next_phase = 0  # Index of the next of self._phases to start
+        thrust = .0  # Acceleration along the velocity vector in feet per second squared
+        thrust_end = .0  # Time at which thrust stops
+        if self._phases:
+            self._reset_phases(shot_info)
+        hooks = self.hooks
+        termination_reason = 'maximum_range'
+        adjustment_reference = self._get_adjustment_reference()
 
         # region Initialize wind-related variables to first wind reading (if any)
         len_winds = len(shot_info.winds)
         current_wind = 0
         current_item = 0
-        next_range_distance = .0
+        # With distances, rows are recorded only by interpolation below
+        next_range_distance = .0 if records is None else math.inf
         next_wind_range = Wind.MAX_DISTANCE_FEET
         if len_winds < 1:
             wind_vector = Vector(.0, .0, .0)
         else:
             wind_vector = wind_to_vector(shot_info.winds[0])
             next_wind_range = shot_info.winds[0].until_distance >> Distance.Foot
+        # Winds aloft as (from_height, until_distance, wind_vector), highest first
+        wind_layers = sorted(((layer.from_height >> Distance.Foot, layer.until_distance >> Distance.Foot,
+                               wind_to_vector(layer)) for layer in shot_info.wind_layers),
+                             key=lambda layer: (-layer[0], layer[1]))
         # endregion
 
         # region Initialize velocity and position of projectile
@@ -259,6 +425,7 @@ class TrajectoryCalc:
         elif range_vector.y < 0 and self.barrel_elevation < self.look_angle:
             seen_zero |= TrajFlag.ZERO_DOWN  # We're below and pointing down from look angle; no zeroes!
 
+        gravity_vector = self.gravity_vector
         # region Trajectory Loop
         while range_vector.x <= maximum_range + self.calc_step:
             _flag = TrajFlag.NONE
@@ -272,10 +439,59 @@ class TrajectoryCalc:
                 else:
                     wind_vector = wind_to_vector(shot_info.winds[current_wind])
                     next_wind_range = shot_info.winds[current_wind].until_distance >> Distance.Foot
+            air_wind = wind_vector
+            for layer_height, layer_until, layer_wind in wind_layers:
+                if range_vector.y >= layer_height and range_vector.x < layer_until:
+                    air_wind = layer_wind
+                    break
 
             # Update air density at current point in trajectory
-            density_factor, mach = shot_info.atmo.get_density_factor_and_mach_for_altitude(
-                self.alt0 + range_vector.y)
+            if self.extreme_range:
+                # Gravity points to the center of the Earth, cEarthRadius below the shooter,
+                #   and air density follows the height above its curved surface
+                center_offset = Vector(range_vector.x, range_vector.y + cEarthRadius, range_vector.z)
+                earth_distance = center_offset.magnitude()
+                gravity_vector = center_offset * (self.gravity_vector.y * cEarthRadius * cEarthRadius
+                                                  / (earth_distance * earth_distance * earth_distance))
+                height = earth_distance - cEarthRadius
+            else:
+                height = range_vector.y
+            density_factor, mach = self.atmo_model.get_density_factor_and_mach_for_altitude(self.alt0 + height)
+
+            if hooks is not None and hooks.on_step is not None:
+                if hooks.on_step(StepState(time, Vector(range_vector.x, range_vector.y, range_vector.z),
+                                           Vector(velocity_vector.x, velocity_vector.y, velocity_vector.z),
+                                           velocity, mach, density_factor, drag)):
+                    termination_reason = 'cancelled'
+                    break
+
+            # Check for onset of transonic degradation
+            if (degrade_onset < 0 < self.degrade_stability and velocity / mach <= self.degrade_mach
+                    and self.stability_at(velocity, density_factor, spin_rate) < self.degrade_stability):
+                degrade_onset = range_vector.x
+
+            # region Record TrajectoryData rows interpolated to requested distances or times
+            if records is not None:
+                current_state = (time, range_vector, velocity_vector,
+                                 velocity, mach, density_factor, drag, spin_rate)
+                start = previous_state or current_state
+                while current_distance < len(records) and (
+                        range_vector.x if times is None else time) >= records[current_distance]:
+                    x = records[current_distance]
+                    if times is not None:
+                        # Distance down range at this time of flight, interpolated as the other fields
+                        f = (x - start[0]) / (time - start[0]) if time > start[0] else 1.0
+                        x = start[1].x + f * (range_vector.x - start[1].x)
+                    ranges.append(self._interpolate_row(x, start, current_state, adjustment_reference, degrade_onset))
+                    if hooks is not None and hooks.on_record is not None:
+                        hooks.on_record(ranges[-1])
+                    current_distance += 1
+                if current_distance == len(records):
+                    break
+                previous_state = (time, Vector(range_vector.x, range_vector.y, range_vector.z),
+                                  Vector(velocity_vector.x, velocity_vector.y, velocity_vector.z),
+                                  velocity, mach, density_factor, drag, spin_rate)
+            # endregion
 
             # region Check whether to record TrajectoryData row at current point
             if filter_flags:
@@ -298,6 +514,10 @@ class TrajectoryCalc:
                 if (velocity / mach <= 1) and (previous_mach > 1):
                     _flag |= TrajFlag.MACH
 
+                # Apex check
+                if velocity_vector.y <= 0 < previous_velocity_y:
+                    _flag |= TrajFlag.APEX
+
                 # Next range check
                 if range_vector.x >= next_range_distance:
                     _flag |= TrajFlag.RANGE
@@ -309,34 +529,98 @@ class TrajectoryCalc:
                     ranges.append(create_trajectory_row(
                         time, range_vector, velocity_vector,
                         velocity, mach, self.spin_drift(time), self.look_angle,
-                        density_factor, drag, self.weight, _flag.value
+                        density_factor, drag, self.weight, _flag.value, adjustment_reference,
+                        spin_rate, self.stability_at(velocity, density_factor, spin_rate),
+                        self.dispersion_at(range_vector.x, degrade_onset)
                     ))
+                    if hooks is not None and hooks.on_record is not None:
+                        hooks.on_record(ranges[-1])
                     if current_item == ranges_length:
                         break
             # endregion
 
             previous_mach = velocity / mach
+            previous_velocity_y = velocity_vector.y
+
+            # region Start projectile phases (see Ammo.phases)
+            while next_phase < len(self._phases) and (time >= self._phases[next_phase][0]
+                                                      or range_vector.x >= self._phases[next_phase][1]):
+                _, _, bc, table, curve, weight, phase_thrust, burn_time = self._phases[next_phase]
+                if bc is not None:
+                    self._bc, self._table_data, self._curve, self._curve_index = bc, table, curve, -1
+                if weight is not None:
+                    self.weight = weight
+                if phase_thrust:
+                    thrust, thrust_end = phase_thrust, time + burn_time
+                next_phase += 1
+            # endregion
 
             # region Ballistic calculation step (point-mass)
-            # Time step is set to advance bullet calc_step distance along x axis
-            delta_time = self.calc_step / velocity_vector.x
             # Air resistance seen by bullet is ground velocity minus wind velocity relative to ground
-            velocity_adjusted = velocity_vector - wind_vector
+            velocity_adjusted = velocity_vector - air_wind
             velocity = velocity_adjusted.magnitude()  # Velocity relative to air
             # Drag is a function of air density and velocity relative to the air
             drag = density_factor * velocity * self.drag_by_mach(velocity / mach)
+            if degrade_onset >= 0:
+                drag *= self.degrade_drag_factor
+            if self.step_tolerance:
+                previous_acceleration, acceleration = acceleration, velocity_adjusted * -drag + gravity_vector
+                jerk = Vector(.0, .0, .0) if previous_acceleration is None else \
+                    (acceleration - previous_acceleration) * (1 / delta_time)
+                calc_step = self._adaptive_step(range_vector.x, velocity_vector.x, acceleration, jerk, maximum_range)
+                # Land on the next distance to record
+                next_record = next_range_distance if distances is None else distances[current_distance]
+                if range_vector.x < next_record < range_vector.x + calc_step:
+                    calc_step = next_record - range_vector.x
+            if self.extreme_range:
+                # Time step is set to advance bullet calc_step distance along its path
+                delta_time = calc_step / velocity_vector.magnitude()
+            else:
+                # Time step is set to advance bullet calc_step distance along x axis
+                delta_time = calc_step / velocity_vector.x
+            # Spin decays with distance travelled through the air
+            spin_rate *= math.exp(-self.spin_decay * density_factor * velocity * delta_time)
             # Bullet velocity changes due to both drag and gravity
-            velocity_vector -= (velocity_adjusted * drag - self.gravity_vector) * delta_time
+            velocity_vector.mul_add_in_place(velocity_adjusted, -drag * delta_time)
+            velocity_vector.mul_add_in_place(gravity_vector, delta_time)
+            if self._aerodynamic_forces:
+                velocity_vector.mul_add_in_place(self._aerodynamic_acceleration(
+                    velocity_adjusted, velocity, drag, gravity_vector, density_factor, velocity / mach, spin_rate
+                ), delta_time)
+            if self.spin_lift:
+                velocity_vector.z += self.spin_lift * spin_rate * velocity_vector.x / (velocity * velocity) * delta_time
+            if time < thrust_end:
+                velocity_vector.mul_add_in_place(velocity_vector, thrust * delta_time / velocity_vector.magnitude())
             # Bullet position changes by velocity times the time step
-            delta_range_vector = Vector(self.calc_step,
-                                        velocity_vector.y * delta_time,
-                                        velocity_vector.z * delta_time)
+            if self.extreme_range:
+                delta_range_vector = velocity_vector * delta_time
+            else:
+                delta_range_vector = Vector(calc_step,
+                                            velocity_vector.y * delta_time,
+                                            velocity_vector.z * delta_time)
             # Update the bullet position
-            range_vector += delta_range_vector
+            range_vector.add_in_place(delta_range_vector)
             velocity = velocity_vector.magnitude()  # Velocity relative to ground
-            time += delta_range_vector.magnitude() / velocity
+            if self.extreme_range:
+                time += delta_time
+            else:
+                time += delta_range_vector.magnitude() / velocity
 
-            if velocity < cMinimumVelocity or range_vector.y < cMaximumDrop:
+            if velocity < self.min_velocity:
+                termination_reason = 'minimum_velocity'
+                break
+            if range_vector.y < self.max_drop:
+                termination_reason = 'maximum_drop'
+                break
+            if self.alt0 + (earth_height(range_vector) if self.extreme_range else range_vector.y) \
+                    < self.min_altitude:
+                termination_reason = 'minimum_altitude'
+                break
+            if velocity < self.min_energy_velocity:
+                termination_reason = 'minimum_energy'
+                break
+            if time > self.max_time:
+                termination_reason = 'maximum_time'
                 break
             # endregion
         # endregion
@@ -345,9 +629,65 @@ class TrajectoryCalc:
             ranges.append(create_trajectory_row(
                 time, range_vector, velocity_vector,
                 velocity, mach, self.spin_drift(time), self.look_angle,
-                density_factor, drag, self.weight, _flag.value))
+                density_factor, drag, self.weight, _flag.value, adjustment_reference,
+                spin_rate, self.stability_at(velocity, density_factor, spin_rate),
+                self.dispersion_at(range_vector.x, degrade_onset)))
+            if hooks is not None and hooks.on_record is not None:
+                hooks.on_record(ranges[-1])
+        if hooks is not None and hooks.on_termination is not None:
+            hooks.on_termination(StepState(time, Vector(range_vector.x, range_vector.y, range_vector.z),
+                                           Vector(velocity_vector.x, velocity_vector.y, velocity_vector.z),
+                                           velocity, mach, density_factor, drag),
+                                 termination_reason)
+        self.termination_reason = termination_reason
+        if next_phase:
+            self._reset_phases(shot_info)
         return ranges
 
+    def _adaptive_step(self, x: float, velocity_x: float, acceleration: Vector, jerk: Vector,
+                       maximum_range: float) -> float:
+        """Allots step_tolerance over maximum_range by the truncation error of the integrator,
+            which holds acceleration through each step.  Per step of delta_time that is
+            1/2 * acceleration * delta_time^2 of position, plus 1/2 * jerk * delta_time^2 of velocity,
+            which carries on over the remaining time of flight.  Only components across the line of fire count,
+            as steps advance exactly down range.
+        :param x: Feet down range
+        :param velocity_x: Feet per second down range
+        :param acceleration: Feet per second squared
+        :param jerk: Feet per second cubed, rate of change of acceleration over the previous step
+        :return: Feet down range to the next step
+        """
+        maximum_range = max(maximum_range, self.calc_step)
+        error_rate = (math.hypot(acceleration.y, acceleration.z)
+                      + math.hypot(jerk.y, jerk.z) * max(maximum_range - x, 0) / velocity_x)
+        if not error_rate:
+            return self.calc_step * cAdaptiveStepRatio
+        step = 2 * self.step_tolerance * velocity_x * velocity_x / (error_rate * maximum_range)
+        return min(max(step, self.calc_step / cAdaptiveStepRatio), self.calc_step * cAdaptiveStepRatio)
+
+    def _reset_phases(self, shot_info: Shot):
+        """Restores the drag model and weight of the projectile before its first phase"""
+        self._bc, self._table_data, self._curve = self._drag0
+        self._curve_index = -1
+        self.weight = shot_info.ammo.dm.weight >> Weight.Grain
+
+    def _interpolate_row(self, x: float, previous: tuple, current: tuple,
+                         adjustment_reference: tuple, degrade_onset: float) -> TrajectoryData:
+        """:return: TrajectoryData at x feet down range, linearly interpolated between
+            (time, range_vector, velocity_vector, velocity, mach, density_factor, drag, spin_rate) states"""
+        dx = current[1].x - previous[1].x
+        f = (x - previous[1].x) / dx if dx else 1.0
+        time, velocity, mach, density_factor, drag, spin_rate = (
+            p + f * (c - p) for p, c in zip((previous[0],) + previous[3:], (current[0],) + current[3:]))
+        range_vector = previous[1] + (current[1] - previous[1]) * f
+        range_vector.x = x
+        velocity_vector = previous[2] + (current[2] - previous[2]) * f
+        return create_trajectory_row(time, range_vector, velocity_vector, velocity, mach,
+                                     self.spin_drift(time), self.look_angle, density_factor, drag,
+                                     self.weight, TrajFlag.RANGE.value, adjustment_reference,
+                                     spin_rate, self.stability_at(velocity, density_factor, spin_rate),
+                                     self.dispersion_at(x, degrade_onset))
+
     def drag_by_mach(self, mach: float) -> float:
         """ Drag force = V^2 * Cd * AirDensity * S / 2m where:
                 cStandardDensity of Air = 0.076474 lb/ft^3
@@ -357,7 +697,8 @@ class TrajectoryCalc:
             Thus: The magic constant found here = StandardDensity * pi / (4 * 2 * 144)
         :return: Drag coefficient at the given mach number
         """
-        cd = calculate_by_curve(self._table_data, self._curve, mach)
+        self._curve_index = find_curve_index(self._table_data, mach, self._curve_index)
+        cd = calculate_by_curve_index(self._table_data, self._curve, mach, self._curve_index)
         return cd * 2.08551e-04 / self._bc
 
     def spin_drift(self, time) -> float:
@@ -365,12 +706,29 @@ class TrajectoryCalc:
         :param time: Time of flight
         :return: windage due to spin drift, in feet
         """
-        if self.twist != 0:
+        if self.twist != 0 and self.use_spin_drift:
             sign = 1 if self.twist > 0 else -1
             return sign * (1.25 * (self.stability_coefficient + 1.2)
                            * math.pow(time, 1.83)) / 12
         return 0
 
+    def stability_at(self, velocity: float, density_factor: float, spin_rate: float) -> float:
+        """Miller stability coefficient at the muzzle scaled to current conditions:
+            gyroscopic stability is proportional to (spin rate / velocity)^2 / air density
+        :return: gyroscopic stability factor, or 0 if it can't be calculated
+        """
+        if not self.stability_coefficient or not self.spin_rate0 or not velocity:
+            return 0
+        return (self.stability_coefficient * (self.density_factor0 / density_factor)
+                * math.pow(spin_rate * self.muzzle_velocity / (self.spin_rate0 * velocity), 2))
+
+    def dispersion_at(self, x: float, degrade_onset: float) -> float:
+        """:return: Additional dispersion (feet) at x feet down range due to transonic degradation
+            that began at degrade_onset (negative if it has not begun)"""
+        if degrade_onset < 0 or x <= degrade_onset:
+            return 0
+        return self.degrade_dispersion * (x - degrade_onset)
+
     def calc_stability_coefficient(self, atmo: Atmo) -> float:
         """Miller stability coefficient"""
         if self.twist and self.length and self.diameter:
@@ -390,6 +748,11 @@ class TrajectoryCalc:
         return 0
 
 
+def earth_height(range_vector: Vector) -> float:
+    """:return: Height in feet above the curved surface of the Earth of a point at range_vector from the shooter"""
+    return math.hypot(range_vector.x, range_vector.y + cEarthRadius, range_vector.z) - cEarthRadius
+
+
 def wind_to_vector(wind: Wind) -> Vector:
     """Calculate wind vector to add to projectile velocity vector each iteration:
         Aerodynamic drag is function of velocity relative to the air stream.
@@ -408,7 +771,10 @@ def wind_to_vector(wind: Wind) -> Vector:
 
 def create_trajectory_row(time: float, range_vector: Vector, velocity_vector: Vector,
                           velocity: float, mach: float, spin_drift: float, look_angle: float,
-                          density_factor: float, drag: float, weight: float, flag: int) -> TrajectoryData:
+                          density_factor: float, drag: float, weight: float, flag: int,
+                          adjustment_reference: tuple[float, float, float, float] = None,
+                          spin_rate: float = 0, stability: float = 0,
+                          dispersion: float = 0) -> TrajectoryData:
     """
     Create a TrajectoryData object representing a single row of trajectory data.
 
@@ -423,12 +789,25 @@ def create_trajectory_row(time: float, range_vector: Vector, velocity_vector: Ve
     :param drag: Drag value.
     :param weight: Weight value.
     :param flag: Flag value.
+    :param adjustment_reference: (height, windage, elevation, azimuth) of the line from which
+        drop_adj and windage_adj are measured; None for the sight line.
+    :param spin_rate: Spin rate in revolutions per second.
+    :param stability: Gyroscopic stability factor (0 if unknown).
+    :param dispersion: Additional dispersion in feet.
 
     :return: A TrajectoryData object representing the trajectory data.
     """
     windage = range_vector.z + spin_drift
-    drop_adjustment = get_correction(range_vector.x, range_vector.y)
-    windage_adjustment = get_correction(range_vector.x, windage)
+    if adjustment_reference is None:
+        adjustment_reference = (.0, .0, look_angle, .0)
+    ref_height, ref_windage, ref_elevation, ref_azimuth = adjustment_reference
+    drop_adjustment = get_correction(range_vector.x, range_vector.y - ref_height)
+    windage_adjustment = get_correction(range_vector.x, windage - ref_windage)
+    if range_vector.x:
+        drop_adjustment -= ref_elevation
+        windage_adjustment -= ref_azimuth
+    if is_unstable(stability, velocity / mach):
+        flag |= TrajFlag.UNSTABLE.value
     trajectory_angle = math.atan(velocity_vector.y / velocity_vector.x)
 
     return TrajectoryData(
@@ -438,7 +817,7 @@ def create_trajectory_row(time: float, range_vector: Vector, velocity_vector: Ve
         mach=velocity / mach,
         height=Distance.Foot(range_vector.y),
         target_drop=Distance.Foot((range_vector.y - range_vector.x * math.tan(look_angle)) * math.cos(look_angle)),
-        drop_adj=Angular.Radian(drop_adjustment - (look_angle if range_vector.x else 0)),
+        drop_adj=Angular.Radian(drop_adjustment),
         windage=Distance.Foot(windage),
         windage_adj=Angular.Radian(windage_adjustment),
         look_distance=Distance.Foot(range_vector.x / math.cos(look_angle)),
@@ -447,10 +826,21 @@ def create_trajectory_row(time: float, range_vector: Vector, velocity_vector: Ve
         drag=drag,
         energy=Energy.FootPound(calculate_energy(weight, velocity)),
         ogw=Weight.Pound(calculate_ogw(weight, velocity)),
-        flag=flag
+        flag=flag,
+        spin_rate=spin_rate,
+        stability=stability,
+        dispersion=Distance.Foot(dispersion)
     )
 
 
+def is_unstable(stability: float, mach: float) -> bool:
+    """:return: True if the projectile is gyroscopically unstable, or marginally stable in transonic flight,
+        where it may become dynamically unstable"""
+    if stability <= 0:  # Unknown
+        return False
+    return stability < 1 or (0.8 <= mach <= 1.2 and stability < cMarginalStability)
+
+
 def get_correction(distance: float, offset: float) -> float:
     """:return: Sight adjustment in radians"""
     if distance != 0:
@@ -516,17 +906,52 @@ def calculate_by_curve(data: list, curve: list, mach: float) -> float:
     :param mach: Mach value for which we're searching for CD
     :return float: drag coefficient
     """
-    num_points = int(len(curve))
-    mlo = 0
-    mhi = num_points - 2
-
-    while mhi - mlo > 1:
-        mid = int(math.floor(mhi + mlo) / 2.0)
-        if data[mid].Mach < mach:
-            mlo = mid
-        else:
-            mhi = mid
+    return calculate_by_curve_index(data, curve, mach, find_curve_index(data, mach))
+
 
+def find_curve_index(data: list, mach: float, hint: int = -1) -> int:
+    """
+    Find the upper bracketing index mhi of mach in data: the first index in [1, len(data) - 2]
+        with data[mhi].Mach >= mach, or len(data) - 2 if there is none.
+    :param data: data in ascending Mach order
+    :param mach: Mach value for which we're searching
+    :param hint: Result of a previous call, or -1 to binary search from scratch.
+        Mach changes little between integration steps, so walking from the hint
+        usually takes zero or one comparison.
+    :return: index mhi
+    """
+    mhi_max = len(data) - 2
+    if mhi_max < 1:
+        return 0
+
+    if hint < 0:
+        mlo = 0
+        mhi = mhi_max
+        while mhi - mlo > 1:
+            mid = int(math.floor(mhi + mlo) / 2.0)
+            if data[mid].Mach < mach:
+                mlo = mid
+            else:
+                mhi = mid
+        return mhi
+
+    mhi = min(max(hint, 1), mhi_max)
+    while mhi > 1 and data[mhi - 1].Mach >= mach:
+        mhi -= 1
+    while mhi < mhi_max and data[mhi].Mach < mach:
+        mhi += 1
+    return mhi
+
+
+def calculate_by_curve_index(data: list, curve: list, mach: float, mhi: int) -> float:
+    """
+    :param data: data
+    :param curve: Output of calculate_curve(data)
+    :param mach: Mach value for which we're searching for CD
+    :param mhi: Output of find_curve_index(data, mach)
+    :return float: drag coefficient
+    """
+    mlo = max(mhi - 1, 0)
     if data[mhi].Mach - mach > mach - data[mlo].Mach:
         m = mlo
     else:
@@ -2,12 +2,14 @@
 import logging
 import math
 import typing
+from array import array
 from dataclasses import dataclass, field
 from enum import Flag
 from typing import NamedTuple
 
-from .unit import Angular, Distance, Weight, Velocity, Energy, AbstractUnit, Unit, PreferredUnits
-from .conditions import Shot
+from .unit import Angular, Distance, Weight, Velocity, Energy, Time, Density, AbstractUnit, Unit, PreferredUnits
+from .conditions import Shot, cStandardDensity
+from .localization import Localization, Locales
 
 try:
     import pandas as pd
@@ -22,14 +24,16 @@ except ImportError as error:
     logging.warning("Install matplotlib to get results as a plot")
     matplotlib = None
 
-__all__ = ('TrajectoryData', 'HitResult', 'TrajFlag')
+__all__ = ('TrajectoryData', 'TrajectoryColumns', 'HitResult', 'TrajFlag', 'RangeError')
+
+cRecommendedStability = 1.5  # Gyroscopic stability below which a projectile is marginally stable
 
 PLOT_FONT_HEIGHT = 72
 PLOT_FONT_SIZE = 552 / PLOT_FONT_HEIGHT
 
 
 class TrajFlag(Flag):
-    """Flags for marking trajectory row if Zero or Mach crossing
+    """Flags for marking trajectory row if Zero or Mach crossing, or the apex
     Also uses to set a filters for a trajectory calculation loop
     """
     NONE = 0
@@ -38,8 +42,11 @@ class TrajFlag(Flag):
     MACH = 4
     RANGE = 8
     DANGER = 16
+    UNSTABLE = 32  # Set on rows where the projectile may be unstable; never used to select rows
+    APEX = 64  # First point after the projectile stops rising
     ZERO = ZERO_UP | ZERO_DOWN
-    ALL = RANGE | ZERO_UP | ZERO_DOWN | MACH | DANGER
+    EVENTS = ZERO_UP | ZERO_DOWN | MACH | APEX
+    ALL = RANGE | ZERO_UP | ZERO_DOWN | MACH | DANGER | APEX
 
 
 class TrajectoryData(NamedTuple):
@@ -64,6 +71,9 @@ class TrajectoryData(NamedTuple):
         energy (Energy):
         ogw (Weight): optimal game weight
         flag (int): row type
+        spin_rate (float): projectile spin in revolutions per second
+        stability (float): gyroscopic stability factor (0 if unknown)
+        dispersion (Distance): additional dispersion due to transonic degradation (see TransonicDegradation)
     """
 
     time: float
@@ -82,21 +92,31 @@ class TrajectoryData(NamedTuple):
     energy: Energy
     ogw: Weight
     flag: typing.Union[TrajFlag, int]
+    spin_rate: float = 0
+    stability: float = 0
+    dispersion: Distance = Distance.Foot(0)
+
+    @property
+    def air_density(self) -> Density:
+        """:return: air density at this point, from density_factor (the density ratio less 1)"""
+        return Density.LbPerCubicFoot((1 + self.density_factor) * cStandardDensity) << PreferredUnits.density
 
-    def formatted(self) -> tuple:
+    def formatted(self, localization: Localization = None) -> tuple:
         """
+        :param localization: Number and unit label formatting (default: Locales.English)
         :return: matrix of formatted strings for each value of trajectory in default prefer_units
         """
+        loc = Locales.English if localization is None else localization
 
         def _fmt(v: AbstractUnit, u: Unit):
             """simple formatter"""
-            return f"{v >> u:.{u.accuracy}f} {u.symbol}"
+            return loc.format(v, u)
 
         return (
-            f'{self.time:.3f} s',
+            f'{loc.number(self.time, 3)} {loc.label("s")}',
             _fmt(self.distance, PreferredUnits.distance),
             _fmt(self.velocity, PreferredUnits.velocity),
-            f'{self.mach:.2f} mach',
+            f'{loc.number(self.mach, 2)} {loc.label("mach")}',
             _fmt(self.height, PreferredUnits.drop),
             _fmt(self.target_drop, PreferredUnits.drop),
             _fmt(self.drop_adj, PreferredUnits.adjustment),
@@ -104,12 +124,15 @@ class TrajectoryData(NamedTuple):
             _fmt(self.windage_adj, PreferredUnits.adjustment),
             _fmt(self.look_distance, PreferredUnits.distance),
             _fmt(self.angle, PreferredUnits.angular),
-            f'{self.density_factor:.3e}',
-            f'{self.drag:.3f}',
+            loc.number(self.density_factor, 3, exponent=True),
+            loc.number(self.drag, 3),
             _fmt(self.energy, PreferredUnits.energy),
             _fmt(self.ogw, PreferredUnits.ogw),
 
-            self.flag
+            self.flag,
+            f'{loc.number(self.spin_rate, 0)} {loc.label("rps")}',
+            loc.number(self.stability, 2),
+            _fmt(self.dispersion, PreferredUnits.drop)
         )
 
     def in_def_units(self) -> tuple:
@@ -132,10 +155,90 @@ class TrajectoryData(NamedTuple):
             self.drag,
             self.energy >> PreferredUnits.energy,
             self.ogw >> PreferredUnits.ogw,
-            TrajFlag(self.flag)
+            TrajFlag(self.flag),
+            self.spin_rate,
+            self.stability,
+            self.dispersion >> PreferredUnits.drop
         )
 
 
+# TrajectoryData fields with units => PreferredUnits attribute of their default units
+COLUMN_UNITS = {
+    'distance': 'distance',
+    'velocity': 'velocity',
+    'height': 'drop',
+    'target_drop': 'drop',
+    'drop_adj': 'adjustment',
+    'windage': 'drop',
+    'windage_adj': 'adjustment',
+    'look_distance': 'distance',
+    'angle': 'angular',
+    'energy': 'energy',
+    'ogw': 'ogw',
+    'dispersion': 'drop',
+}
+
+
+class TrajectoryColumns:
+    """
+    Trajectory stored by column: each field of TrajectoryData is a contiguous array of floats
+        (flag is an array of ints) in the units chosen for it.  Takes about half the memory of
+        a list of TrajectoryData, and arrays support the buffer protocol, so they can be passed
+        to numpy.frombuffer() or plotting libraries without conversion.
+    Use Calculator.fire_columns(), HitResult.columns(), or pass as `out` to TrajectoryCalc.trajectory().
+
+    :param units: Units of fields, e.g. {'distance': Unit.Meter, 'height': Unit.Centimeter};
+        fields not listed use PreferredUnits (as TrajectoryData.in_def_units())
+    """
+
+    def __init__(self, units: dict[str, Unit] = None):
+        self.units = {name: getattr(PreferredUnits, preferred) for name, preferred in COLUMN_UNITS.items()}
+        for name, unit in (units or {}).items():
+            if name not in COLUMN_UNITS:
+                raise KeyError(f"TrajectoryData has no field {name!r} with units")
+            self.units[name] = unit
+        self.termination_reason = 'maximum_range'
+        for name in TrajectoryData._fields:
+            setattr(self, name, array('i' if name == 'flag' else 'd'))
+
+    @classmethod
+    def from_rows(cls, rows: typing.Iterable[TrajectoryData], units: dict[str, Unit] = None) -> 'TrajectoryColumns':
+        """:return: columns of rows"""
+        columns = cls(units)
+        for row in rows:
+            columns.append(row)
+        return columns
+
+    def append(self, row: TrajectoryData) -> None:
+        """Adds row at the end of each column"""
+        for name, value in zip(TrajectoryData._fields, row):
+            if name in self.units:
+                value = value >> self.units[name]
+            elif name == 'flag':
+                value = value.value if isinstance(value, TrajFlag) else value
+            getattr(self, name).append(value)
+
+    def clear(self) -> None:
+        """Removes all rows, keeping units"""
+        for name in TrajectoryData._fields:
+            del getattr(self, name)[:]
+        self.termination_reason = 'maximum_range'
+
+    def __len__(self) -> int:
+        return len(self.time)
+
+    def __getitem__(self, index: int) -> TrajectoryData:
+        """:return: TrajectoryData of one row"""
+        return TrajectoryData(*(
+            self.units[name](getattr(self, name)[index]) if name in self.units else getattr(self, name)[index]
+            for name in TrajectoryData._fields
+        ))
+
+    def __iter__(self) -> typing.Iterator[TrajectoryData]:
+        for index in range(len(self)):
+            yield self[index]
+
+
 class DangerSpace(NamedTuple):
     """Stores the danger space data for distance specified"""
     at_range: TrajectoryData
@@ -183,12 +286,69 @@ class DangerSpace(NamedTuple):
                     linespacing=1.2, fontsize=PLOT_FONT_SIZE, ha='center', va='top')
 
 
+class RangeError(ArithmeticError):
+    """
+    Raised when a trajectory ends before what was requested of it
+
+    :param reason: HitResult.termination_reason, why the trajectory ended: e.g. 'minimum_velocity',
+        'maximum_drop' or 'minimum_altitude' (ground)
+    :param requested: what the trajectory doesn't reach, e.g. 'distance 1000.0yd'
+    :param last_point: last row of the trajectory, None if it has no rows
+    """
+
+    def __init__(self, reason: str, requested: str, last_point: typing.Optional[TrajectoryData] = None):
+        super().__init__(f"Calculated trajectory doesn't reach requested {requested} ({reason})")
+        self.reason = reason
+        self.requested = requested
+        self.last_point = last_point
+
+    @property
+    def achieved_distance(self) -> typing.Optional[Distance]:
+        """:return: distance of the last row, None if the trajectory has no rows"""
+        return None if self.last_point is None else self.last_point.distance
+
+
 @dataclass(frozen=True)
 class HitResult:
-    """Results of the shot"""
+    """Results of the shot
+    :param termination_reason: Why the calculation ended: 'maximum_range', 'minimum_velocity',
+        'minimum_energy', 'maximum_drop', 'minimum_altitude' (ground), 'maximum_time'
+        or 'cancelled' (by TrajectoryHooks.on_step)
+    :param stability: Miller gyroscopic stability factor (SG) at the muzzle in shot.atmo; 0 if unknown
+        (requires twist and DragModel weight, diameter and length).  TrajectoryData.stability follows it
+        along the trajectory.
+    :param trajectory_step: Distance between the rows of Calculator.fire() and fire_volley(), a tenth of
+        the range when they were not given one; None for rows at other distances, times or every step
+    """
     shot: Shot
     trajectory: list[TrajectoryData] = field(repr=False)
     extra: bool = False
+    termination_reason: str = 'maximum_range'
+    stability: float = 0
+    trajectory_step: typing.Optional[Distance] = None
+
+    @property
+    def stability_warning(self) -> typing.Optional[str]:
+        """:return: 'unstable' if stability is below 1, 'marginal' if below cRecommendedStability (1.5),
+            None if the projectile is stable or its stability is unknown"""
+        if not self.stability:
+            return None
+        if self.stability < 1:
+            return 'unstable'
+        if self.stability < cRecommendedStability:
+            return 'marginal'
+        return None
+
+    @property
+    def incomplete(self) -> bool:
+        """:return: True if the calculation stopped before reaching the requested range"""
+        return self.termination_reason != 'maximum_range'
+
+    def range_error(self, requested: [str, Distance]) -> RangeError:
+        """:return: RangeError for requested (e.g. the distance) beyond the end of this trajectory"""
+        if isinstance(requested, Distance):
+            requested = f"distance {requested}"
+        return RangeError(self.termination_reason, requested, self.trajectory[-1] if self.trajectory else None)
 
     def __iter__(self):
         yield from self.trajectory
@@ -211,6 +371,23 @@ class HitResult:
             raise ArithmeticError("Can't find zero crossing points")
         return data
 
+    def zero_distances(self) -> list[Distance]:
+        """:return: distances of the zero crossing points"""
+        return [row.distance for row in self.zeros()]
+
+    def apex(self) -> TrajectoryData:
+        """:return: highest point of the trajectory, the first row after the projectile stops rising"""
+        self.__check_extra__()
+        data = next((row for row in self.trajectory if row.flag & TrajFlag.APEX.value), None)
+        if data is None:
+            raise ArithmeticError("Can't find apex: the projectile doesn't rise")
+        return data
+
+    def events(self) -> list[TrajectoryData]:
+        """:return: rows of zero crossings, the transonic (Mach 1) crossing and the apex, in order of time"""
+        self.__check_extra__()
+        return [row for row in self.trajectory if row.flag & TrajFlag.EVENTS.value]
+
     def index_at_distance(self, d: Distance) -> int:
         """
         :param d: Distance for which we want Trajectory Data
@@ -226,11 +403,68 @@ class HitResult:
         :return: First trajectory row with .distance >= d
         """
         if (i := self.index_at_distance(d)) < 0:
-            raise ArithmeticError(
-                f"Calculated trajectory doesn't reach requested distance {d}"
-            )
+            raise self.range_error(d)
         return self.trajectory[i]
 
+    def flag_at(self, d: [float, Distance]) -> TrajFlag:
+        """
+        :param d: Distance for which we want the flags
+        :return: flags of the first trajectory row with .distance >= d (e.g. TrajFlag.ZERO_DOWN | TrajFlag.RANGE)
+        """
+        return TrajFlag(self.get_at_distance(PreferredUnits.distance(d)).flag)
+
+    def at(self, d: [float, Distance]) -> TrajectoryData:
+        """
+        :param d: Distance for which we want Trajectory Data
+        :return: TrajectoryData linearly interpolated between the rows around d.
+            Rows of Calculator.fire(..., extra_data=True) are every calculation step, for the most precise result.
+        """
+        x = PreferredUnits.distance(d) >> Distance.Foot
+        return self._interpolate(lambda row: row.distance >> Distance.Foot, x, f"distance {d}")
+
+    def at_time(self, time: [float, Time]) -> TrajectoryData:
+        """
+        :param time: Time (float in seconds) of flight for which we want Trajectory Data
+        :return: TrajectoryData linearly interpolated between the rows around time
+        """
+        time = Time.Second(time) >> Time.Second
+        return self._interpolate(lambda row: row.time, time, f"time {time}")
+
+    def _interpolate(self, key: typing.Callable[[TrajectoryData], float], value: float,
+                     requested: str) -> TrajectoryData:
+        """:return: TrajectoryData interpolated where key(row) equals value, with flag NONE between rows"""
+        for previous, current in zip(self.trajectory, self.trajectory[1:]):
+            k0, k1 = key(previous), key(current)
+            if k0 <= value <= k1:
+                if value == k0:
+                    return previous
+                if value == k1:
+                    return current
+                f = (value - k0) / (k1 - k0)
+
+                def lerp(a, b):
+                    if isinstance(a, AbstractUnit):
+                        return a.units(a.unit_value + f * ((b >> a.units) - a.unit_value))
+                    return a + f * (b - a)
+
+                return TrajectoryData(*(TrajFlag.NONE.value if name == 'flag' else lerp(a, b)
+                                        for name, a, b in zip(TrajectoryData._fields, previous, current)))
+        if self.trajectory and key(self.trajectory[0]) == value:
+            return self.trajectory[0]
+        raise self.range_error(requested)
+
+    def subsonic_distance(self) -> typing.Optional[Distance]:
+        """:return: distance where the projectile slows below Mach 1, interpolated between rows;
+            None if it doesn't within the trajectory
+        """
+        for previous, current in zip(self.trajectory, self.trajectory[1:]):
+            if previous.mach >= 1 > current.mach:
+                f = (previous.mach - 1) / (previous.mach - current.mach)
+                x0 = previous.distance >> Distance.Foot
+                x = x0 + f * ((current.distance >> Distance.Foot) - x0)
+                return Distance.Foot(x) << PreferredUnits.distance
+        return None
+
     def danger_space(self,
                      at_range: [float, Distance],
                      target_height: [float, Distance],
@@ -259,9 +493,7 @@ class HitResult:
 
         # Get index of first trajectory point with distance >= at_range
         if (index := self.index_at_distance(at_range)) < 0:
-            raise ArithmeticError(
-                f"Calculated trajectory doesn't reach requested distance {at_range}"
-            )
+            raise self.range_error(at_range)
 
         def find_begin_danger(row_num: int) -> TrajectoryData:
             """
@@ -295,16 +527,26 @@ class HitResult:
                            find_end_danger(index),
                            look_angle)
 
-    def dataframe(self, formatted: bool = False) -> 'DataFrame':
+    def columns(self, units: dict[str, Unit] = None) -> TrajectoryColumns:
+        """
+        :param units: Units of fields (see TrajectoryColumns); others use PreferredUnits
+        :return: the trajectory stored by column
+        """
+        columns = TrajectoryColumns.from_rows(self.trajectory, units)
+        columns.termination_reason = self.termination_reason
+        return columns
+
+    def dataframe(self, formatted: bool = False, localization: Localization = None) -> 'DataFrame':
         """
         :param formatted: False for values as floats; True for strings with prefer_units
+        :param localization: Number and unit label formatting of formatted strings (default: Locales.English)
         :return: the trajectory table as a DataFrame
         """
         if pd is None:
             raise ImportError("Install pandas to get trajectory as dataframe")
         col_names = list(TrajectoryData._fields)
         if formatted:
-            trajectory = [p.formatted() for p in self]
+            trajectory = [p.formatted(localization) for p in self]
         else:
             trajectory = [p.in_def_units() for p in self]
         return pd.DataFrame(trajectory, columns=col_names)
@@ -0,0 +1,116 @@
+"""Truing: adjusting the BC or muzzle velocity of ammunition to reproduce observed drops (DOPE)"""
+import math
+from dataclasses import replace
+from typing import Iterable, NamedTuple
+
+from .conditions import Shot
+from .interface import Calculator
+from .munition import Ammo
+from .unit import Angular, Distance, PreferredUnits
+
+__all__ = ('TRUING_PARAMETERS', 'DropObservation', 'TruingResult', 'true_ammo')
+
+TRUING_PARAMETERS = ('bc', 'velocity')
+cTruingMaxIterations = 20
+cTruingTolerance = 1e-6  # Relative change of the factor at which the fit is done
+cTruingStep = 1e-3  # Relative change of the factor for the numerical derivative of the drops
+
+
+class DropObservation(NamedTuple):
+    """
+    Attributes:
+        distance (Distance): distance of the target
+        elevation (Angular): elevation adjustment that hit, in the sign convention of TrajectoryData.drop_adj
+    """
+    distance: Distance
+    elevation: Angular
+
+
+class TruingResult(NamedTuple):
+    """
+    Attributes:
+        ammo (Ammo): ammunition with the trued BC or muzzle velocity
+        shot (Shot): shot with the trued ammo (re-zeroed if a zero distance was given)
+        parameter (str): which of TRUING_PARAMETERS was adjusted
+        factor (float): trued value over the original value of the parameter
+        residuals (list[Angular]): calculated drop_adj - observed elevation, for each observation
+        rms (Angular): root mean square of residuals
+    """
+    ammo: Ammo
+    shot: Shot
+    parameter: str
+    factor: float
+    residuals: list[Angular]
+    rms: Angular
+
+
+def _scaled(ammo: Ammo, parameter: str, factor: float) -> Ammo:
+    """:return: Copy of ammo with the BC or the muzzle velocity multiplied by factor"""
+    if parameter == 'velocity':
+        return ammo.with_mv(ammo.mv.units(ammo.mv.unit_value * factor))
+    return ammo.with_bc(ammo.dm.BC * factor)
+
+
+def _observation(observation) -> DropObservation:
+    """Accepts DropObservation, (distance, elevation) or DopeImpact"""
+    if hasattr(observation, 'elevation'):
+        distance, elevation = observation.distance, observation.elevation
+    else:
+        distance, elevation = observation
+    return DropObservation(PreferredUnits.distance(distance), PreferredUnits.adjustment(elevation))
+
+
+def true_ammo(calc: Calculator, shot: Shot, observations: Iterable, parameter: str = 'bc',
+              zero_distance: [float, Distance] = None) -> TruingResult:
+    """Least-squares fit of one factor on the BC or muzzle velocity of shot.ammo to observed elevations
+    :param calc: Calculator to solve the trajectories
+    :param shot: shot in the conditions of the observations
+    :param observations: DropObservation, (distance, elevation) tuples or DopeImpact of DopeBook.impacts()
+    :param parameter: 'bc' or 'velocity'
+    :param zero_distance: distance at which the weapon was zeroed: each candidate is zeroed there again,
+        as the zero was confirmed by shooting.  None keeps shot.weapon.zero_elevation.
+    """
+    if parameter not in TRUING_PARAMETERS:
+        raise ValueError(f"Unknown parameter {parameter!r}, use one of {TRUING_PARAMETERS}")
+    observations = sorted((_observation(o) for o in observations), key=lambda o: o.distance.raw_value)
+    if not observations:
+        raise ValueError("At least one observation is required")
+    distances = [o.distance for o in observations]
+    observed = [o.elevation >> Angular.Radian for o in observations]
+
+    def candidate(factor: float) -> Shot:
+        trial = replace(shot, weapon=replace(shot.weapon), ammo=_scaled(shot.ammo, parameter, factor))
+        if zero_distance is not None:
+            calc.set_weapon_zero(trial, zero_distance)
+        return trial
+
+    def residuals(trial: Shot) -> list[float]:
+        result = calc.fire_at_ranges(trial, distances)
+        rows = result.trajectory
+        if len(rows) < len(distances):
+            raise result.range_error(distances[len(rows)])
+        return [(row.drop_adj >> Angular.Radian) - o for row, o in zip(rows, observed)]
+
+    factor = 1.0
+    trial = candidate(factor)
+    errors = residuals(trial)
+    for _ in range(cTruingMaxIterations):
+        h = factor * cTruingStep
+        slopes = [(e1 - e0) / h for e0, e1 in zip(errors, residuals(candidate(factor + h)))]
+        curvature = sum(s * s for s in slopes)
+        if not curvature:
+            raise ArithmeticError(f"Observed drops don't depend on {parameter}")
+        step = -sum(e * s for e, s in zip(errors, slopes)) / curvature
+        # Keep the factor positive and the step within a factor of two
+        factor = min(max(factor + step, factor / 2), factor * 2)
+        trial = candidate(factor)
+        errors = residuals(trial)
+        if math.fabs(step) < cTruingTolerance * factor:
+            break
+    else:
+        raise ArithmeticError(f"Truing did not converge in {cTruingMaxIterations} iterations")
+
+    rms = math.sqrt(sum(e * e for e in errors) / len(errors))
+    return TruingResult(trial.ammo, trial, parameter, factor,
+                        [Angular.Radian(e) << PreferredUnits.adjustment for e in errors],
+                        Angular.Radian(rms) << PreferredUnits.adjustment)
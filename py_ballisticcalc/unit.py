@@ -2,6 +2,8 @@
 Useful types for prefer_units of measurement conversion for ballistics calculations
 """
 
+import datetime
+import json
 import sys
 from abc import ABC, abstractmethod
 from dataclasses import dataclass, MISSING, Field
@@ -17,8 +19,9 @@ __all__ = ('Unit', 'UnitType',
            'UnitProps', 'UnitAliases',
            'UnitPropsDict', 'Distance',
            'Velocity', 'Angular', 'Temperature', 'Pressure',
-           'Energy', 'Weight', 'Dimension', 'PreferredUnits',
-           'UnitAliasError', 'UnitTypeError', 'UnitConversionError')
+           'Energy', 'Time', 'Density', 'Acceleration', 'Weight', 'Dimension', 'PreferredUnits',
+           'UnitAliasError', 'UnitTypeError', 'UnitConversionError',
+           'UnitJSONEncoder', 'unit_json_hook', 'IMPERIAL_UNITS', 'METRIC_UNITS')
 
 UnitType = TypeVar('UnitType', bound='Unit')
 AbstractUnitType = TypeVar('AbstractUnitType', bound='AbstractUnit')
@@ -62,14 +65,24 @@ class Unit(IntEnum):
     Kilometer = 18
     Line = 19
 
+    Second = 20
+    Millisecond = 21
+    Minute = 22
+
     FootPound = 30
     Joule = 31
+    Kilojoule = 32
+    Megajoule = 33
+    KgfMeter = 34
 
     MmHg = 40
     InHg = 41
     Bar = 42
     hPa = 43
     PSI = 44
+    Pascal = 45
+    kPa = 46
+    Millibar = 47
 
     Fahrenheit = 50
     Celsius = 51
@@ -89,6 +102,14 @@ class Unit(IntEnum):
     Kilogram = 74
     Newton = 75
 
+    KgPerCubicMeter = 80
+    LbPerCubicFoot = 81
+    GramPerLiter = 82
+
+    FootPerSecondSquared = 90
+    MeterPerSecondSquared = 91
+    StandardGravity = 92
+
     @property
     def key(self) -> str:
         """
@@ -129,6 +150,8 @@ class Unit(IntEnum):
             obj = Angular(value, self)
         elif 10 <= self < 20:
             obj = Distance(value, self)
+        elif 20 <= self < 30:
+            obj = Time(value, self)
         elif 30 <= self < 40:
             obj = Energy(value, self)
         elif 40 <= self < 50:
@@ -139,6 +162,10 @@ class Unit(IntEnum):
             obj = Velocity(value, self)
         elif 70 <= self < 80:
             obj = Weight(value, self)
+        elif 80 <= self < 90:
+            obj = Density(value, self)
+        elif 90 <= self < 100:
+            obj = Acceleration(value, self)
         else:
             raise UnitTypeError(f"{self} Unit is not supported")
         return obj
@@ -226,14 +253,24 @@ UnitPropsDict = {
     Unit.Kilometer: UnitProps("kilometer", 3, "km"),
     Unit.Line: UnitProps("line", 3, "ln"),
 
+    Unit.Second: UnitProps('second', 3, 's'),
+    Unit.Millisecond: UnitProps('millisecond', 1, 'ms'),
+    Unit.Minute: UnitProps('minute', 4, 'min'),
+
     Unit.FootPound: UnitProps('foot-pound', 0, 'ft·lb'),
     Unit.Joule: UnitProps('joule', 0, 'J'),
+    Unit.Kilojoule: UnitProps('kilojoule', 3, 'kJ'),
+    Unit.Megajoule: UnitProps('megajoule', 6, 'MJ'),
+    Unit.KgfMeter: UnitProps('kgf-meter', 1, 'kgf·m'),
 
     Unit.MmHg: UnitProps('mmHg', 0, 'mmHg'),
     Unit.InHg: UnitProps('inHg', 6, 'inHg'),
     Unit.Bar: UnitProps('bar', 2, 'bar'),
     Unit.hPa: UnitProps('hPa', 4, 'hPa'),
     Unit.PSI: UnitProps('psi', 4, 'psi'),
+    Unit.Pascal: UnitProps('Pa', 0, 'Pa'),
+    Unit.kPa: UnitProps('kPa', 2, 'kPa'),
+    Unit.Millibar: UnitProps('millibar', 1, 'mbar'),
 
     Unit.Fahrenheit: UnitProps('fahrenheit', 1, '°F'),
     Unit.Celsius: UnitProps('celsius', 1, '°C'),
@@ -252,6 +289,14 @@ UnitPropsDict = {
     Unit.Pound: UnitProps('pound', 0, 'lb'),
     Unit.Kilogram: UnitProps('kilogram', 3, 'kg'),
     Unit.Newton: UnitProps('newton', 3, 'N'),
+
+    Unit.KgPerCubicMeter: UnitProps('kg/m3', 4, 'kg/m³'),
+    Unit.LbPerCubicFoot: UnitProps('lb/ft3', 5, 'lb/ft³'),
+    Unit.GramPerLiter: UnitProps('g/l', 4, 'g/l'),
+
+    Unit.FootPerSecondSquared: UnitProps('ft/s2', 3, 'ft/s²'),
+    Unit.MeterPerSecondSquared: UnitProps('m/s2', 3, 'm/s²'),
+    Unit.StandardGravity: UnitProps('g0', 4, 'g₀'),
 }
 
 UnitAliases = {
@@ -276,15 +321,25 @@ UnitAliases = {
     ('kilometer', 'km'): Unit.Kilometer,
     ('line', 'ln', 'liniа'): Unit.Line,
 
+    ('second', 's', 'sec'): Unit.Second,
+    ('millisecond', 'ms', 'msec'): Unit.Millisecond,
+    ('minute', 'min'): Unit.Minute,
+
     ('footpound', 'foot-pound', 'ft⋅lbf', 'ft⋅lbf', 'ft⋅lb',
      'foot*pound', 'ft*lbf', 'ft*lbf', 'ft*lb'): Unit.FootPound,
     ('joule', 'J'): Unit.Joule,
+    ('kilojoule', 'kJ'): Unit.Kilojoule,
+    ('megajoule', 'MJ'): Unit.Megajoule,
+    ('kgfmeter', 'kgf-meter', 'kgf⋅m', 'kgf·m', 'kgf*m', 'kgm'): Unit.KgfMeter,
 
     ('mmHg',): Unit.MmHg,
     ('inHg', '″Hg'): Unit.InHg,
     ('bar',): Unit.Bar,
     ('hectopascal', 'hPa'): Unit.hPa,
     ('psi', 'lbf/in2'): Unit.PSI,
+    ('pascal', 'Pa'): Unit.Pascal,
+    ('kilopascal', 'kPa'): Unit.kPa,
+    ('millibar', 'mbar', 'mb'): Unit.Millibar,
 
     ('fahrenheit', '°F', 'F', 'degF'): Unit.Fahrenheit,
     ('celsius', '°C', 'C', 'degC'): Unit.Celsius,
@@ -303,6 +358,14 @@ UnitAliases = {
     ('pound', 'lb'): Unit.Pound,
     ('kilogram', 'kilogramme', 'kg'): Unit.Kilogram,
     ('newton', 'N'): Unit.Kilogram,
+
+    ('kg/m3', 'kg/m³', 'kilogram/meter3', 'kgpm3'): Unit.KgPerCubicMeter,
+    ('lb/ft3', 'lb/ft³', 'pound/foot3', 'lbpft3'): Unit.LbPerCubicFoot,
+    ('g/l', 'gram/liter', 'g/liter'): Unit.GramPerLiter,
+
+    ('ft/s2', 'ft/s²', 'foot/second2', 'fps2'): Unit.FootPerSecondSquared,
+    ('m/s2', 'm/s²', 'meter/second2', 'mps2'): Unit.MeterPerSecondSquared,
+    ('g0', 'g₀', 'gn', 'standard gravity'): Unit.StandardGravity,
 }
 
 
@@ -426,6 +489,34 @@ class AbstractUnit:
         """
         return self._value
 
+    def to_dict(self) -> dict:
+        """:return: JSON-compatible form {'value': value in defined units, 'units': name of the units}"""
+        return {'value': self.unit_value, 'units': self.units.name}
+
+    @classmethod
+    def from_dict(cls, data: dict) -> AbstractUnitType:
+        """Creates unit instance from the form of to_dict()
+        :param data: {'value': number, 'units': Unit name (e.g. 'Yard'), alias (e.g. 'yard', 'yd')
+            or Unit value (e.g. 12)}
+        :return: instance of the class of the units, which must be cls unless cls is AbstractUnit
+        """
+        try:
+            value, name = data['value'], data['units']
+        except (KeyError, TypeError) as error:
+            raise ValueError(f"Expected {{'value': ..., 'units': ...}}, got {data!r}") from error
+        if isinstance(name, int) and not isinstance(name, bool):
+            units = Unit._value2member_map_.get(name)
+        elif isinstance(name, str):
+            units = Unit.__members__.get(name) or Unit.find_unit_by_alias(name.strip().lower(), UnitAliases)
+        else:
+            units = None
+        if units is None:
+            raise UnitAliasError(f"Unsupported unit {name!r}")
+        obj = units(value)
+        if cls is not AbstractUnit and not isinstance(obj, cls):
+            raise UnitTypeError(f"{cls.__name__} expected, got {obj.__class__.__name__} units {name!r}")
+        return obj
+
 
 class Distance(AbstractUnit):
     """Distance unit"""
@@ -506,6 +597,13 @@ class Pressure(AbstractUnit):
             result = value * 750.061683 / 1000
         elif units == Pressure.PSI:
             result = value * 51.714924102396
+        elif units == Pressure.Pascal:
+            result = value * 750.061683 / 100000
+        elif units == Pressure.kPa:
+            result = value * 750.061683 / 100
+        elif units == Pressure.Millibar:
+            # Millibar is the same as hectopascal
+            result = value * 750.061683 / 1000
         else:
             return super().to_raw(value, units)
         return result
@@ -521,6 +619,12 @@ class Pressure(AbstractUnit):
             result = value / 750.061683 * 1000
         elif units == Pressure.PSI:
             result = value / 51.714924102396
+        elif units == Pressure.Pascal:
+            result = value / 750.061683 * 100000
+        elif units == Pressure.kPa:
+            result = value / 750.061683 * 100
+        elif units == Pressure.Millibar:
+            result = value / 750.061683 * 1000
         else:
             return super().from_raw(value, units)
         return result
@@ -530,6 +634,9 @@ class Pressure(AbstractUnit):
     Bar = Unit.Bar
     hPa = Unit.hPa
     PSI = Unit.PSI
+    Pascal = Unit.Pascal
+    kPa = Unit.kPa
+    Millibar = Unit.Millibar
 
 
 class Weight(AbstractUnit):
@@ -718,6 +825,12 @@ class Energy(AbstractUnit):
             return value
         if units == Energy.Joule:
             return value * 0.737562149277
+        if units == Energy.Kilojoule:
+            return value * 737.562149277
+        if units == Energy.Megajoule:
+            return value * 737562.149277
+        if units == Energy.KgfMeter:
+            return value * 7.23301385121
         return super().to_raw(value, units)
 
     def from_raw(self, value: float, units: Unit):
@@ -725,10 +838,167 @@ class Energy(AbstractUnit):
             return value
         if units == Energy.Joule:
             return value / 0.737562149277
+        if units == Energy.Kilojoule:
+            return value / 737.562149277
+        if units == Energy.Megajoule:
+            return value / 737562.149277
+        if units == Energy.KgfMeter:
+            return value / 7.23301385121
         return super().from_raw(value, units)
 
     FootPound = Unit.FootPound
     Joule = Unit.Joule
+    Kilojoule = Unit.Kilojoule
+    Megajoule = Unit.Megajoule
+    KgfMeter = Unit.KgfMeter
+
+
+class Time(AbstractUnit):
+    """Time unit, e.g. time of flight"""
+
+    def to_raw(self, value: float, units: Unit):
+        if units == Time.Second:
+            return value
+        if units == Time.Millisecond:
+            return value / 1000
+        if units == Time.Minute:
+            return value * 60
+        return super().to_raw(value, units)
+
+    def from_raw(self, value: float, units: Unit):
+        if units == Time.Second:
+            return value
+        if units == Time.Millisecond:
+            return value * 1000
+        if units == Time.Minute:
+            return value / 60
+        return super().from_raw(value, units)
+
+    def timedelta(self) -> datetime.timedelta:
+        """:return: the time as datetime.timedelta"""
+        return datetime.timedelta(seconds=self._value)
+
+    def clock(self, accuracy: int = 2) -> str:
+        """
+        :param accuracy: digits of the fraction of seconds
+        :return: time as minutes:seconds, e.g. '0:01.35'
+        """
+        sign = '-' if self._value < 0 else ''
+        seconds = round(abs(self._value), accuracy)
+        minutes, seconds = divmod(seconds, 60)
+        width = accuracy + 3 if accuracy else 2
+        return f'{sign}{int(minutes)}:{seconds:0{width}.{accuracy}f}'
+
+    Second = Unit.Second
+    Millisecond = Unit.Millisecond
+    Minute = Unit.Minute
+
+
+class Density(AbstractUnit):
+    """Density unit, e.g. of air"""
+
+    def to_raw(self, value: float, units: Unit):
+        if units in (Density.KgPerCubicMeter, Density.GramPerLiter):
+            return value
+        if units == Density.LbPerCubicFoot:
+            return value * 16.0184634
+        return super().to_raw(value, units)
+
+    def from_raw(self, value: float, units: Unit):
+        if units in (Density.KgPerCubicMeter, Density.GramPerLiter):
+            return value
+        if units == Density.LbPerCubicFoot:
+            return value / 16.0184634
+        return super().from_raw(value, units)
+
+    KgPerCubicMeter = Unit.KgPerCubicMeter
+    LbPerCubicFoot = Unit.LbPerCubicFoot
+    GramPerLiter = Unit.GramPerLiter
+
+
+class Acceleration(AbstractUnit):
+    """Acceleration unit, e.g. of gravity or thrust"""
+
+    def to_raw(self, value: float, units: Unit):
+        if units == Acceleration.FootPerSecondSquared:
+            return value
+        if units == Acceleration.MeterPerSecondSquared:
+            return value / 0.3048
+        if units == Acceleration.StandardGravity:
+            return value * 9.80665 / 0.3048
+        return super().to_raw(value, units)
+
+    def from_raw(self, value: float, units: Unit):
+        if units == Acceleration.FootPerSecondSquared:
+            return value
+        if units == Acceleration.MeterPerSecondSquared:
+            return value * 0.3048
+        if units == Acceleration.StandardGravity:
+            return value * 0.3048 / 9.80665
+        return super().from_raw(value, units)
+
+    FootPerSecondSquared = Unit.FootPerSecondSquared
+    MeterPerSecondSquared = Unit.MeterPerSecondSquared
+    StandardGravity = Unit.StandardGravity
+
+
+class UnitJSONEncoder(json.JSONEncoder):
+    """JSON encoder writing units as AbstractUnit.to_dict(), e.g. json.dumps(data, cls=UnitJSONEncoder)"""
+
+    def default(self, o):
+        if isinstance(o, AbstractUnit):
+            return o.to_dict()
+        return super().default(o)
+
+
+def unit_json_hook(data: dict):
+    """json.loads() object_hook reading objects of AbstractUnit.to_dict() as units,
+        e.g. json.loads(text, object_hook=unit_json_hook)"""
+    if data.keys() == {'value', 'units'}:
+        return AbstractUnit.from_dict(data)
+    return data
+
+
+# Preference profiles for PreferredUnits.set()
+IMPERIAL_UNITS = {
+    'angular': Unit.Degree,
+    'distance': Unit.Yard,
+    'velocity': Unit.FPS,
+    'pressure': Unit.InHg,
+    'temperature': Unit.Fahrenheit,
+    'diameter': Unit.Inch,
+    'length': Unit.Inch,
+    'weight': Unit.Grain,
+    'adjustment': Unit.Mil,
+    'drop': Unit.Inch,
+    'energy': Unit.FootPound,
+    'ogw': Unit.Pound,
+    'sight_height': Unit.Inch,
+    'target_height': Unit.Inch,
+    'twist': Unit.Inch,
+    'density': Unit.LbPerCubicFoot,
+    'acceleration': Unit.FootPerSecondSquared,
+}
+
+METRIC_UNITS = {
+    'angular': Unit.Degree,
+    'distance': Unit.Meter,
+    'velocity': Unit.MPS,
+    'pressure': Unit.hPa,
+    'temperature': Unit.Celsius,
+    'diameter': Unit.Millimeter,
+    'length': Unit.Millimeter,
+    'weight': Unit.Gram,
+    'adjustment': Unit.Mil,
+    'drop': Unit.Centimeter,
+    'energy': Unit.Joule,
+    'ogw': Unit.Kilogram,
+    'sight_height': Unit.Centimeter,
+    'target_height': Unit.Centimeter,
+    'twist': Unit.Centimeter,
+    'density': Unit.KgPerCubicMeter,
+    'acceleration': Unit.MeterPerSecondSquared,
+}
 
 
 class PreferredUnitsMeta(type):
@@ -758,6 +1028,8 @@ class PreferredUnits(metaclass=PreferredUnitsMeta):  # pylint: disable=too-many-
     sight_height: Unit = Unit.Inch
     target_height: Unit = Unit.Inch
     twist: Unit = Unit.Inch
+    density: Unit = Unit.LbPerCubicFoot
+    acceleration: Unit = Unit.FootPerSecondSquared
 
     @dataclass
     class Mixin(ABC):  # pylint: disable=too-few-public-methods
@@ -791,23 +1063,26 @@ class PreferredUnits(metaclass=PreferredUnitsMeta):  # pylint: disable=too-many-
             super().__setattr__(key, value)
 
     @classmethod
-    def defaults(self):
+    def defaults(cls):
         """resets preferred units to defaults"""
-        self.angular = Unit.Degree
-        self.distance = Unit.Yard
-        self.velocity = Unit.FPS
-        self.pressure = Unit.InHg
-        self.temperature = Unit.Fahrenheit
-        self.diameter = Unit.Inch
-        self.length = Unit.Inch
-        self.weight = Unit.Grain
-        self.adjustment = Unit.Mil
-        self.drop = Unit.Inch
-        self.energy = Unit.FootPound
-        self.ogw = Unit.Pound
-        self.sight_height = Unit.Inch
-        self.target_height = Unit.Inch
-        self.twist = Unit.Inch
+        cls.use_imperial()
+
+    @classmethod
+    def use_imperial(cls):
+        """sets preferred units to IMPERIAL_UNITS"""
+        for attribute, units in IMPERIAL_UNITS.items():
+            setattr(cls, attribute, units)
+
+    @classmethod
+    def use_metric(cls):
+        """sets preferred units to METRIC_UNITS"""
+        for attribute, units in METRIC_UNITS.items():
+            setattr(cls, attribute, units)
+
+    @classmethod
+    def current(cls) -> dict[str, Unit]:
+        """:return: current preferred units by attribute, to restore later with set()"""
+        return {attribute: getattr(cls, attribute) for attribute in getattr(cls, '__dataclass_fields__')}
 
     @classmethod
     def set(cls, **kwargs):
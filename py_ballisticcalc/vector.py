@@ -0,0 +1,114 @@
+# pylint: disable=missing-function-docstring
+"""Three-dimensional vector algebra used by the trajectory calculation"""
+
+import math
+from dataclasses import dataclass
+
+__all__ = ('Vector',)
+
+
+@dataclass
+class Vector:
+    """Three-dimensional vector used by the trajectory calculation:
+    x = downrange distance, y = height, z = windage
+    """
+    x: float
+    y: float
+    z: float
+
+    def magnitude(self):
+        return math.sqrt(self.x * self.x + self.y * self.y + self.z * self.z)
+
+    def mul_by_const(self, a: float):
+        return Vector(self.x * a, self.y * a, self.z * a)
+
+    def mul_by_vector(self, b: 'Vector'):
+        return self.x * b.x + self.y * b.y + self.z * b.z
+
+    def dot(self, b: 'Vector') -> float:
+        """:return: scalar (dot) product of self and b"""
+        return self.mul_by_vector(b)
+
+    def cross(self, b: 'Vector') -> 'Vector':
+        """:return: vector (cross) product self × b"""
+        return Vector(self.y * b.z - self.z * b.y,
+                      self.z * b.x - self.x * b.z,
+                      self.x * b.y - self.y * b.x)
+
+    def angle_to(self, b: 'Vector') -> float:
+        """:return: angle between self and b in radians, in range [0, pi]"""
+        m = self.magnitude() * b.magnitude()
+        if math.fabs(m) < 1e-10:
+            return 0.0
+        # Clamp to guard acos against rounding just outside [-1, 1]
+        return math.acos(max(-1.0, min(1.0, self.dot(b) / m)))
+
+    def project_onto(self, b: 'Vector') -> 'Vector':
+        """:return: component of self parallel to b"""
+        m2 = b.dot(b)
+        if math.fabs(m2) < 1e-10:
+            return Vector(.0, .0, .0)
+        return b.mul_by_const(self.dot(b) / m2)
+
+    def add(self, b: 'Vector'):
+        return Vector(self.x + b.x, self.y + b.y, self.z + b.z)
+
+    def subtract(self, b: 'Vector'):
+        return Vector(self.x - b.x, self.y - b.y, self.z - b.z)
+
+    def negate(self):
+        return Vector(-self.x, -self.y, -self.z)
+
+    def normalize(self):
+        m = self.magnitude()
+        if math.fabs(m) < 1e-10:
+            return Vector(self.x, self.y, self.z)
+        return self.mul_by_const(1.0 / m)
+
+    def add_in_place(self, b: 'Vector') -> 'Vector':
+        """self += b without allocating a new Vector"""
+        self.x += b.x
+        self.y += b.y
+        self.z += b.z
+        return self
+
+    def mul_add_in_place(self, b: 'Vector', a: float) -> 'Vector':
+        """self += b * a without allocating a new Vector"""
+        self.x += b.x * a
+        self.y += b.y * a
+        self.z += b.z * a
+        return self
+
+    def __add__(self, other: 'Vector'):
+        return self.add(other)
+
+    def __radd__(self, other: 'Vector'):
+        return self.add(other)
+
+    def __iadd__(self, other: 'Vector'):
+        return self.add(other)
+
+    def __sub__(self, other: 'Vector'):
+        return self.subtract(other)
+
+    def __rsub__(self, other: 'Vector'):
+        return self.subtract(other)
+
+    def __isub__(self, other: 'Vector'):
+        return self.subtract(other)
+
+    def __mul__(self, other: [int, float, 'Vector']):
+        if isinstance(other, (int, float)):
+            return self.mul_by_const(other)
+        if isinstance(other, Vector):
+            return self.mul_by_vector(other)
+        raise TypeError(other)
+
+    def __rmul__(self, other: [int, float, 'Vector']):
+        return self.__mul__(other)
+
+    def __imul__(self, other):
+        return self.__mul__(other)
+
+    def __neg__(self):
+        return self.negate()
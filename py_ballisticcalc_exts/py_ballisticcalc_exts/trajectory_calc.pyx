@@ -1,7 +1,11 @@
-from libc.math cimport sqrt, fabs, pow, sin, cos, tan, atan, floor
+from libc.math cimport sqrt, fabs, pow, sin, cos, tan, atan, floor, exp, INFINITY
 cimport cython
 
 from py_ballisticcalc.conditions import Shot, Wind
+from py_ballisticcalc.config import CalculatorConfig, AdjustmentReference, SpinDriftModel
+from py_ballisticcalc.hooks import StepState
+from py_ballisticcalc.logger import logger
+from py_ballisticcalc.vector import Vector as PyVector
 from py_ballisticcalc.munition import Ammo
 from py_ballisticcalc.trajectory_data import TrajectoryData
 from py_ballisticcalc.unit import *
@@ -12,6 +16,8 @@ __all__ = (
     'get_global_use_powder_sensitivity',
     'set_global_max_calc_step_size',
     'set_global_use_powder_sensitivity',
+    'get_global_gravity',
+    'set_global_gravity',
     'reset_globals'
 )
 
@@ -20,9 +26,16 @@ cdef double cMinimumVelocity = 50.0
 cdef double cMaximumDrop = -15000
 cdef int cMaxIterations = 20
 cdef double cGravityConstant = -32.17405
+cdef double cStandardDensity = 0.076474  # lb/ft^3
+cdef double cRollDampingCoefficient = 0.005
+cdef double cMarginalStability = 1.4
+cdef double cLiftToOverturningRatio = 1.0
+cdef double cAdaptiveStepRatio = 16
+cdef double cEarthRadius = 20902231.0  # Mean radius of the Earth in feet, 6371 km
 
 cdef int _globalUsePowderSensitivity = False
 cdef object _globalMaxCalcStepSize = Distance.Foot(0.5)
+cdef object _globalGravity = Acceleration.FootPerSecondSquared(-cGravityConstant)
 
 def get_global_max_calc_step_size() -> Distance:
     return _globalMaxCalcStepSize
@@ -39,6 +52,17 @@ def set_global_max_calc_step_size(value: [object, float]) -> None:
     _globalMaxCalcStepSize = PreferredUnits.distance(value)
 
 
+def get_global_gravity() -> Acceleration:
+    return _globalGravity
+
+
+def set_global_gravity(value: [object, float]) -> None:
+    global _globalGravity
+    if (_value := PreferredUnits.acceleration(value)).raw_value < 0:
+        raise ValueError("_globalGravity have to be >= 0")
+    _globalGravity = _value
+
+
 def set_global_use_powder_sensitivity(value: bool) -> None:
     global _globalUsePowderSensitivity
     if not isinstance(value, bool):
@@ -47,9 +71,10 @@ def set_global_use_powder_sensitivity(value: bool) -> None:
 
 
 def reset_globals() -> None:
-    global _globalUsePowderSensitivity, _globalMaxCalcStepSize
+    global _globalUsePowderSensitivity, _globalMaxCalcStepSize, _globalGravity
     _globalUsePowderSensitivity = False
     _globalMaxCalcStepSize = Distance.Foot(0.5)
+    _globalGravity = Acceleration.FootPerSecondSquared(-cGravityConstant)
 
 
 cdef struct CurvePoint:
@@ -62,8 +87,10 @@ cdef enum CTrajFlag:
     MACH = 4
     RANGE = 8
     DANGER = 16
+    UNSTABLE = 32
+    APEX = 64
     ZERO = ZERO_UP | ZERO_DOWN
-    ALL = RANGE | ZERO_UP | ZERO_DOWN | MACH | DANGER
+    ALL = RANGE | ZERO_UP | ZERO_DOWN | MACH | DANGER | APEX
 
 
 cdef class Vector:
@@ -85,6 +112,14 @@ cdef class Vector:
     cdef double mul_by_vector(Vector self, Vector b):
         return self.x * b.x + self.y * b.y + self.z * b.z
 
+    cdef double dot(Vector self, Vector b):
+        return self.x * b.x + self.y * b.y + self.z * b.z
+
+    cdef Vector cross(Vector self, Vector b):
+        return Vector(self.y * b.z - self.z * b.y,
+                      self.z * b.x - self.x * b.z,
+                      self.x * b.y - self.y * b.x)
+
     cdef Vector add(Vector self, Vector b):
         return Vector(self.x + b.x, self.y + b.y, self.z + b.z)
 
@@ -100,6 +135,18 @@ cdef class Vector:
             return Vector(self.x, self.y, self.z)
         return self.mul_by_const(1.0 / m)
 
+    cdef Vector add_in_place(Vector self, Vector b):
+        self.x += b.x
+        self.y += b.y
+        self.z += b.z
+        return self
+
+    cdef Vector mul_add_in_place(Vector self, Vector b, double a):
+        self.x += b.x * a
+        self.y += b.y * a
+        self.z += b.z * a
+        return self
+
     def __add__(Vector self, Vector other):
         return self.add(other)
 
@@ -137,9 +184,15 @@ cdef class Vector:
 cdef class TrajectoryCalc:
     cdef:
         object ammo
+        public object hooks
+        public object config
+        public object termination_reason
         double _bc
         list _table_data
         list _curve
+        int _curve_index
+        tuple _drag0
+        list _phases
         Vector gravity_vector
         double look_angle
         double twist
@@ -152,22 +205,50 @@ cdef class TrajectoryCalc:
         double cant_cosine
         double cant_sine
         double alt0
+        object atmo_model
         double calc_step
+        double step_tolerance
+        double min_velocity
+        double max_drop
+        double min_altitude
+        double max_time
+        double min_energy
+        double min_energy_velocity
+        double zero_finding_accuracy
+        int max_iterations
+        int use_powder_sensitivity
+        int use_spin_drift
+        int use_yaw_of_repose
+        int extreme_range
+        object adjustment_reference
         double muzzle_velocity
-        double stability_coefficient
-
-    def __init__(self, ammo: Ammo):
+        readonly double stability_coefficient
+        double density_factor0
+        double spin_rate0
+        double spin_decay
+        double spin_lift
+        double degrade_stability
+        double degrade_mach
+        double degrade_drag_factor
+        double degrade_dispersion
+
+    def __init__(self, ammo: Ammo, hooks: object = None, config: CalculatorConfig = None):
         self.ammo = ammo
-        self._bc = self.ammo.dm.BC
+        self.hooks = hooks
+        self.config = config
+        self._bc = self.ammo.dm.standard_bc
         self._table_data = ammo.dm.drag_table
         self._curve = calculate_curve(self._table_data)
-        self.gravity_vector = Vector(.0, cGravityConstant, .0)
+        self._curve_index = -1
+        self.termination_reason = None
+        self._drag0 = (self._bc, self._table_data, self._curve)
+        self._phases = []
 
-    def zero_angle(self, shot_info: Shot, distance: Distance):
-        return self._zero_angle(shot_info, distance)
+    def zero_angle(self, shot_info: Shot, distance: Distance, initial_elevation: Angular = None):
+        return self._zero_angle(shot_info, distance, initial_elevation)
 
     def trajectory(self, shot_info: Shot, max_range: Distance, dist_step: Distance,
-                   extra_data: bool = False):
+                   extra_data: bool = False, out: object = None):
         cdef:
             # object atmo = shot_info.atmo
             # list winds = shot_info.winds
@@ -179,10 +260,93 @@ cdef class TrajectoryCalc:
             dist_step = Distance.Foot(0.2)
             filter_flags = CTrajFlag.ALL
 
-        self._init_trajectory(shot_info)            
-        return self._trajectory(shot_info, max_range >> Distance.Foot, dist_step >> Distance.Foot, filter_flags)
+        self._init_trajectory(shot_info)
+        if filter_flags == CTrajFlag.RANGE:
+            # Interpolate rows onto exact multiples of dist_step rather than the first step past each
+            step = dist_step >> Distance.Foot
+            distances = [i * step for i in range(int((max_range >> Distance.Foot) / step) + 1)]
+            return self._trajectory(shot_info, distances[-1], step, filter_flags, out, distances)
+        return self._trajectory(shot_info, max_range >> Distance.Foot, dist_step >> Distance.Foot,
+                                filter_flags, out)
+
+    def trajectory_at_ranges(self, shot_info: Shot, distances: list, out: object = None):
+        cdef list feet = sorted(d >> Distance.Foot for d in distances)
+        if not feet:
+            raise ValueError("At least one distance is required")
+        if feet[0] < 0:
+            raise ValueError("Distances have to be >= 0")
+        self._init_trajectory(shot_info)
+        return self._trajectory(shot_info, feet[-1], feet[-1], CTrajFlag.RANGE, out, feet)
+
+    def trajectory_terminal(self, shot_info: Shot, distance: Distance, bint zero_crossings = False,
+                            out: object = None):
+        cdef double feet = distance >> Distance.Foot
+        cdef int filter_flags = CTrajFlag.RANGE
+        if feet < 0:
+            raise ValueError("Distance has to be >= 0")
+        if zero_crossings:
+            filter_flags |= CTrajFlag.ZERO
+        self._init_trajectory(shot_info)
+        return self._trajectory(shot_info, feet, feet, filter_flags, out, [feet])
+
+    def trajectory_at_times(self, shot_info: Shot, times: list, out: object = None):
+        cdef list seconds = sorted(times)
+        cdef double speed
+        if not seconds:
+            raise ValueError("At least one time is required")
+        if seconds[0] < 0:
+            raise ValueError("Times have to be >= 0")
+        self._init_trajectory(shot_info)
+        speed = self.muzzle_velocity + sum(phase[6] * phase[7] for phase in self._phases)
+        return self._trajectory(shot_info, speed * seconds[-1], speed * seconds[-1], CTrajFlag.RANGE, out,
+                                None, seconds)
+
+    cdef _init_config(self):
+        cdef object config = self.config
+        if config is None:
+            config = CalculatorConfig(max_calc_step_size=_globalMaxCalcStepSize,
+                                      gravity=_globalGravity,
+                                      use_powder_sensitivity=bool(_globalUsePowderSensitivity))
+        self.calc_step = (config.max_calc_step_size >> Distance.Foot) / 2.0
+        self.step_tolerance = 0 if config.step_tolerance is None else config.step_tolerance >> Distance.Foot
+        self.gravity_vector = Vector(.0, -(config.gravity >> Acceleration.FootPerSecondSquared), .0)
+        self.extreme_range = config.extreme_range
+        self.min_velocity = config.minimum_velocity >> Velocity.FPS
+        self.max_drop = config.maximum_drop >> Distance.Foot
+        if config.minimum_altitude is None:
+            self.min_altitude = -INFINITY
+        else:
+            self.min_altitude = config.minimum_altitude >> Distance.Foot
+        self.min_energy = 0 if config.minimum_energy is None else config.minimum_energy >> Energy.FootPound
+        self.max_time = INFINITY if config.maximum_time is None else config.maximum_time >> Time.Second
+        self.zero_finding_accuracy = config.zero_finding_accuracy >> Distance.Foot
+        self.max_iterations = config.max_iterations
+        self.use_powder_sensitivity = config.use_powder_sensitivity
+        self.use_spin_drift = config.use_spin_drift and config.spin_drift_model == SpinDriftModel.LITZ
+        self.use_yaw_of_repose = config.use_spin_drift and config.spin_drift_model == SpinDriftModel.YAW_OF_REPOSE
+        self.adjustment_reference = config.adjustment_reference
+        degradation = config.transonic_degradation
+        if degradation is None:
+            self.degrade_stability = 0
+            self.degrade_mach = 0
+            self.degrade_drag_factor = 1
+            self.degrade_dispersion = 0
+        else:
+            self.degrade_stability = degradation.stability_threshold
+            self.degrade_mach = degradation.mach_threshold
+            self.degrade_drag_factor = 1 + degradation.drag_increase
+            self.degrade_dispersion = tan(degradation.dispersion >> Angular.Radian)
+
+    cdef tuple _get_adjustment_reference(self):
+        if self.adjustment_reference == AdjustmentReference.BORE:
+            return (-self.cant_cosine * self.sight_height, -self.cant_sine * self.sight_height,
+                    self.barrel_elevation, self.barrel_azimuth)
+        if self.adjustment_reference == AdjustmentReference.HORIZONTAL:
+            return (.0, .0, .0, .0)
+        return (.0, .0, self.look_angle, .0)
 
     cdef _init_trajectory(self, shot_info: Shot):
+        self._init_config()
         self.look_angle = shot_info.look_angle >> Angular.Radian
         self.twist = shot_info.weapon.twist >> Distance.Inch
         self.length = shot_info.ammo.dm.length >> Distance.Inch
@@ -194,72 +358,146 @@ cdef class TrajectoryCalc:
         self.cant_cosine = cos(shot_info.cant_angle >> Angular.Radian)
         self.cant_sine = sin(shot_info.cant_angle >> Angular.Radian)
         self.alt0 = shot_info.atmo.altitude >> Distance.Foot
-        self.calc_step = get_calc_step()
-        if _globalUsePowderSensitivity:
+        self.atmo_model = shot_info.atmo_model or shot_info.atmo
+        if self.use_powder_sensitivity:
             self.muzzle_velocity = shot_info.ammo.get_velocity_for_temp(shot_info.atmo.temperature) >> Velocity.FPS
         else:
             self.muzzle_velocity = shot_info.ammo.mv >> Velocity.FPS
+        self.muzzle_velocity += shot_info.ammo.barrel_velocity_change(shot_info.weapon.barrel_length) >> Velocity.FPS
+        bc_reference = shot_info.ammo.dm.bc_reference
+        if bc_reference is not None and not bc_reference.in_band(Velocity.FPS(self.muzzle_velocity)):
+            logger.warning("Muzzle velocity %.0f fps is outside the velocity band of the BC measurement",
+                           self.muzzle_velocity)
         self.stability_coefficient = self.calc_stability_coefficient(shot_info.atmo)
-
-    cdef _zero_angle(TrajectoryCalc self, object shot_info, object distance):
+        self._phases = []
+        for phase in shot_info.ammo.phases:
+            dm = phase.dm
+            self._phases.append((
+                INFINITY if phase.start_time is None else phase.start_time,
+                INFINITY if phase.start_distance is None else phase.start_distance >> Distance.Foot,
+                None if dm is None else dm.standard_bc,
+                None if dm is None else dm.drag_table,
+                None if dm is None else calculate_curve(dm.drag_table),
+                None if dm is None or not dm.weight else dm.weight >> Weight.Grain,
+                phase.thrust >> Acceleration.FootPerSecondSquared,
+                phase.burn_time))
+        self.density_factor0 = self.atmo_model.get_density_factor_and_mach_for_altitude(self.alt0)[0]
+        self.spin_rate0 = self.muzzle_velocity * 12 / fabs(self.twist) if self.twist else 0
+        self.spin_decay = 0
+        if self.weight and self.diameter:
+            self.spin_decay = (10 * 3.141592653589793 * cStandardDensity * pow(self.diameter / 12, 2)
+                               * cRollDampingCoefficient / (16 * self.weight / 7000))
+        self.spin_lift = 0
+        if self.use_yaw_of_repose and self.twist and self.diameter:
+            self.spin_lift = ((1 if self.twist > 0 else -1) * cLiftToOverturningRatio * (self.diameter / 12)
+                              * 2 * 3.141592653589793 * -self.gravity_vector.y / 10)
+        # Velocity at which kinetic energy falls to min_energy
+        self.min_energy_velocity = 0
+        if self.min_energy > 0:
+            if self.weight <= 0:
+                raise ValueError("minimum_energy requires DragModel.weight")
+            self.min_energy_velocity = sqrt(self.min_energy * 450400 / self.weight)
+
+    cdef _zero_angle(TrajectoryCalc self, object shot_info, object distance, object initial_elevation):
         cdef:
             double zero_distance = cos(shot_info.look_angle >> Angular.Radian) * (distance >> Distance.Foot)
             double height_at_zero = sin(shot_info.look_angle >> Angular.Radian) * (distance >> Distance.Foot)
             double maximum_range = zero_distance
             int iterations_count = 0
-            double zero_finding_error = cZeroFindingAccuracy * 2
+            double zero_finding_error
 
             object t
             double height
 
         self._init_trajectory(shot_info)
+        zero_finding_error = self.zero_finding_accuracy * 2
         self.barrel_azimuth = 0.0
-        self.barrel_elevation = atan(height_at_zero / zero_distance)
+        if initial_elevation is None:
+            self.barrel_elevation = atan(height_at_zero / zero_distance)
+        else:
+            self.barrel_elevation = initial_elevation >> Angular.Radian
         self.twist = 0
+        self.spin_rate0 = 0
+        self.spin_lift = 0
+        self.degrade_stability = 0
         maximum_range -= 1.5*self.calc_step
 
         # x = horizontal distance down range, y = drop, z = windage
-        while zero_finding_error > cZeroFindingAccuracy and iterations_count < cMaxIterations:
-            t = self._trajectory(shot_info, maximum_range, zero_distance, CTrajFlag.NONE)[0]
+        while zero_finding_error > self.zero_finding_accuracy and iterations_count < self.max_iterations:
+            if self.step_tolerance:
+                t = self._trajectory(shot_info, zero_distance, zero_distance, CTrajFlag.RANGE,
+                                     distances=[zero_distance])[0]
+            else:
+                t = self._trajectory(shot_info, maximum_range, zero_distance, CTrajFlag.NONE)[0]
             height = t.height >> Distance.Foot
             zero_finding_error = fabs(height - height_at_zero)
-            if zero_finding_error > cZeroFindingAccuracy:
+            if zero_finding_error > self.zero_finding_accuracy:
                 self.barrel_elevation -= (height - height_at_zero) / zero_distance
             else:  # last barrel_elevation hit zero!
                 break
             iterations_count += 1
-        if zero_finding_error > cZeroFindingAccuracy:
+        if zero_finding_error > self.zero_finding_accuracy:
             raise Exception(f'Zero vertical error {zero_finding_error} feet, after {iterations_count} iterations.')
         return Angular.Radian(self.barrel_elevation)
 
     cdef _trajectory(TrajectoryCalc self, object shot_info,
-                     double maximum_range, double step, int filter_flags):
+                     double maximum_range, double step, int filter_flags, object ranges = None,
+                     list distances = None, list times = None):
         cdef:
             int _flag, seen_zero  # CTrajFlag
             double density_factor, mach, velocity, delta_time
-            list ranges = []
-            int ranges_length = int(maximum_range / step) + 1
+            double calc_step = self.calc_step, next_record
+            int ranges_length = int(maximum_range / step) + 1 if step else 1
             int current_item = 0
+            int current_distance = 0
+            tuple previous_state = None, current_state, start
+            double x, f
             double time = .0
             double previous_mach = .0
+            double previous_velocity_y = .0
             double drag = .0
+            double spin_rate = self.spin_rate0
+            double degrade_onset = -1.0
+            int next_phase = 0
+            double thrust = .0
+            double thrust_end = .0
 
             int len_winds = len(shot_info.winds)
             int current_wind = 0
-            double next_range_distance = .0
+            list records = distances if times is None else times
+            double next_range_distance = .0 if records is None else INFINITY
             double next_wind_range = Wind.MAX_DISTANCE_FEET
             double _max_wind_distance_feed = Wind.MAX_DISTANCE_FEET
 
             double reference_height
+            double height, earth_distance
+
+            object hooks = self.hooks
+            str termination_reason = 'maximum_range'
+            tuple adjustment_reference = self._get_adjustment_reference()
 
             Vector velocity_vector, velocity_adjusted
-            Vector range_vector, delta_range_vector, wind_vector
+            Vector acceleration = None, previous_acceleration, jerk
+            Vector range_vector, delta_range_vector, wind_vector, air_wind
+            Vector gravity_vector = self.gravity_vector, center_offset
+            list wind_layers
+            tuple layer
+
+        if ranges is None:
+            ranges = []
+
+        if self._phases:
+            self._reset_phases(shot_info)
 
         if len_winds < 1:
             wind_vector = Vector(.0, .0, .0)
         else:
             wind_vector = wind_to_vector(shot_info.winds[0])
             next_wind_range = shot_info.winds[0].until_distance >> Distance.Foot
+        # Winds aloft as (from_height, until_distance, wind_vector), highest first
+        wind_layers = sorted([(layer.from_height >> Distance.Foot, layer.until_distance >> Distance.Foot,
+                               wind_to_vector(layer)) for layer in shot_info.wind_layers],
+                             key=lambda layer: (-layer[0], layer[1]))
 
         velocity = self.muzzle_velocity
         # x: downrange distance, y: drop, z: windage
@@ -288,9 +526,53 @@ cdef class TrajectoryCalc:
                 else:
                     wind_vector = wind_to_vector(shot_info.winds[current_wind])
                     next_wind_range = shot_info.winds[current_wind].until_distance >> Distance.Foot
-
-            density_factor, mach = shot_info.atmo.get_density_factor_and_mach_for_altitude(
-                self.alt0 + range_vector.y)
+            air_wind = wind_vector
+            for layer in wind_layers:
+                if range_vector.y >= layer[0] and range_vector.x < layer[1]:
+                    air_wind = layer[2]
+                    break
+
+            if self.extreme_range:
+                # Gravity points to the center of the Earth, cEarthRadius below the shooter,
+                #   and air density follows the height above its curved surface
+                center_offset = Vector(range_vector.x, range_vector.y + cEarthRadius, range_vector.z)
+                earth_distance = center_offset.magnitude()
+                gravity_vector = center_offset.mul_by_const(self.gravity_vector.y * cEarthRadius * cEarthRadius
+                                                            / (earth_distance * earth_distance * earth_distance))
+                height = earth_distance - cEarthRadius
+            else:
+                height = range_vector.y
+            density_factor, mach = self.atmo_model.get_density_factor_and_mach_for_altitude(self.alt0 + height)
+
+            if hooks is not None and hooks.on_step is not None:
+                if hooks.on_step(create_step_state(time, range_vector, velocity_vector,
+                                                   velocity, mach, density_factor, drag)):
+                    termination_reason = 'cancelled'
+                    break
+
+            if (degrade_onset < 0 < self.degrade_stability and velocity / mach <= self.degrade_mach
+                    and self.stability_at(velocity, density_factor, spin_rate) < self.degrade_stability):
+                degrade_onset = range_vector.x
+
+            if records is not None:
+                current_state = (time, range_vector, velocity_vector,
+                                 velocity, mach, density_factor, drag, spin_rate)
+                start = previous_state or current_state
+                while current_distance < len(records) and (
+                        range_vector.x if times is None else time) >= records[current_distance]:
+                    x = records[current_distance]
+                    if times is not None:
+                        f = (x - start[0]) / (time - start[0]) if time > start[0] else 1.0
+                        x = start[1].x + f * (range_vector.x - start[1].x)
+                    ranges.append(self._interpolate_row(x, start, current_state, adjustment_reference, degrade_onset))
+                    if hooks is not None and hooks.on_record is not None:
+                        hooks.on_record(ranges[-1])
+                    current_distance += 1
+                if current_distance == len(records):
+                    break
+                previous_state = (time, Vector(range_vector.x, range_vector.y, range_vector.z),
+                                  Vector(velocity_vector.x, velocity_vector.y, velocity_vector.z),
+                                  velocity, mach, density_factor, drag, spin_rate)
 
             if filter_flags:
                 # Zero-crossing checks
@@ -313,6 +595,10 @@ cdef class TrajectoryCalc:
                 if velocity / mach <= 1 < previous_mach:  # better cython optimization
                     _flag |= CTrajFlag.MACH
 
+                # Apex check
+                if velocity_vector.y <= 0 < previous_velocity_y:
+                    _flag |= CTrajFlag.APEX
+
                 # Next range check
                 if range_vector.x >= next_range_distance:
                     _flag |= CTrajFlag.RANGE
@@ -324,29 +610,88 @@ cdef class TrajectoryCalc:
                     ranges.append(create_trajectory_row(
                         time, range_vector, velocity_vector,
                         velocity, mach, self.spin_drift(time), self.look_angle,
-                        density_factor, drag, self.weight, _flag
+                        density_factor, drag, self.weight, _flag, adjustment_reference,
+                        spin_rate, self.stability_at(velocity, density_factor, spin_rate),
+                        self.dispersion_at(range_vector.x, degrade_onset)
                     ))
+                    if hooks is not None and hooks.on_record is not None:
+                        hooks.on_record(ranges[-1])
                     if current_item == ranges_length:
                         break
 
             previous_mach = velocity / mach
+            previous_velocity_y = velocity_vector.y
+
+            #region Start projectile phases
+            while next_phase < len(self._phases) and (time >= self._phases[next_phase][0]
+                                                      or range_vector.x >= self._phases[next_phase][1]):
+                _, _, bc, table, curve, weight, phase_thrust, burn_time = self._phases[next_phase]
+                if bc is not None:
+                    self._bc, self._table_data, self._curve, self._curve_index = bc, table, curve, -1
+                if weight is not None:
+                    self.weight = weight
+                if phase_thrust:
+                    thrust, thrust_end = phase_thrust, time + burn_time
+                next_phase += 1
+            #endregion
 
             #region Ballistic calculation step
-            delta_time = self.calc_step / velocity_vector.x
-
             # using .subtract insstead of "/" better optimized by cython
-            velocity_adjusted = velocity_vector - wind_vector
+            velocity_adjusted = velocity_vector - air_wind
             velocity = velocity_adjusted.magnitude()
             drag = density_factor * velocity * self.drag_by_mach(velocity / mach)
-            velocity_vector -= (velocity_adjusted * drag - self.gravity_vector) * delta_time
-            delta_range_vector = Vector(self.calc_step,
-                                        velocity_vector.y * delta_time,
-                                        velocity_vector.z * delta_time)
-            range_vector += delta_range_vector
+            if degrade_onset >= 0:
+                drag *= self.degrade_drag_factor
+            if self.step_tolerance:
+                previous_acceleration = acceleration
+                acceleration = velocity_adjusted.mul_by_const(-drag).add(gravity_vector)
+                if previous_acceleration is None:
+                    jerk = Vector(.0, .0, .0)
+                else:
+                    jerk = acceleration.subtract(previous_acceleration).mul_by_const(1 / delta_time)
+                calc_step = self._adaptive_step(range_vector.x, velocity_vector.x, acceleration, jerk, maximum_range)
+                next_record = next_range_distance if distances is None else distances[current_distance]
+                if range_vector.x < next_record < range_vector.x + calc_step:
+                    calc_step = next_record - range_vector.x
+            if self.extreme_range:
+                delta_time = calc_step / velocity_vector.magnitude()
+            else:
+                delta_time = calc_step / velocity_vector.x
+            spin_rate *= exp(-self.spin_decay * density_factor * velocity * delta_time)
+            velocity_vector.mul_add_in_place(velocity_adjusted, -drag * delta_time)
+            velocity_vector.mul_add_in_place(gravity_vector, delta_time)
+            if self.spin_lift:
+                velocity_vector.z += self.spin_lift * spin_rate * velocity_vector.x / (velocity * velocity) * delta_time
+            if time < thrust_end:
+                velocity_vector.mul_add_in_place(velocity_vector, thrust * delta_time / velocity_vector.magnitude())
+            if self.extreme_range:
+                delta_range_vector = velocity_vector.mul_by_const(delta_time)
+            else:
+                delta_range_vector = Vector(calc_step,
+                                            velocity_vector.y * delta_time,
+                                            velocity_vector.z * delta_time)
+            range_vector.add_in_place(delta_range_vector)
             velocity = velocity_vector.magnitude()
-            time += delta_range_vector.magnitude() / velocity
+            if self.extreme_range:
+                time += delta_time
+            else:
+                time += delta_range_vector.magnitude() / velocity
 
-            if velocity < cMinimumVelocity or range_vector.y < cMaximumDrop:
+            if velocity < self.min_velocity:
+                termination_reason = 'minimum_velocity'
+                break
+            if range_vector.y < self.max_drop:
+                termination_reason = 'maximum_drop'
+                break
+            if self.alt0 + (earth_height(range_vector) if self.extreme_range else range_vector.y) \
+                    < self.min_altitude:
+                termination_reason = 'minimum_altitude'
+                break
+            if velocity < self.min_energy_velocity:
+                termination_reason = 'minimum_energy'
+                break
+            if time > self.max_time:
+                termination_reason = 'maximum_time'
                 break
             #endregion
         #endregion
@@ -355,9 +700,59 @@ cdef class TrajectoryCalc:
             ranges.append(create_trajectory_row(
                         time, range_vector, velocity_vector,
                         velocity, mach, self.spin_drift(time), self.look_angle,
-                        density_factor, drag, self.weight, _flag))
+                        density_factor, drag, self.weight, _flag, adjustment_reference,
+                        spin_rate, self.stability_at(velocity, density_factor, spin_rate),
+                        self.dispersion_at(range_vector.x, degrade_onset)))
+            if hooks is not None and hooks.on_record is not None:
+                hooks.on_record(ranges[-1])
+        if hooks is not None and hooks.on_termination is not None:
+            hooks.on_termination(create_step_state(time, range_vector, velocity_vector,
+                                                   velocity, mach, density_factor, drag),
+                                 termination_reason)
+        self.termination_reason = termination_reason
+        if next_phase:
+            self._reset_phases(shot_info)
         return ranges
 
+    cdef double _adaptive_step(self, double x, double velocity_x, Vector acceleration, Vector jerk,
+                               double maximum_range):
+        cdef double error_rate, step
+        maximum_range = max(maximum_range, self.calc_step)
+        error_rate = (sqrt(acceleration.y * acceleration.y + acceleration.z * acceleration.z)
+                      + sqrt(jerk.y * jerk.y + jerk.z * jerk.z) * max(maximum_range - x, 0) / velocity_x)
+        if error_rate == 0:
+            return self.calc_step * cAdaptiveStepRatio
+        step = 2 * self.step_tolerance * velocity_x * velocity_x / (error_rate * maximum_range)
+        return min(max(step, self.calc_step / cAdaptiveStepRatio), self.calc_step * cAdaptiveStepRatio)
+
+    cdef _reset_phases(self, object shot_info):
+        self._bc, self._table_data, self._curve = self._drag0
+        self._curve_index = -1
+        self.weight = shot_info.ammo.dm.weight >> Weight.Grain
+
+    cdef _interpolate_row(self, double x, tuple previous, tuple current, tuple adjustment_reference,
+                          double degrade_onset):
+        cdef:
+            Vector previous_range = previous[1], current_range = current[1]
+            Vector previous_velocity = previous[2], current_velocity = current[2]
+            double dx = current_range.x - previous_range.x
+            double f = (x - previous_range.x) / dx if dx else 1.0
+            double time = previous[0] + f * (current[0] - previous[0])
+            double velocity = previous[3] + f * (current[3] - previous[3])
+            double density_factor = previous[5] + f * (current[5] - previous[5])
+            double spin_rate = previous[7] + f * (current[7] - previous[7])
+            Vector range_vector = previous_range.add(current_range.subtract(previous_range).mul_by_const(f))
+            Vector velocity_vector = previous_velocity.add(
+                current_velocity.subtract(previous_velocity).mul_by_const(f))
+        range_vector.x = x
+        return create_trajectory_row(time, range_vector, velocity_vector, velocity,
+                                     previous[4] + f * (current[4] - previous[4]),
+                                     self.spin_drift(time), self.look_angle, density_factor,
+                                     previous[6] + f * (current[6] - previous[6]),
+                                     self.weight, CTrajFlag.RANGE, adjustment_reference,
+                                     spin_rate, self.stability_at(velocity, density_factor, spin_rate),
+                                     self.dispersion_at(x, degrade_onset))
+
     cdef double drag_by_mach(self, double mach):
         """ Drag force = V^2 * Cd * AirDensity * S / 2m where:
             cStandardDensity of Air = 0.076474 lb/ft^3
@@ -366,7 +761,9 @@ cdef class TrajectoryCalc:
         bc contains m/d^2 in units lb/in^2, which we multiply by 144 to convert to lb/ft^2
         Thus: The magic constant found here = StandardDensity * pi / (4 * 2 * 144)
         """
-        cdef double cd = calculate_by_curve(self._table_data, self._curve, mach)
+        cdef double cd
+        self._curve_index = find_curve_index(self._table_data, mach, self._curve_index)
+        cd = calculate_by_curve_index(self._table_data, self._curve, mach, self._curve_index)
         return cd * 2.08551e-04 / self._bc
 
     cdef double spin_drift(self, double time):
@@ -375,11 +772,22 @@ cdef class TrajectoryCalc:
         :return: windage due to spin drift, in feet
         """
         cdef int sign
-        if self.twist != 0:
+        if self.twist != 0 and self.use_spin_drift:
             sign = 1 if self.twist > 0 else -1
             return sign * (1.25 * (self.stability_coefficient + 1.2) * pow(time, 1.83) ) / 12
         return 0
 
+    cdef double stability_at(self, double velocity, double density_factor, double spin_rate):
+        if not self.stability_coefficient or not self.spin_rate0 or not velocity:
+            return 0
+        return (self.stability_coefficient * (self.density_factor0 / density_factor)
+                * pow(spin_rate * self.muzzle_velocity / (self.spin_rate0 * velocity), 2))
+
+    cdef double dispersion_at(self, double x, double degrade_onset):
+        if degrade_onset < 0 or x <= degrade_onset:
+            return 0
+        return self.degrade_dispersion * (x - degrade_onset)
+
     cdef double calc_stability_coefficient(self, object atmo):
         """Miller stability coefficient"""
         cdef:
@@ -395,21 +803,43 @@ cdef class TrajectoryCalc:
             return sd * fv * ftp
         return 0
 
+cdef double earth_height(Vector range_vector):
+    cdef double y = range_vector.y + cEarthRadius
+    return sqrt(range_vector.x * range_vector.x + y * y + range_vector.z * range_vector.z) - cEarthRadius
+
 cdef Vector wind_to_vector(object wind):
     cdef:
         double range_component = (wind.velocity >> Velocity.FPS) * cos(wind.direction_from >> Angular.Radian)
         double cross_component = (wind.velocity >> Velocity.FPS) * sin(wind.direction_from >> Angular.Radian)
     return Vector(range_component, 0., cross_component)
 
+cdef create_step_state(double time, Vector range_vector, Vector velocity_vector,
+                       double velocity, double mach, double density_factor, double drag):
+    return StepState(time, PyVector(range_vector.x, range_vector.y, range_vector.z),
+                     PyVector(velocity_vector.x, velocity_vector.y, velocity_vector.z),
+                     velocity, mach, density_factor, drag)
+
 cdef create_trajectory_row(double time, Vector range_vector, Vector velocity_vector,
                            double velocity, double mach, double spin_drift, double look_angle,
-                           double density_factor, double drag, double weight, object flag):
+                           double density_factor, double drag, double weight, object flag,
+                           tuple adjustment_reference = None,
+                           double spin_rate = 0, double stability = 0, double dispersion = 0):
     cdef:
         double windage = range_vector.z + spin_drift
-        double drop_adjustment = get_correction(range_vector.x, range_vector.y)
-        double windage_adjustment = get_correction(range_vector.x, windage)
+        double ref_height = 0, ref_windage = 0, ref_elevation = look_angle, ref_azimuth = 0
+        double drop_adjustment, windage_adjustment
         double trajectory_angle = atan(velocity_vector.y / velocity_vector.x)
 
+    if adjustment_reference is not None:
+        ref_height, ref_windage, ref_elevation, ref_azimuth = adjustment_reference
+    drop_adjustment = get_correction(range_vector.x, range_vector.y - ref_height)
+    windage_adjustment = get_correction(range_vector.x, windage - ref_windage)
+    if range_vector.x:
+        drop_adjustment -= ref_elevation
+        windage_adjustment -= ref_azimuth
+    if is_unstable(stability, velocity / mach):
+        flag |= CTrajFlag.UNSTABLE
+
     return TrajectoryData(
         time=time,
         distance=Distance.Foot(range_vector.x),
@@ -417,7 +847,7 @@ cdef create_trajectory_row(double time, Vector range_vector, Vector velocity_vec
         mach=velocity / mach,
         height=Distance.Foot(range_vector.y),
         target_drop=Distance.Foot((range_vector.y - range_vector.x * tan(look_angle)) * cos(look_angle)),
-        drop_adj=Angular.Radian(drop_adjustment - (look_angle if range_vector.x else 0)),
+        drop_adj=Angular.Radian(drop_adjustment),
         windage=Distance.Foot(windage),
         windage_adj=Angular.Radian(windage_adjustment),
         look_distance= Distance.Foot(range_vector.x / cos(look_angle)),
@@ -426,9 +856,17 @@ cdef create_trajectory_row(double time, Vector range_vector, Vector velocity_vec
         drag = drag,
         energy=Energy.FootPound(calculate_energy(weight, velocity)),
         ogw=Weight.Pound(calculate_ogv(weight, velocity)),
-        flag=flag
+        flag=flag,
+        spin_rate=spin_rate,
+        stability=stability,
+        dispersion=Distance.Foot(dispersion)
     )
 
+cdef bint is_unstable(double stability, double mach):
+    if stability <= 0:
+        return False
+    return stability < 1 or (0.8 <= mach <= 1.2 and stability < cMarginalStability)
+
 @cython.cdivision(True)
 cdef double get_correction(double distance, double offset):
     if distance != 0:
@@ -481,21 +919,36 @@ cdef list calculate_curve(list data_points):
     curve.append(curve_point)
     return curve
 
-cdef double calculate_by_curve(list data, list curve, double mach):
-    cdef int num_points, mlo, mhi, mid, m
-    cdef CurvePoint curve_m
+cdef int find_curve_index(list data, double mach, int hint):
+    cdef int mhi_max, mlo, mhi, mid
 
-    num_points = int(len(curve))
-    mlo = 0
-    mhi = num_points - 2
+    mhi_max = len(data) - 2
+    if mhi_max < 1:
+        return 0
 
-    while mhi - mlo > 1:
-        mid = int(floor(mhi + mlo) / 2.0)
-        if data[mid].Mach < mach:
-            mlo = mid
-        else:
-            mhi = mid
+    if hint < 0:
+        mlo = 0
+        mhi = mhi_max
+        while mhi - mlo > 1:
+            mid = int(floor(mhi + mlo) / 2.0)
+            if data[mid].Mach < mach:
+                mlo = mid
+            else:
+                mhi = mid
+        return mhi
+
+    mhi = min(max(hint, 1), mhi_max)
+    while mhi > 1 and data[mhi - 1].Mach >= mach:
+        mhi -= 1
+    while mhi < mhi_max and data[mhi].Mach < mach:
+        mhi += 1
+    return mhi
+
+cdef double calculate_by_curve_index(list data, list curve, double mach, int mhi):
+    cdef int mlo, m
+    cdef CurvePoint curve_m
 
+    mlo = max(mhi - 1, 0)
     if data[mhi].Mach - mach > mach - data[mlo].Mach:
         m = mlo
     else:
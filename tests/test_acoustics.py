@@ -0,0 +1,62 @@
+"""Unittests for crack and thump sound arrival times"""
+
+import math
+import unittest
+from py_ballisticcalc import *
+
+
+class TestAcoustics(unittest.TestCase):
+
+    def setUp(self) -> None:
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        self.shot = Shot(weapon=Weapon(2, 12), ammo=Ammo(dm, Velocity.FPS(2750)))
+        self.calc = Calculator()
+        self.calc.set_weapon_zero(self.shot, Distance.Yard(100))
+        self.speed_of_sound = self.shot.atmo.mach >> Velocity.FPS
+
+    def test_on_bullet_path(self):
+        arrivals = sound_arrivals(self.calc, self.shot, [Distance.Yard(300), Distance.Yard(600)])
+        for arrival in arrivals:
+            # Crack arrives with the bullet; report travels the distance at the speed of sound
+            self.assertAlmostEqual(arrival.crack_time, arrival.bullet_time, 6)
+            self.assertAlmostEqual(arrival.report_time, (arrival.distance >> Distance.Foot) / self.speed_of_sound,
+                                   2)
+            self.assertAlmostEqual(arrival.crack_thump, arrival.report_time - arrival.bullet_time)
+            self.assertAlmostEqual(arrival.mach_angle >> Angular.Radian, math.asin(1 / arrival.mach))
+        self.assertGreater(arrivals[1].crack_thump, arrivals[0].crack_thump)
+
+    def test_mach_cone(self):
+        offset = Distance.Yard(10)
+        arrival = sound_arrivals(self.calc, self.shot, [Distance.Yard(100)], offset)[0]
+        self.assertGreater(arrival.crack_time, arrival.bullet_time)
+        # The crack is emitted offset * tan(mach angle) before the listener
+        upstream = (arrival.distance >> Distance.Foot) - (arrival.crack_origin >> Distance.Foot)
+        self.assertAlmostEqual(upstream, (offset >> Distance.Foot) * math.tan(arrival.mach_angle >> Angular.Radian),
+                               delta=8)
+
+    def test_subsonic(self):
+        arrival = sound_arrivals(self.calc, self.shot, [Distance.Yard(1300)])[0]
+        self.assertLess(arrival.mach, 1)
+        self.assertIsNone(arrival.mach_angle)
+        # The last shock wave, from where the bullet went subsonic, still arrives before the bullet
+        self.assertLess(arrival.crack_origin >> Distance.Yard, 1300)
+        subsonic = Shot(weapon=Weapon(2, 12), ammo=Ammo(DragModel(0.223, TableG7), Velocity.FPS(1000)))
+        arrival = sound_arrivals(self.calc, subsonic, [Distance.Yard(100)])[0]
+        self.assertIsNone(arrival.crack_time)
+        self.assertIsNone(arrival.crack_thump)
+
+    def test_ranging(self):
+        arrivals = sound_arrivals(self.calc, self.shot, [Distance.Yard(d) for d in range(100, 1000, 100)])
+        distance = range_from_crack_thump(arrivals, arrivals[4].crack_thump)
+        self.assertAlmostEqual(distance >> Distance.Yard, 500)
+        middle = (arrivals[4].crack_thump + arrivals[5].crack_thump) / 2
+        self.assertTrue(500 < (range_from_crack_thump(arrivals, middle) >> Distance.Yard) < 600)
+        self.assertIsNone(range_from_crack_thump(arrivals, 10))
+
+    def test_no_ranges(self):
+        with self.assertRaises(ValueError):
+            sound_arrivals(self.calc, self.shot, [])
+
+
+if __name__ == '__main__':
+    unittest.main()
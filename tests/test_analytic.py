@@ -0,0 +1,52 @@
+"""Unittests comparing TrajectoryCalc with exact vacuum and constant-drag solutions"""
+
+import unittest
+from py_ballisticcalc import *
+
+NO_DRAG = [{'Mach': 0, 'CD': 0}, {'Mach': 1, 'CD': 0}, {'Mach': 5, 'CD': 0}]
+CONSTANT_DRAG = [{'Mach': 0, 'CD': 0.3}, {'Mach': 1, 'CD': 0.3}, {'Mach': 5, 'CD': 0.3}]
+
+
+class TestAnalytic(unittest.TestCase):
+
+    def compare(self, shot: Shot, engine: type, config: CalculatorConfig = None,
+                height_delta: float = 0.01, velocity_delta: float = 0.05):
+        expected = Calculator(engine=engine, config=config).fire(shot, Distance.Yard(1000), Distance.Yard(100))
+        actual = Calculator(config=config).fire(shot, Distance.Yard(1000), Distance.Yard(100))
+        self.assertEqual(len(actual.trajectory), len(expected.trajectory))
+        for a, e in zip(actual.trajectory, expected.trajectory):
+            with self.subTest(distance=e.distance):
+                self.assertAlmostEqual(a.velocity >> Velocity.FPS, e.velocity >> Velocity.FPS, delta=velocity_delta)
+                self.assertAlmostEqual(a.time, e.time, delta=1e-4)
+                self.assertAlmostEqual(a.height >> Distance.Inch, e.height >> Distance.Inch, delta=height_delta)
+                self.assertAlmostEqual(a.windage >> Distance.Inch, e.windage >> Distance.Inch, delta=0.01)
+
+    def test_vacuum(self):
+        dm = DragModel(0.3, NO_DRAG, 168, 0.308)
+        shot = Shot(weapon=Weapon(2, zero_elevation=Angular.Degree(5)), ammo=Ammo(dm, Velocity.FPS(2700)),
+                    winds=[Wind(Velocity.MPH(10), Angular.Degree(90))])
+        self.compare(shot, VacuumCalc, height_delta=0.05)
+
+    def test_vacuum_zero(self):
+        dm = DragModel(0.3, NO_DRAG, 168, 0.308)
+        shot = Shot(weapon=Weapon(2), ammo=Ammo(dm, Velocity.FPS(2700)), look_angle=Angular.Degree(10))
+        expected = Calculator(engine=VacuumCalc).barrel_elevation_for_target(shot, Distance.Yard(500))
+        actual = Calculator().barrel_elevation_for_target(shot, Distance.Yard(500))
+        self.assertAlmostEqual(actual >> Angular.MOA, expected >> Angular.MOA, delta=0.02)
+        with self.assertRaises(Exception):
+            Calculator(engine=VacuumCalc).barrel_elevation_for_target(shot, Distance.Mile(200))
+
+    def test_constant_drag_without_gravity(self):
+        dm = DragModel(0.3, CONSTANT_DRAG, 168, 0.308)
+        shot = Shot(weapon=Weapon(2), ammo=Ammo(dm, Velocity.FPS(2700)))
+        self.compare(shot, ConstantDragCalc, CalculatorConfig(gravity=0), height_delta=1e-9)
+
+    def test_constant_drag_flat_fire(self):
+        dm = DragModel(0.3, CONSTANT_DRAG, 168, 0.308)
+        shot = Shot(weapon=Weapon(2, zero_elevation=Angular.MOA(5)), ammo=Ammo(dm, Velocity.FPS(2700)))
+        # Drop and velocity are approximations: only flat-fire accuracy is expected
+        self.compare(shot, ConstantDragCalc, height_delta=0.1, velocity_delta=0.5)
+
+
+if __name__ == '__main__':
+    unittest.main()
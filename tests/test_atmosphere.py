@@ -32,6 +32,89 @@ class TestAtmosphere(unittest.TestCase):
         self.assertAlmostEqual(Atmo.machC(-20), 318.94, places=1)
         self.assertAlmostEqual(self.highISA.mach >> Velocity.MPS, 336.4, places=1)
 
+    def test_density(self):
+        self.assertIsInstance(self.standard.density, Density)
+        self.assertEqual(self.standard.density.units, PreferredUnits.density)
+        self.assertAlmostEqual(self.standard.density >> Density.KgPerCubicMeter, 1.225, places=3)
+        self.assertAlmostEqual(self.standard.density >> Density.LbPerCubicFoot, 0.076474, places=5)
+        self.assertAlmostEqual(self.highISA.density >> Density.GramPerLiter, 1.225 * 0.9075, places=3)
+        self.assertAlmostEqual(Density.from_dict({'value': 1, 'units': 'lb/ft3'}) >> Density.KgPerCubicMeter,
+                               16.0185, places=4)
+
+    def test_humidity(self):
+        # Ref CIPM-2007 (Picard et al., 2008): dry air with 400 ppm CO2 at 20 °C and 101325 Pa
+        self.assertAlmostEqual(Atmo.moist_air_density(20, 101325, 0), 1.2046, places=4)
+        self.assertAlmostEqual(Atmo.moist_air_density(30, 101325, 1), 1.1464, places=3)
+        dry = Atmo(temperature=Temperature.Celsius(30), pressure=Pressure.hPa(1013.25))
+        tropical = Atmo(temperature=Temperature.Celsius(30), pressure=Pressure.hPa(1013.25), humidity=100)
+        self.assertAlmostEqual(tropical.density_metric / dry.density_metric, 1.1464 / 1.1644, places=3)
+        # Moist air is lighter, so sound is faster
+        self.assertAlmostEqual(tropical.mach >> Velocity.MPS, 351.4, places=1)
+        self.assertGreater(tropical.mach >> Velocity.MPS, dry.mach >> Velocity.MPS)
+        high = tropical.get_density_factor_and_mach_for_altitude((tropical.altitude >> Distance.Foot) + 1000)[1]
+        self.assertGreater(high, dry.get_density_factor_and_mach_for_altitude(1000)[1])
+        # Humidity correction follows the temperature and pressure at altitude
+        altitude = 10000
+        self.assertAlmostEqual(tropical.pressure_at_altitude(0), tropical.pressure >> Pressure.InHg)
+        self.assertAlmostEqual(tropical.pressure_at_altitude(altitude) / (tropical.pressure >> Pressure.InHg),
+                               (Atmo.standard_pressure(Distance.Foot(altitude)) >> Pressure.InHg)
+                               / (Atmo.standard_pressure(0) >> Pressure.InHg), places=6)
+        fahrenheit = tropical.temperature_at_altitude(altitude)
+        celsius = Temperature.Fahrenheit(fahrenheit) >> Temperature.Celsius
+        pascals = Pressure.InHg(tropical.pressure_at_altitude(altitude)) >> Pressure.Pascal
+        high = tropical.get_density_factor_and_mach_for_altitude(altitude)[1]
+        self.assertAlmostEqual(high, Atmo.machF(fahrenheit) * Atmo.moist_air_mach_factor(celsius, pascals, 1))
+        self.assertLess(Atmo.moist_air_mach_factor(celsius, pascals, 1),
+                        Atmo.moist_air_mach_factor(30, 101325, 1))
+        # Vapor pressure is negligible in very cold air
+        cold = Atmo(temperature=Temperature.Fahrenheit(-10), pressure=Pressure.InHg(29.92), humidity=100)
+        self.assertAlmostEqual(cold.density_ratio, Atmo(temperature=Temperature.Fahrenheit(-10),
+                                                        pressure=Pressure.InHg(29.92)).density_ratio, places=3)
+
+    def test_trajectory_density(self):
+        from py_ballisticcalc import Calculator, DragModel, Shot, Weapon, Ammo, TableG7
+        shot = Shot(weapon=Weapon(2, 10, zero_elevation=Angular.Degree(5)),
+                    ammo=Ammo(DragModel(0.223, TableG7), Velocity.FPS(2750)), atmo=self.highISA)
+        hit = Calculator().fire(shot, Distance.Yard(2000))
+        self.assertAlmostEqual(hit[0].air_density >> Density.KgPerCubicMeter,
+                               self.highISA.density >> Density.KgPerCubicMeter, places=4)
+        # Air is thinner at the top of the trajectory
+        top = max(hit, key=lambda row: row.height >> Distance.Foot)
+        self.assertGreater(top.height >> Distance.Foot, 100)
+        self.assertLess(top.air_density >> Density.KgPerCubicMeter, hit[0].air_density >> Density.KgPerCubicMeter)
+
+    def test_atmosphere_model(self):
+        from py_ballisticcalc import AtmosphereModel, Calculator, DragModel, Shot, Weapon, Ammo, TableG7
+
+        class Sounding(AtmosphereModel):
+            """Measured density ratio and Mach 1, constant with altitude"""
+
+            def __init__(self, density_ratio, mach):
+                self.density_ratio, self.mach = density_ratio, mach
+                self.altitudes = []
+
+            def get_density_factor_and_mach_for_altitude(self, altitude):
+                self.altitudes.append(altitude)
+                return self.density_ratio, self.mach
+
+        self.assertIsInstance(self.standard, AtmosphereModel)
+        calc = Calculator()
+        ammo = Ammo(DragModel(0.223, TableG7), Velocity.FPS(2750))
+        shot = Shot(weapon=Weapon(2, 10), ammo=ammo, atmo=self.highISA)
+        calc.set_weapon_zero(shot, Distance.Yard(100))
+        expected = calc.fire(shot, Distance.Yard(500))[-1]
+        # Model matching the atmosphere at the muzzle, without its density falling with height
+        sounding = Sounding(self.highISA.density_ratio, self.highISA.mach >> Velocity.FPS)
+        custom = calc.fire(Shot(weapon=shot.weapon, ammo=ammo, atmo=self.highISA, atmo_model=sounding),
+                           Distance.Yard(500))[-1]
+        self.assertAlmostEqual(custom.drop_adj >> Angular.MOA, expected.drop_adj >> Angular.MOA, places=1)
+        self.assertAlmostEqual(sounding.altitudes[0], self.highISA.altitude >> Distance.Foot)
+        thin = calc.fire(Shot(weapon=shot.weapon, ammo=ammo, atmo=self.highISA, atmo_model=Sounding(0.5, 1100)),
+                         Distance.Yard(500))[-1]
+        self.assertGreater(thin.velocity >> Velocity.FPS, custom.velocity >> Velocity.FPS)
+        with self.assertRaises(TypeError):
+            AtmosphereModel()
+
 
 if __name__ == '__main__':
     unittest.main()
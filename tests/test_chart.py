@@ -0,0 +1,62 @@
+"""Unittests for the SVG and PNG trajectory charts"""
+
+import os
+import struct
+import tempfile
+import unittest
+import zlib
+from xml.etree import ElementTree
+from py_ballisticcalc import *
+
+
+class TestChart(unittest.TestCase):
+
+    def setUp(self) -> None:
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        self.shot = Shot(weapon=Weapon(2, 12), ammo=Ammo(dm, Velocity.FPS(2750)),
+                         winds=[Wind(Velocity.MPH(10), Angular.OClock(3))])
+        self.calc = Calculator()
+        self.calc.set_weapon_zero(self.shot, Distance.Yard(100))
+        self.hit = self.calc.fire(self.shot, Distance.Yard(1500), Distance.Yard(50))
+
+    def test_svg(self):
+        svg = render_svg(self.hit)
+        root = ElementTree.fromstring(svg)
+        ns = '{http://www.w3.org/2000/svg}'
+        self.assertEqual(len(root.findall(f'{ns}polyline')), 3)
+        texts = [t.text for t in root.findall(f'{ns}text')]
+        self.assertEqual(texts.count('Mach 1'), 3)
+        self.assertIn(f"Drop, {PreferredUnits.drop.symbol}", texts)
+        self.assertIn(f"Range, {PreferredUnits.distance.symbol}", texts)
+
+    def test_no_mach_marker_when_supersonic(self):
+        hit = self.calc.fire(self.shot, Distance.Yard(300), Distance.Yard(50))
+        self.assertNotIn('Mach 1', render_svg(hit, ('velocity',)))
+
+    def test_png(self):
+        png = render_png(self.hit, ('drop', 'velocity'), width=320, height=240)
+        self.assertEqual(png[:8], b'\x89PNG\r\n\x1a\n')
+        width, height = struct.unpack('>II', png[16:24])
+        self.assertEqual((width, height), (320, 240))
+        length = struct.unpack('>I', png[33:37])[0]
+        self.assertEqual(png[37:41], b'IDAT')
+        raw = zlib.decompress(png[41:41 + length])
+        self.assertEqual(len(raw), height * (1 + 3 * width))
+        self.assertGreater(len(set(raw)), 3)
+
+    def test_save_chart(self):
+        with tempfile.TemporaryDirectory() as tmp:
+            for name in ('chart.svg', 'chart.PNG'):
+                path = os.path.join(tmp, name)
+                save_chart(self.hit, path)
+                self.assertGreater(os.path.getsize(path), 0)
+            with self.assertRaises(ValueError):
+                save_chart(self.hit, os.path.join(tmp, 'chart.jpg'))
+
+    def test_unknown_quantity(self):
+        with self.assertRaises(KeyError):
+            render_svg(self.hit, ('spin',))
+
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,142 @@
+"""Unittests for chronograph imports and the two-chronograph BC measurement"""
+
+import math
+import os
+import tempfile
+import unittest
+from py_ballisticcalc import *
+
+
+class TestMeasureBC(unittest.TestCase):
+
+    def setUp(self) -> None:
+        self.calc = Calculator()
+        self.atmo = Atmo(Distance.Foot(5000), Pressure.InHg(25), Temperature.Fahrenheit(40), 30)
+
+    def far_velocity(self, bc: float, table, near: float, separation: Distance, atmo: Atmo = None) -> Velocity:
+        shot = Shot(weapon=Weapon(), ammo=Ammo(DragModel(bc, table), Velocity.FPS(near)), atmo=atmo)
+        return self.calc.fire_at_ranges(shot, [separation]).trajectory[0].velocity
+
+    def test_recovers_bc(self):
+        for bc, table in ((0.3, TableG7), (0.5, TableG1)):
+            far = self.far_velocity(bc, table, 2800, Distance.Yard(100), self.atmo)
+            result = measure_bc(Velocity.FPS(2800), far, Distance.Yard(100), table, self.atmo, calc=self.calc)
+            self.assertAlmostEqual(result.bc, bc, places=5)
+            self.assertEqual(result.sd, 0)
+            self.assertEqual(result.drag_model(175, 0.308, 1.24).BC, result.bc)
+
+    def test_uncertainty(self):
+        far = self.far_velocity(0.3, TableG7, 2800, Distance.Yard(100))
+        single = measure_bc(2800, far, Distance.Yard(100), TableG7, near_sd=Velocity.FPS(5),
+                            far_sd=Velocity.FPS(5), calc=self.calc)
+        averaged = measure_bc(2800, far, Distance.Yard(100), TableG7, near_sd=Velocity.FPS(5),
+                              far_sd=Velocity.FPS(5), shots=16, calc=self.calc)
+        self.assertGreater(single.sd, 0)
+        self.assertAlmostEqual(averaged.sd, single.sd / 4, delta=single.sd * 0.01)
+        # Longer separation loses more velocity, so the same reading errors matter less
+        far = self.far_velocity(0.3, TableG7, 2800, Distance.Yard(300))
+        longer = measure_bc(2800, far, Distance.Yard(300), TableG7, near_sd=Velocity.FPS(5),
+                            far_sd=Velocity.FPS(5), calc=self.calc)
+        self.assertLess(longer.sd, single.sd)
+        # Error of one chronograph propagates roughly as bc / velocity loss
+        near_only = measure_bc(2800, far, Distance.Yard(300), TableG7, near_sd=Velocity.FPS(5), calc=self.calc)
+        expected = 0.3 * 5 / (2800 - (far >> Velocity.FPS))
+        self.assertTrue(math.isclose(near_only.sd, expected, rel_tol=0.1))
+
+    def test_invalid(self):
+        with self.assertRaises(ValueError):
+            measure_bc(2600, 2800, 100, TableG7)
+        with self.assertRaises(ValueError):
+            measure_bc(2800, 2600, 0, TableG7)
+        with self.assertRaises(ValueError):
+            measure_bc(2800, 2600, 100, TableG7, shots=0)
+
+
+LABRADAR = """sep=;
+Device ID;LBR-0013497;;
+Series No;0003;;
+Total number of Shots;0004;;
+Units velocity;m/s;;
+Units distances;m;;
+Stats - Average;853,50;m/s;
+;;;
+Shot ID;V0;V10;V20;Ke0;Date;Time
+0001;850,00;846,10;842,20;3250;21-05-2024;10:01:02
+0002;855,00;851,10;847,20;3280;21-05-2024;10:01:40
+0003;852,00;848,10;844,20;3260;21-05-2024;10:02:15
+0004;857,00;853,10;849,20;3290;21-05-2024;10:02:51
+"""
+
+MAGNETOSPEED = """Series,Shot,Speed (FPS),AKE,Date
+1,1,2790,2900,2024-05-21
+1,2,2801,2923,2024-05-21
+1,3,2795,2911,2024-05-21
+2,1,2700,2720,2024-05-21
+2,2,2710,2740,2024-05-21
+"""
+
+GARMIN_XERO = """"SESSION","308 load"
+#,SPEED (FPS),Δ AVG (FPS),KE (FT-LB),CLEAN BORE,COLD BORE,SHOT NOTES
+1,2770.0,-25.0,2980,No,Yes,
+2,2790.0,-5.0,3020,No,No,
+3,2795.0,0.0,3030,No,No,
+4,2800.0,5.0,3045,No,No,
+-,,
+AVERAGE SPEED,2788.8
+STD DEV,13.1
+"""
+
+
+class TestChronographImport(unittest.TestCase):
+
+    def setUp(self) -> None:
+        self.tmp = tempfile.TemporaryDirectory()
+
+    def tearDown(self) -> None:
+        self.tmp.cleanup()
+
+    def write(self, name: str, text: str) -> str:
+        path = os.path.join(self.tmp.name, name)
+        with open(path, 'w', encoding='utf-8', newline='') as fp:
+            fp.write(text)
+        return path
+
+    def test_labradar(self):
+        string = read_labradar(self.write('SR0003 Report.csv', LABRADAR))
+        self.assertEqual(string.count, 4)
+        self.assertAlmostEqual(string.mean >> Velocity.MPS, 853.5)
+        self.assertAlmostEqual(string.es >> Velocity.MPS, 7)
+        self.assertAlmostEqual(string.sd >> Velocity.MPS, math.sqrt((12.25 + 2.25 + 2.25 + 12.25) / 3))
+        self.assertEqual(string.cold_bore, [False] * 4)
+
+    def test_magnetospeed(self):
+        path = self.write('magnetospeed.csv', MAGNETOSPEED)
+        first = read_magnetospeed(path)
+        self.assertEqual([v >> Velocity.FPS for v in first.velocities], [2790, 2801, 2795])
+        second = read_magnetospeed(path, series=2)
+        self.assertAlmostEqual(second.mean >> Velocity.FPS, 2705)
+
+    def test_garmin_xero(self):
+        string = read_garmin_xero(self.write('xero.csv', GARMIN_XERO))
+        self.assertEqual(string.count, 4)
+        self.assertEqual(string.cold_bore, [True, False, False, False])
+        dm = DragModel(0.243, TableG7, 175, 0.308, 1.24)
+        ammo = string.ammo(dm, Temperature.Fahrenheit(60))
+        self.assertAlmostEqual(ammo.mv >> Velocity.FPS, 2795)
+        self.assertAlmostEqual(ammo.cold_bore_offset >> Velocity.FPS, -25)
+        self.assertAlmostEqual(ammo.powder_temp >> Temperature.Fahrenheit, 60)
+        self.assertAlmostEqual(ammo.mv_for_shot(1) >> Velocity.FPS, 2770)
+        fitted = string.ammo(dm, fit_drift=True)
+        self.assertAlmostEqual(fitted.shot_mv_drift >> Velocity.FPS, 5)
+        for shot_number, velocity in enumerate(string.velocities, 1):
+            self.assertAlmostEqual(fitted.mv_for_shot(shot_number) >> Velocity.FPS, velocity >> Velocity.FPS)
+
+    def test_read_chronograph(self):
+        for name, text, count in (('a.csv', LABRADAR, 4), ('b.csv', MAGNETOSPEED, 3), ('c.csv', GARMIN_XERO, 4)):
+            self.assertEqual(read_chronograph(self.write(name, text)).count, count)
+        with self.assertRaises(ValueError):
+            read_chronograph(self.write('d.csv', 'range,drop\n100,0\n'))
+
+
+if __name__ == '__main__':
+    unittest.main()
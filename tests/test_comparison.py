@@ -0,0 +1,118 @@
+"""Unittests for the ammunition comparison matrix"""
+
+import unittest
+from py_ballisticcalc import *
+from py_ballisticcalc.comparison import pd
+
+
+class TestAmmoComparison(unittest.TestCase):
+
+    def setUp(self) -> None:
+        self.weapon = Weapon(Distance.Inch(2), Distance.Inch(10))
+        self.candidates = {
+            '175gr SMK': Ammo(DragModel(0.243, TableG7, 175, 0.308, 1.24), Velocity.FPS(2650)),
+            '155gr Scenar': Ammo(DragModel(0.236, TableG7, 155, 0.308, 1.2), Velocity.FPS(2950)),
+            '147gr FMJ': Ammo(DragModel(0.2, TableG7, 147, 0.308, 1.1), Velocity.FPS(2750)),
+        }
+        self.distances = [Distance.Yard(d) for d in (300, 600, 900)]
+        self.calc = Calculator()
+        self.comparison = compare_ammo(self.calc, self.weapon, self.candidates, self.distances)
+
+    def test_entries(self):
+        self.assertEqual([e.name for e in self.comparison.entries], list(self.candidates))
+        self.assertEqual(self.weapon.zero_elevation.raw_value, 0)
+        for entry in self.comparison.entries:
+            self.assertNotEqual(entry.shot.weapon.zero_elevation.raw_value, 0)
+            self.assertEqual(len(entry.rows), 3)
+            for distance, row in zip(self.distances, entry.rows):
+                self.assertAlmostEqual(row.distance >> Distance.Yard, distance >> Distance.Yard, places=3)
+            self.assertIsNotNone(entry.supersonic_range)
+            # All candidates stay supersonic past 600 yards
+            self.assertGreater(entry.supersonic_range >> Distance.Yard, 600)
+        # Each candidate matches a separately zeroed shot
+        entry = self.comparison.entries[1]
+        shot = Shot(weapon=Weapon(Distance.Inch(2), Distance.Inch(10)), ammo=self.candidates['155gr Scenar'],
+                    winds=[Wind(Velocity.MPH(10), Angular.OClock(3))])
+        self.calc.set_weapon_zero(shot, Distance.Yard(100))
+        row = self.calc.fire_at_ranges(shot, [Distance.Yard(600)]).trajectory[0]
+        self.assertAlmostEqual(row.drop_adj >> Angular.Mil, entry.rows[1].drop_adj >> Angular.Mil, places=6)
+        self.assertAlmostEqual(row.windage >> Distance.Inch, entry.rows[1].windage >> Distance.Inch, places=6)
+
+    def test_matrix(self):
+        drops = self.comparison.matrix('drop_adj')
+        self.assertEqual(len(drops), 3)
+        self.assertEqual(len(drops[0]), 3)
+        # Low-BC bullet drifts the most, fast high-BC bullet the least
+        winds = [row[-1] >> Distance.Inch for row in self.comparison.matrix('windage')]
+        self.assertEqual(max(winds), winds[2])
+        self.assertEqual(min(winds), winds[1])
+        with self.assertRaises(KeyError):
+            self.comparison.matrix('spin')
+
+    def test_unnamed_candidates(self):
+        comparison = compare_ammo(self.calc, self.weapon, list(self.candidates.values())[:2], [500])
+        self.assertEqual([e.name for e in comparison.entries], ['1', '2'])
+
+    @unittest.skipIf(pd is None, "pandas is not installed")
+    def test_dataframe(self):
+        frame = self.comparison.dataframe()
+        self.assertEqual(len(frame), 9)
+        self.assertIn('supersonic_range', frame.columns)
+
+
+class TestLoadComparison(unittest.TestCase):
+
+    def setUp(self) -> None:
+        self.smk = Ammo(DragModel(0.243, TableG7, 175, 0.308, 1.24), Velocity.FPS(2650))
+        self.scenar = Ammo(DragModel(0.236, TableG7, 155, 0.308, 1.2), Velocity.FPS(2950))
+        self.shot = Shot(weapon=Weapon(Distance.Inch(2), Distance.Inch(10)), ammo=self.smk,
+                         winds=[Wind(Velocity.MPH(10), Angular.OClock(3))])
+        self.distances = [Distance.Yard(d) for d in (300, 600)]
+        self.calc = Calculator()
+
+    def test_deltas(self):
+        short_barrel = Weapon(Distance.Inch(2.5), Distance.Inch(10))
+        comparison = compare_loads(self.calc, self.shot,
+                                   {'smk': self.smk, 'scenar': self.scenar, 'short': (short_barrel, self.scenar)},
+                                   self.distances, Distance.Yard(100))
+        self.assertEqual(comparison.baseline, 'smk')
+        self.assertEqual(self.shot.weapon.zero_elevation.raw_value, 0)
+        for delta in comparison.entry('smk').deltas:
+            self.assertEqual(delta.drop.raw_value, 0)
+            self.assertEqual(delta.time, 0)
+        scenar = comparison.entry('scenar')
+        base = comparison.entry('smk')
+        for delta, row, base_row in zip(scenar.deltas, scenar.rows, base.rows):
+            self.assertAlmostEqual(delta.drop >> Distance.Inch,
+                                   (row.target_drop >> Distance.Inch) - (base_row.target_drop >> Distance.Inch))
+            self.assertAlmostEqual(delta.drift >> Distance.Inch,
+                                   (row.windage >> Distance.Inch) - (base_row.windage >> Distance.Inch))
+            self.assertAlmostEqual(delta.time, row.time - base_row.time)
+            # Faster bullet hits higher and sooner
+            self.assertGreater(delta.drop >> Distance.Inch, 0)
+            self.assertLess(delta.time, 0)
+        # Each load matches its separately zeroed shot
+        shot = Shot(weapon=Weapon(Distance.Inch(2.5), Distance.Inch(10)), ammo=self.scenar, winds=self.shot.winds)
+        self.calc.set_weapon_zero(shot, Distance.Yard(100))
+        row = self.calc.fire_at_ranges(shot, self.distances).trajectory[1]
+        self.assertAlmostEqual(row.drop_adj >> Angular.Mil,
+                               comparison.entry('short').rows[1].drop_adj >> Angular.Mil, places=6)
+        self.assertEqual(len(comparison.delta_matrix('drift')), 3)
+        with self.assertRaises(KeyError):
+            comparison.delta_matrix('distance')
+
+    def test_baseline_and_zero(self):
+        self.shot.weapon.zero_elevation = Angular.MOA(5)
+        comparison = compare_loads(self.calc, self.shot, [self.smk, self.scenar], self.distances, baseline='2')
+        self.assertEqual([e.name for e in comparison.entries], ['1', '2'])
+        self.assertEqual(comparison.entry('2').deltas[0].drop.raw_value, 0)
+        # Without zero_distance both loads keep the zero of the weapon
+        for entry in comparison.entries:
+            self.assertEqual(entry.shot.weapon.zero_elevation >> Angular.MOA, 5)
+        self.assertLess(comparison.entry('1').deltas[1].drop >> Distance.Inch, 0)
+        with self.assertRaises(KeyError):
+            compare_loads(self.calc, self.shot, [self.smk], self.distances, baseline='smk')
+
+
+if __name__ == '__main__':
+    unittest.main()
@@ -1,10 +1,13 @@
 """Unittests for the py_ballisticcalc library"""
 
+import math
 import unittest
 import copy
 from py_ballisticcalc import (
-    DragModel, Ammo, Weapon, Calculator, Shot, Wind, Atmo, TableG7,
-    get_global_use_powder_sensitivity, set_global_use_powder_sensitivity
+    DragModel, Ammo, Weapon, Calculator, Shot, Wind, WindLayer, Atmo, TableG7, Gravity, TrajFlag, BCReference,
+    CalculatorConfig, PowderTemperaturePoint,
+    get_global_use_powder_sensitivity, set_global_use_powder_sensitivity,
+    get_global_gravity, set_global_gravity
 )
 from py_ballisticcalc.unit import *
 
@@ -89,6 +92,36 @@ class TestComputer(unittest.TestCase):
                     winds=[Wind(Velocity(5, Velocity.MPH), Angular(6, Angular.OClock))])
         t = self.calc.fire(shot, trajectory_range=self.range, trajectory_step=self.step)
         self.assertLess(t.trajectory[5].height, self.baseline_trajectory[5].height)
+
+    def test_wind_layers(self):
+        """Wind aloft only acts where the projectile is above its from_height"""
+        wind = Wind(Velocity.MPH(10), Angular.OClock(3))
+        shot = Shot(weapon=self.weapon, ammo=self.ammo, atmo=self.atmosphere, relative_angle=Angular.MOA(60),
+                    winds=[wind])
+        ground = self.calc.fire(shot, self.range, self.step)
+        shot.winds = []
+        calm = self.calc.fire(shot, self.range, self.step)
+        apex = max(row.height >> Distance.Foot for row in calm.trajectory)
+
+        def windage_with(*layers: WindLayer) -> float:
+            shot.wind_layers = list(layers)
+            return self.calc.fire(shot, self.range, self.step)[5].windage >> Distance.Inch
+
+        self.assertAlmostEqual(windage_with(WindLayer(wind.velocity, wind.direction_from, None, Distance.Foot(-100))),
+                               ground[5].windage >> Distance.Inch)
+        self.assertAlmostEqual(windage_with(WindLayer(wind.velocity, wind.direction_from, None,
+                                                      Distance.Foot(apex + 1))),
+                               calm[5].windage >> Distance.Inch)
+        aloft = windage_with(WindLayer(wind.velocity, wind.direction_from, None, Distance.Foot(apex / 2)))
+        self.assertGreater(aloft, calm[5].windage >> Distance.Inch)
+        self.assertLess(aloft, ground[5].windage >> Distance.Inch)
+        # The highest layer reached replaces the lower ones, and layers end at until_distance
+        self.assertAlmostEqual(windage_with(WindLayer(wind.velocity, wind.direction_from, None, Distance.Foot(-100)),
+                                            WindLayer(0, 0, None, Distance.Foot(-10))),
+                               calm[5].windage >> Distance.Inch)
+        first_yard = windage_with(WindLayer(wind.velocity, wind.direction_from, Distance.Yard(1), Distance.Foot(-100)))
+        drift = (ground[5].windage >> Distance.Inch) - (calm[5].windage >> Distance.Inch)
+        self.assertLess(first_yard - (calm[5].windage >> Distance.Inch), drift / 10)
 #endregion Wind
         
 #region Twist
@@ -169,11 +202,216 @@ class TestComputer(unittest.TestCase):
         self.ammo.calc_powder_sens(Velocity.FPS(2550), Temperature.Celsius(0))
         cold = Atmo(temperature=Temperature.Celsius(-5))
         shot = Shot(weapon=self.weapon, ammo=self.ammo, atmo=cold)
-        t = self.calc.fire(shot=shot, trajectory_range=self.range, trajectory_step=self.step)
+        t = Calculator().fire(shot=shot, trajectory_range=self.range, trajectory_step=self.step)
         self.assertLess(t.trajectory[0].velocity, self.baseline_trajectory[0].velocity)
+        # Calculators created before keep the global settings they were created with
+        t = self.calc.fire(shot=shot, trajectory_range=self.range, trajectory_step=self.step)
+        self.assertEqual(t.trajectory[0].velocity, self.baseline_trajectory[0].velocity)
         set_global_use_powder_sensitivity(previous)
 
+    def test_powder_temperature_table(self):
+        """Measured velocities replace temp_modifier, interpolated and extrapolated by powder temperature"""
+        ammo = Ammo(self.dm, Velocity.FPS(2600), mv_by_temperature=[
+            (Temperature.Celsius(30), Velocity.FPS(2640)), PowderTemperaturePoint(Temperature.Celsius(0), 2550),
+            (Temperature.Celsius(15), Velocity.FPS(2600))])
+        self.assertEqual([p.temperature >> Temperature.Celsius for p in ammo.mv_by_temperature], [0, 15, 30])
+        self.assertAlmostEqual(ammo.get_velocity_for_temp(Temperature.Celsius(7.5)) >> Velocity.FPS, 2575)
+        self.assertAlmostEqual(ammo.get_velocity_for_temp(Temperature.Celsius(-15)) >> Velocity.FPS, 2500)
+        self.assertAlmostEqual(ammo.get_velocity_for_temp(Temperature.Celsius(45)) >> Velocity.FPS, 2680)
+
+        calc = Calculator(config=CalculatorConfig(use_powder_sensitivity=True))
+        cold = Shot(weapon=self.weapon, ammo=ammo, atmo=Atmo(temperature=Temperature.Celsius(0)))
+        self.assertAlmostEqual(calc.fire(cold, self.range, self.step)[0].velocity >> Velocity.FPS, 2550)
+        # Copies with another mv shift the table
+        self.assertAlmostEqual(ammo.with_mv(Velocity.FPS(2620)).get_velocity_for_temp(Temperature.Celsius(0))
+                               >> Velocity.FPS, 2570)
+        ammo.cold_bore_offset = Velocity.FPS(-15)
+        self.assertAlmostEqual(ammo.with_mv(ammo.mv_for_shot(1)).get_velocity_for_temp(Temperature.Celsius(30))
+                               >> Velocity.FPS, 2625)
+        with self.assertRaises(ValueError):
+            Ammo(self.dm, 2600, mv_by_temperature=[(15, 2600)])
+        with self.assertRaises(ValueError):
+            Ammo(self.dm, 2600, mv_by_temperature=[(15, 2600), (15, 2650)])
+
+    def test_set_powder_sensitivity(self):
+        """Sensitivity in velocity per degree of any temperature units"""
+        ammo = Ammo(self.dm, Velocity.FPS(2600), Temperature.Fahrenheit(59))
+        ammo.set_powder_sensitivity(Velocity.FPS(1.2), Unit.Fahrenheit)
+        self.assertAlmostEqual(ammo.get_velocity_for_temp(Temperature.Fahrenheit(79)) >> Velocity.FPS, 2624)
+        ammo.set_powder_sensitivity(Velocity.MPS(0.5), Unit.Celsius)
+        self.assertAlmostEqual(ammo.get_velocity_for_temp(Temperature.Celsius(5)) >> Velocity.MPS,
+                               (Velocity.FPS(2600) >> Velocity.MPS) - 5)
+
+    def test_barrel_length(self):
+        """Muzzle velocity measured in a test barrel is adjusted to the barrel of the weapon"""
+        ammo = Ammo(self.dm, Velocity.FPS(2600), barrel_length=Distance.Inch(24))
+        self.assertIsNone(Weapon().barrel_length)
+        self.assertEqual(ammo.barrel_velocity_change(None) >> Velocity.FPS, 0)
+        self.assertAlmostEqual(ammo.barrel_velocity_change(Distance.Inch(20)) >> Velocity.FPS, -100)
+        self.assertEqual(Ammo(self.dm, 2600).barrel_velocity_change(Distance.Inch(20)) >> Velocity.FPS, 0)
+
+        short = Shot(weapon=Weapon(4, 12, barrel_length=Distance.Inch(20)), ammo=ammo, atmo=self.atmosphere)
+        self.assertAlmostEqual(self.calc.fire(short, self.range, self.step)[0].velocity >> Velocity.FPS, 2500)
+        ammo.velocity_per_barrel_inch = Velocity.MPS(10)
+        self.assertAlmostEqual(ammo.barrel_velocity_change(Distance.Centimeter(71)) >> Velocity.MPS,
+                               10 * (71 / 2.54 - 24))
+
 #endregion Ammo
 
+#region Gravity
+    def test_gravity_presets(self):
+        """Drop with negligible drag scales with gravity and matches vacuum solution g*t^2/2"""
+        previous = get_global_gravity()
+        try:
+            shot = Shot(weapon=Weapon(), ammo=Ammo(DragModel(1e4, TableG7), Velocity.FPS(2600)))
+            drops = {}
+            for name in ('Earth', 'Moon', 'Mars'):
+                set_global_gravity(getattr(Gravity, name))
+                row = Calculator().fire(shot=shot, trajectory_range=self.range, trajectory_step=self.step)[5]
+                g = getattr(Gravity, name) >> Acceleration.FootPerSecondSquared
+                self.assertAlmostEqual(-(row.height >> Distance.Foot), g * row.time ** 2 / 2, 1)
+                drops[name] = row.height >> Distance.Foot
+            self.assertAlmostEqual(drops['Moon'] / drops['Earth'],
+                                   (Gravity.Moon >> Unit.MeterPerSecondSquared) / 9.80665, 2)
+        finally:
+            set_global_gravity(previous)
+        self.assertAlmostEqual(get_global_gravity() >> Acceleration.MeterPerSecondSquared, 9.80665, 4)
+
+    def test_gravity_validation(self):
+        with self.assertRaises(ValueError):
+            set_global_gravity(Acceleration.MeterPerSecondSquared(-1))
+        with self.assertRaises(UnitTypeError):
+            set_global_gravity(Distance.Meter(9.8))
+
+    def test_gravity_units(self):
+        """Floats are accelerations in PreferredUnits.acceleration, never distances"""
+        self.assertAlmostEqual(Acceleration.StandardGravity(1) >> Acceleration.FootPerSecondSquared, 32.17405, 5)
+        previous = PreferredUnits.acceleration
+        try:
+            PreferredUnits.acceleration = Unit.MeterPerSecondSquared
+            gravity = CalculatorConfig(gravity=9.80665).gravity
+            self.assertAlmostEqual(gravity >> Unit.FootPerSecondSquared, 32.17405, 5)
+        finally:
+            PreferredUnits.acceleration = previous
+        self.assertAlmostEqual(CalculatorConfig(gravity=32.17405).gravity >> Unit.StandardGravity, 1, 6)
+        with self.assertRaises(UnitTypeError):
+            CalculatorConfig(gravity=Distance.Meter(9.80665))
+#endregion Gravity
+
+    def test_drag_curve_reuse(self):
+        """Calculator keeps its TrajectoryCalc while the DragModel is unchanged"""
+        calc = Calculator()
+        calc.fire(self.baseline_shot, trajectory_range=self.range, trajectory_step=self.step)
+        trajectory_calc = calc._calc
+        t = calc.fire(self.baseline_shot, trajectory_range=self.range, trajectory_step=self.step)
+        self.assertIs(calc._calc, trajectory_calc)
+        self.assertEqual(t.trajectory[5].formatted(), self.baseline_trajectory[5].formatted())
+        dm = DragModel(0.3, TableG7, 168, 0.308, 1.22)
+        shot = Shot(weapon=self.weapon, ammo=Ammo(dm, self.ammo.mv), atmo=self.atmosphere)
+        calc.fire(shot, trajectory_range=self.range, trajectory_step=self.step)
+        self.assertIsNot(calc._calc, trajectory_calc)
+        trajectory_calc = calc._calc
+        dm.BC = 0.22  # Changing BC must not reuse the curve prepared for the old BC
+        t = calc.fire(shot, trajectory_range=self.range, trajectory_step=self.step)
+        self.assertIsNot(calc._calc, trajectory_calc)
+        self.assertEqual(t.trajectory[5].formatted(), self.baseline_trajectory[5].formatted())
+
+    def test_spin_and_stability(self):
+        t = self.baseline_trajectory.trajectory
+        self.assertAlmostEqual(t[0].spin_rate, (self.ammo.mv >> Velocity.FPS) * 12 / 12)
+        for previous, current in zip(t, t[1:]):
+            self.assertLess(current.spin_rate, previous.spin_rate)
+            # Spin decays slower than velocity, so gyroscopic stability increases downrange
+            self.assertGreater(current.stability, previous.stability)
+        self.assertFalse(any(row.flag & TrajFlag.UNSTABLE.value for row in t))
+
+        slow_twist = Shot(weapon=Weapon(4, 16), ammo=self.ammo, atmo=self.atmosphere)
+        t = self.calc.fire(slow_twist, trajectory_range=self.range, trajectory_step=self.step).trajectory
+        self.assertLess(t[0].stability, 1)
+        self.assertTrue(t[0].flag & TrajFlag.UNSTABLE.value)
+
+        no_twist = Shot(weapon=Weapon(4), ammo=self.ammo, atmo=self.atmosphere)
+        t = self.calc.fire(no_twist, trajectory_range=self.range, trajectory_step=self.step).trajectory
+        self.assertEqual(t[-1].spin_rate, 0)
+        self.assertEqual(t[-1].stability, 0)
+
+    def test_bc_reference(self):
+        """BC measured at altitude without correction is reduced to its standard-atmosphere value"""
+        reference = BCReference(atmo=Atmo.icao(altitude=Distance.Foot(5000)),
+                                velocity_low=Velocity.FPS(2000), velocity_high=Velocity.FPS(3000))
+        dm = DragModel(0.22, TableG7, 168, 0.308, 1.22, bc_reference=reference)
+        self.assertAlmostEqual(dm.standard_bc, 0.22 * reference.atmo.density_ratio)
+        self.assertLess(dm.standard_bc, dm.BC)
+        shot = Shot(weapon=self.weapon, ammo=Ammo(dm, self.ammo.mv), atmo=self.atmosphere)
+        t = self.calc.fire(shot, trajectory_range=self.range, trajectory_step=self.step)
+        self.assertLess(t.trajectory[5].velocity, self.baseline_trajectory[5].velocity)
+        corrected = Shot(weapon=self.weapon, ammo=Ammo(DragModel(dm.standard_bc, TableG7, 168, 0.308, 1.22),
+                                                       self.ammo.mv), atmo=self.atmosphere)
+        expected = self.calc.fire(corrected, trajectory_range=self.range, trajectory_step=self.step)
+        self.assertEqual(t.trajectory[5].formatted(), expected.trajectory[5].formatted())
+        self.assertTrue(reference.in_band(Velocity.FPS(2600)))
+        self.assertFalse(reference.in_band(Velocity.FPS(3100)))
+        with self.assertLogs('py_balcalc', level='WARNING'):
+            self.calc.fire(Shot(weapon=self.weapon, ammo=Ammo(dm, Velocity.FPS(3100))), trajectory_range=self.range)
+
+    def test_target_offset(self):
+        """Target 120m below over 900m ground distance"""
+        shot = Shot(weapon=Weapon(4, 12), ammo=self.ammo, atmo=self.atmosphere)
+        look_distance = shot.set_target_offset(Distance.Meter(900), Distance.Meter(-120))
+        self.assertAlmostEqual(shot.look_angle >> Angular.Radian, math.atan2(-120, 900))
+        self.assertAlmostEqual(look_distance >> Distance.Meter, math.hypot(900, 120))
+        self.calc.set_weapon_zero(shot, look_distance)
+        t = self.calc.fire(shot, trajectory_range=Distance.Meter(1000), extra_data=True)
+        row = t.get_at_distance(Distance.Meter(900))
+        self.assertAlmostEqual(row.height >> Distance.Meter, -120, 0)
+        with self.assertRaises(ValueError):
+            shot.set_target_offset(0, Distance.Meter(10))
+
+    def test_winds_along_sight_line(self):
+        """Wind flag ranged at 300yd along a 60 degree slope is 150yd down range"""
+        shot = Shot(weapon=Weapon(2, 12, zero_elevation=Angular.MOA(4)), ammo=self.ammo, atmo=self.atmosphere,
+                    look_angle=Angular.Degree(60))
+        winds = [Wind(Velocity.MPH(5), Angular.OClock(9), Distance.Yard(600)),
+                 Wind(Velocity.MPH(10), Angular.OClock(3), Distance.Yard(300))]
+        converted = shot.set_winds_along_sight_line(winds)
+        self.assertIs(shot.winds, converted)
+        self.assertEqual(winds[1].until_distance >> Distance.Yard, 300)
+        self.assertEqual([round(w.until_distance >> Distance.Yard, 9) for w in converted], [150, 300])
+        self.assertEqual(converted[0].until_distance.units, Unit.Yard)
+
+        ranges = [Distance.Yard(d) for d in (100, 200, 300)]
+        along_sight_line = self.calc.fire_at_ranges(shot, ranges)
+        shot.winds = [Wind(Velocity.MPH(10), Angular.OClock(3), Distance.Yard(150)),
+                      Wind(Velocity.MPH(5), Angular.OClock(9), Distance.Yard(300))]
+        horizontal = self.calc.fire_at_ranges(shot, ranges)
+        for a, b in zip(along_sight_line, horizontal):
+            self.assertAlmostEqual(a.windage >> Distance.Inch, b.windage >> Distance.Inch, 1)
+        shot.winds = sorted(winds, key=lambda w: w.until_distance.raw_value)
+        unconverted = self.calc.fire_at_ranges(shot, ranges)
+        self.assertGreater((unconverted[2].windage >> Distance.Inch) - (horizontal[2].windage >> Distance.Inch), 1)
+
+        self.assertEqual(shot.set_winds_along_sight_line([Wind(Velocity.MPH(5), Angular.OClock(9))])[0]
+                         .until_distance >> Distance.Foot, Wind.MAX_DISTANCE_FEET)
+
+    def test_zero_conditions(self):
+        """Zero found in the zeroing atmosphere and ammo carries over to the conditions of the shot"""
+        cold = Atmo(temperature=Temperature.Celsius(-20))
+        weapon = Weapon(4, 12)
+        shot = Shot(weapon=weapon, ammo=self.ammo, atmo=self.atmosphere)
+        zero = self.calc.set_weapon_zero(shot, Distance.Yard(100), zero_atmo=cold)
+        expected = self.calc.barrel_elevation_for_target(Shot(weapon=Weapon(4, 12), ammo=self.ammo, atmo=cold),
+                                                         Distance.Yard(100))
+        self.assertAlmostEqual(zero >> Angular.MOA, expected >> Angular.MOA, 6)
+        self.assertIs(shot.atmo, self.atmosphere)
+        # Thinner air than at zeroing: the shot lands high at the zero distance
+        self.assertGreater(self.calc.fire_at_ranges(shot, [Distance.Yard(100)])[0].target_drop >> Distance.Inch, 0)
+
+        slow = Ammo(self.dm, Velocity.FPS(2400))
+        zero = self.calc.set_weapon_zero(shot, Distance.Yard(100), zero_ammo=slow)
+        self.assertIs(shot.ammo, self.ammo)
+        self.assertIs(weapon.zero_elevation, zero)
+        self.assertGreater(self.calc.fire_at_ranges(shot, [Distance.Yard(100)])[0].target_drop >> Distance.Inch, 0)
+
+
 if __name__ == '__main__':
     unittest.main()
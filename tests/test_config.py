@@ -0,0 +1,259 @@
+"""Unittests for CalculatorConfig"""
+
+import json
+import os
+import tempfile
+import unittest
+from dataclasses import FrozenInstanceError, replace
+from py_ballisticcalc import *
+
+ROOT_DIR = os.path.dirname(os.path.dirname(__file__))
+
+
+class TestCalculatorConfig(unittest.TestCase):
+
+    def setUp(self) -> None:
+        self.terminations = []
+        self.hooks = TrajectoryHooks(on_termination=lambda state, reason: self.terminations.append(reason))
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        self.shot = Shot(weapon=Weapon(2, 12, zero_elevation=Angular.MOA(4)), ammo=Ammo(dm, Velocity.FPS(2750)))
+
+    def test_defaults_match_globals(self):
+        default = Calculator().fire(self.shot, Distance.Yard(1000), Distance.Yard(100))
+        configured = Calculator(config=CalculatorConfig()).fire(self.shot, Distance.Yard(1000), Distance.Yard(100))
+        for a, b in zip(default.trajectory, configured.trajectory):
+            self.assertEqual(a.height.raw_value, b.height.raw_value)
+            self.assertEqual(a.windage.raw_value, b.windage.raw_value)
+
+    def test_validation(self):
+        with self.assertRaises(ValueError):
+            CalculatorConfig(max_calc_step_size=Distance.Foot(0))
+        with self.assertRaises(ValueError):
+            CalculatorConfig(gravity=Acceleration.FootPerSecondSquared(-1))
+        with self.assertRaises(ValueError):
+            CalculatorConfig(max_iterations=0)
+
+    def test_frozen(self):
+        degradation = TransonicDegradation()
+        config = CalculatorConfig(max_calc_step_size=Distance.Foot(0.5), transonic_degradation=degradation)
+        with self.assertRaises(FrozenInstanceError):
+            config.max_calc_step_size = Distance.Foot(5)
+        with self.assertRaises(FrozenInstanceError):
+            config.transonic_degradation.drag_increase = 1
+        self.assertEqual(TransonicDegradation(dispersion=2).dispersion, PreferredUnits.adjustment(2))
+        self.assertEqual(CalculatorConfig(minimum_velocity=100).minimum_velocity, PreferredUnits.velocity(100))
+        changed = replace(config, max_calc_step_size=Distance.Foot(5))
+        self.assertEqual(changed.max_calc_step_size >> Distance.Foot, 5)
+        self.assertEqual(config.max_calc_step_size >> Distance.Foot, 0.5)
+        # Global settings are read when a Calculator is created
+        calc = Calculator()
+        previous = get_global_max_calc_step_size()
+        set_global_max_calc_step_size(Distance.Foot(5))
+        try:
+            self.assertEqual(calc.config.max_calc_step_size, previous)
+            self.assertEqual(Calculator().config.max_calc_step_size >> Distance.Foot, 5)
+        finally:
+            set_global_max_calc_step_size(previous)
+
+    def test_from_dict(self):
+        config = CalculatorConfig.from_dict({
+            'max_calc_step_size': {'value': 0.2, 'units': 'Meter'},
+            'minimum_velocity': '100fps',
+            'gravity': Gravity.Moon,
+            'use_spin_drift': False,
+        })
+        self.assertAlmostEqual(config.max_calc_step_size >> Distance.Meter, 0.2)
+        self.assertAlmostEqual(config.minimum_velocity >> Velocity.FPS, 100)
+        self.assertEqual(config.gravity, Gravity.Moon)
+        self.assertFalse(config.use_spin_drift)
+        with self.assertRaises(KeyError):
+            CalculatorConfig.from_dict({'unknown': 1})
+        config = CalculatorConfig.from_dict({'maximum_drop': {'value': -30, 'units': 'm'}})
+        self.assertAlmostEqual(config.maximum_drop >> Distance.Meter, -30)
+        with self.assertRaises(UnitAliasError):
+            CalculatorConfig.from_dict({'maximum_drop': {'value': -30, 'units': 'furlong'}})
+        with self.assertRaises(UnitTypeError):
+            CalculatorConfig.from_dict({'maximum_drop': {'value': -30, 'units': 'fps'}})
+
+    def test_from_files(self):
+        config = CalculatorConfig.from_toml(os.path.join(ROOT_DIR, '.pybc.toml'))
+        self.assertAlmostEqual(config.max_calc_step_size >> Distance.Foot, 0.5)
+        self.assertFalse(config.use_powder_sensitivity)
+
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, 'config.json')
+            with open(path, 'w', encoding='utf-8') as fp:
+                json.dump({'maximum_drop': {'value': -100, 'units': 'Foot'}, 'max_iterations': 5}, fp)
+            config = CalculatorConfig.from_json(path)
+        self.assertAlmostEqual(config.maximum_drop >> Distance.Foot, -100)
+        self.assertEqual(config.max_iterations, 5)
+
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, 'config.toml')
+            with open(path, 'w', encoding='utf-8') as fp:
+                fp.write('[pybc.preferred_units]\ndistance = "meter"\n')
+            self.assertEqual(CalculatorConfig.from_toml(path), CalculatorConfig())
+            with open(path, 'w', encoding='utf-8') as fp:
+                fp.write('max_iterations = 7\n')
+            self.assertEqual(CalculatorConfig.from_toml(path).max_iterations, 7)
+
+    def test_minimum_velocity(self):
+        config = CalculatorConfig(minimum_velocity=Velocity.FPS(2000))
+        hit = Calculator(hooks=self.hooks, config=config).fire(self.shot, Distance.Yard(1000), Distance.Yard(100))
+        self.assertEqual(self.terminations[-1], 'minimum_velocity')
+        self.assertLess(hit.trajectory[-1].distance >> Distance.Yard, 1000)
+
+    def test_minimum_altitude(self):
+        self.shot.atmo = Atmo.icao(altitude=Distance.Foot(100))
+        config = CalculatorConfig(minimum_altitude=Distance.Foot(0))
+        Calculator(hooks=self.hooks, config=config).fire(self.shot, Distance.Yard(2000), Distance.Yard(100))
+        self.assertEqual(self.terminations[-1], 'minimum_altitude')
+
+    def test_maximum_time(self):
+        config = CalculatorConfig(maximum_time=0.5)
+        hit = Calculator(hooks=self.hooks, config=config).fire(self.shot, Distance.Yard(1000), Distance.Yard(10))
+        self.assertEqual(hit.termination_reason, 'maximum_time')
+        self.assertTrue(hit.incomplete)
+        self.assertLessEqual(hit.trajectory[-1].time, 0.5)
+        self.assertGreater(hit.trajectory[-1].time, 0.45)
+        with self.assertRaises(ValueError):
+            CalculatorConfig(maximum_time=0)
+        self.assertEqual(config.maximum_time, Time.Second(0.5))
+        self.assertEqual(CalculatorConfig(maximum_time=Time.Millisecond(500)).maximum_time >> Time.Second, 0.5)
+        self.assertEqual(CalculatorConfig.from_dict({'maximum_time': {'value': 500, 'units': 'ms'}}).maximum_time
+                         >> Time.Second, 0.5)
+        self.assertEqual(CalculatorConfig.from_dict({'maximum_time': '500ms'}).maximum_time >> Time.Second, 0.5)
+        with self.assertRaises(UnitTypeError):
+            CalculatorConfig.from_dict({'maximum_time': {'value': 5, 'units': 'Meter'}})
+
+    def test_minimum_energy(self):
+        config = CalculatorConfig(minimum_energy=Energy.FootPound(1000))
+        hit = Calculator(config=config).fire(self.shot, Distance.Yard(1500), Distance.Yard(10))
+        self.assertEqual(hit.termination_reason, 'minimum_energy')
+        self.assertGreaterEqual(hit.trajectory[-1].energy >> Energy.FootPound, 1000)
+        self.assertLess(hit.trajectory[-1].energy >> Energy.FootPound, 1050)
+        no_weight = Shot(weapon=self.shot.weapon, ammo=Ammo(DragModel(0.223, TableG7), Velocity.FPS(2750)))
+        with self.assertRaises(ValueError):
+            Calculator(config=config).fire(no_weight, Distance.Yard(1000))
+
+    def test_step_tolerance(self):
+        def steps(config: CalculatorConfig):
+            counted = []
+            hooks = TrajectoryHooks(on_step=lambda state: counted.append(state) and False)
+            calc = Calculator(hooks=hooks, config=config)
+            return calc.fire(self.shot, Distance.Yard(1500), Distance.Yard(100)), len(counted)
+
+        reference, _ = steps(CalculatorConfig(max_calc_step_size=Distance.Foot(0.05)))
+        fixed, fixed_steps = steps(CalculatorConfig())
+        adaptive, adaptive_steps = steps(CalculatorConfig(step_tolerance=Distance.Inch(2)))
+        self.assertLess(adaptive_steps, fixed_steps / 3)
+        self.assertEqual(len(adaptive.trajectory), len(fixed.trajectory))
+        for a, b in zip(adaptive.trajectory, reference.trajectory):
+            self.assertEqual(a.distance >> Distance.Yard, b.distance >> Distance.Yard)
+            self.assertAlmostEqual(a.height >> Distance.Inch, b.height >> Distance.Inch, delta=2)
+
+        tight, tight_steps = steps(CalculatorConfig(step_tolerance=Distance.Inch(0.2)))
+        self.assertGreater(tight_steps, adaptive_steps)
+        self.assertAlmostEqual(tight[-1].height >> Distance.Inch, reference[-1].height >> Distance.Inch, delta=0.2)
+
+        calc = Calculator(config=CalculatorConfig(step_tolerance=Distance.Inch(0.5)))
+        zero = calc.barrel_elevation_for_target(self.shot, Distance.Yard(300))
+        expected = Calculator().barrel_elevation_for_target(self.shot, Distance.Yard(300))
+        self.assertAlmostEqual(zero >> Angular.MOA, expected >> Angular.MOA, delta=0.05)
+        with self.assertRaises(ValueError):
+            CalculatorConfig(step_tolerance=Distance.Inch(0))
+
+    def test_transonic_degradation(self):
+        degradation = TransonicDegradation(stability_threshold=3, drag_increase=0.2, dispersion=Angular.MOA(2))
+        config = CalculatorConfig(transonic_degradation=degradation)
+        # Stable projectile is not affected
+        stable = Calculator(config=config).fire(self.shot, Distance.Yard(1400), Distance.Yard(100))
+        baseline = Calculator().fire(self.shot, Distance.Yard(1400), Distance.Yard(100))
+        self.assertEqual(stable.trajectory[-1].formatted(), baseline.trajectory[-1].formatted())
+
+        marginal = Shot(weapon=Weapon(2, 20, zero_elevation=Angular.MOA(4)), ammo=self.shot.ammo)
+        baseline = Calculator().fire(marginal, Distance.Yard(1400), Distance.Yard(100))
+        degraded = Calculator(config=config).fire(marginal, Distance.Yard(1400), Distance.Yard(100))
+        self.assertLess(degraded.trajectory[-1].velocity >> Velocity.FPS, baseline.trajectory[-1].velocity >> Velocity.FPS)
+        self.assertLess(degraded.trajectory[-1].height >> Distance.Inch, baseline.trajectory[-1].height >> Distance.Inch)
+        self.assertGreater(degraded.trajectory[-1].dispersion >> Distance.Inch, 0)
+        self.assertEqual(degraded.trajectory[0].dispersion.raw_value, 0)
+        self.assertEqual(baseline.trajectory[-1].dispersion.raw_value, 0)
+
+        config = CalculatorConfig.from_dict({'transonic_degradation': {'drag_increase': 0.3}})
+        self.assertEqual(config.transonic_degradation.drag_increase, 0.3)
+
+    def test_spin_drift_disabled(self):
+        config = CalculatorConfig(use_spin_drift=False)
+        with_drift = Calculator().fire(self.shot, Distance.Yard(1000), Distance.Yard(100))
+        without_drift = Calculator(config=config).fire(self.shot, Distance.Yard(1000), Distance.Yard(100))
+        self.assertNotAlmostEqual(with_drift.trajectory[-1].windage >> Distance.Inch, 0, 1)
+        self.assertAlmostEqual(without_drift.trajectory[-1].windage >> Distance.Inch, 0, 9)
+
+    def test_spin_drift_model(self):
+        def drift(model, twist=12) -> list[float]:
+            self.shot.weapon.twist = twist
+            hit = Calculator(config=CalculatorConfig(spin_drift_model=model)).fire(
+                self.shot, Distance.Yard(1500), Distance.Yard(300))
+            return [row.windage >> Distance.Inch for row in hit]
+
+        litz = drift(SpinDriftModel.LITZ)
+        integrated = drift('yaw_of_repose')
+        # Close to Litz's approximation while supersonic, growing faster once subsonic
+        for a, b in zip(litz[1:4], integrated[1:4]):
+            self.assertAlmostEqual(b / a, 1, delta=0.25)
+        self.assertGreater(integrated[-1], litz[-1])
+        self.assertAlmostEqual(drift(SpinDriftModel.YAW_OF_REPOSE, -12)[-1], -integrated[-1], 6)
+        self.assertEqual(drift(SpinDriftModel.NONE)[-1], 0)
+        self.assertEqual(drift(SpinDriftModel.YAW_OF_REPOSE, 0)[-1], 0)
+        self.shot.weapon.twist = 12
+        pejsa = Calculator(engine=PejsaCalc, config=CalculatorConfig(spin_drift_model=SpinDriftModel.YAW_OF_REPOSE))
+        self.assertGreater(pejsa.fire(self.shot, Distance.Yard(600), Distance.Yard(600))[-1].windage >> Distance.Inch, 1)
+        with self.assertRaises(ValueError):
+            CalculatorConfig(spin_drift_model='unknown')
+
+    def test_extreme_range(self):
+        def heights(shot: Shot, ranges: list, extreme_range: bool, step: float = 0.5) -> list[float]:
+            calc = Calculator(config=CalculatorConfig(extreme_range=extreme_range,
+                                                      max_calc_step_size=Distance.Foot(step)))
+            return [row.height >> Distance.Meter for row in calc.fire_at_ranges(shot, ranges)]
+
+        ranges = [Distance.Meter(d) for d in (300, 1000, 2500)]
+        flat = heights(self.shot, ranges, False)
+        extreme = heights(self.shot, ranges, True)
+        self.assertAlmostEqual(extreme[0], flat[0], 3)
+        # Gravity turns against the direction of fire over the curvature of the Earth
+        self.assertLess(extreme[-1], flat[-1] - 0.005)
+
+        # Steps along the path stay accurate as the trajectory turns vertical
+        steep = Shot(weapon=self.shot.weapon, ammo=self.shot.ammo, relative_angle=Angular.Degree(88))
+        ranges = [Distance.Meter(50)]
+        coarse, fine = heights(steep, ranges, True, 2), heights(steep, ranges, True, 0.5)
+        self.assertAlmostEqual(coarse[0], fine[0], delta=0.05)
+        self.assertGreater(fine[0] - heights(steep, ranges, False, 2)[0], 1)
+
+    def test_adjustment_reference(self):
+        self.shot.look_angle = Angular.Degree(5)
+        results = {reference: Calculator(config=CalculatorConfig(adjustment_reference=reference))
+                   .fire(self.shot, Distance.Yard(1000), Distance.Yard(100)).trajectory
+                   for reference in AdjustmentReference}
+        for reference, trajectory in results.items():
+            with self.subTest(reference=reference):
+                self.assertEqual(trajectory[0].drop_adj.raw_value, 0)
+                self.assertEqual(trajectory[0].windage_adj.raw_value, 0)
+        sight = results[AdjustmentReference.SIGHT]
+        horizontal = results[AdjustmentReference.HORIZONTAL]
+        bore = results[AdjustmentReference.BORE]
+        for s, h, b in zip(sight[1:], horizontal[1:], bore[1:]):
+            self.assertAlmostEqual((h.drop_adj >> Angular.Degree) - (s.drop_adj >> Angular.Degree), 5, 9)
+            # Bullet only falls below the bore line
+            self.assertLess(b.drop_adj >> Angular.Radian, 0)
+            self.assertLess(b.drop_adj >> Angular.Radian, s.drop_adj >> Angular.Radian)
+        self.assertEqual(CalculatorConfig.from_dict({'adjustment_reference': 'bore'}).adjustment_reference,
+                         AdjustmentReference.BORE)
+        with self.assertRaises(ValueError):
+            CalculatorConfig(adjustment_reference='muzzle')
+
+
+if __name__ == '__main__':
+    unittest.main()
@@ -1,7 +1,8 @@
 import os
+import tempfile
 from unittest import TestCase
-from py_ballisticcalc import (basicConfig, PreferredUnits, Unit,
-                              get_global_max_calc_step_size, reset_globals)
+from py_ballisticcalc import (basicConfig, PreferredUnits, Unit, Acceleration,
+                              get_global_max_calc_step_size, get_global_gravity, reset_globals)
 
 ASSETS_DIR = os.path.join(
     os.path.dirname(
@@ -38,3 +39,25 @@ class TestConfigLoader(TestCase):
         basicConfig()
         reset_globals()
         PreferredUnits.defaults()
+
+    def test_zero_gravity(self):
+        try:
+            basicConfig(gravity=0)
+            self.assertEqual(get_global_gravity().raw_value, 0)
+            reset_globals()
+            basicConfig(gravity=Acceleration.MeterPerSecondSquared(0))
+            self.assertEqual(get_global_gravity().raw_value, 0)
+            with self.assertRaises(ValueError):
+                basicConfig(os.path.join(ASSETS_DIR, ".pybc-imperial.toml"), gravity=0)
+
+            reset_globals()
+            with tempfile.TemporaryDirectory() as tmp:
+                path = os.path.join(tmp, '.pybc.toml')
+                with open(path, 'w', encoding='utf-8') as fp:
+                    fp.write('[pybc.calculator]\ngravity = {value = 0, units = "MeterPerSecondSquared"}\n')
+                basicConfig(path)
+            self.assertEqual(get_global_gravity().raw_value, 0)
+        finally:
+            basicConfig()
+            reset_globals()
+            PreferredUnits.defaults()
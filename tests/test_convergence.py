@@ -0,0 +1,53 @@
+"""Unittests for the step-size convergence report"""
+
+import unittest
+from py_ballisticcalc import *
+
+
+class TestConvergence(unittest.TestCase):
+
+    def setUp(self) -> None:
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        self.shot = Shot(weapon=Weapon(2, 12, zero_elevation=Angular.MOA(4)),
+                         ammo=Ammo(dm, Velocity.FPS(2750)),
+                         winds=[Wind(Velocity.MPH(10), Angular.Degree(90))])
+
+    def test_errors_shrink_with_step_size(self):
+        ranges = [Distance.Yard(300), Distance.Yard(600)]
+        step_sizes = [Distance.Foot(8), Distance.Foot(4), Distance.Foot(2), Distance.Foot(0.5)]
+        report = step_size_convergence(self.shot, ranges, step_sizes)
+        self.assertEqual(len(report), len(ranges) * len(step_sizes))
+        self.assertEqual([r.step_size for r in report[::2]], step_sizes)
+        # Finest step size is the reference
+        for row in report[-2:]:
+            self.assertEqual(row.height_error.raw_value, 0)
+            self.assertEqual(row.time_error, 0)
+        for i in range(len(ranges)):
+            errors = [abs(row.height_error.raw_value) for row in report[i::len(ranges)]]
+            self.assertEqual(errors, sorted(errors, reverse=True))
+            self.assertGreater(errors[0], 0)
+        self.assertAlmostEqual(report[1].distance >> Distance.Yard, 600)
+
+    def test_globals_unchanged(self):
+        previous = get_global_max_calc_step_size()
+        report = step_size_convergence(self.shot, [Distance.Yard(100)])
+        self.assertEqual(get_global_max_calc_step_size().raw_value, previous.raw_value)
+        self.assertAlmostEqual(report[0].step_size >> Distance.Foot, 4 * (previous >> Distance.Foot))
+
+    def test_config(self):
+        config = CalculatorConfig(max_calc_step_size=Distance.Foot(1), gravity=Gravity.Moon)
+        report = step_size_convergence(self.shot, [Distance.Yard(300)], config=config)
+        self.assertAlmostEqual(report[0].step_size >> Distance.Foot, 4)
+        # Other settings of config apply to every step size
+        expected = Calculator(config=config).fire_at_ranges(self.shot, [Distance.Yard(300)])[0]
+        self.assertAlmostEqual(report[2].height >> Distance.Inch, expected.height >> Distance.Inch, 6)
+        self.assertAlmostEqual(config.max_calc_step_size >> Distance.Foot, 1)
+
+    def test_unreachable_range(self):
+        with self.assertRaises(ArithmeticError):
+            step_size_convergence(Shot(weapon=Weapon(), ammo=Ammo(DragModel(0.01, TableG1), Velocity.FPS(1000))),
+                                  [Distance.Yard(5000)], [Distance.Foot(2)])
+
+
+if __name__ == '__main__':
+    unittest.main()
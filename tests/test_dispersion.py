@@ -0,0 +1,64 @@
+"""Unittests for the Monte Carlo dispersion and hit probability"""
+
+import unittest
+from py_ballisticcalc import *
+
+
+class TestDispersion(unittest.TestCase):
+
+    def setUp(self) -> None:
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        self.shot = Shot(weapon=Weapon(2, 10), ammo=Ammo(dm, Velocity.FPS(2750)))
+        self.calc = Calculator()
+        self.calc.set_weapon_zero(self.shot, Distance.Yard(100))
+        self.uncertainty = ShotUncertainty(mv_sd=Velocity.FPS(10), bc_sd=0.02, wind_sd=Velocity.MPH(2),
+                                           range_sd=Distance.Yard(10), precision=Angular.MOA(1))
+
+    def sample(self, uncertainty: ShotUncertainty, samples: int = 20, **kwargs) -> ImpactDistribution:
+        return sample_impacts(self.calc, self.shot, Distance.Yard(600), uncertainty, samples, seed=1, **kwargs)
+
+    def test_no_uncertainty(self):
+        result = self.sample(ShotUncertainty(), 5)
+        for impact in result.impacts:
+            self.assertAlmostEqual(impact.vertical >> Distance.Inch, 0, 6)
+            self.assertAlmostEqual(impact.horizontal >> Distance.Inch, 0, 6)
+        self.assertEqual(result.hit_probability(Distance.Inch(1)), 1)
+
+    def test_error_sources(self):
+        vertical = self.sample(ShotUncertainty(mv_sd=Velocity.FPS(15))).standard_deviation
+        self.assertGreater(vertical.vertical >> Distance.Inch, 1)
+        self.assertLess(vertical.horizontal >> Distance.Inch, 0.1)
+        wind = self.sample(ShotUncertainty(wind_sd=Velocity.MPH(2))).standard_deviation
+        self.assertGreater(wind.horizontal >> Distance.Inch, 3)
+        self.assertLess(wind.vertical >> Distance.Inch, 0.5)
+        ranging = self.sample(ShotUncertainty(range_sd=Distance.Yard(20))).standard_deviation
+        self.assertGreater(ranging.vertical >> Distance.Inch, 3)
+        group = self.sample(ShotUncertainty(precision=Angular.MOA(2)), 200).standard_deviation
+        expected = (Angular.MOA(2) >> Angular.Radian) * (Distance.Yard(600) >> Distance.Inch) / 2 / 2.4477
+        self.assertAlmostEqual(group.vertical >> Distance.Inch, expected, delta=expected * 0.2)
+
+    def test_hit_probability(self):
+        result = self.sample(self.uncertainty, 100)
+        self.assertEqual(len(result.impacts), 100)
+        large = result.hit_probability(Distance.Inch(24))
+        small = result.hit_probability(Distance.Inch(6))
+        self.assertGreater(large, small)
+        self.assertLessEqual(result.hit_probability(Distance.Inch(24), Distance.Inch(6)), large)
+        self.assertEqual(result.hit_probability(Distance.Inch(1000)), 1)
+
+    def test_repeatable(self):
+        first = self.sample(self.uncertainty, 10)
+        self.assertEqual(first, self.sample(self.uncertainty, 10))
+        self.assertEqual(first, self.sample(self.uncertainty, 10, workers=2))
+
+    def test_invalid(self):
+        with self.assertRaises(ValueError):
+            ShotUncertainty(bc_sd=-0.1)
+        with self.assertRaises(ValueError):
+            self.sample(self.uncertainty, 0)
+        with self.assertRaises(ArithmeticError):
+            sample_impacts(self.calc, self.shot, Distance.Yard(10000), ShotUncertainty(), 1)
+
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,87 @@
+"""Unittests for the SQLite DOPE book"""
+
+import os
+import tempfile
+import unittest
+from py_ballisticcalc import *
+
+
+class TestDopeBook(unittest.TestCase):
+
+    def setUp(self) -> None:
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282, bc_reference=BCReference(Atmo.icao(5000)))
+        self.shot = Shot(weapon=Weapon(2, 12, sight=Sight(Sight.FocalPlane.FFP, 2, Angular.MOA(0.25),
+                                                            Angular.MOA(0.25))),
+                         ammo=Ammo(dm, Velocity.FPS(2750), mv_by_temperature=[(Temperature.Celsius(0), 2700),
+                                                                              (Temperature.Celsius(30), 2780)]),
+                         atmo=Atmo(Distance.Foot(1000), Pressure.InHg(29), Temperature.Fahrenheit(40), 50),
+                         winds=[Wind(Velocity.MPH(5), Angular.OClock(3), Distance.Yard(500)),
+                                Wind(Velocity.MPH(8), Angular.OClock(4))],
+                         look_angle=Angular.Degree(5))
+        self.calc = Calculator()
+        self.calc.set_weapon_zero(self.shot, Distance.Yard(100))
+
+    def test_scenario_round_trip(self):
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, 'dope.db')
+            with DopeBook(path) as book:
+                scenario_id = book.add_scenario('308 at 1000ft', self.shot)
+            with DopeBook(path) as book:
+                self.assertEqual([s[:2] for s in book.scenarios()], [(scenario_id, '308 at 1000ft')])
+                self.assertEqual(book.scenarios('other'), [])
+                shot = book.scenario(scenario_id).shot
+        self.assertEqual(shot.weapon.sight.focal_plane, Sight.FocalPlane.FFP)
+        self.assertEqual(len(shot.winds), 2)
+        self.assertEqual(shot.ammo.mv_by_temperature, self.shot.ammo.mv_by_temperature)
+        expected = self.calc.fire(self.shot, Distance.Yard(1000), Distance.Yard(100))
+        actual = Calculator().fire(shot, Distance.Yard(1000), Distance.Yard(100))
+        self.assertEqual([r.formatted() for r in actual], [r.formatted() for r in expected])
+
+    def test_atmo_model_round_trip(self):
+        shot = Shot(weapon=self.shot.weapon, ammo=self.shot.ammo, atmo=self.shot.atmo,
+                    atmo_model=Atmo.icao(Distance.Foot(5000)))
+        with DopeBook() as book:
+            stored = book.scenario(book.add_scenario('thin air', shot)).shot
+            self.assertIsInstance(stored.atmo_model, Atmo)
+            self.assertIsNone(book.scenario(book.add_scenario('plain', self.shot)).shot.atmo_model)
+
+            class Sounding(AtmosphereModel):
+                def get_density_factor_and_mach_for_altitude(self, altitude):
+                    return 0.9, 1100
+
+            with self.assertRaises(TypeError):
+                book.add_scenario('sounding', Shot(weapon=self.shot.weapon, ammo=self.shot.ammo,
+                                                   atmo_model=Sounding()))
+            self.assertEqual(len(book.scenarios()), 2)
+        expected = self.calc.fire(shot, Distance.Yard(1000), Distance.Yard(100))
+        actual = Calculator().fire(stored, Distance.Yard(1000), Distance.Yard(100))
+        self.assertEqual([r.formatted() for r in actual], [r.formatted() for r in expected])
+
+    def test_solutions_and_impacts(self):
+        with DopeBook() as book:
+            scenario_id = book.add_scenario('308', self.shot)
+            hit = self.calc.fire(self.shot, Distance.Yard(1000), Distance.Yard(100))
+            self.assertEqual(len(book.add_solutions(scenario_id, hit)), 11)
+            solutions = book.solutions(scenario_id, Distance.Yard(300), Distance.Yard(500))
+            self.assertEqual([round(s.distance >> Distance.Yard) for s in solutions], [300, 400, 500])
+            self.assertAlmostEqual(solutions[0].drop_adj >> Angular.MOA, hit[3].drop_adj >> Angular.MOA, 9)
+
+            book.add_impact(scenario_id, Distance.Yard(600), Angular.MOA(-12), Angular.MOA(1), 'cold bore')
+            book.add_impact(scenario_id, Distance.Yard(650), Angular.MOA(-14))
+            impacts = book.impacts(scenario_id)
+            self.assertEqual(len(impacts), 2)
+            self.assertEqual(impacts[0].note, 'cold bore')
+            entries = book.entries(scenario_id)
+            self.assertEqual(len(entries), 1)  # No solution at 650 yards
+            self.assertAlmostEqual(entries[0].elevation_error >> Angular.MOA, (hit[6].drop_adj >> Angular.MOA) + 12, 9)
+            self.assertAlmostEqual(entries[0].windage_error >> Angular.MOA, (hit[6].windage_adj >> Angular.MOA) - 1, 9)
+
+            book.delete_scenario(scenario_id)
+            self.assertEqual(book.solutions(scenario_id), [])
+            self.assertEqual(book.impacts(scenario_id), [])
+            with self.assertRaises(KeyError):
+                book.scenario(scenario_id)
+
+
+if __name__ == '__main__':
+    unittest.main()
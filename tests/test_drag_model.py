@@ -0,0 +1,125 @@
+"""Unittests for sectional density, form factor, BC conversion and the drag table registry"""
+
+import unittest
+from py_ballisticcalc import *
+from py_ballisticcalc import drag_tables
+from py_ballisticcalc.profile_loader import parse_drag
+
+
+class TestBCConversion(unittest.TestCase):
+
+    def test_form_factor(self):
+        # 175gr .308 with G7 BC 0.243 and G1 BC 0.505
+        self.assertAlmostEqual(sectional_density(175, 0.308), 0.2635, 4)
+        self.assertAlmostEqual(form_factor(0.243, Weight.Grain(175), Distance.Inch(0.308)), 1.0845, 4)
+        self.assertAlmostEqual(form_factor(0.505, Weight.Grain(175), Distance.Inch(0.308)), 0.5219, 4)
+        self.assertAlmostEqual(form_factor(0.243, Weight.Gram(11.34), Distance.Millimeter(7.823)), 1.0845, 3)
+        with self.assertRaises(ValueError):
+            form_factor(0, 175, 0.308)
+
+    def test_convert_bc(self):
+        g7 = convert_bc(0.505, TableG1, TableG7, Velocity.FPS(2600))
+        self.assertAlmostEqual(g7, 0.255, 2)
+        self.assertAlmostEqual(convert_bc(g7, TableG7, TableG1, Velocity.FPS(2600)), 0.505)
+        # The tables differ in shape, so the conversion depends on velocity
+        self.assertGreater(convert_bc(0.505, TableG1, TableG7, Velocity.FPS(1300)), g7 + 0.05)
+        with self.assertRaises(ValueError):
+            convert_bc(-1, TableG1, TableG7, 2600)
+
+    def test_same_drag_at_velocity(self):
+        g7 = convert_bc(0.505, TableG1, TableG7, Velocity.FPS(2600))
+        rows = []
+        for dm in (DragModel(0.505, TableG1), DragModel(g7, TableG7)):
+            shot = Shot(weapon=Weapon(), ammo=Ammo(dm, Velocity.FPS(2610)))
+            rows.append(Calculator().fire_at_ranges(shot, [Distance.Yard(10)]).trajectory[0])
+        self.assertAlmostEqual(rows[0].velocity >> Velocity.FPS, rows[1].velocity >> Velocity.FPS, 1)
+
+
+class TestDragTableRegistry(unittest.TestCase):
+
+    def setUp(self) -> None:
+        self.table = [{'Mach': 0.0, 'CD': 0.3}, {'Mach': 1.0, 'CD': 0.5}, {'Mach': 3.0, 'CD': 0.4}]
+
+    def tearDown(self) -> None:
+        drag_tables._drag_table_registry.pop('G1-CUSTOM', None)
+
+    def test_builtin(self):
+        self.assertEqual(registered_drag_tables()[:2], ['G1', 'G7'])
+        self.assertIs(get_drag_table('g7'), TableG7)
+        self.assertIs(get_drag_table('TableG7'), TableG7)
+        with self.assertRaises(KeyError):
+            get_drag_table('G9')
+
+    def test_register(self):
+        register_drag_table('G1-custom', self.table)
+        self.table[0]['CD'] = 1  # The registry keeps its own copy
+        self.assertEqual(get_drag_table('g1-CUSTOM')[0]['CD'], 0.3)
+        self.assertIn('G1-CUSTOM', registered_drag_tables())
+        with self.assertRaises(ValueError):
+            register_drag_table('G1-custom', self.table)
+        register_drag_table('G1-custom', [DragDataPoint(0, 0.2), DragDataPoint(2, 0.3)], replace=True)
+        self.assertEqual(get_drag_table('G1-custom'), [{'Mach': 0, 'CD': 0.2}, {'Mach': 2, 'CD': 0.3}])
+        with self.assertRaises(ValueError):
+            register_drag_table('', self.table)
+        with self.assertRaises(ValueError):
+            register_drag_table('empty', [])
+
+    def test_drag_model_by_name(self):
+        register_drag_table('G1-custom', self.table)
+        dm = DragModel(0.3, 'G1-custom')
+        self.assertEqual([p.CD for p in dm.drag_table], [0.3, 0.5, 0.4])
+        multi = DragModelMultiBC([BCPoint(0.3, 1), BCPoint(0.25, 2)], 'G1-custom')
+        self.assertEqual(get_drag_table('G1-custom')[1]['CD'], 0.5)  # Not changed by the multi-BC fit
+        self.assertEqual(len(multi.drag_table), 3)
+        by_name = Calculator().fire(Shot(weapon=Weapon(), ammo=Ammo(DragModel(0.223, 'G7'), 2750)), 1000)
+        by_table = Calculator().fire(Shot(weapon=Weapon(), ammo=Ammo(DragModel(0.223, TableG7), 2750)), 1000)
+        self.assertEqual([r.formatted() for r in by_name], [r.formatted() for r in by_table])
+
+    def test_profile_model(self):
+        register_drag_table('G1-custom', self.table)
+        drag = {'bullet_weight': '175gr', 'bullet_diameter': '0.308in', 'bullet_length': '1.24in',
+                'model': 'g1-custom', 'bc': 0.3}
+        self.assertEqual([p.CD for p in parse_drag(drag).drag_table], [0.3, 0.5, 0.4])
+        self.assertEqual(len(parse_drag(dict(drag, model='TableG7')).drag_table), len(TableG7))
+        with self.assertRaises(ValueError):
+            parse_drag(dict(drag, model='G9'))
+
+
+class TestDragModelValidation(unittest.TestCase):
+
+    def test_bc(self):
+        for bc in (0, -0.2, float('nan'), float('inf'), None, '0.3', True):
+            with self.subTest(bc=bc), self.assertRaises(ValueError):
+                DragModel(bc, TableG7)
+        with self.assertRaises(ValueError):
+            BCPoint(0, 1)
+        with self.assertRaises(ValueError):
+            BCPoint(0.3)
+        with self.assertRaises(ValueError):
+            DragModelMultiBC([], TableG7)
+        with self.assertRaises(ValueError):
+            DragModel(0.3, TableG7, weight=-175)
+
+    def test_drag_table(self):
+        invalid = {
+            'unknown name': 'G9',
+            'none': None,
+            'empty': [],
+            'single point': [{'Mach': 1, 'CD': 0.3}],
+            'missing CD': [{'Mach': 0}, {'Mach': 1}],
+            'descending': [{'Mach': 1, 'CD': 0.3}, {'Mach': 0, 'CD': 0.3}],
+            'repeated Mach': [DragDataPoint(1, 0.3), DragDataPoint(1, 0.4)],
+            'negative CD': [DragDataPoint(0, 0.3), DragDataPoint(1, -0.4)],
+            'not a number': [DragDataPoint(0, 0.3), DragDataPoint(float('nan'), 0.4)],
+        }
+        for name, table in invalid.items():
+            with self.subTest(name), self.assertRaises(ValueError):
+                DragModel(0.3, table)
+        with self.assertRaises(ValueError):
+            register_drag_table('G1-custom', [{'Mach': 1, 'CD': 0.3}, {'Mach': 0, 'CD': 0.3}])
+        self.assertNotIn('G1-CUSTOM', registered_drag_tables())
+        self.assertEqual(len(DragModel(0.3, [DragDataPoint(0, 0.3), DragDataPoint(1, 0.4)]).drag_table), 2)
+
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,55 @@
+"""Unittests for the maximum engagement distance calculator"""
+
+import unittest
+from py_ballisticcalc import *
+
+
+class TestEngagement(unittest.TestCase):
+
+    def setUp(self) -> None:
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        self.shot = Shot(weapon=Weapon(2, 12), ammo=Ammo(dm, Velocity.FPS(2750)))
+        self.calc = Calculator()
+        self.calc.set_weapon_zero(self.shot, Distance.Yard(100))
+
+    def limit(self, **kwargs) -> float:
+        result = max_engagement_distance(self.calc, self.shot, Distance.Inch(12), Distance.Yard(1500), **kwargs)
+        self.assertFalse(result.limited_by_range)
+        return result.distance >> Distance.Yard
+
+    def test_limit_is_where_probability_crosses(self):
+        result = max_engagement_distance(self.calc, self.shot, Distance.Inch(12), Distance.Yard(1500))
+        limit = result.distance >> Distance.Yard
+        self.assertGreater(limit, 400)
+        self.assertLess(limit, 1000)
+        for point in result.profile:
+            if (point.distance >> Distance.Yard) < limit:
+                self.assertGreaterEqual(point.hit_probability, 0.5)
+        self.assertAlmostEqual(result.profile[0].hit_probability, 1)
+
+    def test_harder_requirements_shorten_limit(self):
+        baseline = self.limit()
+        self.assertLess(self.limit(hit_probability=0.9), baseline)
+        self.assertLess(self.limit(wind_uncertainty=Velocity.MPH(4)), baseline)
+        self.assertLess(self.limit(precision=Angular.MOA(2)), baseline)
+        self.assertLess(self.limit(wind_bracket=Velocity.MPH(8)), self.limit(wind_bracket=Velocity.MPH(4)))
+
+    def test_angular_target(self):
+        result = max_engagement_distance(self.calc, self.shot, Angular.MOA(4), Distance.Yard(1500))
+        for point in result.profile[1:]:
+            size = Angular.Radian((point.target_size >> Distance.Inch) / (point.distance >> Distance.Inch))
+            self.assertAlmostEqual(size >> Angular.MOA, 4, places=3)
+        self.assertGreater(result.distance >> Distance.Yard, self.limit())
+
+    def test_limited_by_range(self):
+        result = max_engagement_distance(self.calc, self.shot, Distance.Inch(40), Distance.Yard(300))
+        self.assertTrue(result.limited_by_range)
+        self.assertAlmostEqual(result.distance >> Distance.Yard, 300)
+
+    def test_invalid_probability(self):
+        with self.assertRaises(ValueError):
+            max_engagement_distance(self.calc, self.shot, Distance.Inch(12), Distance.Yard(500), hit_probability=1)
+
+
+if __name__ == '__main__':
+    unittest.main()
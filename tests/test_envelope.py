@@ -0,0 +1,52 @@
+"""Unittests for the elevation sweep reachability envelope"""
+
+import math
+import unittest
+from py_ballisticcalc import *
+
+NO_DRAG = [{'Mach': 0, 'CD': 0}, {'Mach': 1, 'CD': 0}, {'Mach': 5, 'CD': 0}]
+
+
+class TestElevationEnvelope(unittest.TestCase):
+
+    def setUp(self) -> None:
+        self.velocity = 300  # fps
+        self.shot = Shot(weapon=Weapon(0), ammo=Ammo(DragModel(0.3, NO_DRAG), Velocity.FPS(self.velocity)))
+        self.calc = Calculator(config=CalculatorConfig(minimum_velocity=0))
+        self.g = math.fabs(get_global_gravity() >> Acceleration.FootPerSecondSquared)
+        self.envelope = elevation_envelope(self.calc, self.shot, [Angular.Degree(d) for d in range(10, 90, 10)],
+                                           Distance.Foot(3000), Distance.Foot(20))
+
+    def test_vacuum_ranges(self):
+        for sweep in self.envelope.sweeps:
+            angle = sweep.elevation >> Angular.Radian
+            expected = self.velocity ** 2 * math.sin(2 * angle) / self.g
+            self.assertAlmostEqual(sweep.impact_distance >> Distance.Foot, expected, delta=expected * 0.01)
+            apex = (self.velocity * math.sin(angle)) ** 2 / (2 * self.g)
+            self.assertAlmostEqual(sweep.apex.height >> Distance.Foot, apex, delta=apex * 0.02)
+        self.assertIn(self.envelope.maximum.elevation >> Angular.Degree, (40, 50))
+
+    def test_elevation_for(self):
+        distance = 0.8 * self.velocity ** 2 / self.g
+        low = self.envelope.elevation_for(Distance.Foot(distance)) >> Angular.Radian
+        high = self.envelope.elevation_for(Distance.Foot(distance), high_angle=True) >> Angular.Radian
+        # Vacuum solutions are symmetric about 45 degrees
+        self.assertAlmostEqual(math.degrees(low), math.degrees(math.asin(0.8) / 2), delta=1)
+        self.assertAlmostEqual(math.degrees(low + high), 90, delta=1)
+        self.assertIsNone(self.envelope.elevation_for(Distance.Foot(2 * self.velocity ** 2 / self.g)))
+
+    def test_height_limit(self):
+        # Vacuum safety parabola: y = v^2 / 2g - g x^2 / 2v^2
+        for x in (1000, 1500, 2000):
+            expected = self.velocity ** 2 / (2 * self.g) - self.g * x ** 2 / (2 * self.velocity ** 2)
+            self.assertAlmostEqual(self.envelope.height_limit(Distance.Foot(x)) >> Distance.Foot, expected,
+                                   delta=0.05 * self.velocity ** 2 / self.g)
+        self.assertIsNone(self.envelope.height_limit(Distance.Foot(5000)))
+
+    def test_no_elevations(self):
+        with self.assertRaises(ValueError):
+            elevation_envelope(self.calc, self.shot, [], 100)
+
+
+if __name__ == '__main__':
+    unittest.main()
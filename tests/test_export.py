@@ -0,0 +1,89 @@
+"""Unittests for Arrow, Parquet and CSV export"""
+
+import csv
+import io
+import os
+import tempfile
+import unittest
+from py_ballisticcalc import *
+
+try:
+    import pyarrow as pa
+    import pyarrow.parquet as pq
+except ImportError:
+    pa = None
+
+
+@unittest.skipIf(pa is None, "pyarrow is not installed")
+class TestExport(unittest.TestCase):
+
+    def setUp(self) -> None:
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        self.shot = Shot(weapon=Weapon(2, 12, zero_elevation=Angular.MOA(4)), ammo=Ammo(dm, Velocity.FPS(2750)))
+        self.calc = Calculator()
+
+    def test_record_batch(self):
+        hit = self.calc.fire(self.shot, Distance.Yard(1000), Distance.Yard(100))
+        batch = to_record_batch(hit, {'distance': Unit.Meter})
+        self.assertEqual(batch.num_rows, len(hit.trajectory))
+        self.assertEqual(batch.schema.names, list(TrajectoryData._fields))
+        self.assertEqual(batch.schema.field('distance').metadata[b'units'], b'Meter')
+        self.assertEqual(batch.schema.metadata[b'termination_reason'], b'maximum_range')
+        distances = batch.column(batch.schema.get_field_index('distance')).to_pylist()
+        for d, row in zip(distances, hit):
+            self.assertAlmostEqual(d, row.distance >> Distance.Meter, 9)
+        self.assertEqual(batch.column(batch.schema.get_field_index('flag')).type, pa.int32())
+
+    def test_table_and_parquet(self):
+        shots = relative_angle_sweep(self.shot, [Angular.MOA(a) for a in (0, 10, 20)])
+        results = list(self.calc.fire_volley(shots, Distance.Yard(500), Distance.Yard(100)))
+        table = to_arrow_table(results)
+        self.assertEqual(table.num_rows, sum(len(r.trajectory) for r in results))
+        self.assertEqual(sorted(set(table.column('shot').to_pylist())), [0, 1, 2])
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, 'sweep.parquet')
+            write_parquet(results, path)
+            loaded = pq.read_table(path)
+        self.assertEqual(loaded.num_rows, table.num_rows)
+        self.assertEqual(loaded.column('distance').to_pylist(), table.column('distance').to_pylist())
+        with self.assertRaises(ValueError):
+            to_arrow_table([])
+
+
+class TestCSV(unittest.TestCase):
+
+    def setUp(self) -> None:
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        self.shot = Shot(weapon=Weapon(2, 12, zero_elevation=Angular.MOA(4)), ammo=Ammo(dm, Velocity.FPS(2750)))
+        self.hit = Calculator().fire(self.shot, Distance.Yard(1000), Distance.Yard(100))
+
+    def test_all_fields(self):
+        out = io.StringIO()
+        write_csv(self.hit, out)
+        rows = list(csv.reader(io.StringIO(out.getvalue())))
+        self.assertEqual(len(rows), len(self.hit.trajectory) + 1)
+        self.assertEqual(len(rows[0]), len(TrajectoryData._fields))
+        self.assertEqual(rows[0][0], 'time (s)')
+        self.assertIn('flag', rows[0])
+        self.assertAlmostEqual(float(rows[-1][0]), self.hit[-1].time, 9)
+
+    def test_units_and_fields(self):
+        out = io.StringIO()
+        write_csv(self.hit, out, {'distance': Unit.Meter, 'drop_adj': Unit.Mil}, ['distance', 'drop_adj', 'time'],
+                  rounded=True)
+        rows = list(csv.reader(io.StringIO(out.getvalue())))
+        self.assertEqual(rows[0], ['distance (m)', f'drop_adj ({Unit.Mil.symbol})', 'time (s)'])
+        for row, data in zip(rows[1:], self.hit):
+            self.assertAlmostEqual(float(row[0]), data.distance >> Distance.Meter, Unit.Meter.accuracy)
+            self.assertAlmostEqual(float(row[1]), data.drop_adj >> Angular.Mil, Unit.Mil.accuracy)
+        # Rows and TrajectoryColumns give the same output
+        from_rows, from_columns = io.StringIO(), io.StringIO()
+        write_csv(self.hit.trajectory, from_rows, {'distance': Unit.Meter})
+        write_csv(self.hit.columns({'distance': Unit.Meter}), from_columns)
+        self.assertEqual(from_rows.getvalue(), from_columns.getvalue())
+        with self.assertRaises(KeyError):
+            write_csv(self.hit, io.StringIO(), fields=['range'])
+
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,105 @@
+"""Unittests for trajectory calculation hooks"""
+
+import unittest
+from py_ballisticcalc import *
+
+
+class TestHooks(unittest.TestCase):
+
+    def setUp(self) -> None:
+        self.steps = []
+        self.records = []
+        self.terminations = []
+        self.hooks = TrajectoryHooks(on_step=self.steps.append,
+                                     on_record=self.records.append,
+                                     on_termination=lambda state, reason: self.terminations.append((state, reason)))
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        self.shot = Shot(weapon=Weapon(2, 12, zero_elevation=Angular.MOA(4)), ammo=Ammo(dm, Velocity.FPS(2750)))
+
+    def test_hooks_receive_state(self):
+        hit = Calculator(hooks=self.hooks).fire(self.shot, Distance.Yard(1000), Distance.Yard(100))
+        self.assertEqual(self.records, hit.trajectory)
+        self.assertEqual(len(self.terminations), 1)
+        state, reason = self.terminations[0]
+        self.assertEqual(reason, 'maximum_range')
+        self.assertGreater(len(self.steps), 3000)  # At least one integration step per foot
+        # Time of flight and downrange position increase monotonically
+        for previous, current in zip(self.steps, self.steps[1:]):
+            self.assertGreater(current.time, previous.time)
+            self.assertGreater(current.position.x, previous.position.x)
+        self.assertGreaterEqual(state.position.x, self.steps[-1].position.x)
+
+    def test_minimum_velocity_termination(self):
+        slow = Shot(weapon=self.shot.weapon, ammo=Ammo(DragModel(0.01, TableG1), Velocity.FPS(1000)))
+        Calculator(hooks=self.hooks).fire(slow, Distance.Yard(5000), Distance.Yard(500))
+        self.assertEqual(self.terminations[-1][1], 'minimum_velocity')
+        self.assertLess(self.terminations[-1][0].velocity.magnitude(), 50)
+
+    def test_result_termination_reason(self):
+        hit = Calculator().fire(self.shot, Distance.Yard(1000), Distance.Yard(100))
+        self.assertEqual(hit.termination_reason, 'maximum_range')
+        self.assertFalse(hit.incomplete)
+
+        slow = Shot(weapon=self.shot.weapon, ammo=Ammo(DragModel(0.01, TableG1), Velocity.FPS(1000)))
+        hit = Calculator().fire(slow, Distance.Yard(5000), Distance.Yard(500))
+        self.assertEqual(hit.termination_reason, 'minimum_velocity')
+        self.assertTrue(hit.incomplete)
+
+    def test_cancel_from_on_step(self):
+        hooks = TrajectoryHooks(on_step=lambda state: state.position.x > 300,
+                                on_termination=lambda state, reason: self.terminations.append((state, reason)))
+        hit = Calculator(hooks=hooks).fire(self.shot, Distance.Yard(1000), Distance.Yard(10))
+        self.assertEqual(hit.termination_reason, 'cancelled')
+        self.assertTrue(hit.incomplete)
+        self.assertEqual(self.terminations[-1][1], 'cancelled')
+        self.assertLessEqual(hit.trajectory[-1].distance >> Distance.Foot, 300)
+
+    def test_hooks_during_zero(self):
+        Calculator(hooks=self.hooks).set_weapon_zero(self.shot, Distance.Yard(100))
+        self.assertGreater(len(self.terminations), 0)
+        self.assertEqual(len(self.records), len(self.terminations))
+
+
+    def test_cancel_context(self):
+        calc = Calculator(hooks=self.hooks)
+        context = CancelContext()
+        hit = calc.fire_with_context(context, self.shot, Distance.Yard(1000), Distance.Yard(100))
+        self.assertEqual(hit.termination_reason, 'maximum_range')
+        self.assertFalse(context.interrupted)
+        # Cancelled from a hook of the calculation, as it would be from another thread
+        context = CancelContext()
+        self.steps.clear()
+        calc.hooks = TrajectoryHooks(on_step=lambda state: context.cancel() if state.position.x > 300 else None,
+                                     on_record=self.records.append)
+        with self.assertRaises(TrajectoryCancelledError) as raised:
+            calc.fire_with_context(context, self.shot, Distance.Yard(1000), Distance.Yard(10))
+        self.assertEqual(raised.exception.reason, 'cancelled')
+        partial = raised.exception.result
+        self.assertTrue(partial.incomplete)
+        self.assertGreater(len(partial.trajectory), 5)
+        self.assertLess(partial.trajectory[-1].distance >> Distance.Foot, 310)
+        # Hooks of the calculator are restored
+        self.assertIsNone(calc.hooks.on_termination)
+        self.assertEqual(calc.fire(self.shot, Distance.Yard(1000)).termination_reason, 'maximum_range')
+
+    def test_deadline(self):
+        calc = Calculator()
+        with self.assertRaises(TrajectoryCancelledError) as raised:
+            calc.fire_with_context(CancelContext(timeout=0), self.shot, Distance.Yard(1000))
+        self.assertEqual(raised.exception.reason, 'deadline_exceeded')
+        self.assertTrue(raised.exception.result.incomplete)
+
+        zero = self.shot.weapon.zero_elevation
+        with self.assertRaises(TrajectoryCancelledError) as raised:
+            calc.set_weapon_zero_with_context(CancelContext(timeout=0), self.shot, Distance.Yard(200))
+        self.assertIsNone(raised.exception.result)
+        self.assertEqual(self.shot.weapon.zero_elevation, zero)
+        # Cancelled zero finding is not cached
+        elevation = calc.set_weapon_zero_with_context(CancelContext(timeout=60), self.shot, Distance.Yard(200))
+        self.assertEqual(self.shot.weapon.zero_elevation, elevation)
+        self.assertAlmostEqual(elevation >> Angular.MOA, Calculator().set_weapon_zero(self.shot, Distance.Yard(200))
+                               >> Angular.MOA, 6)
+
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,37 @@
+"""Unittests for rifleman's rule incline corrections"""
+
+import unittest
+from py_ballisticcalc import *
+
+
+class TestInclineHolds(unittest.TestCase):
+
+    def setUp(self) -> None:
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        self.shot = Shot(weapon=Weapon(2, 12), ammo=Ammo(dm, Velocity.FPS(2750)))
+        self.calc = Calculator()
+        self.calc.set_weapon_zero(self.shot, Distance.Yard(100))
+
+    def test_level(self):
+        holds = incline_holds(self.calc, self.shot, Distance.Yard(600))
+        self.assertAlmostEqual(holds.riflemans_rule_error >> Angular.MOA, 0, 9)
+        self.assertAlmostEqual(holds.improved_riflemans_rule_error >> Angular.MOA, 0, 9)
+        expected = self.calc.fire_at_ranges(self.shot, [Distance.Yard(600)])[0]
+        self.assertAlmostEqual(holds.exact >> Angular.MOA, expected.drop_adj >> Angular.MOA, 9)
+
+    def test_inclined(self):
+        for degrees in (-30, 30):
+            with self.subTest(look_angle=degrees):
+                self.shot.look_angle = Angular.Degree(degrees)
+                holds = incline_holds(self.calc, self.shot, Distance.Yard(600))
+                self.assertAlmostEqual(holds.riflemans_rule_error >> Angular.MOA,
+                                       (holds.riflemans_rule >> Angular.MOA) - (holds.exact >> Angular.MOA), 9)
+                # Rifleman's rule under-corrects, the improved rule over-corrects
+                self.assertGreater(holds.riflemans_rule_error >> Angular.MOA, 0.1)
+                self.assertLess(holds.improved_riflemans_rule_error >> Angular.MOA, -0.1)
+        with self.assertRaises(ArithmeticError):
+            incline_holds(self.calc, self.shot, Distance.Mile(10))
+
+
+if __name__ == '__main__':
+    unittest.main()
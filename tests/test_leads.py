@@ -0,0 +1,48 @@
+"""Unittests for moving target lead holds"""
+
+import math
+import unittest
+from py_ballisticcalc import *
+
+
+class TestLeads(unittest.TestCase):
+
+    def setUp(self) -> None:
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        self.shot = Shot(weapon=Weapon(2, 12), ammo=Ammo(dm, Velocity.FPS(2750)))
+        self.calc = Calculator()
+        self.calc.set_weapon_zero(self.shot, Distance.Yard(100))
+        self.ranges = [Distance.Yard(d) for d in (100, 300, 500)]
+
+    def test_lead_holds(self):
+        rows = list(lead_holds(self.calc, self.shot, self.ranges))
+        self.assertEqual(len(rows), 3)
+        trajectory = self.calc.fire_at_ranges(self.shot, self.ranges).trajectory
+        for row, point in zip(rows, trajectory):
+            self.assertEqual(list(row.holds), list(TARGET_SPEEDS))
+            self.assertAlmostEqual(row.time, point.time)
+            lead = (TARGET_SPEEDS['walk'] >> Velocity.FPS) * point.time
+            self.assertAlmostEqual(row.leads['walk'] >> Distance.Foot, lead)
+            self.assertAlmostEqual(row.holds['walk'] >> Angular.Radian,
+                                   math.atan2(lead, point.look_distance >> Distance.Foot))
+            # Hold is proportional to speed
+            self.assertAlmostEqual((row.holds['vehicle'] >> Angular.Mil) / (row.holds['walk'] >> Angular.Mil),
+                                   20 / 3, places=3)
+        # Bullet slows down, so angular lead grows with range
+        holds = [row.holds['run'] >> Angular.Mil for row in rows]
+        self.assertEqual(holds, sorted(holds))
+
+    def test_lead_card(self):
+        speeds = {'walk': Velocity.KMH(5), 'truck': Velocity.KMH(40)}
+        card = lead_card(self.calc, self.shot, self.ranges, speeds, Locales.German, Unit.MOA)
+        self.assertEqual(card[0], ('range', 'time', 'walk 5,0 km/h', 'truck 40,0 km/h'))
+        self.assertEqual(len(card), 4)
+        self.assertEqual(card[2][0], '300,0 yd')
+        self.assertTrue(card[2][2].endswith(Unit.MOA.symbol))
+        localized = lead_card(self.calc, self.shot, self.ranges[:1], speeds,
+                              Localization(labels={'range': 'Distanz', 'walk': 'Gehen'}))
+        self.assertEqual(localized[0][:3], ('Distanz', 'time', 'Gehen 5.0 km/h'))
+
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,36 @@
+"""Unittests for localized formatting"""
+
+import unittest
+from py_ballisticcalc import *
+
+
+class TestLocalization(unittest.TestCase):
+
+    def test_number(self):
+        self.assertEqual(Locales.English.number(12345.678, 2), '12345.68')
+        self.assertEqual(Locales.German.number(12345.678, 2), '12.345,68')
+        self.assertEqual(Locales.Ukrainian.number(-1234567.5, 1), '-1\u00a0234\u00a0567,5')
+        self.assertEqual(Locales.German.number(0.000123, 3, exponent=True), '1,230e-04')
+        custom = Localization(decimal_separator='·', thousands_separator="'")
+        self.assertEqual(custom.number(1234.5, 1), "1'234·5")
+
+    def test_format(self):
+        self.assertEqual(Locales.English.format(Distance.Meter(1000.5), Unit.Meter), '1000.5 m')
+        self.assertEqual(Locales.Ukrainian.format(Distance.Meter(1000.5), Unit.Meter), '1\u00a0000,5 м')
+        self.assertEqual(Locales.German.format(Velocity.MPS(800), Unit.MPS), '800 m/s')
+        custom = Localization(unit_labels={Unit.MPS: 'mps'})
+        self.assertEqual(custom.format(Velocity.MPS(800), Unit.MPS), '800 mps')
+
+    def test_trajectory_formatted(self):
+        shot = Shot(weapon=Weapon(2, 12, zero_elevation=Angular.MOA(4)),
+                    ammo=Ammo(DragModel(0.223, TableG7, 168, 0.308, 1.282), Velocity.FPS(2750)))
+        row = Calculator().fire(shot, Distance.Yard(500), Distance.Yard(500)).trajectory[-1]
+        self.assertEqual(row.formatted(), row.formatted(Locales.English))
+        english, ukrainian = row.formatted(), row.formatted(Locales.Ukrainian)
+        self.assertEqual(ukrainian[0], english[0].replace('.', ',').replace(' s', ' с'))
+        self.assertEqual(ukrainian[3], english[3].replace('.', ',').replace('mach', 'Мах'))
+        self.assertEqual(ukrainian[11], english[11].replace('.', ','))
+
+
+if __name__ == '__main__':
+    unittest.main()
@@ -1,5 +1,6 @@
 "Unit tests of multiple-bc drag models"
 
+import math
 import unittest
 from py_ballisticcalc import *
 
@@ -70,3 +71,23 @@ class TestMBC(unittest.TestCase):
             idx = machs.index(mach)
             with self.subTest(mach=mach):
                 self.assertAlmostEqual(cds[idx], cd, 3)
+
+
+class TestDragModelCD(unittest.TestCase):
+
+    def test_sectional_density_bc(self):
+        dm = DragModelCD(TableG7, Weight.Grain(175), Distance.Inch(0.308), Distance.Inch(1.24))
+        self.assertAlmostEqual(dm.BC, 175 / 0.308 ** 2 / 7000)
+        self.assertAlmostEqual(dm.form_factor, 1)
+        with self.assertRaises(ValueError):
+            DragModelCD(TableG7, 0, Distance.Inch(0.308))
+
+    def test_deceleration_from_cd(self):
+        # Constant Cd: velocity decays as exp(-AirDensity * Cd * S * x / 2m)
+        cd, mass, diameter = 0.3, Weight.Grain(150) >> Weight.Kilogram, Distance.Inch(0.308) >> Distance.Meter
+        dm = DragModelCD([{'Mach': 0, 'CD': cd}, {'Mach': 2, 'CD': cd}, {'Mach': 4, 'CD': cd}],
+                         Weight.Grain(150), Distance.Inch(0.308))
+        shot = Shot(weapon=Weapon(), ammo=Ammo(dm, Velocity.MPS(800)), atmo=Atmo.icao())
+        row = Calculator().fire_at_ranges(shot, [Distance.Meter(100)]).trajectory[0]
+        k = 1.22498 * cd * math.pi * diameter ** 2 / 4 / (2 * mass)
+        self.assertAlmostEqual(row.velocity >> Velocity.MPS, 800 * math.exp(-k * 100), delta=0.2)
@@ -0,0 +1,91 @@
+"""Unittests for drag estimation from projectile geometry"""
+
+import unittest
+from py_ballisticcalc import *
+from py_ballisticcalc.drag_model import sectional_density
+from py_ballisticcalc.mcdrag import MCDRAG_MACHS
+from py_ballisticcalc.profile_loader import parse_drag
+
+
+class TestMcDrag(unittest.TestCase):
+
+    def setUp(self) -> None:
+        # 175gr .308 match bullet, published G7 BC 0.243
+        self.geometry = ProjectileGeometry(Distance.Inch(0.308), Distance.Inch(1.24), Distance.Inch(0.70),
+                                           0.9, Distance.Inch(0.06), Distance.Inch(0.17), Distance.Inch(0.245))
+
+    def test_cone_wave_drag(self):
+        # Supersonic cone pressure drag (0.083 + 0.096 / M^2) * (half-angle / 10 deg)^1.69
+        cone = ProjectileGeometry(1, 10, 3, ogive_ratio=0)
+        expected = (0.083 + 0.096 / 4) * pow(9.4623 / 10, 1.69)
+        self.assertAlmostEqual(drag_breakdown(cone, 2).nose_wave, expected, 2)
+
+    def test_matches_published_bc(self):
+        g7 = {point['Mach']: point['CD'] for point in TableG7}
+        form_factor = sectional_density(175, 0.308) / 0.243
+        for mach in (0.5, 0.8, 1.0, 1.2, 1.5, 2.0, 2.5, 3.0):
+            with self.subTest(mach=mach):
+                self.assertAlmostEqual(drag_breakdown(self.geometry, mach).total / (g7[mach] * form_factor), 1,
+                                       delta=0.1)
+
+    def test_components(self):
+        subsonic = drag_breakdown(self.geometry, 0.7)
+        self.assertEqual(subsonic.nose_wave, 0)
+        self.assertEqual(subsonic.band, 0)
+        self.assertGreater(drag_breakdown(self.geometry, 1.5).nose_wave, 0)
+        flat_base = ProjectileGeometry(Distance.Inch(0.308), Distance.Inch(1.24), Distance.Inch(0.70), 0.9,
+                                       Distance.Inch(0.06))
+        self.assertLess(subsonic.base, drag_breakdown(flat_base, 0.7).base)
+        banded = ProjectileGeometry(Distance.Inch(0.308), Distance.Inch(1.24), Distance.Inch(0.70),
+                                    band_diameter=Distance.Inch(0.318))
+        self.assertGreater(drag_breakdown(banded, 1.5).band, 0)
+        laminar = ProjectileGeometry(Distance.Inch(0.308), Distance.Inch(1.24), Distance.Inch(0.70),
+                                     boundary_layer=BoundaryLayer.LAMINAR)
+        turbulent = ProjectileGeometry(Distance.Inch(0.308), Distance.Inch(1.24), Distance.Inch(0.70))
+        self.assertLess(drag_breakdown(laminar, 0.7).skin_friction, drag_breakdown(turbulent, 0.7).skin_friction)
+
+    def test_drag_table(self):
+        table = estimate_drag_table(self.geometry)
+        self.assertEqual(len(table), len(MCDRAG_MACHS))
+        self.assertEqual(table[0].CD, table[1].CD)  # Held below cMinimumMach
+        self.assertEqual([p.Mach for p in estimate_drag_table(self.geometry, (2, 1))], [1, 2])
+
+    def test_drag_model(self):
+        dm = estimate_drag_model(self.geometry, Weight.Grain(175))
+        self.assertAlmostEqual(dm.form_factor, 1)
+        self.assertAlmostEqual(dm.length >> Distance.Inch, 1.24)
+        estimated = Calculator().fire(Shot(weapon=Weapon(2), ammo=Ammo(dm, Velocity.FPS(2600))),
+                                      Distance.Yard(1000), Distance.Yard(1000))
+        published = Calculator().fire(Shot(weapon=Weapon(2), ammo=Ammo(DragModel(0.243, TableG7),
+                                                                        Velocity.FPS(2600))),
+                                      Distance.Yard(1000), Distance.Yard(1000))
+        self.assertAlmostEqual(estimated[-1].velocity >> Velocity.FPS, published[-1].velocity >> Velocity.FPS,
+                               delta=50)
+        with self.assertRaises(ValueError):
+            estimate_drag_model(self.geometry, 0)
+
+    def test_invalid_geometry(self):
+        with self.assertRaises(ValueError):
+            ProjectileGeometry(Distance.Inch(0.308), Distance.Inch(1.24))
+        with self.assertRaises(ValueError):
+            ProjectileGeometry(Distance.Inch(0.308), Distance.Inch(1.0), Distance.Inch(0.8),
+                               boattail_length=Distance.Inch(0.3))
+        with self.assertRaises(ValueError):
+            ProjectileGeometry(Distance.Inch(0.308), Distance.Inch(1.24), Distance.Inch(0.7),
+                               base_diameter=Distance.Inch(0.32))
+        with self.assertRaises(ValueError):
+            ProjectileGeometry(Distance.Inch(0.308), Distance.Inch(1.24), Distance.Inch(0.7), ogive_ratio=2)
+
+    def test_profile_geometry(self):
+        drag = {'bullet_weight': '175gr', 'bullet_diameter': '0.308in', 'bullet_length': '1.24in',
+                'geometry': {'nose_length': '0.70in', 'ogive_ratio': 0.9, 'meplat_diameter': '0.06in',
+                             'boattail_length': '0.17in', 'base_diameter': '0.245in', 'boundary_layer': 'T/T'}}
+        dm = parse_drag(drag)
+        expected = estimate_drag_model(self.geometry, Weight.Grain(175))
+        self.assertEqual([p.CD for p in dm.drag_table], [p.CD for p in expected.drag_table])
+        with self.assertRaises(ValueError):
+            parse_drag(dict(drag, model='G7', bc=0.243))
+
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,74 @@
+"""Unittests for the modified point-mass trajectory model"""
+
+import unittest
+from dataclasses import replace
+from unittest import mock
+from py_ballisticcalc import *
+from py_ballisticcalc import backend, trajectory_calc
+
+
+class TestModifiedPointMass(unittest.TestCase):
+
+    def setUp(self) -> None:
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        self.shot = Shot(weapon=Weapon(2, 12, zero_elevation=Angular.MOA(4)),
+                         ammo=Ammo(dm, Velocity.FPS(2750), aerodynamics=AeroCoefficients(1.0, 1.0)))
+        self.mpm = Calculator(engine=ModifiedPointMassCalc)
+
+    def fire(self, calc: Calculator) -> HitResult:
+        return calc.fire(self.shot, Distance.Yard(1500), Distance.Yard(300))
+
+    def test_matches_integrated_yaw_of_repose(self):
+        """With CLa = CMa and default inertia and spin damping, the lift is that of SpinDriftModel.YAW_OF_REPOSE"""
+        expected = self.fire(Calculator(config=CalculatorConfig(spin_drift_model=SpinDriftModel.YAW_OF_REPOSE)))
+        actual = self.fire(self.mpm)
+        for a, e in zip(actual.trajectory[1:], expected.trajectory[1:]):
+            with self.subTest(distance=e.distance):
+                self.assertAlmostEqual(a.windage >> Distance.Inch, e.windage >> Distance.Inch, delta=0.01)
+                self.assertAlmostEqual(a.height >> Distance.Inch, e.height >> Distance.Inch, delta=0.01)
+
+    def test_coefficients(self):
+        self.shot.ammo.aerodynamics = AeroCoefficients([(0.8, 1.5), (1.2, 2.5), (2.5, 2.0)],
+                                                       [(0.8, 1.8), (1.2, 3.2), (2.5, 2.6)],
+                                                       magnus_slope=-0.5, yaw_drag=5)
+        self.assertAlmostEqual(self.shot.ammo.aerodynamics.at_mach('lift_slope', 1.0), 2.0)
+        self.assertEqual(self.shot.ammo.aerodynamics.at_mach('lift_slope', 3), 2.0)
+        self.assertEqual(self.shot.ammo.aerodynamics.at_mach('lift_slope', 0.5), 1.5)
+        detailed = self.fire(self.mpm)
+        self.shot.ammo.aerodynamics = replace(self.shot.ammo.aerodynamics, yaw_drag=0)
+        without_yaw_drag = self.fire(self.mpm)
+        self.assertLess(detailed[-1].velocity >> Velocity.FPS, without_yaw_drag[-1].velocity >> Velocity.FPS)
+        self.assertGreater(detailed[-1].windage >> Distance.Inch, 0)
+
+        self.shot.weapon.twist = -12
+        left = self.fire(self.mpm)
+        for a, b in zip(left.trajectory, without_yaw_drag.trajectory):
+            self.assertAlmostEqual(a.windage >> Distance.Inch, -(b.windage >> Distance.Inch), 9)
+            self.assertAlmostEqual(a.height >> Distance.Inch, b.height >> Distance.Inch, 9)
+
+    def test_inertia(self):
+        default = self.fire(self.mpm)
+        self.shot.ammo.aerodynamics.axial_inertia = 168 * 0.308 * 0.308 / 5
+        heavier = self.fire(self.mpm)
+        # Yaw of repose grows with axial inertia, and spin lasts longer
+        self.assertGreater(heavier[-1].windage >> Distance.Inch, 1.5 * (default[-1].windage >> Distance.Inch))
+
+    def test_invalid(self):
+        with self.assertRaises(ValueError):
+            AeroCoefficients(1.0, 0)
+        with self.assertRaises(ValueError):
+            AeroCoefficients(1.0, [(0.5, 2.0), (1.0, -1.0)])
+        self.shot.ammo.aerodynamics = None
+        with self.assertRaises(ValueError):
+            self.fire(self.mpm)
+
+    def test_binary_backend_warning(self):
+        """ModifiedPointMassCalc always runs in pure python, and says so if the binary backend is installed"""
+        self.assertTrue(issubclass(ModifiedPointMassCalc, trajectory_calc.TrajectoryCalc))
+        with mock.patch.object(backend, 'TrajectoryCalc', object):
+            with self.assertLogs('py_balcalc', 'WARNING'):
+                ModifiedPointMassCalc(self.shot.ammo)
+
+
+if __name__ == '__main__':
+    unittest.main()
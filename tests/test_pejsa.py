@@ -0,0 +1,81 @@
+"""Unittests for the Pejsa analytic trajectory model"""
+
+import unittest
+from py_ballisticcalc import *
+
+
+class TestPejsa(unittest.TestCase):
+
+    def setUp(self) -> None:
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        self.shot = Shot(weapon=Weapon(2, 12), ammo=Ammo(dm, Velocity.FPS(2750)),
+                         winds=[Wind(Velocity.MPH(10), Angular.Degree(90))])
+        self.numeric = Calculator()
+        self.pejsa = Calculator(engine=PejsaCalc)
+
+    def assertRelative(self, a: float, b: float, tolerance: float):
+        self.assertLess(abs(a - b), tolerance * abs(b), f'{a} != {b} within {tolerance:.0%}')
+
+    def test_matches_numeric_while_supersonic(self):
+        zero = self.numeric.barrel_elevation_for_target(self.shot, Distance.Yard(100))
+        self.assertAlmostEqual(self.pejsa.barrel_elevation_for_target(self.shot, Distance.Yard(100)) >> Angular.MOA,
+                               zero >> Angular.MOA, 1)
+        self.shot.weapon.zero_elevation = zero
+        expected = self.numeric.fire(self.shot, Distance.Yard(800), Distance.Yard(100))
+        actual = self.pejsa.fire(self.shot, Distance.Yard(800), Distance.Yard(100))
+        self.assertEqual(len(actual.trajectory), len(expected.trajectory))
+        for a, e in zip(actual.trajectory[3:], expected.trajectory[3:]):
+            with self.subTest(distance=e.distance):
+                self.assertAlmostEqual(a.distance >> Distance.Yard, e.distance >> Distance.Yard, 6)
+                self.assertRelative(a.velocity >> Velocity.FPS, e.velocity >> Velocity.FPS, 0.01)
+                self.assertRelative(a.time, e.time, 0.01)
+                self.assertRelative(a.height >> Distance.Inch, e.height >> Distance.Inch, 0.01)
+                self.assertRelative(a.windage >> Distance.Inch, e.windage >> Distance.Inch, 0.02)
+
+    def test_extra_data_flags(self):
+        self.pejsa.set_weapon_zero(self.shot, Distance.Yard(100))
+        hit = self.pejsa.fire(self.shot, Distance.Yard(1200), Distance.Yard(100), extra_data=True)
+        self.assertEqual(len(hit.zeros()), 2)
+        self.assertAlmostEqual(hit.zeros()[1].distance >> Distance.Yard, 100, 0)
+        self.assertEqual(len([row for row in hit if row.flag & TrajFlag.MACH.value]), 1)
+        self.assertLess(hit.apex().distance >> Distance.Yard, 100)
+
+    def test_fire_by_time(self):
+        self.shot.weapon.zero_elevation = Angular.MOA(4)
+        hit = self.pejsa.fire_by_time(self.shot, 1, 0.1)
+        expected = self.numeric.fire_by_time(self.shot, 1, 0.1)
+        self.assertEqual(len(hit.trajectory), 11)
+        for a, e in zip(hit.trajectory[1:], expected.trajectory[1:]):
+            with self.subTest(time=e.time):
+                self.assertAlmostEqual(a.time, e.time, 9)
+                self.assertRelative(a.distance >> Distance.Foot, e.distance >> Distance.Foot, 0.01)
+
+    def test_stop_conditions(self):
+        config = CalculatorConfig(minimum_velocity=Velocity.FPS(2000))
+        terminations = []
+        hooks = TrajectoryHooks(on_termination=lambda state, reason: terminations.append(reason))
+        hit = Calculator(engine=PejsaCalc, config=config, hooks=hooks).fire(
+            self.shot, Distance.Yard(1000), Distance.Yard(10))
+        self.assertEqual(hit.termination_reason, 'minimum_velocity')
+        self.assertEqual(terminations, ['minimum_velocity'])
+        self.assertGreaterEqual(hit.trajectory[-1].velocity >> Velocity.FPS, 2000)
+
+    def test_fire_at_ranges(self):
+        hit = self.pejsa.fire_at_ranges(self.shot, [Distance.Meter(d) for d in (575, 100)])
+        self.assertEqual([round(row.distance >> Distance.Meter, 6) for row in hit], [100, 575])
+
+    def test_estimate_drop(self):
+        self.numeric.set_weapon_zero(self.shot, Distance.Yard(100))
+        for distance in (300, 600, 900):
+            with self.subTest(distance=distance):
+                estimate = self.numeric.estimate_drop(self.shot, Distance.Yard(distance))
+                expected = self.numeric.fire_at_ranges(self.shot, [Distance.Yard(distance)])[0]
+                self.assertAlmostEqual(estimate.distance >> Distance.Yard, distance, 6)
+                self.assertRelative(estimate.height >> Distance.Inch, expected.height >> Distance.Inch, 0.03)
+                self.assertRelative(estimate.windage >> Distance.Inch, expected.windage >> Distance.Inch, 0.03)
+        with self.assertRaises(ArithmeticError):
+            self.numeric.estimate_drop(self.shot, Distance.Mile(10))
+
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,86 @@
+"""Unittests for projectile phases: sabot discard and rocket assist"""
+
+import unittest
+from py_ballisticcalc import *
+
+
+class TestProjectilePhases(unittest.TestCase):
+
+    def setUp(self) -> None:
+        # Sabot carrier and the flechette it releases
+        self.carrier = DragModel(0.15, TableG1, 300, 0.5, 1.0)
+        self.flechette = DragModel(0.45, TableG7, 120, 0.224, 1.5)
+        self.calc = Calculator()
+
+    def fire(self, ammo: Ammo, trajectory_range=Distance.Yard(500)) -> HitResult:
+        shot = Shot(weapon=Weapon(2), ammo=ammo)
+        return self.calc.fire(shot, trajectory_range, Distance.Yard(100))
+
+    def test_requires_start(self):
+        with self.assertRaises(ValueError):
+            ProjectilePhase(dm=self.flechette)
+
+    def test_sabot_discard(self):
+        plain = self.fire(Ammo(self.carrier, Velocity.FPS(3000)))
+        sabot = self.fire(Ammo(self.carrier, Velocity.FPS(3000),
+                               phases=[ProjectilePhase(start_distance=Distance.Yard(10), dm=self.flechette)]))
+        self.assertAlmostEqual(sabot[0].energy >> Energy.FootPound, plain[0].energy >> Energy.FootPound)
+        self.assertGreater(sabot[-1].velocity.raw_value, plain[-1].velocity.raw_value)
+        # Energy after discard is that of the lighter flechette
+        row = sabot[-1]
+        expected = 120 * pow(row.velocity >> Velocity.FPS, 2) / 450400
+        self.assertAlmostEqual(row.energy >> Energy.FootPound, expected, 3)
+
+    def test_phase_by_time(self):
+        by_distance = self.fire(Ammo(self.carrier, Velocity.FPS(3000),
+                                     phases=[ProjectilePhase(start_distance=Distance.Yard(200),
+                                                             dm=self.flechette)]))
+        by_time = self.fire(Ammo(self.carrier, Velocity.FPS(3000),
+                                 phases=[ProjectilePhase(start_time=0.05, dm=self.flechette)]))
+        self.assertGreater(by_time[-1].velocity.raw_value, by_distance[-1].velocity.raw_value)
+
+    def test_rocket_assist(self):
+        dm = DragModel(0.3, TableG1, 500, 0.5, 2.0)
+        plain = self.fire(Ammo(dm, Velocity.FPS(1000)), Distance.Yard(1000))
+        rocket = self.fire(Ammo(dm, Velocity.FPS(1000),
+                                phases=[ProjectilePhase(start_time=0, thrust=Acceleration.FootPerSecondSquared(3000),
+                                                        burn_time=1)]),
+                           Distance.Yard(1000))
+        self.assertGreater(rocket[1].velocity.raw_value, rocket[0].velocity.raw_value)
+        self.assertGreater(rocket[-1].velocity.raw_value, plain[-1].velocity.raw_value)
+        self.assertLess(rocket[-1].time, plain[-1].time)
+        # Floats are accelerations in PreferredUnits.acceleration, not distances
+        self.assertAlmostEqual(ProjectilePhase(start_time=0, thrust=100).thrust >> PreferredUnits.acceleration, 100)
+        with self.assertRaises(UnitTypeError):
+            ProjectilePhase(start_time=0, thrust=Distance.Foot(3000))
+
+    def test_engine_state_restored(self):
+        ammo = Ammo(self.carrier, Velocity.FPS(3000))
+        plain = self.fire(ammo)
+        self.fire(Ammo(self.carrier, Velocity.FPS(3000),
+                       phases=[ProjectilePhase(start_distance=Distance.Yard(10), dm=self.flechette)]))
+        self.assertEqual([r.formatted() for r in self.fire(ammo)], [r.formatted() for r in plain])
+
+    def test_dope_book_round_trip(self):
+        ammo = Ammo(self.carrier, Velocity.FPS(3000),
+                    phases=[ProjectilePhase(start_distance=Distance.Yard(10), dm=self.flechette),
+                            ProjectilePhase(start_time=0.1, thrust=Acceleration.MeterPerSecondSquared(30),
+                                            burn_time=0.5)])
+        shot = Shot(weapon=Weapon(2), ammo=ammo)
+        with DopeBook() as book:
+            stored = book.scenario(book.add_scenario('sabot', shot)).shot
+        self.assertEqual(len(stored.ammo.phases), 2)
+        self.assertIsNone(stored.ammo.phases[1].dm)
+        expected = self.calc.fire(shot, Distance.Yard(500), Distance.Yard(100))
+        actual = self.calc.fire(stored, Distance.Yard(500), Distance.Yard(100))
+        self.assertEqual([r.formatted() for r in actual], [r.formatted() for r in expected])
+
+    def test_pejsa_unsupported(self):
+        ammo = Ammo(self.carrier, Velocity.FPS(3000),
+                    phases=[ProjectilePhase(start_distance=Distance.Yard(10), dm=self.flechette)])
+        with self.assertRaises(ValueError):
+            Calculator(engine=PejsaCalc).fire(Shot(weapon=Weapon(2), ammo=ammo), Distance.Yard(500))
+
+
+if __name__ == '__main__':
+    unittest.main()
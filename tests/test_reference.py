@@ -0,0 +1,67 @@
+"""Unittests for comparison with reference trajectories"""
+
+import os
+import tempfile
+import unittest
+from py_ballisticcalc import *
+
+
+class TestReferenceTrajectory(unittest.TestCase):
+
+    def setUp(self) -> None:
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        self.shot = Shot(weapon=Weapon(2, 12, zero_elevation=Angular.MOA(4)), ammo=Ammo(dm, Velocity.FPS(2750)))
+        self.calc = Calculator()
+
+    def test_self_comparison(self):
+        hit = self.calc.fire(self.shot, Distance.Yard(1000), Distance.Yard(100))
+        reference = ReferenceTrajectory(
+            [ReferenceRow(r.distance, r.target_drop, r.windage, r.velocity, r.time) for r in hit.trajectory[1:]])
+        comparison = reference.compare(self.calc, self.shot)
+        self.assertEqual(len(comparison.residuals), 10)
+        stats = comparison.stats()
+        self.assertEqual(set(stats), {'drop', 'windage', 'velocity', 'time'})
+        for name, s in stats.items():
+            with self.subTest(quantity=name):
+                self.assertEqual(s.count, 10)
+                self.assertAlmostEqual(s.max_abs, 0, 6)
+
+    def test_from_csv(self):
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, 'jbm.csv')
+            with open(path, 'w', encoding='utf-8') as fp:
+                fp.write('Range [yd],Drop [in],Velocity [fps],Time [s],Windage\n'
+                         '500,-50.0,1860.0,0.6744,\n'
+                         '100,0.0,2545.0,0.1131,0.1in\n')
+            reference = ReferenceTrajectory.from_csv(path)
+        self.assertEqual(reference.name, path)
+        self.assertEqual([r.distance >> Distance.Yard for r in reference.rows], [100, 500])
+        self.assertIsNone(reference.rows[1].windage)
+        self.assertAlmostEqual(reference.rows[0].windage >> Distance.Inch, 0.1)
+
+        hit = self.calc.fire_at_ranges(self.shot, [Distance.Yard(100), Distance.Yard(500)])
+        comparison = reference.compare(self.calc, self.shot)
+        residual = comparison.residuals[1]
+        self.assertAlmostEqual(residual.drop >> Distance.Inch, (hit[1].target_drop >> Distance.Inch) + 50, 6)
+        self.assertAlmostEqual(residual.velocity >> Velocity.FPS, (hit[1].velocity >> Velocity.FPS) - 1860, 6)
+        self.assertAlmostEqual(residual.time, hit[1].time - 0.6744, 9)
+        self.assertIsNone(residual.windage)
+        stats = comparison.stats()
+        self.assertEqual(stats['windage'].count, 1)
+        self.assertEqual(stats['drop'].count, 2)
+        self.assertGreaterEqual(stats['drop'].rms, abs(stats['drop'].mean))
+
+    def test_invalid(self):
+        with self.assertRaises(KeyError):
+            ReferenceTrajectory.from_dicts([{'distance': 100, 'energy': 1000}])
+        with self.assertRaises(KeyError):
+            ReferenceTrajectory.from_dicts([{'distance [furlong]': 1}])
+        with self.assertRaises(ValueError):
+            ReferenceTrajectory.from_dicts([{'drop': -1}])
+        reference = ReferenceTrajectory.from_dicts([{'range': '10mi', 'drop': '-100ft'}])
+        with self.assertRaises(ArithmeticError):
+            reference.compare(self.calc, self.shot)
+
+
+if __name__ == '__main__':
+    unittest.main()
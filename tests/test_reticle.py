@@ -0,0 +1,63 @@
+"""Unittests for the reticle holdover chart"""
+
+import unittest
+from py_ballisticcalc import *
+
+
+class TestReticle(unittest.TestCase):
+
+    def setUp(self) -> None:
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        self.shot = Shot(weapon=Weapon(2, 10), ammo=Ammo(dm, Velocity.FPS(2750)))
+        self.calc = Calculator()
+        self.calc.set_weapon_zero(self.shot, Distance.Yard(100))
+        self.reticle = Reticle([Angular.Mil(m) for m in (4, 1, 2, 3)])
+
+    def test_holdovers(self):
+        marks = reticle_holdovers(self.calc, self.shot, self.reticle, Distance.Yard(1000))
+        self.assertEqual([round(m.mark >> Angular.Mil, 6) for m in marks], [1, 2, 3, 4])
+        distances = [m.distance >> Distance.Yard for m in marks]
+        self.assertEqual(distances, sorted(distances))
+        self.assertGreater(distances[0], 100)
+        for mark in marks:
+            # The trajectory at the distance drops by the hold of the mark
+            self.assertAlmostEqual(-(mark.point.drop_adj >> Angular.Mil), mark.hold >> Angular.Mil, 2)
+            self.assertAlmostEqual(mark.point.look_distance >> Distance.Yard, mark.distance >> Distance.Yard)
+        # Center of the reticle holds for the zero
+        zero = reticle_holdovers(self.calc, self.shot, Reticle([0]), Distance.Yard(500))[0]
+        self.assertAlmostEqual(zero.distance >> Distance.Yard, 100, 0)
+
+    def test_beyond_trajectory(self):
+        marks = reticle_holdovers(self.calc, self.shot, self.reticle, Distance.Yard(500))
+        self.assertIsNotNone(marks[0].distance)
+        self.assertIsNone(marks[-1].distance)
+        self.assertIsNone(marks[-1].point)
+        card = holdover_card(self.calc, self.shot, self.reticle, Distance.Yard(500), units=Unit.Mil)
+        self.assertEqual(card[0], ('mark', 'range'))
+        self.assertEqual(len(card), 5)
+        self.assertTrue(card[1][0].startswith('1.0'))
+        self.assertEqual(card[-1][1], '-')
+
+    def test_second_focal_plane(self):
+        sfp = Reticle(self.reticle.marks, Sight.FocalPlane.SFP, magnification=10)
+        ffp = reticle_holdovers(self.calc, self.shot, self.reticle, Distance.Yard(1000))
+        # At half the calibrated magnification marks subtend twice the angle
+        half = reticle_holdovers(self.calc, self.shot, sfp, Distance.Yard(1000), magnification=5)
+        self.assertAlmostEqual(half[0].hold >> Angular.Mil, 2)
+        self.assertAlmostEqual(half[0].distance >> Distance.Yard, ffp[1].distance >> Distance.Yard, 3)
+        same = reticle_holdovers(self.calc, self.shot, sfp, Distance.Yard(1000), magnification=10)
+        self.assertAlmostEqual(same[2].distance >> Distance.Yard, ffp[2].distance >> Distance.Yard)
+        with self.assertRaises(ValueError):
+            reticle_holdovers(self.calc, self.shot, sfp, Distance.Yard(1000))
+
+    def test_invalid(self):
+        with self.assertRaises(ValueError):
+            Reticle([])
+        with self.assertRaises(ValueError):
+            Reticle([1], Sight.FocalPlane.SFP)
+        with self.assertRaises(ValueError):
+            Reticle([1], Sight.FocalPlane.LWIR)
+
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,82 @@
+"""Unittests for quaternion and rotation matrix helpers"""
+
+import math
+import unittest
+
+from py_ballisticcalc.rotation import (Quaternion, rotation_matrix_x, rotation_matrix_y,
+                                       rotation_matrix_z, matrix_multiply, matrix_vector,
+                                       matrix_transpose)
+from py_ballisticcalc.vector import Vector
+
+
+class TestRotation(unittest.TestCase):
+
+    def assertVectorAlmostEqual(self, a: Vector, b: Vector, places: int = 9):
+        self.assertAlmostEqual(a.x, b.x, places)
+        self.assertAlmostEqual(a.y, b.y, places)
+        self.assertAlmostEqual(a.z, b.z, places)
+
+    def test_axis_rotations(self):
+        q = Quaternion.from_axis_angle(Vector(0, 0, 1), math.pi / 2)
+        self.assertVectorAlmostEqual(q.rotate(Vector(1, 0, 0)), Vector(0, 1, 0))
+        self.assertVectorAlmostEqual(matrix_vector(rotation_matrix_z(math.pi / 2), Vector(1, 0, 0)),
+                                     Vector(0, 1, 0))
+        self.assertVectorAlmostEqual(matrix_vector(rotation_matrix_x(math.pi / 2), Vector(0, 1, 0)),
+                                     Vector(0, 0, 1))
+        self.assertVectorAlmostEqual(matrix_vector(rotation_matrix_y(math.pi / 2), Vector(0, 0, 1)),
+                                     Vector(1, 0, 0))
+
+    def test_euler_matches_barrel_direction(self):
+        """Rotated bore axis must match the direction used by the trajectory calculation"""
+        azimuth, elevation = 0.02, 0.15
+        q = Quaternion.from_euler(azimuth, elevation, 0.3)
+        expected = Vector(math.cos(elevation) * math.cos(azimuth),
+                          math.sin(elevation),
+                          math.cos(elevation) * math.sin(azimuth))
+        self.assertVectorAlmostEqual(q.rotate(Vector(1, 0, 0)), expected)
+
+    def test_cant_matches_sight_offset(self):
+        """Cant rotates the sight height offset as in the trajectory calculation"""
+        cant, sight_height = 0.4, 0.2
+        v = Quaternion.from_euler(0, 0, cant).rotate(Vector(0, -sight_height, 0))
+        self.assertVectorAlmostEqual(v, Vector(0, -math.cos(cant) * sight_height,
+                                               -math.sin(cant) * sight_height))
+
+    def test_euler_round_trip(self):
+        for angles in ((0.1, 0.2, 0.3), (-1.2, 0.7, -2.5), (3.0, -1.4, 0.0)):
+            with self.subTest(angles=angles):
+                result = Quaternion.from_euler(*angles).to_euler()
+                for a, b in zip(angles, result):
+                    self.assertAlmostEqual(a, b, 9)
+
+    def test_composition(self):
+        a = Quaternion.from_axis_angle(Vector(1, 2, 3), 0.7)
+        b = Quaternion.from_axis_angle(Vector(-2, 0.5, 1), -1.1)
+        v = Vector(0.3, -4, 2.5)
+        self.assertVectorAlmostEqual((a * b).rotate(v), a.rotate(b.rotate(v)))
+        self.assertVectorAlmostEqual(matrix_vector(matrix_multiply(a.to_matrix(), b.to_matrix()), v),
+                                     (a * b).rotate(v))
+
+    def test_inverse(self):
+        q = Quaternion.from_euler(0.5, -0.25, 1.0)
+        v = Vector(1, 2, 3)
+        self.assertVectorAlmostEqual(q.conjugate().rotate(q.rotate(v)), v)
+        self.assertVectorAlmostEqual(q.inverse().rotate(q.rotate(v)), v)
+        self.assertVectorAlmostEqual(matrix_vector(matrix_transpose(q.to_matrix()), q.rotate(v)), v)
+
+    def test_matrix_round_trip(self):
+        for angles in ((0.1, 0.2, 0.3), (2.9, 0.1, -3.0), (0.0, 0.0, math.pi - 0.01)):
+            with self.subTest(angles=angles):
+                q = Quaternion.from_euler(*angles)
+                r = Quaternion.from_matrix(q.to_matrix())
+                # q and -q represent the same rotation
+                sign = 1 if q.w * r.w + q.x * r.x + q.y * r.y + q.z * r.z > 0 else -1
+                for a, b in zip(q, r):
+                    self.assertAlmostEqual(a, sign * b, 9)
+
+    def test_degenerate_axis(self):
+        self.assertEqual(Quaternion.from_axis_angle(Vector(0, 0, 0), 1.0), Quaternion.identity())
+
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,62 @@
+"""Unittests for the Siacci trajectory model"""
+
+import unittest
+from py_ballisticcalc import *
+
+
+class TestSiacci(unittest.TestCase):
+
+    def setUp(self) -> None:
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        self.shot = Shot(weapon=Weapon(2, 12), ammo=Ammo(dm, Velocity.FPS(2750)),
+                         winds=[Wind(Velocity.MPH(10), Angular.Degree(90))])
+        self.numeric = Calculator()
+        self.siacci = Calculator(engine=SiacciCalc)
+
+    def assertRelative(self, a: float, b: float, tolerance: float):
+        self.assertLess(abs(a - b), tolerance * abs(b), f'{a} != {b} within {tolerance:.1%}')
+
+    def compare(self, shot: Shot, max_range: Distance, step: Distance, tolerance: float, moa: float, windage_tolerance: float):
+        expected = self.numeric.fire(shot, max_range, step)
+        actual = self.siacci.fire(shot, max_range, step)
+        self.assertEqual(len(actual.trajectory), len(expected.trajectory))
+        for a, e in zip(actual.trajectory[1:], expected.trajectory[1:]):
+            with self.subTest(distance=e.distance):
+                self.assertRelative(a.velocity >> Velocity.FPS, e.velocity >> Velocity.FPS, tolerance)
+                self.assertRelative(a.time, e.time, tolerance)
+                self.assertAlmostEqual(a.drop_adj >> Angular.MOA, e.drop_adj >> Angular.MOA, delta=moa)
+                self.assertRelative(a.windage >> Distance.Inch, e.windage >> Distance.Inch, windage_tolerance)
+
+    def test_flat_fire(self):
+        zero = self.numeric.barrel_elevation_for_target(self.shot, Distance.Yard(100))
+        self.assertAlmostEqual(self.siacci.barrel_elevation_for_target(self.shot, Distance.Yard(100)) >> Angular.MOA,
+                               zero >> Angular.MOA, 1)
+        self.shot.weapon.zero_elevation = zero
+        self.compare(self.shot, Distance.Yard(1000), Distance.Yard(200), 0.002, 0.01, 0.01)
+
+    def test_blackpowder(self):
+        dm = DragModel(0.281, TableG1, 405, 0.458, 1.15)
+        shot = Shot(weapon=Weapon(1, 18), ammo=Ammo(dm, Velocity.FPS(1330)))
+        self.siacci.set_weapon_zero(shot, Distance.Yard(200))
+        self.assertAlmostEqual(self.numeric.barrel_elevation_for_target(shot, Distance.Yard(200)) >> Angular.MOA,
+                               shot.weapon.zero_elevation >> Angular.MOA, 1)
+        self.compare(shot, Distance.Yard(800), Distance.Yard(100), 0.005, 0.05, 0.01)
+
+    def test_inclined_fire(self):
+        self.shot.look_angle = Angular.Degree(15)
+        self.shot.weapon.zero_elevation = Angular.MOA(4)
+        self.compare(self.shot, Distance.Yard(1000), Distance.Yard(200), 0.015, 0.5, 0.02)
+
+    def test_table_functions(self):
+        calc = SiacciCalc(self.shot.ammo)
+        calc.trajectory(self.shot, Distance.Yard(100), Distance.Yard(100))
+        table = calc.table
+        for column in (table.s, table.t, table.a, table.i):
+            self.assertTrue(all(a < b for a, b in zip(column, column[1:])))
+        u, s, *_ = table.at_velocity(2000)
+        self.assertAlmostEqual(u, 2000)
+        self.assertAlmostEqual(table.at_space(s)[0], 2000, 6)
+
+
+if __name__ == '__main__':
+    unittest.main()
@@ -1,6 +1,6 @@
 import unittest
 
-from py_ballisticcalc import Sight, Unit
+from py_ballisticcalc import Sight, Unit, Angular
 
 
 class TestSight(unittest.TestCase):
@@ -82,4 +82,25 @@ class TestSight(unittest.TestCase):
                                        Unit.Mil(1),
                                        Unit.Mil(1),
                                        case['mag']).vertical
-                self.assertAlmostEqual(adj, case['adj'], places=7)
\ No newline at end of file
+                self.assertAlmostEqual(adj, case['adj'], places=7)
+
+    def test_round_clicks(self):
+        rounded = Sight.round_clicks(Unit.Mil(0.76), 7.6)
+        self.assertEqual(rounded.clicks, 8)
+        self.assertEqual(rounded.residual.units, Unit.Mil)
+        self.assertAlmostEqual(rounded.residual >> Angular.Mil, -0.04)
+        self.assertEqual(Sight.round_clicks(Unit.MOA(-0.625), -2.5).clicks, -3)
+        self.assertEqual(Sight.round_clicks(Unit.MOA(0.625), 2.5).clicks, 3)
+        zero = Sight.round_clicks(Unit.MOA(0), 0)
+        self.assertEqual(zero.clicks, 0)
+        self.assertEqual(zero.residual.raw_value, 0)
+
+    def test_rounded_adjustment(self):
+        s = Sight(focal_plane=Sight.FocalPlane.LWIR, scale_factor=Unit.Meter(100),
+                  h_click_size=Unit.Mil(0.25), v_click_size=Unit.Mil(0.25))
+        # Clicks of LWIR sight are 0.25 / 2 mil at magnification 2
+        rounded = s.get_rounded_adjustment(Unit.Meter(100), Unit.Mil(1.3), Unit.Mil(-0.2), 2)
+        self.assertEqual(rounded.vertical.clicks, 10)
+        self.assertAlmostEqual(rounded.vertical.residual >> Angular.Mil, 0.05)
+        self.assertEqual(rounded.horizontal.clicks, -2)
+        self.assertAlmostEqual(rounded.horizontal.residual >> Angular.Mil, 0.05)
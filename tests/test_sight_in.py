@@ -0,0 +1,64 @@
+"""Unittests for sight-in corrections from observed group offsets"""
+
+import unittest
+from dataclasses import replace
+from py_ballisticcalc import *
+
+
+class TestSightIn(unittest.TestCase):
+
+    def setUp(self) -> None:
+        self.sight = Sight(Sight.FocalPlane.FFP, 0, Angular.MRad(0.1), Angular.MRad(0.1))
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        self.shot = Shot(weapon=Weapon(Distance.Inch(2), 12, sight=self.sight), ammo=Ammo(dm, Velocity.FPS(2750)))
+        self.calc = Calculator()
+        self.calc.set_weapon_zero(self.shot, Distance.Meter(100))
+
+    def test_same_distance(self):
+        correction = sight_in_correction(Distance.Centimeter(-4), Distance.Centimeter(-6), Distance.Meter(100),
+                                         self.sight)
+        self.assertAlmostEqual(correction.elevation >> Angular.MRad, 0.4, 2)
+        self.assertAlmostEqual(correction.windage >> Angular.MRad, 0.6, 2)
+        self.assertAlmostEqual(correction.clicks.vertical, 4, 1)
+        self.assertAlmostEqual(correction.clicks.horizontal, 6, 1)
+        self.assertIsNone(correction.zero_elevation)
+        high_right = sight_in_correction(Distance.Centimeter(4), Distance.Centimeter(6), Distance.Meter(100))
+        self.assertLess(high_right.elevation.raw_value, 0)
+        self.assertLess(high_right.windage.raw_value, 0)
+        self.assertIsNone(high_right.clicks)
+
+    def test_rezero_at_group_distance(self):
+        expected = sight_in_correction(Distance.Centimeter(-4), Distance.Centimeter(-6), Distance.Meter(100),
+                                       self.sight)
+        correction = rezero_correction(self.calc, self.shot, Distance.Centimeter(-4), Distance.Centimeter(-6),
+                                       Distance.Meter(100))
+        # The trajectory crosses the sight line at its zero, so only the spin drift differs
+        self.assertAlmostEqual(correction.clicks.vertical, expected.clicks.vertical, 1)
+        self.assertAlmostEqual(correction.windage >> Angular.MRad, expected.windage >> Angular.MRad, delta=0.1)
+
+    def test_rezero_from_other_distance(self):
+        # Sight is off by 0.5 mrad low and 0.3 mrad left; the group is shot at 200 m to zero at 100 m
+        error = Angular.MRad(0.5)
+        actual = replace(self.shot, weapon=replace(self.shot.weapon, zero_elevation=Angular.Radian(
+            (self.shot.weapon.zero_elevation >> Angular.Radian) - (error >> Angular.Radian))))
+        row = self.calc.fire_at_ranges(actual, [Distance.Meter(200)]).trajectory[0]
+        horizontal = Distance.Meter((row.windage >> Distance.Meter) - 200 * 0.0003)
+        correction = rezero_correction(self.calc, actual, row.target_drop, horizontal, Distance.Meter(200),
+                                       Distance.Meter(100))
+        self.assertAlmostEqual(correction.elevation >> Angular.MRad, 0.5, 1)
+        self.assertAlmostEqual(correction.zero_elevation >> Angular.MRad,
+                               self.shot.weapon.zero_elevation >> Angular.MRad, 1)
+        # Windage removes the sight error and the drift calculated at 100 m
+        drift = self.calc.fire_at_ranges(self.shot, [Distance.Meter(100)]).trajectory[0].windage_adj
+        self.assertAlmostEqual(correction.windage >> Angular.MRad, 0.3 - (drift >> Angular.MRad), 2)
+        corrected = replace(actual, weapon=replace(actual.weapon, zero_elevation=correction.zero_elevation))
+        at_zero = self.calc.fire_at_ranges(corrected, [Distance.Meter(100)]).trajectory[0]
+        self.assertAlmostEqual(at_zero.target_drop >> Distance.Centimeter, 0, 0)
+
+    def test_unreachable(self):
+        with self.assertRaises(ArithmeticError):
+            rezero_correction(self.calc, self.shot, 0, 0, Distance.Meter(100), Distance.Kilometer(30))
+
+
+if __name__ == '__main__':
+    unittest.main()
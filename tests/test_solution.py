@@ -0,0 +1,57 @@
+"""Unittests for firing solutions"""
+
+import math
+import unittest
+from py_ballisticcalc import *
+
+
+class TestFiringSolution(unittest.TestCase):
+
+    def setUp(self) -> None:
+        self.calc = Calculator()
+        sight = Sight(Sight.FocalPlane.FFP, 0, Angular.MRad(0.1), Angular.MRad(0.1))
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        self.shot = Shot(weapon=Weapon(Distance.Inch(2), 12, sight=sight), ammo=Ammo(dm, Velocity.FPS(2750)),
+                         winds=[Wind(Velocity.MPH(10), Angular.OClock(3))])
+        self.calc.set_weapon_zero(self.shot, Distance.Yard(100))
+
+    def test_solution(self):
+        solution = firing_solution(self.calc, self.shot, Distance.Yard(600))
+        row = self.calc.fire_at_ranges(self.shot, [Distance.Yard(600)])[0]
+        self.assertAlmostEqual(solution.elevation >> Angular.MRad, -(row.drop_adj >> Angular.MRad))
+        self.assertAlmostEqual(solution.windage >> Angular.MRad, -(row.windage_adj >> Angular.MRad))
+        self.assertGreater(solution.elevation >> Angular.MOA, 0)  # Dial up beyond the zero
+        self.assertLess(solution.windage >> Angular.MOA, 0)  # Wind from the right pushes left: dial left
+        self.assertAlmostEqual(solution.clicks.vertical, (solution.elevation >> Angular.MRad) * 10)
+        self.assertAlmostEqual(solution.clicks.horizontal, (solution.windage >> Angular.MRad) * 10)
+        rounded = solution.rounded_clicks
+        self.assertEqual(rounded.vertical.clicks, round(solution.clicks.vertical))
+        self.assertEqual(rounded.horizontal.clicks, round(solution.clicks.horizontal))
+        for whole, angle in ((rounded.vertical, solution.elevation), (rounded.horizontal, solution.windage)):
+            self.assertIsInstance(whole.clicks, int)
+            self.assertAlmostEqual(whole.clicks * 0.1 + (whole.residual >> Angular.MRad), angle >> Angular.MRad)
+            self.assertLessEqual(abs(whole.residual >> Angular.MRad), 0.05 + 1e-9)
+        self.assertEqual(solution.time, row.time)
+        self.assertEqual(solution.velocity, row.velocity)
+        self.assertGreater(solution.energy >> Energy.FootPound, 0)
+
+    def test_incline(self):
+        level = firing_solution(self.calc, self.shot, Distance.Yard(600))
+        uphill = firing_solution(self.calc, self.shot, Distance.Yard(600), Angular.Degree(30))
+        self.assertAlmostEqual(uphill.look_angle >> Angular.Degree, 30)
+        self.assertAlmostEqual(uphill.trajectory.distance >> Distance.Yard, 600 * math.cos(math.radians(30)), 6)
+        self.assertAlmostEqual(uphill.trajectory.look_distance >> Distance.Yard, 600, 6)
+        self.assertLess(uphill.elevation >> Angular.MOA, level.elevation >> Angular.MOA)
+        self.assertEqual(self.shot.look_angle >> Angular.Degree, 0)  # Shot is not changed
+
+    def test_without_sight(self):
+        self.shot.weapon.sight = None
+        self.assertIsNone(firing_solution(self.calc, self.shot, 300).clicks)
+        self.assertIsNone(firing_solution(self.calc, self.shot, 300).rounded_clicks)
+        with self.assertRaises(ArithmeticError):
+            firing_solution(Calculator(config=CalculatorConfig(minimum_velocity=Velocity.FPS(2500))),
+                            self.shot, Distance.Yard(600))
+
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,66 @@
+"""Unittests for the Miller stability and twist rate recommendation"""
+
+import unittest
+from py_ballisticcalc import *
+from py_ballisticcalc.trajectory_calc import TrajectoryCalc
+
+
+class TestStability(unittest.TestCase):
+
+    def test_matches_trajectory_calc(self):
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        shot = Shot(weapon=Weapon(2, 12), ammo=Ammo(dm, Velocity.FPS(2750)),
+                    atmo=Atmo.icao(Distance.Foot(3000)))
+        calc = TrajectoryCalc(shot.ammo)
+        calc._init_trajectory(shot)
+        self.assertAlmostEqual(miller_stability(168, 0.308, 1.282, 12, 2750, shot.atmo),
+                               calc.stability_coefficient)
+        self.assertEqual(miller_stability(168, 0.308, 1.282, 0, 2750), 0)
+        self.assertAlmostEqual(miller_stability(168, 0.308, 1.282, -12, 2750),
+                               miller_stability(168, 0.308, 1.282, 12, 2750))
+
+    def test_recommend_twist(self):
+        velocities = [Velocity.FPS(2600), Velocity.FPS(2800)]
+        altitudes = [Distance.Foot(0), Distance.Foot(5000)]
+        result = recommend_twist(Weight.Grain(168), Distance.Inch(0.308), Distance.Inch(1.282),
+                                 velocities, altitudes)
+        self.assertEqual(len(result.requirements), 4)
+        # Slowest velocity in the densest air needs the fastest twist
+        self.assertAlmostEqual(result.limiting.velocity >> Velocity.FPS, 2600)
+        self.assertAlmostEqual(result.limiting.atmo.altitude >> Distance.Foot, 0)
+        twist = result.twist >> Distance.Inch
+        for requirement in result.requirements:
+            stability = miller_stability(168, 0.308, 1.282, twist, requirement.velocity, requirement.atmo)
+            self.assertGreaterEqual(stability, 1.5 - 1e-9)
+        self.assertAlmostEqual(miller_stability(168, 0.308, 1.282, twist, 2600), 1.5)
+        self.assertAlmostEqual(result.stability(), 1.5)
+        self.assertGreater(result.stability(Distance.Inch(10)), 1.5)
+
+    def test_longer_bullet_needs_faster_twist(self):
+        short = recommend_twist(168, 0.308, 1.2, [2700])
+        long = recommend_twist(168, 0.308, 1.4, [2700])
+        self.assertLess(long.twist.raw_value, short.twist.raw_value)
+
+    def test_hit_result_warning(self):
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        calc = Calculator()
+        warnings = {}
+        for twist in (10, 12, 18, 0):
+            shot = Shot(weapon=Weapon(2, twist), ammo=Ammo(dm, Velocity.FPS(2750)))
+            result = calc.fire(shot, Distance.Yard(100))
+            self.assertAlmostEqual(result.stability, miller_stability(168, 0.308, 1.282, twist, 2750, shot.atmo))
+            warnings[twist] = result.stability_warning
+        self.assertEqual(warnings, {10: None, 12: 'marginal', 18: 'unstable', 0: None})
+        with self.assertLogs('py_balcalc', 'DEBUG') as logs:
+            calc.fire(Shot(weapon=Weapon(2, 18), ammo=Ammo(dm, Velocity.FPS(2750))), Distance.Yard(100))
+        self.assertEqual([record.levelname for record in logs.records], ['DEBUG'])
+
+    def test_invalid(self):
+        with self.assertRaises(ValueError):
+            recommend_twist(168, 0.308, 0, [2700])
+        with self.assertRaises(ValueError):
+            recommend_twist(168, 0.308, 1.282, [])
+
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,68 @@
+"""Unittests for streaming trajectory rows"""
+
+import unittest
+from py_ballisticcalc import *
+
+
+class TestStream(unittest.TestCase):
+
+    def setUp(self) -> None:
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        self.shot = Shot(weapon=Weapon(2, 10, zero_elevation=Angular.MOA(4)), ammo=Ammo(dm, Velocity.FPS(2750)))
+        self.calc = Calculator()
+        self.expected = self.calc.fire(self.shot, Distance.Yard(1000), Distance.Yard(100)).trajectory
+
+    def test_fire_stream(self):
+        rows = []
+        reason = self.calc.fire_stream(self.shot, Distance.Yard(1000), rows.append, Distance.Yard(100))
+        self.assertEqual(reason, 'maximum_range')
+        self.assertEqual([row.formatted() for row in rows], [row.formatted() for row in self.expected])
+
+    def test_stop_from_callback(self):
+        rows = []
+
+        def on_row(row: TrajectoryData) -> bool:
+            rows.append(row)
+            return (row.distance >> Distance.Yard) >= 300
+
+        reason = self.calc.fire_stream(self.shot, Distance.Yard(1000), on_row, Distance.Yard(100))
+        self.assertEqual(reason, 'cancelled')
+        self.assertEqual(len(rows), 4)
+        self.assertAlmostEqual(rows[-1].distance >> Distance.Yard, 300)
+        # Hooks of the calculator are restored
+        self.assertIsNone(self.calc.hooks)
+        self.assertEqual(self.calc.fire(self.shot, Distance.Yard(1000)).termination_reason, 'maximum_range')
+
+    def test_fire_iter(self):
+        rows = list(self.calc.fire_iter(self.shot, Distance.Yard(1000), Distance.Yard(100)))
+        self.assertEqual([row.formatted() for row in rows], [row.formatted() for row in self.expected])
+        every_step = 0
+        for row in self.calc.fire_iter(self.shot, Distance.Yard(1000), extra_data=True):
+            every_step += 1
+        self.assertGreater(every_step, 1000)
+
+    def test_fire_iter_closed_early(self):
+        terminations = []
+        self.calc.hooks = TrajectoryHooks(on_termination=lambda state, reason: terminations.append(reason))
+        for row in self.calc.fire_iter(self.shot, Distance.Yard(1000), extra_data=True):
+            if (row.distance >> Distance.Yard) > 100:
+                break
+        self.assertEqual(terminations, ['cancelled'])
+        # Calculator is free for other calls once the iterator is closed
+        self.assertEqual(self.calc.fire(self.shot, Distance.Yard(1000)).termination_reason, 'maximum_range')
+
+    def test_fire_iter_error(self):
+        def on_step(state: StepState):
+            if state.position.x > 600:
+                raise ValueError("Failed in the calculation thread")
+
+        self.calc.hooks = TrajectoryHooks(on_step=on_step)
+        rows = []
+        with self.assertRaises(ValueError):
+            for row in self.calc.fire_iter(self.shot, Distance.Yard(1000), Distance.Yard(100)):
+                rows.append(row)
+        self.assertEqual(len(rows), 3)
+
+
+if __name__ == '__main__':
+    unittest.main()
@@ -1,8 +1,12 @@
 """Unittests for the py_ballisticcalc library"""
 
+import math
 import unittest
 from math import fabs
 from py_ballisticcalc import *
+from py_ballisticcalc.drag_model import make_data_points
+from py_ballisticcalc.trajectory_calc import (calculate_curve, calculate_by_curve,
+                                              calculate_by_curve_index, find_curve_index)
 
 
 class TestTrajectory(unittest.TestCase):
@@ -31,6 +35,91 @@ class TestTrajectory(unittest.TestCase):
         self.assertAlmostEqual(zero_angle >> Angular.Radian, 0.001228, 6,
                                f'TestZero2 failed {zero_angle >> Angular.Radian:.10f}')
 
+    def test_zero_height(self):
+        """Trajectory of a fresh TrajectoryCalc's zero angle crosses the sight line at the zero distance"""
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        ammo = Ammo(dm, 2750)
+        for look_angle in (0, 5):
+            shot = Shot(weapon=Weapon(2), ammo=ammo, look_angle=Angular.Degree(look_angle))
+            zero = TrajectoryCalc(ammo).zero_angle(shot, Distance.Yard(300))
+            self.assertNotAlmostEqual(zero >> Angular.Radian, shot.look_angle >> Angular.Radian, 4)
+            shot.weapon.zero_elevation = Angular.Radian((zero >> Angular.Radian) - (shot.look_angle >> Angular.Radian))
+            zero_distance = Distance.Yard(300 * math.cos(math.radians(look_angle)))
+            row = TrajectoryCalc(ammo).trajectory(shot, zero_distance, zero_distance)[-1]
+            self.assertAlmostEqual(row.distance >> Distance.Yard, zero_distance >> Distance.Yard, 6)
+            self.assertAlmostEqual(row.height >> Distance.Inch, 10800 * math.sin(math.radians(look_angle)), delta=0.1)
+
+    def test_zero_without_spin_effects(self):
+        """Spin lift, yaw of repose forces and transonic degradation are off while zeroing, like spin drift"""
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        shot = Shot(weapon=Weapon(2, 12), ammo=Ammo(dm, 2750, aerodynamics=AeroCoefficients(1.0, 1.0)))
+        expected = Calculator(config=CalculatorConfig(use_spin_drift=False)).barrel_elevation_for_target(
+            shot, Distance.Yard(300))
+        degradation = TransonicDegradation(stability_threshold=100, mach_threshold=5)
+        for calc in (Calculator(config=CalculatorConfig(spin_drift_model=SpinDriftModel.YAW_OF_REPOSE,
+                                                        transonic_degradation=degradation)),
+                     Calculator(engine=ModifiedPointMassCalc)):
+            with self.subTest(engine=calc.engine):
+                zero = calc.barrel_elevation_for_target(shot, Distance.Yard(300))
+                self.assertAlmostEqual(zero >> Angular.Radian, expected >> Angular.Radian, 9)
+        # Trajectories fired at the zero include them
+        calc = Calculator(config=CalculatorConfig(transonic_degradation=degradation))
+        calc.set_weapon_zero(shot, Distance.Yard(300))
+        self.assertLess(calc.fire(shot, Distance.Yard(300), Distance.Yard(300))[-1].height >> Distance.Inch, -0.1)
+
+    def test_zero_warm_start(self):
+        """Repeated zeroing of the same weapon and ammo starts from the previous solution"""
+        iterations = []
+        calc = Calculator(hooks=TrajectoryHooks(on_termination=lambda state, reason: iterations.append(reason)))
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        shot = Shot(weapon=Weapon(2), ammo=Ammo(dm, 2750))
+        cold = calc.barrel_elevation_for_target(shot, Distance.Yard(300))
+        cold_iterations = len(iterations)
+        iterations.clear()
+        calc.invalidate_zero()  # Skip the sight angle cache
+        warm = calc.barrel_elevation_for_target(shot, Distance.Yard(300))
+        self.assertLess(len(iterations), cold_iterations)
+        self.assertAlmostEqual(warm >> Angular.Radian, cold >> Angular.Radian, 6)
+
+        # Different atmosphere still converges to the same answer as a cold start
+        shot.atmo = Atmo.icao(altitude=Distance.Meter(2000))
+        warm = calc.barrel_elevation_for_target(shot, Distance.Yard(300))
+        cold = Calculator().barrel_elevation_for_target(shot, Distance.Yard(300))
+        self.assertAlmostEqual(warm >> Angular.Radian, cold >> Angular.Radian, 6)
+
+        # Sweeps don't grow the cache without limit
+        for variant in muzzle_velocity_sweep(shot, [Velocity.FPS(2600 + v) for v in range(70)]):
+            calc.barrel_elevation_for_target(variant, Distance.Yard(100))
+        self.assertEqual(len(calc._zero_cache), 64)
+        self.assertEqual(len(calc.clone()._zero_cache), 64)
+
+    def test_sight_angle_cache(self):
+        """Repeated zeroing with unchanged inputs doesn't run zero finding"""
+        iterations = []
+        calc = Calculator(hooks=TrajectoryHooks(on_termination=lambda state, reason: iterations.append(reason)))
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        shot = Shot(weapon=Weapon(2), ammo=Ammo(dm, 2750))
+        first = calc.set_weapon_zero(shot, Distance.Yard(300))
+        self.assertGreater(len(iterations), 0)
+        iterations.clear()
+        # Changed zero_elevation doesn't affect the sight angle
+        second = calc.set_weapon_zero(shot, Distance.Yard(300))
+        self.assertEqual(len(iterations), 0)
+        self.assertEqual(first, second)
+
+        shot.atmo = Atmo.icao(altitude=Distance.Meter(2000))
+        calc.barrel_elevation_for_target(shot, Distance.Yard(300))
+        self.assertGreater(len(iterations), 0)
+        iterations.clear()
+        dm.BC = 0.25
+        calc.barrel_elevation_for_target(shot, Distance.Yard(300))
+        self.assertGreater(len(iterations), 0)
+
+        iterations.clear()
+        calc.invalidate_zero()
+        calc.barrel_elevation_for_target(shot, Distance.Yard(300))
+        self.assertGreater(len(iterations), 0)
+
     def custom_assert_equal(self, a, b, accuracy, name):
         with self.subTest(name=name):
             self.assertLess(fabs(a - b), accuracy, f'Equality {name} failed (|{a} - {b}|, {accuracy} digits)')
@@ -114,6 +203,231 @@ class TestTrajectory(unittest.TestCase):
             with self.subTest(f"validate one {i}"):
                 self.validate_one(*d)
 
+    def test_trajectory_out_buffer(self):
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        shot_info = Shot(weapon=Weapon(2, 12), ammo=Ammo(dm, Velocity.FPS(2750)))
+        calc = TrajectoryCalc(shot_info.ammo)
+        expected = calc.trajectory(shot_info, Distance.Yard(1000), Distance.Yard(100))
+
+        buffer = []
+        for _ in range(2):
+            buffer.clear()
+            data = calc.trajectory(shot_info, Distance.Yard(1000), Distance.Yard(100), out=buffer)
+            self.assertIs(data, buffer)
+            self.assertEqual([row.formatted() for row in data], [row.formatted() for row in expected])
+
+    def test_trajectory_columns(self):
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        shot_info = Shot(weapon=Weapon(2, 12, zero_elevation=Angular.MOA(4)), ammo=Ammo(dm, Velocity.FPS(2750)))
+        calc = Calculator(config=CalculatorConfig(minimum_velocity=Velocity.FPS(1500)))
+        hit = calc.fire(shot_info, Distance.Yard(1000), Distance.Yard(100))
+        units = {'distance': Unit.Meter, 'height': Unit.Centimeter}
+        columns = calc.fire_columns(shot_info, Distance.Yard(1000), Distance.Yard(100), units=units)
+        self.assertEqual(len(columns), len(hit.trajectory))
+        self.assertEqual(columns.termination_reason, 'minimum_velocity')
+        self.assertEqual(columns.distance.typecode, 'd')
+        for i, row in enumerate(hit):
+            self.assertAlmostEqual(columns.distance[i], row.distance >> Distance.Meter, 9)
+            self.assertAlmostEqual(columns.height[i], row.height >> Distance.Centimeter, 9)
+            self.assertAlmostEqual(columns.velocity[i], row.velocity >> PreferredUnits.velocity, 9)
+            self.assertEqual(columns.flag[i], row.flag)
+            self.assertEqual(columns[i].formatted(), row.formatted())
+        self.assertEqual([r.formatted() for r in hit.columns(units)], [r.formatted() for r in columns])
+        self.assertEqual(hit.columns().termination_reason, 'minimum_velocity')
+        columns.clear()
+        self.assertEqual(len(columns), 0)
+        with self.assertRaises(KeyError):
+            TrajectoryColumns({'time': Unit.Meter})
+
+    def test_fire_at_ranges(self):
+        """Rows are recorded exactly at the requested distances"""
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        shot_info = Shot(weapon=Weapon(2, 12, zero_elevation=Angular.MOA(4)), ammo=Ammo(dm, Velocity.FPS(2750)))
+        requested = [Distance.Meter(d) for d in (840, 100, 230, 575, 0)]
+        hit = Calculator().fire_at_ranges(shot_info, requested)
+        self.assertEqual(len(hit.trajectory), len(requested))
+        self.assertFalse(hit.incomplete)
+        reference = Calculator().fire(shot_info, Distance.Meter(900), Distance.Meter(5)).trajectory
+        for distance, row in zip(sorted(d >> Distance.Meter for d in requested), hit):
+            with self.subTest(distance=distance):
+                self.assertAlmostEqual(row.distance >> Distance.Meter, distance, 9)
+                self.assertTrue(row.flag & TrajFlag.RANGE.value)
+                # Matches rows recorded at step multiples to within interpolation error
+                expected = next(r for r in reference if abs((r.distance >> Distance.Meter) - distance) < 0.5)
+                self.assertAlmostEqual(row.height >> Distance.Inch, expected.height >> Distance.Inch, 0)
+                self.assertAlmostEqual(row.time, expected.time, 2)
+        with self.assertRaises(ValueError):
+            Calculator().fire_at_ranges(shot_info, [])
+
+    def test_fire_terminal(self):
+        """Only the row at the target distance, and optionally the zero crossings, is recorded"""
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        shot_info = Shot(weapon=Weapon(2, 12), ammo=Ammo(dm, Velocity.FPS(2750)))
+        calc = Calculator()
+        calc.set_weapon_zero(shot_info, Distance.Yard(100))
+        hit = calc.fire_terminal(shot_info, Distance.Yard(600))
+        self.assertEqual(len(hit.trajectory), 1)
+        expected = calc.fire_at_ranges(shot_info, [Distance.Yard(600)])[0]
+        self.assertEqual(hit[0].formatted(), expected.formatted())
+        with_zeros = calc.fire_terminal(shot_info, Distance.Yard(600), zero_crossings=True)
+        self.assertEqual([row.flag for row in with_zeros],
+                         [TrajFlag.ZERO_UP.value, TrajFlag.ZERO_DOWN.value, TrajFlag.RANGE.value])
+        self.assertFalse(with_zeros.extra)
+        self.assertAlmostEqual(with_zeros[1].distance >> Distance.Yard, 100, 0)
+        self.assertEqual(with_zeros.flag_at(with_zeros[1].distance), TrajFlag.ZERO_DOWN)
+        with self.assertRaises(AttributeError):
+            with_zeros.zeros()
+        self.assertEqual(with_zeros[-1].formatted(), expected.formatted())
+        slow = Calculator(config=CalculatorConfig(minimum_velocity=Velocity.FPS(2000)))
+        with self.assertRaises(RangeError) as error:
+            slow.fire_terminal(shot_info, Distance.Yard(900), zero_crossings=True)
+        self.assertEqual(error.exception.reason, 'minimum_velocity')
+        with self.assertRaises(ValueError):
+            calc.fire_terminal(shot_info, Distance.Yard(-1))
+        pejsa = Calculator(engine=PejsaCalc).fire_terminal(shot_info, Distance.Yard(600), zero_crossings=True)
+        self.assertEqual(len(pejsa.trajectory), 1)
+
+    def test_fire_by_time(self):
+        """Rows are recorded at exact multiples of the time step"""
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        shot_info = Shot(weapon=Weapon(2, 12, zero_elevation=Angular.MOA(4)), ammo=Ammo(dm, Velocity.FPS(2750)))
+        hit = Calculator().fire_by_time(shot_info, 1.5, 0.01)
+        self.assertEqual(len(hit.trajectory), 151)
+        self.assertFalse(hit.incomplete)
+        for i, row in enumerate(hit):
+            self.assertAlmostEqual(row.time, 0.01 * i, 9)
+        ranges = Calculator().fire_at_ranges(shot_info, [row.distance for row in hit[1::30]])
+        for expected, row in zip(ranges, hit[1::30]):
+            with self.subTest(time=row.time):
+                self.assertAlmostEqual(row.time, expected.time, 6)
+                self.assertAlmostEqual(row.height >> Distance.Inch, expected.height >> Distance.Inch, 2)
+        self.assertEqual(len(Calculator().fire_by_time(shot_info, 1).trajectory), 11)
+        in_units = Calculator().fire_by_time(shot_info, Time.Millisecond(1500), Time.Millisecond(10))
+        self.assertEqual([row.formatted() for row in in_units], [row.formatted() for row in hit])
+        self.assertAlmostEqual(hit.at_time(Time.Millisecond(1234)).time, 1.234, 9)
+        stopped = Calculator(config=CalculatorConfig(minimum_velocity=Velocity.FPS(2000))).fire_by_time(shot_info, 2)
+        self.assertTrue(stopped.incomplete)
+        self.assertLess(len(stopped.trajectory), 11)
+        with self.assertRaises(ValueError):
+            Calculator().fire_by_time(shot_info, 0)
+
+    def test_at(self):
+        """Interpolated queries between rows match rows calculated at the same distance"""
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        shot_info = Shot(weapon=Weapon(2, 12, zero_elevation=Angular.MOA(4)), ammo=Ammo(dm, Velocity.FPS(2750)))
+        dense = Calculator().fire(shot_info, Distance.Yard(1000), extra_data=True)
+        expected = Calculator().fire_at_ranges(shot_info, [Distance.Yard(437.1)])[0]
+        row = dense.at(Distance.Yard(437.1))
+        self.assertAlmostEqual(row.distance >> Distance.Yard, 437.1, 9)
+        self.assertEqual(row.flag, TrajFlag.NONE.value)
+        for field in ('height', 'windage', 'velocity', 'drop_adj', 'energy'):
+            with self.subTest(field=field):
+                a, b = getattr(row, field), getattr(expected, field)
+                self.assertAlmostEqual(a >> b.units, b.unit_value, 3)
+        self.assertAlmostEqual(row.time, expected.time, 6)
+        self.assertAlmostEqual(dense.at_time(row.time).distance >> Distance.Yard, 437.1, 3)
+        coarse = Calculator().fire(shot_info, Distance.Yard(1000), Distance.Yard(100))
+        self.assertIs(coarse.at(Distance.Yard(300)), coarse[3])
+        self.assertIs(coarse.at(0), coarse[0])
+        self.assertAlmostEqual(coarse.at(Distance.Yard(437)).height >> Distance.Inch,
+                               expected.height >> Distance.Inch, delta=2)
+        with self.assertRaises(ArithmeticError):
+            coarse.at(Distance.Yard(1001))
+
+    def test_range_error(self):
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        shot = Shot(weapon=Weapon(2, 12), ammo=Ammo(dm, Velocity.FPS(2750)))
+        slow = Calculator(config=CalculatorConfig(minimum_velocity=Velocity.FPS(2000)))
+        result = slow.fire(shot, Distance.Yard(1000), Distance.Yard(100))
+        with self.assertRaises(RangeError) as error:
+            result.at(Distance.Yard(900))
+        self.assertIsInstance(error.exception, ArithmeticError)
+        self.assertEqual(error.exception.reason, 'minimum_velocity')
+        self.assertIs(error.exception.last_point, result[-1])
+        self.assertLess(error.exception.achieved_distance >> Distance.Yard, 900)
+        with self.assertRaises(RangeError) as error:
+            firing_solution(slow, shot, Distance.Yard(900))
+        self.assertEqual(error.exception.reason, 'minimum_velocity')
+        short = Calculator(config=CalculatorConfig(maximum_drop=Distance.Foot(-10)))
+        with self.assertRaises(RangeError) as error:
+            short.fire_string(shot, 2, Distance.Yard(1000))
+        self.assertEqual(error.exception.reason, 'maximum_drop')
+        # Rows of fire_at_ranges() are only at the requested distances
+        self.assertIsNone(error.exception.achieved_distance)
+
+    def test_invalid_range(self):
+        shot = Shot(weapon=Weapon(2, 12), ammo=Ammo(DragModel(0.223, TableG7), Velocity.FPS(2750)))
+        calc = Calculator()
+        for trajectory_range, step in ((0, 0), (-100, 0), (100, -10), (Distance.Yard(100), Distance.Yard(-1))):
+            with self.subTest(trajectory_range=trajectory_range, step=step):
+                with self.assertRaises(ValueError):
+                    calc.fire(shot, trajectory_range, step)
+                with self.assertRaises(ValueError):
+                    calc.fire_columns(shot, trajectory_range, step)
+                with self.assertRaises(ValueError):
+                    list(calc.fire_volley([shot], trajectory_range, step))
+                with self.assertRaises(ValueError):
+                    next(calc.fire_iter(shot, trajectory_range, step))
+        # Step defaults to a tenth of the range
+        result = calc.fire(shot, Distance.Yard(500))
+        self.assertEqual(len(result.trajectory), 11)
+        self.assertAlmostEqual(result.trajectory_step >> Distance.Yard, 50)
+        self.assertAlmostEqual(calc.fire(shot, Distance.Yard(500), Distance.Yard(25)).trajectory_step
+                               >> Distance.Yard, 25)
+        self.assertAlmostEqual(next(calc.fire_volley([shot], Distance.Meter(300))).trajectory_step
+                               >> Distance.Meter, 30)
+        self.assertIsNone(calc.fire(shot, Distance.Yard(500), extra_data=True).trajectory_step)
+        self.assertIsNone(calc.fire_at_ranges(shot, [Distance.Yard(100)]).trajectory_step)
+
+    def test_exact_range_rows(self):
+        """Rows are interpolated onto exact multiples of trajectory_step, not the first step past them"""
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        shot_info = Shot(weapon=Weapon(2, 12, zero_elevation=Angular.MOA(4)), ammo=Ammo(dm, Velocity.FPS(2750)))
+        hit = Calculator().fire(shot_info, Distance.Yard(1000), Distance.Yard(100))
+        self.assertEqual(len(hit.trajectory), 11)
+        for i, row in enumerate(hit):
+            self.assertAlmostEqual(row.distance >> Distance.Yard, 100 * i, 9)
+
+    def test_events(self):
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        shot_info = Shot(weapon=Weapon(2, 12), ammo=Ammo(dm, Velocity.FPS(2750)))
+        calc = Calculator()
+        calc.set_weapon_zero(shot_info, Distance.Yard(300))
+        hit = calc.fire(shot_info, Distance.Yard(1500), extra_data=True)
+        events = hit.events()
+        self.assertEqual([TrajFlag(row.flag) & TrajFlag.EVENTS for row in events],
+                         [TrajFlag.ZERO_UP, TrajFlag.APEX, TrajFlag.ZERO_DOWN, TrajFlag.MACH])
+        apex = hit.apex()
+        self.assertAlmostEqual(apex.height >> Distance.Inch, max(row.height >> Distance.Inch for row in hit), 3)
+        self.assertLess(apex.distance >> Distance.Yard, 300)
+        self.assertAlmostEqual(events[2].distance >> Distance.Yard, 300, 0)
+        self.assertAlmostEqual(events[3].distance >> Distance.Foot, hit.subsonic_distance() >> Distance.Foot,
+                               delta=0.5)
+        self.assertEqual(len([row for row in hit if row.flag & TrajFlag.APEX.value]), 1)
+        self.assertEqual(hit.zero_distances(), [events[0].distance, events[2].distance])
+        self.assertTrue(hit.flag_at(events[2].distance) & TrajFlag.ZERO_DOWN)
+        self.assertEqual(hit.flag_at(apex.distance) & TrajFlag.EVENTS, TrajFlag.APEX)
+        with self.assertRaises(ArithmeticError):
+            hit.flag_at(Distance.Yard(2000))
+
+        shot_info.weapon.zero_elevation = Angular.Degree(-1)
+        with self.assertRaises(ArithmeticError):
+            calc.fire(shot_info, Distance.Yard(100), extra_data=True).apex()
+        with self.assertRaises(AttributeError):
+            calc.fire(shot_info, Distance.Yard(100)).apex()
+
+    def test_curve_index_hint(self):
+        """Walking from any index hint must find the same drag curve segment as binary search"""
+        data = make_data_points(TableG7)
+        curve = calculate_curve(data)
+        hint = -1
+        for mach in [3.5 - 0.01 * i for i in range(350)] + [5.0, 0.0, -1.0, 1.0, 4.5, 0.3]:
+            with self.subTest(mach=mach):
+                hint = find_curve_index(data, mach, hint)
+                self.assertEqual(hint, find_curve_index(data, mach))
+                self.assertEqual(calculate_by_curve_index(data, curve, mach, hint),
+                                 calculate_by_curve(data, curve, mach))
+
 
 if __name__ == '__main__':
     unittest.main()
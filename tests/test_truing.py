@@ -0,0 +1,59 @@
+"""Unittests for truing BC and muzzle velocity to observed drops"""
+
+import unittest
+from dataclasses import replace
+from py_ballisticcalc import *
+
+
+class TestTruing(unittest.TestCase):
+
+    def setUp(self) -> None:
+        self.calc = Calculator()
+        self.weapon = Weapon(Distance.Inch(2), 12)
+        self.nominal = Ammo(DragModel(0.223, TableG7, 168, 0.308, 1.282), Velocity.FPS(2750))
+        self.shot = Shot(weapon=self.weapon, ammo=self.nominal)
+        self.calc.set_weapon_zero(self.shot, Distance.Yard(100))
+        self.distances = [Distance.Yard(d) for d in (400, 600, 800, 1000)]
+
+    def observe(self, ammo: Ammo) -> list[DropObservation]:
+        actual = Shot(weapon=replace(self.weapon), ammo=ammo)
+        self.calc.set_weapon_zero(actual, Distance.Yard(100))
+        return [DropObservation(row.distance, row.drop_adj)
+                for row in self.calc.fire_at_ranges(actual, self.distances).trajectory]
+
+    def test_true_bc(self):
+        observations = self.observe(Ammo(DragModel(0.2, TableG7, 168, 0.308, 1.282), Velocity.FPS(2750)))
+        result = true_ammo(self.calc, self.shot, observations, zero_distance=Distance.Yard(100))
+        self.assertEqual(result.parameter, 'bc')
+        self.assertAlmostEqual(result.ammo.dm.BC, 0.2, 4)
+        self.assertAlmostEqual(result.ammo.dm.weight >> Weight.Grain, 168)
+        self.assertAlmostEqual(result.rms >> Angular.MOA, 0, 2)
+        self.assertEqual(len(result.residuals), len(observations))
+        # The original shot is not changed
+        self.assertEqual(self.shot.ammo.dm.BC, 0.223)
+
+    def test_true_velocity(self):
+        observations = self.observe(Ammo(DragModel(0.223, TableG7, 168, 0.308, 1.282), Velocity.FPS(2650)))
+        result = true_ammo(self.calc, self.shot, [(o.distance, o.elevation) for o in observations], 'velocity',
+                           Distance.Yard(100))
+        self.assertAlmostEqual(result.ammo.mv >> Velocity.FPS, 2650, 0)
+        self.assertAlmostEqual(result.factor, 2650 / 2750, 4)
+
+    def test_dope_book_impacts(self):
+        observations = self.observe(Ammo(DragModel(0.21, TableG7, 168, 0.308, 1.282), Velocity.FPS(2750)))
+        with DopeBook() as book:
+            scenario_id = book.add_scenario('308', self.shot)
+            for observation in observations:
+                book.add_impact(scenario_id, observation.distance, observation.elevation)
+            result = true_ammo(self.calc, self.shot, book.impacts(scenario_id), zero_distance=Distance.Yard(100))
+        self.assertAlmostEqual(result.ammo.dm.BC, 0.21, 3)
+
+    def test_invalid(self):
+        with self.assertRaises(ValueError):
+            true_ammo(self.calc, self.shot, [], zero_distance=Distance.Yard(100))
+        with self.assertRaises(ValueError):
+            true_ammo(self.calc, self.shot, [(Distance.Yard(500), Angular.MOA(10))], 'twist')
+
+
+if __name__ == '__main__':
+    unittest.main()
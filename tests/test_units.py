@@ -1,3 +1,5 @@
+import datetime
+import json
 import unittest
 from dataclasses import dataclass
 
@@ -32,6 +34,24 @@ class TestPrefUnits(unittest.TestCase):
         self.assertEqual(tc3.as_metadata_str.units, Unit.Centimeter)
         self.assertEqual(tc3.as_metadata_unit.units, Unit.Meter)
 
+    def test_profiles(self):
+        saved = PreferredUnits.current()
+        try:
+            PreferredUnits.use_metric()
+            self.assertEqual(PreferredUnits.current(), METRIC_UNITS)
+            self.assertEqual(PreferredUnits.distance(100).units, Unit.Meter)
+            PreferredUnits.set(adjustment='MOA')
+            self.assertEqual(PreferredUnits.velocity, Unit.MPS)
+            self.assertEqual(PreferredUnits.adjustment, Unit.MOA)
+            PreferredUnits.use_imperial()
+            self.assertEqual(PreferredUnits.current(), IMPERIAL_UNITS)
+            PreferredUnits.set(**METRIC_UNITS)
+            self.assertEqual(PreferredUnits.temperature, Unit.Celsius)
+            PreferredUnits.defaults()
+            self.assertEqual(PreferredUnits.current(), IMPERIAL_UNITS)
+        finally:
+            PreferredUnits.set(**saved)
+
 
 class TestUnitsParser(unittest.TestCase):
     def test_parse_values(self):
@@ -119,7 +139,10 @@ class TestEnergy(unittest.TestCase):
         self.unit_class = Energy
         self.unit_list = [
             Energy.FootPound,
-            Energy.Joule
+            Energy.Joule,
+            Energy.Kilojoule,
+            Energy.Megajoule,
+            Energy.KgfMeter
         ]
 
     def test_energy(self):
@@ -127,6 +150,12 @@ class TestEnergy(unittest.TestCase):
             with self.subTest(unit=u):
                 back_n_forth(self, 3, u)
 
+    def test_conversion(self):
+        self.assertAlmostEqual(Energy.Kilojoule(3.5) >> Energy.Joule, 3500)
+        self.assertAlmostEqual(Energy.Megajoule(0.002) >> Energy.Kilojoule, 2)
+        self.assertAlmostEqual(Energy.KgfMeter(1) >> Energy.Joule, 9.80665)
+        self.assertEqual(Unit.parse_unit('kgf*m'), Unit.KgfMeter)
+
 
 class TestPressure(unittest.TestCase):
 
@@ -136,7 +165,11 @@ class TestPressure(unittest.TestCase):
             Pressure.Bar,
             Pressure.hPa,
             Pressure.MmHg,
-            Pressure.InHg
+            Pressure.InHg,
+            Pressure.PSI,
+            Pressure.Pascal,
+            Pressure.kPa,
+            Pressure.Millibar
         ]
 
     def test_pressure(self):
@@ -144,6 +177,14 @@ class TestPressure(unittest.TestCase):
             with self.subTest(unit=u):
                 back_n_forth(self, 3, u)
 
+    def test_conversion(self):
+        self.assertAlmostEqual(Pressure.Pascal(101325) >> Pressure.hPa, 1013.25)
+        self.assertAlmostEqual(Pressure.kPa(101.325) >> Pressure.Millibar, 1013.25)
+        self.assertAlmostEqual(Pressure.Millibar(1000) >> Pressure.Bar, 1)
+        self.assertAlmostEqual(Pressure.kPa(101.325) >> Pressure.InHg, 29.92, 2)
+        for alias, unit in (('Pa', Unit.Pascal), ('kPa', Unit.kPa), ('mbar', Unit.Millibar)):
+            self.assertEqual(Unit.parse_unit(alias), unit)
+
 
 class TestTemperature(unittest.TestCase):
 
@@ -199,6 +240,34 @@ class TestWeight(unittest.TestCase):
                 back_n_forth(self, 3, u)
 
 
+class TestTime(unittest.TestCase):
+    def setUp(self) -> None:
+        self.unit_class = Time
+        self.unit_list = [
+            Time.Second,
+            Time.Millisecond,
+            Time.Minute,
+        ]
+
+    def test_time(self):
+        for u in self.unit_list:
+            with self.subTest(unit=u):
+                back_n_forth(self, 3, u)
+
+    def test_conversions(self):
+        flight = Time.Millisecond(1347)
+        self.assertAlmostEqual(flight >> Time.Second, 1.347)
+        self.assertEqual(str(flight << Time.Second), '1.347s')
+        self.assertAlmostEqual(Time.Minute(1.5) >> Time.Second, 90)
+        self.assertEqual(Unit.parse_value('250ms', Unit.Second).units, Unit.Millisecond)
+        self.assertEqual(flight.timedelta(), datetime.timedelta(seconds=1.347))
+        self.assertEqual(flight.clock(), '0:01.35')
+        self.assertEqual(Time.Second(75.5).clock(1), '1:15.5')
+        self.assertEqual(Time.Second(59.999).clock(), '1:00.00')
+        self.assertEqual(Time.Second(-5).clock(0), '-0:05')
+        self.assertEqual(Time.from_dict(flight.to_dict()).units, Unit.Millisecond)
+
+
 class TestUnitConversionSyntax(unittest.TestCase):
 
     def setUp(self) -> None:
@@ -251,3 +320,45 @@ class TestUnitConversionSyntax(unittest.TestCase):
         self.assertEqual(converted.units, desired_units)
         self.low <<= desired_units
         self.assertEqual(self.low.units, desired_units)
+
+
+class TestUnitJSON(unittest.TestCase):
+
+    def test_round_trip(self):
+        for value in (Distance.Yard(100), Angular.MOA(2.5), Velocity.MPS(800), Weight.Grain(168),
+                      Pressure.InHg(29.92), Temperature.Celsius(-15), Energy.Joule(3500)):
+            with self.subTest(value=value):
+                data = value.to_dict()
+                self.assertEqual(data, {'value': value.unit_value, 'units': value.units.name})
+                restored = value.__class__.from_dict(data)
+                self.assertEqual(restored.units, value.units)
+                self.assertAlmostEqual(restored.raw_value, value.raw_value)
+
+    def test_aliases(self):
+        self.assertEqual(Distance.from_dict({'value': 100, 'units': 'yard'}).units, Unit.Yard)
+        self.assertEqual(AbstractUnit.from_dict({'value': 3, 'units': 'mil'}).units, Unit.Mil)
+        self.assertEqual(Velocity.from_dict({'value': 10, 'units': 'MPH'}).units, Unit.MPH)
+        with self.assertRaises(UnitTypeError):
+            Distance.from_dict({'value': 100, 'units': 'fps'})
+        with self.assertRaises(UnitAliasError):
+            Distance.from_dict({'value': 100, 'units': 'furlong'})
+        with self.assertRaises(ValueError):
+            Distance.from_dict({'value': 100})
+        self.assertEqual(Distance.from_dict({'value': 100, 'units': Unit.Yard}).units, Unit.Yard)
+        self.assertEqual(Distance.from_dict({'value': 100, 'units': 17}).units, Unit.Meter)
+        for units in (999, 1.5, None, ['yd']):
+            with self.subTest(units=units), self.assertRaises(UnitAliasError):
+                Distance.from_dict({'value': 100, 'units': units})
+
+    def test_json(self):
+        data = {'range': Distance.Meter(500), 'winds': [Velocity.MPH(5), Velocity.MPH(10)], 'name': 'test'}
+        text = json.dumps(data, cls=UnitJSONEncoder)
+        self.assertEqual(json.loads(text)['range']['units'], 'Meter')
+        self.assertAlmostEqual(json.loads(text)['range']['value'], 500)
+        restored = json.loads(text, object_hook=unit_json_hook)
+        self.assertEqual(restored['name'], 'test')
+        self.assertIsInstance(restored['range'], Distance)
+        self.assertAlmostEqual(restored['winds'][1] >> Velocity.MPH, 10)
+        self.assertEqual(json.loads('{"value": 100, "units": "yard"}', object_hook=unit_json_hook).units, Unit.Yard)
+        with self.assertRaises(TypeError):
+            json.dumps(object(), cls=UnitJSONEncoder)
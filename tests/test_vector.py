@@ -0,0 +1,54 @@
+"""Unittests for the Vector helper"""
+
+import math
+import unittest
+from py_ballisticcalc.vector import Vector
+
+
+class TestVector(unittest.TestCase):
+
+    def test_add_in_place(self):
+        v = Vector(1, 2, 3)
+        result = v.add_in_place(Vector(4, 5, 6))
+        self.assertIs(result, v)
+        self.assertEqual(v, Vector(5, 7, 9))
+
+    def test_mul_add_in_place(self):
+        v = Vector(1, 2, 3)
+        result = v.mul_add_in_place(Vector(1, -1, 2), 0.5)
+        self.assertIs(result, v)
+        self.assertEqual(v, Vector(1.5, 1.5, 4))
+
+    def test_mul_add_matches_value_semantics(self):
+        v, b = Vector(10, -3, 0.5), Vector(0.25, 4, -2)
+        expected = v + b * 3.0
+        self.assertEqual(v.mul_add_in_place(b, 3.0), expected)
+
+    def test_dot(self):
+        self.assertEqual(Vector(1, 2, 3).dot(Vector(4, -5, 6)), 12)
+        self.assertEqual(Vector(1, 2, 3) * Vector(4, -5, 6), 12)
+
+    def test_cross(self):
+        x, y, z = Vector(1, 0, 0), Vector(0, 1, 0), Vector(0, 0, 1)
+        self.assertEqual(x.cross(y), z)
+        self.assertEqual(y.cross(z), x)
+        self.assertEqual(z.cross(x), y)
+        self.assertEqual(y.cross(x), -z)
+        a, b = Vector(2, -1, 3), Vector(0.5, 4, -2)
+        c = a.cross(b)
+        self.assertAlmostEqual(c.dot(a), 0)
+        self.assertAlmostEqual(c.dot(b), 0)
+
+    def test_angle_to(self):
+        self.assertAlmostEqual(Vector(1, 0, 0).angle_to(Vector(0, 3, 0)), math.pi / 2)
+        self.assertAlmostEqual(Vector(1, 1, 0).angle_to(Vector(2, 0, 0)), math.pi / 4)
+        self.assertAlmostEqual(Vector(1, 0, 0).angle_to(Vector(-1, 0, 0)), math.pi)
+        self.assertEqual(Vector(0, 0, 0).angle_to(Vector(1, 0, 0)), 0)
+
+    def test_project_onto(self):
+        self.assertEqual(Vector(3, 4, 5).project_onto(Vector(0, 2, 0)), Vector(0, 4, 0))
+        self.assertEqual(Vector(3, 4, 5).project_onto(Vector(0, 0, 0)), Vector(0, 0, 0))
+
+
+if __name__ == '__main__':
+    unittest.main()
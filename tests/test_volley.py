@@ -0,0 +1,132 @@
+"""Unittests for solving sequences of shots"""
+
+import threading
+import unittest
+from dataclasses import FrozenInstanceError
+from py_ballisticcalc import *
+
+
+class TestVolley(unittest.TestCase):
+
+    def setUp(self) -> None:
+        dm = DragModel(0.223, TableG7, 168, 0.308, 1.282)
+        self.shot = Shot(weapon=Weapon(2, 12), ammo=Ammo(dm, Velocity.FPS(2750)))
+        self.calc = Calculator()
+        self.calc.set_weapon_zero(self.shot, Distance.Yard(100))
+
+    def test_elevation_sweep(self):
+        angles = [Angular.MOA(a) for a in (0, 5, 10)]
+        results = list(self.calc.fire_volley(relative_angle_sweep(self.shot, angles), Distance.Yard(500)))
+        self.assertEqual(len(results), len(angles))
+        heights = [r[-1].height.raw_value for r in results]
+        self.assertEqual(heights, sorted(heights))
+        # Each result matches an individual fire() of the same shot
+        single = self.calc.fire(results[1].shot, Distance.Yard(500))
+        self.assertEqual([p.formatted() for p in single], [p.formatted() for p in results[1]])
+        self.assertEqual(self.shot.relative_angle.raw_value, 0)
+
+    def test_muzzle_velocity_sweep(self):
+        velocities = [Velocity.FPS(v) for v in (2700, 2750, 2800)]
+        results = self.calc.fire_volley(muzzle_velocity_sweep(self.shot, velocities),
+                                        Distance.Yard(600), Distance.Yard(100))
+        first = next(results)  # Results are produced lazily
+        self.assertAlmostEqual(first[0].velocity >> Velocity.FPS, 2700)
+        times = [first[-1].time] + [r[-1].time for r in results]
+        self.assertEqual(times, sorted(times, reverse=True))
+        self.assertAlmostEqual(self.shot.ammo.mv >> Velocity.FPS, 2750)
+
+    def test_with_copies(self):
+        ammo = self.shot.ammo
+        faster = ammo.with_mv(Velocity.FPS(2800))
+        self.assertAlmostEqual(faster.mv >> Velocity.FPS, 2800)
+        self.assertAlmostEqual(ammo.mv >> Velocity.FPS, 2750)
+        self.assertIs(faster.dm, ammo.dm)
+        better = ammo.with_bc(0.25)
+        self.assertEqual(better.dm.BC, 0.25)
+        self.assertEqual(ammo.dm.BC, 0.223)
+        self.assertIs(better.dm.drag_table, ammo.dm.drag_table)
+        self.assertEqual(better.dm.weight, ammo.dm.weight)
+        with self.assertRaises(ValueError):
+            ammo.with_bc(0)
+        weapon = self.shot.weapon.with_sight_height(Distance.Inch(3)).with_twist(Distance.Inch(8))
+        self.assertAlmostEqual(weapon.sight_height >> Distance.Inch, 3)
+        self.assertAlmostEqual(weapon.twist >> Distance.Inch, 8)
+        self.assertEqual(weapon.zero_elevation, self.shot.weapon.zero_elevation)
+        self.assertNotAlmostEqual(self.shot.weapon.sight_height >> Distance.Inch, 3)
+        # Variant flies as a rebuilt shot does
+        rebuilt = Shot(weapon=self.shot.weapon, ammo=Ammo(DragModel(0.25, TableG7, 168, 0.308, 1.282), Velocity.FPS(2750)))
+        variant = Shot(weapon=self.shot.weapon, ammo=better)
+        self.assertEqual([p.formatted() for p in self.calc.fire(rebuilt, Distance.Yard(500))],
+                         [p.formatted() for p in self.calc.fire(variant, Distance.Yard(500))])
+
+    def test_fire_batch(self):
+        scenarios = [ShotScenario(shot, Distance.Yard(500), Distance.Yard(100))
+                     for shot in relative_angle_sweep(self.shot, [Angular.MOA(a) for a in (0, 5, 10, 15, 20)])]
+        scenarios.append((self.shot, Distance.Yard(300)))
+        sequential = self.calc.fire_batch(scenarios, workers=1)
+        parallel = self.calc.fire_batch(scenarios, workers=3)
+        self.assertEqual(len(parallel), len(scenarios))
+        for scenario, a, b in zip(scenarios, sequential, parallel):
+            self.assertEqual([p.formatted() for p in a], [p.formatted() for p in b])
+            self.assertAlmostEqual(b.shot.relative_angle >> Angular.MOA, scenario[0].relative_angle >> Angular.MOA)
+        self.assertAlmostEqual(parallel[-1][-1].distance >> Distance.Yard, 300)
+        with self.assertRaises(ValueError):
+            self.calc.fire_batch(scenarios, workers=0)
+
+    def test_clone(self):
+        calc = Calculator(config=CalculatorConfig(max_calc_step_size=Distance.Foot(0.5)))
+        calc.set_weapon_zero(self.shot, Distance.Yard(100))
+        clone = calc.clone()
+        self.assertIs(clone.config, calc.config)
+        # Cached zero carries over
+        self.assertEqual(clone.barrel_elevation_for_target(self.shot, Distance.Yard(100)),
+                         calc.barrel_elevation_for_target(self.shot, Distance.Yard(100)))
+        # Shared config is frozen
+        with self.assertRaises(FrozenInstanceError):
+            calc.config.max_calc_step_size = Distance.Foot(5)
+        self.assertAlmostEqual(clone.config.max_calc_step_size >> Distance.Foot, 0.5)
+
+        expected = [p.formatted() for p in clone.fire(self.shot, Distance.Yard(800), Distance.Yard(100))]
+        results = [None] * 4
+
+        def fire(i):
+            results[i] = [p.formatted() for p in clones[i].fire(self.shot, Distance.Yard(800), Distance.Yard(100))]
+
+        clones = [clone.clone() for _ in results]
+        threads = [threading.Thread(target=fire, args=(i,)) for i in range(len(results))]
+        for thread in threads:
+            thread.start()
+        for thread in threads:
+            thread.join()
+        self.assertEqual(results, [expected] * len(results))
+
+    def test_mv_for_shot(self):
+        ammo = Ammo(self.shot.ammo.dm, Velocity.FPS(2750), cold_bore_offset=Velocity.FPS(-15),
+                    shot_mv_drift=Velocity.FPS(3))
+        self.assertAlmostEqual(ammo.mv_for_shot(1) >> Velocity.FPS, 2735)
+        self.assertAlmostEqual(ammo.mv_for_shot(2) >> Velocity.FPS, 2753)
+        self.assertAlmostEqual(ammo.mv_for_shot(5) >> Velocity.FPS, 2762)
+        self.assertAlmostEqual(self.shot.ammo.mv_for_shot(5) >> Velocity.FPS, 2750)
+        with self.assertRaises(ValueError):
+            ammo.mv_for_shot(0)
+
+    def test_fire_string(self):
+        ammo = Ammo(self.shot.ammo.dm, Velocity.FPS(2750), cold_bore_offset=Velocity.FPS(-15),
+                    shot_mv_drift=Velocity.FPS(3))
+        shot = Shot(weapon=self.shot.weapon, ammo=ammo)
+        string = self.calc.fire_string(shot, 5, Distance.Yard(600))
+        self.assertEqual(len(string.impacts), 5)
+        heights = [row.height >> Distance.Inch for row in string.impacts]
+        # Cold-bore shot is slow and lands lowest, then each heated shot lands higher
+        self.assertEqual(heights, sorted(heights))
+        self.assertAlmostEqual(string.vertical_spread >> Distance.Inch, heights[-1] - heights[0])
+        self.assertAlmostEqual(string.mean_height >> Distance.Inch, sum(heights) / 5)
+        for impact in string.impacts:
+            self.assertAlmostEqual(impact.distance >> Distance.Yard, 600, places=3)
+        # Without offsets the string has no vertical spread
+        flat = self.calc.fire_string(self.shot, 3, Distance.Yard(600))
+        self.assertAlmostEqual(flat.vertical_spread >> Distance.Inch, 0)
+
+
+if __name__ == '__main__':
+    unittest.main()